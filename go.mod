@@ -2,3 +2,4 @@ module puppeteer-command-server
 
 go 1.21
 
+require github.com/klauspost/compress v1.17.11
@@ -0,0 +1,91 @@
+import http from 'node:http';
+import type { AddressInfo } from 'node:net';
+import { chalk } from 'zx';
+
+import { BrowserManager } from '../src/browser/BrowserManager.js';
+
+// Measures open -> navigate -> screenshot -> close of a headless tab with and without the
+// page pool (PCS_PAGE_POOL_SIZE). The page is served locally so the numbers show what the
+// browser costs, not the network. Usage:
+//   npm run bench:pool -- [--iterations 50] [--pool-size 2]
+
+const PAGE = '<!doctype html><title>pcs</title><h1>Page pool benchmark</h1>';
+
+function option(args: string[], name: string, fallback: number): number {
+  const index = args.indexOf(`--${name}`);
+  const value = index === -1 ? fallback : Number(args[index + 1]);
+  if (!Number.isInteger(value) || value < 1) {
+    throw new Error(`--${name} must be a positive integer`);
+  }
+  return value;
+}
+
+function percentile(sorted: number[], p: number): number {
+  return sorted[Math.min(sorted.length - 1, Math.floor((sorted.length * p) / 100))] ?? 0;
+}
+
+// Milliseconds of each iteration, sorted
+async function measure(poolSize: number, url: string, iterations: number): Promise<number[]> {
+  // The pool size is read when the manager is created
+  process.env['PCS_PAGE_POOL_SIZE'] = String(poolSize);
+  const manager = new BrowserManager();
+  delete process.env['PCS_PAGE_POOL_SIZE'];
+
+  try {
+    await manager.initialize(true);
+    // One untimed round so the launch and the first page load stay out of the numbers,
+    // then give the pool time to warm its pages
+    await manager.closeTab(await manager.openTab({ url, headless: true }));
+    await new Promise(resolve => setTimeout(resolve, 1000));
+
+    const timings: number[] = [];
+    for (let i = 0; i < iterations; i++) {
+      const start = performance.now();
+      const tabId = await manager.openTab({ url: '', headless: true });
+      await manager.navigateTab(tabId, url);
+      await manager.captureScreenshot(tabId, {});
+      await manager.closeTab(tabId);
+      timings.push(performance.now() - start);
+    }
+    return timings.sort((a, b) => a - b);
+  } finally {
+    await manager.close();
+  }
+}
+
+function report(label: string, timings: number[]): number {
+  const mean = timings.reduce((sum, ms) => sum + ms, 0) / timings.length;
+  const p50 = percentile(timings, 50);
+  console.log(
+    `${label.padEnd(10)} mean ${mean.toFixed(1)} ms, p50 ${p50.toFixed(1)} ms, ` +
+      `p95 ${percentile(timings, 95).toFixed(1)} ms`
+  );
+  return p50;
+}
+
+async function main() {
+  const args = process.argv.slice(2);
+  const iterations = option(args, 'iterations', 30);
+  const poolSize = option(args, 'pool-size', 2);
+
+  const server = http.createServer((_req, res) => {
+    res.writeHead(200, { 'Content-Type': 'text/html' });
+    res.end(PAGE);
+  });
+  await new Promise<void>(resolve => server.listen(0, '127.0.0.1', resolve));
+  const url = `http://127.0.0.1:${(server.address() as AddressInfo).port}/`;
+
+  try {
+    console.log(chalk.blue(`Running ${iterations} iterations per mode...`));
+    const unpooled = report('unpooled', await measure(0, url, iterations));
+    const pooled = report(`pool of ${poolSize}`, await measure(poolSize, url, iterations));
+    console.log(chalk.green(`The pool saves ${(unpooled - pooled).toFixed(1)} ms per tab at p50`));
+  } finally {
+    server.close();
+  }
+}
+
+main().catch(err => {
+  console.error(chalk.red('Error during the benchmark:'), err);
+  process.exit(1);
+});
@@ -11,7 +11,6 @@ esbuild
     external: [
       'puppeteer-extra',
       'puppeteer-extra-plugin-stealth',
-      'puppeteer-extra-plugin-anonymize-ua',
       'puppeteer-extra-plugin-user-preferences'
     ],
     plugins: []
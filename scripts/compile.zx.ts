@@ -1,7 +1,8 @@
+import crypto from 'node:crypto';
 import path from 'node:path';
-import { $, chalk, which, within } from 'zx';
+import { $, chalk, fs, which, within } from 'zx';
 
-import { name } from '../package.json';
+import { name, version } from '../package.json';
 
 const BUILD_DIR = path.join(__dirname, '../build');
 
@@ -40,7 +41,8 @@ async function main() {
 
   if (buildLinux || buildAll) {
     pkgCommands.push(
-      $`npx pkg --public dist/server.js -c package.json ${compressionArgs} -t "node18-linux-x64" -o ${path.join(BUILD_DIR, 'linux', 'pcs')}`
+      $`npx pkg --public dist/server.js -c package.json ${compressionArgs} -t "node18-linux-x64" -o ${path.join(BUILD_DIR, 'linux', 'pcs')}`,
+      $`npx pkg --public dist/server.js -c package.json ${compressionArgs} -t "node18-linux-arm64" -o ${path.join(BUILD_DIR, 'linux-arm64', 'pcs')}`
     );
   }
 
@@ -102,8 +104,10 @@ async function main() {
   const SIG_BUILD_DIR = path.join(__dirname, '../sig/build');
 
   if (buildLinux || buildAll) {
-    await $`mkdir -p ${path.join(SIG_BUILD_DIR, 'linux')}`;
-    await $`cp ${path.join(BUILD_DIR, 'linux', 'pcs')} ${path.join(SIG_BUILD_DIR, 'linux', 'pcs')}`;
+    await $`mkdir -p ${path.join(SIG_BUILD_DIR, 'linux-amd64')}`;
+    await $`cp ${path.join(BUILD_DIR, 'linux', 'pcs')} ${path.join(SIG_BUILD_DIR, 'linux-amd64', 'pcs')}`;
+    await $`mkdir -p ${path.join(SIG_BUILD_DIR, 'linux-arm64')}`;
+    await $`cp ${path.join(BUILD_DIR, 'linux-arm64', 'pcs')} ${path.join(SIG_BUILD_DIR, 'linux-arm64', 'pcs')}`;
   }
 
   if (buildMac || buildAll) {
@@ -116,6 +120,25 @@ async function main() {
     await $`cp ${path.join(BUILD_DIR, 'win', 'pcs.exe')} ${path.join(SIG_BUILD_DIR, 'win', 'pcs.exe')}`;
   }
 
+  // Compress the payloads with zstd; the launcher embeds the .zst and decodes it only when
+  // extracting. zstd decodes a typical binary within the launcher's 50ms startup budget,
+  // which gzip misses several times over.
+  const zstd = await which('zstd').catch(() => null);
+  if (!zstd) {
+    throw new Error('zstd not found. Cannot compress the embedded binaries.');
+  }
+  console.log(chalk.blue('Compressing embedded binaries with zstd...'));
+  const payloads = [
+    ...(buildLinux || buildAll ? ['linux-amd64/pcs', 'linux-arm64/pcs'] : []),
+    ...(buildMac || buildAll ? ['mac/pcs'] : []),
+    ...(buildWin || buildAll ? ['win/pcs.exe'] : [])
+  ];
+  for (const payload of payloads) {
+    const payloadPath = path.join(SIG_BUILD_DIR, payload);
+    const level = fastMode ? '-1' : '-19';
+    await $`${zstd} ${level} -T0 -q -f ${payloadPath} -o ${payloadPath}.zst`;
+  }
+
   // Compile Go binaries for all platforms
   async function findGo() {
     const goPath = await which('go').catch(() => null);
@@ -132,6 +155,7 @@ async function main() {
 
     if (buildLinux || buildAll) {
       await $`mkdir -p ${SIG_BUILD_OUTPUT_DIR}/linux`;
+      await $`mkdir -p ${SIG_BUILD_OUTPUT_DIR}/linux-arm64`;
     }
     if (buildMac || buildAll) {
       await $`mkdir -p ${SIG_BUILD_OUTPUT_DIR}/mac`;
@@ -142,6 +166,27 @@ async function main() {
 
     const repoRoot = path.join(__dirname, '..');
 
+    // Stamp the launcher with the commit it was built from, when available
+    const commit = await $`git rev-parse --short HEAD`.then(
+      out => out.stdout.trim(),
+      () => ''
+    );
+    const launcherVersion = commit ? `${version}+${commit}` : version;
+
+    // Embed the SHA-256 of each uncompressed payload so the launcher can verify extracted
+    // binaries without inflating the embedded copy
+    const ldflags = (payload: string) => {
+      const hash = crypto
+        .createHash('sha256')
+        .update(fs.readFileSync(path.join(SIG_BUILD_DIR, payload)))
+        .digest('hex');
+      return [
+        `-X main.embeddedBinaryHash=${hash}`,
+        `-X main.version=${version}`,
+        `-X main.launcherVersion=${launcherVersion}`
+      ].join(' ');
+    };
+
     // Build for selected platforms
     await within(async () => {
       $.cwd = repoRoot;
@@ -151,7 +196,11 @@ async function main() {
         goBuilds.push(
           (async () => {
             console.log(chalk.blue('Building Go binary for Linux...'));
-            await $`GOOS=linux GOARCH=amd64 ${go} build -o ${path.join(SIG_BUILD_OUTPUT_DIR, 'linux/sig')} ./sig`;
+            await $`GOOS=linux GOARCH=amd64 ${go} build -ldflags ${ldflags('linux-amd64/pcs')} -o ${path.join(SIG_BUILD_OUTPUT_DIR, 'linux/sig')} ./sig`;
+          })(),
+          (async () => {
+            console.log(chalk.blue('Building Go binary for Linux ARM64...'));
+            await $`GOOS=linux GOARCH=arm64 ${go} build -ldflags ${ldflags('linux-arm64/pcs')} -o ${path.join(SIG_BUILD_OUTPUT_DIR, 'linux-arm64/sig')} ./sig`;
           })()
         );
       }
@@ -160,7 +209,11 @@ async function main() {
         goBuilds.push(
           (async () => {
             console.log(chalk.blue('Building Go binary for macOS...'));
-            await $`GOOS=darwin GOARCH=amd64 ${go} build -o ${path.join(SIG_BUILD_OUTPUT_DIR, 'mac/sig')} ./sig`;
+            await $`GOOS=darwin GOARCH=amd64 ${go} build -ldflags ${ldflags('mac/pcs')} -o ${path.join(SIG_BUILD_OUTPUT_DIR, 'mac/sig')} ./sig`;
+          })(),
+          (async () => {
+            console.log(chalk.blue('Building Go binary for macOS ARM64...'));
+            await $`GOOS=darwin GOARCH=arm64 ${go} build -ldflags ${ldflags('mac/pcs')} -o ${path.join(SIG_BUILD_OUTPUT_DIR, 'mac-arm64/sig')} ./sig`;
           })()
         );
       }
@@ -169,7 +222,7 @@ async function main() {
         goBuilds.push(
           (async () => {
             console.log(chalk.blue('Building Go binary for Windows...'));
-            await $`GOOS=windows GOARCH=amd64 ${go} build -o ${path.join(SIG_BUILD_OUTPUT_DIR, 'win/sig.exe')} ./sig`;
+            await $`GOOS=windows GOARCH=amd64 ${go} build -ldflags ${ldflags('win/pcs.exe')} -o ${path.join(SIG_BUILD_OUTPUT_DIR, 'win/sig.exe')} ./sig`;
           })()
         );
       }
@@ -195,8 +248,10 @@ async function main() {
 
     if (buildLinux || buildAll) {
       await $`mkdir -p ${MIN_BUILD_OUTPUT_DIR}/linux`;
+      await $`mkdir -p ${MIN_BUILD_OUTPUT_DIR}/linux-arm64`;
       upxCommands.push(
-        $`${upx} ${upxLevel} ${path.join(SIG_BUILD_OUTPUT_DIR, 'linux', 'sig')} -o ${path.join(MIN_BUILD_OUTPUT_DIR, 'linux', 'pcs')}`
+        $`${upx} ${upxLevel} ${path.join(SIG_BUILD_OUTPUT_DIR, 'linux', 'sig')} -o ${path.join(MIN_BUILD_OUTPUT_DIR, 'linux', 'pcs')}`,
+        $`${upx} ${upxLevel} ${path.join(SIG_BUILD_OUTPUT_DIR, 'linux-arm64', 'sig')} -o ${path.join(MIN_BUILD_OUTPUT_DIR, 'linux-arm64', 'pcs')}`
       );
     }
 
@@ -208,6 +263,11 @@ async function main() {
       // upxCommands.push(
       //   $`cp ${path.join(SIG_BUILD_OUTPUT_DIR, 'mac', 'sig')} ${path.join(MIN_BUILD_OUTPUT_DIR, 'mac', 'pcs')}`
       // );
+      // Apple Silicon only runs signed binaries and upx breaks the linker's ad-hoc signature
+      await $`mkdir -p ${MIN_BUILD_OUTPUT_DIR}/mac-arm64`;
+      upxCommands.push(
+        $`cp ${path.join(SIG_BUILD_OUTPUT_DIR, 'mac-arm64', 'sig')} ${path.join(MIN_BUILD_OUTPUT_DIR, 'mac-arm64', 'pcs')}`
+      );
     }
 
     if (buildWin || buildAll) {
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// defaultTempMaxAge is how old a temporary binary must be before it is removed
+const defaultTempMaxAge = 24 * time.Hour
+
+// tempMaxAge returns the PCS_TEMP_MAX_AGE override (a Go duration such as "12h")
+// or defaultTempMaxAge
+func tempMaxAge() time.Duration {
+	value := os.Getenv("PCS_TEMP_MAX_AGE")
+	if value == "" {
+		return defaultTempMaxAge
+	}
+
+	maxAge, err := time.ParseDuration(value)
+	if err != nil || maxAge < 0 {
+		logger.Warn("invalid PCS_TEMP_MAX_AGE, using the default", "value", value, "default", defaultTempMaxAge)
+		return defaultTempMaxAge
+	}
+	return maxAge
+}
+
+// tempBinaryPattern matches binaries created by createTempBinary (pcs-<random>, with
+// -<pid> appended when extracted while busy) and partial extractions left behind by
+// extractBinary
+var tempBinaryPattern = regexp.MustCompile(`^(pcs-\d+(-\d+)?(\.exe)?|\.pcs-.+\.\d+\.tmp)$`)
+
+// partialExtractionPattern matches the temporary files extractBinary writes next to
+// the binary (.<name>.<random>.tmp) and leaves behind when the launcher dies mid-extract
+var partialExtractionPattern = regexp.MustCompile(`^\.pcs-.+\.\d+\.tmp$`)
+
+// isTempBinary reports whether name is a binary created by createTempBinary or a
+// partial extraction left behind by extractBinary
+func isTempBinary(name string) bool {
+	return tempBinaryPattern.MatchString(name)
+}
+
+// isPartialExtraction reports whether name is a partial extraction left behind by
+// extractBinary
+func isPartialExtraction(name string) bool {
+	return partialExtractionPattern.MatchString(name)
+}
+
+// cleanupTempBinaries removes temporary binaries in the OS temp directory older than
+// maxAge, except keep, and returns the removed paths. Binaries that are running
+// cannot be removed on Windows and are skipped; on Linux and macOS removing them is
+// safe because a running process keeps its executable open.
+func cleanupTempBinaries(maxAge time.Duration, keep string) []string {
+	return removeOldFiles(os.TempDir(), isTempBinary, maxAge, keep)
+}
+
+// cleanupPartialExtractions removes partial extractions in an extraction directory
+// older than maxAge and returns the removed paths. The age gate spares the file of a
+// launcher that holds the extraction lock and is still writing it.
+func cleanupPartialExtractions(dir string, maxAge time.Duration) []string {
+	return removeOldFiles(dir, isPartialExtraction, maxAge, "")
+}
+
+// removeOldFiles removes the files in dir whose names match and that were last
+// modified more than maxAge ago, except keep, and returns the removed paths
+func removeOldFiles(dir string, match func(string) bool, maxAge time.Duration, keep string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var removed []string
+	cutoff := time.Now().Add(-maxAge)
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !match(name) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		if path == keep {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(path); err == nil {
+			removed = append(removed, path)
+		}
+	}
+
+	return removed
+}
+
+// runGC implements the `gc` subcommand: it removes stale temporary binaries, partial
+// extractions and binaries extracted by other launcher versions, reporting what it deleted
+func runGC() {
+	maxAge := tempMaxAge()
+	removed := cleanupTempBinaries(maxAge, "")
+
+	binaryName := versionedBinaryName()
+	for _, dir := range binaryDirCandidates() {
+		if isTempDir(dir) {
+			continue
+		}
+		removed = append(removed, cleanupPartialExtractions(dir, maxAge)...)
+		removed = append(removed, removeStaleBinaries(dir, binaryName)...)
+	}
+
+	for _, path := range removed {
+		fmt.Printf("removed %s\n", path)
+	}
+	fmt.Printf("%d stale binaries removed (temporary binaries older than %s)\n", len(removed), maxAge)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockTimeout bounds how long a launcher waits for another one to finish extracting
+const lockTimeout = 30 * time.Second
+
+// lockPollInterval is how often a waiting launcher retries the lock
+const lockPollInterval = 50 * time.Millisecond
+
+// fileLock is an exclusive advisory lock held on an open lock file.
+// The platform-specific tryLockFile and unlockFile are defined in:
+// - lock_unix.go for Linux and macOS (flock)
+// - lock_windows.go for Windows (LockFileEx)
+type fileLock struct {
+	file *os.File
+}
+
+// acquireLock blocks until the lock at path is held or timeout elapses
+func acquireLock(path string, timeout time.Duration) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLockFile(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if locked {
+			return &fileLock{file: file}, nil
+		}
+		if time.Now().After(deadline) {
+			file.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for %s; remove it if no other launcher is running", timeout, path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// release unlocks and closes the lock file
+func (l *fileLock) release() {
+	unlockFile(l.file)
+	l.file.Close()
+}
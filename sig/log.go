@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// levelSilent is above every level the launcher logs at, for PCS_LOG_LEVEL=silent
+const levelSilent = slog.LevelError + 4
+
+// logger writes the launcher's own messages the way pcs writes its logs, so both end up
+// in the same stream or PCS_LOG_FILE in the same format. Output of subcommands such as
+// gc and --version stays plain text on stdout.
+var logger = newLogger()
+
+// parseLogLevel maps a PCS_LOG_LEVEL value to a slog level, defaulting to info
+func parseLogLevel(value string) slog.Level {
+	switch strings.ToLower(value) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	case "silent":
+		return levelSilent
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// newLogHandler returns a JSON handler for format "json" and a logfmt one otherwise.
+// Levels are written in lower case like pcs writes them.
+func newLogHandler(w io.Writer, level slog.Level, format string) slog.Handler {
+	options := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && attr.Key == slog.LevelKey {
+				attr.Value = slog.StringValue(strings.ToLower(attr.Value.String()))
+			}
+			return attr
+		},
+	}
+	if strings.ToLower(format) == "json" {
+		return slog.NewJSONHandler(w, options)
+	}
+	return slog.NewTextHandler(w, options)
+}
+
+// newLogger configures the logger from PCS_LOG_LEVEL, PCS_LOG_FORMAT and PCS_LOG_FILE.
+// A log file that cannot be opened falls back to stderr.
+func newLogger() *slog.Logger {
+	var w io.Writer = os.Stderr
+	if path := os.Getenv("PCS_LOG_FILE"); path != "" {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err == nil {
+			w = file
+		} else {
+			fmt.Fprintf(os.Stderr, "Cannot open PCS_LOG_FILE %s, logging to stderr: %v\n", path, err)
+		}
+	}
+	level := parseLogLevel(os.Getenv("PCS_LOG_LEVEL"))
+	return slog.New(newLogHandler(w, level, os.Getenv("PCS_LOG_FORMAT")))
+}
+
+// fatal logs msg at error level and exits with status 1
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	cases := map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"WARN":    slog.LevelWarn,
+		"error":   slog.LevelError,
+		"silent":  levelSilent,
+		"verbose": slog.LevelInfo,
+	}
+	for value, want := range cases {
+		if got := parseLogLevel(value); got != want {
+			t.Errorf("parseLogLevel(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestLogHandlerFormats(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(newLogHandler(&buf, slog.LevelInfo, "")).Warn("cannot use extraction directory", "dir", "/ro")
+	line := buf.String()
+	if !strings.Contains(line, `level=warn msg="cannot use extraction directory" dir=/ro`) {
+		t.Errorf("unexpected logfmt line %q", line)
+	}
+
+	buf.Reset()
+	slog.New(newLogHandler(&buf, slog.LevelInfo, "json")).Error("failed to extract binary", "path", "/x")
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "error" || entry["msg"] != "failed to extract binary" || entry["path"] != "/x" {
+		t.Errorf("unexpected JSON entry %v", entry)
+	}
+}
+
+func TestLogHandlerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(newLogHandler(&buf, levelSilent, "json")).Error("hidden")
+	slog.New(newLogHandler(&buf, slog.LevelWarn, "json")).Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
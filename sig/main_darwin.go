@@ -5,6 +5,7 @@ package main
 
 import _ "embed"
 
-//go:embed build/mac/pcs
-var embeddedBinary []byte
-
+// The macOS binary is universal (amd64 and arm64)
+//
+//go:embed build/mac/pcs.zst
+var compressedBinary []byte
@@ -0,0 +1,9 @@
+//go:build linux && amd64
+// +build linux,amd64
+
+package main
+
+import _ "embed"
+
+//go:embed build/linux-amd64/pcs.zst
+var compressedBinary []byte
@@ -0,0 +1,9 @@
+//go:build linux && arm64
+// +build linux,arm64
+
+package main
+
+import _ "embed"
+
+//go:embed build/linux-arm64/pcs.zst
+var compressedBinary []byte
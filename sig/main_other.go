@@ -0,0 +1,10 @@
+//go:build !(linux && (amd64 || arm64)) && !darwin && !(windows && amd64)
+// +build !linux !amd64,!arm64
+// +build !darwin
+// +build !windows !amd64
+
+package main
+
+// compressedBinary is empty on platforms without an embedded binary so the launcher
+// can report which platforms are available instead of failing to build
+var compressedBinary []byte
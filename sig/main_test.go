@@ -0,0 +1,304 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// typicalBinarySize approximates an uncompressed pkg-built pcs binary
+const typicalBinarySize = 48 << 20
+
+// syntheticBinary returns size bytes with roughly executable-like compressibility:
+// a mix of incompressible blocks and repeated ones
+func syntheticBinary(size int) []byte {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, size)
+	block := make([]byte, 4096)
+	rng.Read(block)
+	for offset := 0; offset < size; offset += len(block) {
+		if rng.Intn(3) == 0 {
+			rng.Read(data[offset:min(offset+len(block), size)])
+		} else {
+			copy(data[offset:], block)
+		}
+	}
+	return data
+}
+
+// decompressBudget is the most extraction may spend decoding a typical binary
+const decompressBudget = 50 * time.Millisecond
+
+func compress(tb testing.TB, data []byte) []byte {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll(data, nil)
+}
+
+// BenchmarkDecompressBinary measures the one-time decoding cost paid when the binary
+// has to be extracted, and fails when it exceeds decompressBudget; reusing an
+// extracted binary skips it entirely
+func BenchmarkDecompressBinary(b *testing.B) {
+	data := syntheticBinary(typicalBinarySize)
+	compressed := compress(b, data)
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := decompressBinary(compressed); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	// The single-iteration probe round also pays for faulting in the heap, so only the
+	// measured rounds are held to the budget
+	if perOp := b.Elapsed() / time.Duration(b.N); b.N > 1 && perOp > decompressBudget {
+		b.Errorf("decompressing %d MiB took %s, over the %s budget", len(data)>>20, perOp, decompressBudget)
+	}
+}
+
+// writeFiles creates empty files named names in dir, each modified at modTime
+func writeFiles(t *testing.T, dir string, names []string, modTime time.Time) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// remainingFiles returns the sorted names of the files left in dir
+func remainingFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestLoadEmbeddedBinaryHash(t *testing.T) {
+	savedBinary, savedHash, savedDecode := compressedBinary, embeddedBinaryHash, embeddedBinary
+	t.Cleanup(func() {
+		compressedBinary, embeddedBinaryHash, embeddedBinary = savedBinary, savedHash, savedDecode
+	})
+
+	binaryPath := filepath.Join(t.TempDir(), "pcs-dev")
+	tests := []struct {
+		name        string
+		payload     string
+		wantDecodes int
+	}{
+		{"decodes on first start", "first build", 1},
+		{"reads the cache afterwards", "first build", 0},
+		{"decodes again after a rebuild", "second build", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressedBinary = compress(t, []byte(tt.payload))
+			embeddedBinaryHash = ""
+			decodes := 0
+			embeddedBinary = func() ([]byte, error) {
+				decodes++
+				return decompressBinary(compressedBinary)
+			}
+
+			if err := loadEmbeddedBinaryHash(binaryPath); err != nil {
+				t.Fatal(err)
+			}
+			if want := hashBytes([]byte(tt.payload)); embeddedBinaryHash != want {
+				t.Errorf("embeddedBinaryHash = %s, want %s", embeddedBinaryHash, want)
+			}
+			if decodes != tt.wantDecodes {
+				t.Errorf("decoded %d times, want %d", decodes, tt.wantDecodes)
+			}
+		})
+	}
+}
+
+func TestRemoveStaleBinaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		current string
+		want    []string
+	}{
+		{"removes other versions", []string{"pcs-1.0.0", "pcs-1.1.0", "pcs-1.2.0"}, "pcs-1.2.0", []string{"pcs-1.2.0"}},
+		{"removes unversioned binaries", []string{"pcs", "pcs.exe", "pcs-1.2.0"}, "pcs-1.2.0", []string{"pcs-1.2.0"}},
+		{"removes busy binaries", []string{"pcs-1.1.0-4242", "pcs-1.2.0-4242.exe", "pcs-1.2.0"}, "pcs-1.2.0", []string{"pcs-1.2.0"}},
+		{"removes prerelease and dev builds", []string{"pcs-v2.0.0-rc.1", "pcs-dev", "pcs-1.2.0"}, "pcs-1.2.0", []string{"pcs-1.2.0"}},
+		{"keeps the current binary", []string{"pcs-dev"}, "pcs-dev", []string{"pcs-dev"}},
+		{"keeps unrelated files", []string{"pcs-notes.txt", "pcs-report.log", "pcs.lock", "pcs-1.0"}, "pcs-1.2.0", []string{"pcs-1.0", "pcs-notes.txt", "pcs-report.log", "pcs.lock"}},
+		{"keeps partial extractions", []string{".pcs-1.2.0.123.tmp"}, "pcs-1.2.0", []string{".pcs-1.2.0.123.tmp"}},
+		{"removes other versions' hash caches", []string{"pcs-dev.sha256", "pcs-1.2.0", "pcs-1.2.0.sha256"}, "pcs-1.2.0", []string{"pcs-1.2.0", "pcs-1.2.0.sha256"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files, time.Now())
+
+			removeStaleBinaries(dir, tt.current)
+			if got := remainingFiles(t, dir); !slices.Equal(got, tt.want) {
+				t.Errorf("removeStaleBinaries left %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsTempBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"pcs-123456789", true},
+		{"pcs-123456789.exe", true},
+		{"pcs-123456789-4242", true},
+		{".pcs-123456789.987654321.tmp", true},
+		{".pcs-1.2.0.987654321.tmp", true},
+		{"pcs-report.log", false},
+		{"pcs-notes.txt", false},
+		{"pcs-1.2.0", false},
+		{".pcs-probe-123", false},
+		{"pcs", false},
+	}
+
+	for _, tt := range tests {
+		if got := isTempBinary(tt.name); got != tt.want {
+			t.Errorf("isTempBinary(%q) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCleanupTempBinaries(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+	t.Setenv("TMP", dir)
+
+	old := time.Now().Add(-2 * time.Hour)
+	writeFiles(t, dir, []string{"pcs-111", "pcs-222", "pcs-report.log", ".pcs-111.333.tmp"}, old)
+	writeFiles(t, dir, []string{"pcs-444"}, time.Now())
+
+	removed := cleanupTempBinaries(time.Hour, filepath.Join(dir, "pcs-222"))
+	if len(removed) != 2 {
+		t.Errorf("cleanupTempBinaries removed %v, want 2 files", removed)
+	}
+	want := []string{"pcs-222", "pcs-444", "pcs-report.log"}
+	if got := remainingFiles(t, dir); !slices.Equal(got, want) {
+		t.Errorf("cleanupTempBinaries left %v, want %v", got, want)
+	}
+}
+
+func TestCleanupPartialExtractions(t *testing.T) {
+	dir := t.TempDir()
+
+	old := time.Now().Add(-2 * time.Hour)
+	writeFiles(t, dir, []string{".pcs-v1.2.3.111.tmp", "pcs-111", "pcs-v1.2.3", ".pcs-notes.tmp"}, old)
+	writeFiles(t, dir, []string{".pcs-v1.2.3.222.tmp"}, time.Now())
+
+	removed := cleanupPartialExtractions(dir, time.Hour)
+	if want := []string{filepath.Join(dir, ".pcs-v1.2.3.111.tmp")}; !slices.Equal(removed, want) {
+		t.Errorf("cleanupPartialExtractions removed %v, want %v", removed, want)
+	}
+	want := []string{".pcs-notes.tmp", ".pcs-v1.2.3.222.tmp", "pcs-111", "pcs-v1.2.3"}
+	if got := remainingFiles(t, dir); !slices.Equal(got, want) {
+		t.Errorf("cleanupPartialExtractions left %v, want %v", got, want)
+	}
+}
+
+func TestBinaryDirCandidates(t *testing.T) {
+	home := t.TempDir()
+	cache := t.TempDir()
+	custom := t.TempDir()
+
+	tests := []struct {
+		name       string
+		binaryDir  string
+		cacheHome  string
+		want       []string
+		linuxExtra bool
+	}{
+		{"home only", "", "", []string{filepath.Join(home, ".pcs")}, false},
+		{"cache before home", "", cache, []string{filepath.Join(cache, "pcs"), filepath.Join(home, ".pcs")}, true},
+		{"override first", custom, cache, []string{custom, filepath.Join(cache, "pcs"), filepath.Join(home, ".pcs")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", home)
+			t.Setenv("USERPROFILE", home)
+			t.Setenv("PCS_BINARY_DIR", tt.binaryDir)
+			t.Setenv("XDG_CACHE_HOME", tt.cacheHome)
+
+			want := tt.want
+			if tt.linuxExtra && runtime.GOOS != "linux" {
+				want = slices.DeleteFunc(slices.Clone(want), func(dir string) bool {
+					return dir == filepath.Join(cache, "pcs")
+				})
+			}
+			if got := binaryDirCandidates(); !slices.Equal(got, want) {
+				t.Errorf("binaryDirCandidates() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestResolveBinaryPathSkipsUnusableDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("XDG_CACHE_HOME", "")
+
+	// A regular file where the directory should be cannot be created or written
+	blocked := filepath.Join(t.TempDir(), "blocked")
+	writeFiles(t, filepath.Dir(blocked), []string{"blocked"}, time.Now())
+	t.Setenv("PCS_BINARY_DIR", blocked)
+
+	got, err := resolveBinaryPath("pcs-1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, ".pcs", "pcs-1.2.0"); got != want {
+		t.Errorf("resolveBinaryPath() = %q, want %q", got, want)
+	}
+}
+
+func TestAcquireLockContention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pcs.lock")
+
+	held, err := acquireLock(path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lock, err := acquireLock(path, 100*time.Millisecond); err == nil {
+		lock.release()
+		t.Fatal("acquireLock succeeded while the lock was held")
+	}
+
+	held.release()
+	lock, err := acquireLock(path, time.Second)
+	if err != nil {
+		t.Fatalf("acquireLock after release: %v", err)
+	}
+	lock.release()
+}
@@ -0,0 +1,9 @@
+//go:build windows && amd64
+// +build windows,amd64
+
+package main
+
+import _ "embed"
+
+//go:embed build/win/pcs.exe.zst
+var compressedBinary []byte
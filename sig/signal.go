@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+)
+
+// shutdownGracePeriod is how long the child may take to exit after a forwarded
+// signal before it is force-killed. Puppeteer leaves orphaned Chrome instances
+// behind when it is killed outright, so it gets a chance to shut down first.
+const shutdownGracePeriod = 10 * time.Second
+
+// runForwardingSignals starts the child via start and relays shutdown signals to it until it exits.
+// The platform-specific forwardedSignals, configureChildProcess, forwardSignal and
+// signalExitCode are defined in signal_unix.go and signal_windows.go.
+// A second signal, or the grace period elapsing, kills the child immediately.
+// The returned exit code is the conventional 128+n once a signal was forwarded, and
+// otherwise the child's own.
+func runForwardingSignals(start func() (*exec.Cmd, error), grace time.Duration) (int, error) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, forwardedSignals...)
+	defer signal.Stop(signals)
+
+	cmd, err := start()
+	if err != nil {
+		return 0, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var received os.Signal
+	var killTimer <-chan time.Time
+
+	for {
+		select {
+		case sig := <-signals:
+			if received != nil {
+				logger.Warn("received signal again, killing pcs", "signal", sig)
+				cmd.Process.Kill()
+				continue
+			}
+			received = sig
+			if err := forwardSignal(cmd.Process, sig); err != nil {
+				logger.Warn("failed to forward signal", "signal", sig, "err", err)
+			}
+			killTimer = time.After(grace)
+
+		case <-killTimer:
+			logger.Warn("pcs did not exit in time, killing it", "grace", grace)
+			cmd.Process.Kill()
+			killTimer = nil
+
+		case err := <-done:
+			// The run was stopped by the signal, however the child went on to exit
+			if received != nil {
+				return signalExitCode(received), nil
+			}
+			if err != nil {
+				// If the command exits with an error, capture the exit code
+				var exitError *exec.ExitError
+				if errors.As(err, &exitError) {
+					return childExitCode(exitError), nil
+				}
+				return 0, err
+			}
+			return 0, nil
+		}
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// forwardedSignals are relayed to the child process
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// configureChildProcess starts the child in its own process group. A Ctrl-C in
+// the terminal then only reaches the launcher, which forwards it once; a second
+// delivery would make pcs exit without closing its browsers.
+func configureChildProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// forwardSignal sends sig to the child process
+func forwardSignal(process *os.Process, sig os.Signal) error {
+	return process.Signal(sig)
+}
+
+// signalExitCode returns the shell convention exit code for a signal-terminated run
+func signalExitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// childExitCode returns the exit code of a child that exited on its own, or the
+// shell convention code for the signal that terminated it
+func childExitCode(exitError *exec.ExitError) int {
+	if status, ok := exitError.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return signalExitCode(status.Signal())
+	}
+	return exitError.ExitCode()
+}
@@ -0,0 +1,92 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"bufio"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRunForwardingSignalsExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   int
+	}{
+		{"child exits with its own code", `trap 'exit 3' TERM; echo ready; while :; do sleep 0.05; done`, 128 + int(syscall.SIGTERM)},
+		{"child exits cleanly", `trap 'exit 0' TERM; echo ready; while :; do sleep 0.05; done`, 128 + int(syscall.SIGTERM)},
+		{"child terminated by the signal", `echo ready; exec sleep 10`, 128 + int(syscall.SIGTERM)},
+		{"child killed after the grace period", `trap '' TERM; echo ready; exec sleep 10`, 128 + int(syscall.SIGTERM)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready := make(chan struct{})
+			go func() {
+				<-ready
+				syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+			}()
+
+			code, err := runForwardingSignals(func() (*exec.Cmd, error) {
+				cmd := exec.Command("sh", "-c", tt.script)
+				configureChildProcess(cmd)
+				stdout, err := cmd.StdoutPipe()
+				if err != nil {
+					return nil, err
+				}
+				if err := cmd.Start(); err != nil {
+					return nil, err
+				}
+				// The signal is sent once the child has its trap in place
+				bufio.NewReader(stdout).ReadString('\n')
+				close(ready)
+				return cmd, nil
+			}, 200*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tt.want {
+				t.Errorf("runForwardingSignals() = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunForwardingSignalsWithoutSignal(t *testing.T) {
+	code, err := runForwardingSignals(func() (*exec.Cmd, error) {
+		cmd := exec.Command("sh", "-c", "exit 3")
+		configureChildProcess(cmd)
+		return cmd, cmd.Start()
+	}, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("runForwardingSignals() = %d, want 3", code)
+	}
+}
+
+func TestConfigureChildProcessNewGroup(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	configureChildProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	// A terminal Ctrl-C goes to the launcher's group and must not reach the child directly
+	childGroup, err := syscall.Getpgid(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if childGroup == syscall.Getpgrp() {
+		t.Errorf("child shares the launcher's process group %d", childGroup)
+	}
+}
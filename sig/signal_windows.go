@@ -0,0 +1,45 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const ctrlBreakEvent = 1
+
+var procGenerateConsoleCtrlEvent = kernel32.NewProc("GenerateConsoleCtrlEvent")
+
+// forwardedSignals are relayed to the child process. Go reports both CTRL_C and
+// CTRL_BREAK as os.Interrupt on Windows.
+var forwardedSignals = []os.Signal{os.Interrupt}
+
+// configureChildProcess starts the child in its own process group so console
+// control events can be targeted at it
+func configureChildProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// forwardSignal sends CTRL_BREAK to the child's process group. CTRL_C cannot be
+// delivered to a process group created with CREATE_NEW_PROCESS_GROUP.
+func forwardSignal(process *os.Process, _ os.Signal) error {
+	r, _, err := procGenerateConsoleCtrlEvent.Call(ctrlBreakEvent, uintptr(process.Pid))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// signalExitCode returns the shell convention exit code for an interrupted run
+func signalExitCode(_ os.Signal) int {
+	return 130
+}
+
+// childExitCode returns the exit code of the child. Windows processes always exit
+// with a code of their own, console control events included.
+func childExitCode(exitError *exec.ExitError) int {
+	return exitError.ExitCode()
+}
@@ -291,6 +291,59 @@ describe('Authentication System', () => {
       });
     });
 
+    describe('Static Bearer Token', () => {
+      const config: Config = {
+        chromePath: null,
+        port: 3000,
+        auth: { token: 'shared-secret', apiKey: { enabled: false } }
+      };
+
+      it('should accept the configured token', async () => {
+        const middleware = createAuthMiddleware(config);
+
+        mockReq.headers = { authorization: 'Bearer shared-secret' };
+
+        await middleware(mockReq as Request, mockRes as Response, mockNext);
+
+        expect(mockNext).toHaveBeenCalled();
+        expect(statusMock).not.toHaveBeenCalled();
+      });
+
+      it('should reject a wrong or missing token', async () => {
+        const middleware = createAuthMiddleware(config);
+
+        for (const authorization of ['Bearer shared-secret2', 'shared-secret', undefined]) {
+          mockReq.headers = authorization ? { authorization } : {};
+          await middleware(mockReq as Request, mockRes as Response, mockNext);
+        }
+
+        expect(mockNext).not.toHaveBeenCalled();
+        expect(statusMock).toHaveBeenCalledTimes(3);
+        expect(statusMock).toHaveBeenCalledWith(401);
+        expect(jsonMock).toHaveBeenCalledWith(
+          expect.objectContaining({
+            error: expect.stringContaining('PCS_AUTH_TOKEN'),
+            code: 'INVALID_CREDENTIALS'
+          })
+        );
+      });
+
+      it('should prefer PCS_AUTH_TOKEN over config.json', async () => {
+        process.env['PCS_AUTH_TOKEN'] = 'from-env';
+        try {
+          const middleware = createAuthMiddleware(config);
+
+          mockReq.headers = { authorization: 'Bearer from-env' };
+
+          await middleware(mockReq as Request, mockRes as Response, mockNext);
+
+          expect(mockNext).toHaveBeenCalled();
+        } finally {
+          delete process.env['PCS_AUTH_TOKEN'];
+        }
+      });
+    });
+
     describe('Multiple Authentication Strategies', () => {
       it('should accept either valid API key or valid JWT when both enabled', async () => {
         const { jwtVerify } = await import('jose');
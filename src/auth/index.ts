@@ -6,7 +6,7 @@ import { createRemoteJWKSet, jwtVerify } from 'jose';
 import createDebug from 'debug';
 import { BrowserManagerSingleton } from '../browser/BrowserManager';
 import type { Config } from '../types';
-import { ensureBaseWorkingDirectory } from '../config';
+import { ensureBaseWorkingDirectory, getAuthToken } from '../config';
 
 const debug = createDebug('pcs:auth');
 
@@ -114,13 +114,31 @@ function verifyApiKey(providedKey: string): boolean {
   return providedKey === validKey;
 }
 
+// Compares digests so the time taken says nothing about the token
+function verifyToken(providedToken: string, validToken: string): boolean {
+  const digest = (value: string) => crypto.createHash('sha256').update(value).digest();
+  return crypto.timingSafeEqual(digest(providedToken), digest(validToken));
+}
+
 export function createAuthMiddleware(config: Config) {
   const apiKeyEnabled = config.auth?.apiKey?.enabled !== false; // default true
   const jwtEnabled = config.auth?.jwt?.enabled === true; // default false
+  const authToken = getAuthToken(config);
 
   return async (req: Request, res: Response, next: NextFunction): Promise<void> => {
     const authStrategies: Array<() => Promise<boolean>> = [];
 
+    // Static bearer token strategy, tried before JWT since both use the Authorization header
+    if (authToken) {
+      authStrategies.push(async () => {
+        const authHeader = req.headers.authorization;
+        if (authHeader?.startsWith('Bearer ')) {
+          return verifyToken(authHeader.substring(7), authToken);
+        }
+        return false;
+      });
+    }
+
     // API Key strategy
     if (apiKeyEnabled) {
       authStrategies.push(async () => {
@@ -168,6 +186,7 @@ export function createAuthMiddleware(config: Config) {
 
     if (!authenticated) {
       const methods: string[] = [];
+      if (authToken) methods.push('Bearer token (PCS_AUTH_TOKEN)');
       if (apiKeyEnabled) methods.push('API key (x-api-key header)');
       if (jwtEnabled) methods.push('JWT Bearer token');
 
@@ -1,6 +1,6 @@
-import { afterAll, beforeEach, describe, expect, it } from 'vitest';
-import { BrowserError, TabNotFoundError } from '../types/index.js';
-import { BrowserManagerSingleton } from './BrowserManager.js';
+import { afterAll, beforeAll, beforeEach, describe, expect, it } from 'vitest';
+import { BrowserError, SessionCrashedError, TabNotFoundError } from '../types/index.js';
+import { BrowserManager, BrowserManagerSingleton } from './BrowserManager.js';
 
 describe('BrowserManager', () => {
   const browserManager = BrowserManagerSingleton();
@@ -172,6 +172,67 @@ describe('BrowserManager', () => {
     });
   });
 
+  describe('Page Pool', () => {
+    let pooled: BrowserManager;
+
+    beforeAll(async () => {
+      await browserManager.close(); // both would launch Chrome on the same profile
+      process.env['PCS_PAGE_POOL_SIZE'] = '1';
+      pooled = new BrowserManager();
+      delete process.env['PCS_PAGE_POOL_SIZE'];
+      await pooled.initialize();
+    });
+
+    afterAll(async () => {
+      await pooled.close();
+    });
+
+    const readState = (tabId: string) =>
+      pooled.evaluateScript(
+        tabId,
+        `(async () => ({
+          cookie: document.cookie,
+          localStorage: localStorage.length,
+          sessionStorage: sessionStorage.length,
+          clipboard: (await navigator.permissions.query({ name: 'clipboard-read' })).state
+        }))()`
+      );
+
+    it('should recycle a page without cookies, storage or permission overrides', async () => {
+      const first = await pooled.openTab({ url: 'https://example.com', headless: true });
+      await pooled.evaluateScript(
+        first,
+        "document.cookie = 'pooled=1'; localStorage.setItem('pooled', '1'); " +
+          "sessionStorage.setItem('pooled', '1')"
+      );
+      await pooled.writeClipboard(first, 'pooled');
+      await pooled.readClipboard(first);
+      expect(await readState(first)).toEqual({
+        cookie: 'pooled=1',
+        localStorage: 1,
+        sessionStorage: 1,
+        clipboard: 'granted'
+      });
+
+      // Make room for the page, the replacement warmed when it was taken fills the pool otherwise
+      const idle = pooled['pagePool']?.drain() ?? [];
+      await Promise.all(idle.map(({ context }) => context.close()));
+      const page = pooled.getPageByTabId(first);
+      await pooled.closeTab(first);
+      const second = await pooled.openTab({ url: '', headless: true });
+      expect(pooled.getPageByTabId(second)).toBe(page);
+      expect(page?.url()).toBe('about:blank');
+
+      await pooled.navigateTab(second, 'https://example.com');
+      expect(await readState(second)).toEqual({
+        cookie: '',
+        localStorage: 0,
+        sessionStorage: 0,
+        clipboard: 'prompt'
+      });
+    });
+  });
+
   describe('Error Handling', () => {
     it('should handle multiple tabs', async () => {
       await browserManager.initialize();
@@ -188,5 +249,23 @@ describe('BrowserManager', () => {
       expect(tabs.find(t => t.id === tab1)).toBeDefined();
       expect(tabs.find(t => t.id === tab2)).toBeDefined();
     });
+
+    it('should invalidate sessions when the browser crashes and relaunch it', async () => {
+      await browserManager.initialize();
+
+      const session = await browserManager.createSession({ headless: true });
+      const page = browserManager.getPageByTabId(session.activePageId ?? '');
+      const crashed = new Promise(resolve => page?.browser().once('disconnected', resolve));
+      page?.browser().process()?.kill('SIGKILL');
+      await crashed;
+
+      expect(() => browserManager.resolveTabId({ sessionId: session.id })).toThrow(
+        SessionCrashedError
+      );
+      await expect(browserManager.closeSession(session.id)).rejects.toThrow(/browser crash/);
+
+      const next = await browserManager.createSession({ headless: true });
+      expect(browserManager.getSessions().map(s => s.id)).toEqual([next.id]);
+    });
   });
 });
@@ -1,28 +1,437 @@
 import fs from 'node:fs/promises';
 import assert from 'node:assert';
 import { randomUUID } from 'node:crypto';
+import os from 'node:os';
 import path from 'node:path';
 import memoize from 'lodash/memoize.js';
 import puppeteer from 'puppeteer-extra';
 import StealthPlugin from 'puppeteer-extra-plugin-stealth';
 import createDebug from 'debug';
-import AnonymizeUA from 'puppeteer-extra-plugin-anonymize-ua';
 // @ts-expect-error no types
 import UserPreferences from 'puppeteer-extra-plugin-user-preferences';
-import type { Browser, Page } from 'puppeteer-core';
+import type {
+  BoundingBox,
+  Browser,
+  BrowserContext,
+  CDPSession,
+  Cookie,
+  DeleteCookiesRequest,
+  Dialog,
+  ElementHandle,
+  Frame,
+  HTTPRequest,
+  HTTPResponse,
+  JSHandle,
+  Page,
+  PageEvents,
+  Permission,
+  Protocol
+} from 'puppeteer-core';
+import { TimeoutError } from 'puppeteer-core';
 import { findChromeBrowser } from '../chrome/FindChrome.js';
+import { logger } from '../logger/index.js';
+import { browserCrashes, browserLaunches, sessionsExpired } from '../metrics/index.js';
 import {
+  type AccessibilityRequest,
+  type AccessibilitySnapshotResult,
+  type AddInitScriptRequest,
+  type AttributeRequest,
+  type AttributeResult,
+  BLOCKABLE_RESOURCE_TYPES,
+  type BasicAuthRequest,
+  type BlockableResourceType,
+  type BoundingBoxResult,
   BrowserError,
+  type BrowserState,
+  type BrowserStatus,
+  type CaptureResponseBodyRequest,
+  type CapturedResponse,
+  type CdpCommandRequest,
+  type CdpCommandResult,
+  type ClickOptions,
+  type ClipRect,
+  type ClipboardResult,
+  type CompareScreenshotRequest,
+  type CompareScreenshotResult,
+  type ConsentRule,
+  type ConsoleEntry,
+  type ConsoleLogsRequest,
+  type ConsoleLogsResult,
+  type CookieFilter,
+  type CookieInput,
+  type CountElementsRequest,
+  type CountElementsResult,
+  type CoverageResult,
+  type CpuThrottlingResult,
+  type CreateSessionRequest,
+  DIALOG_ACTIONS,
+  DOWNLOAD_POLICIES,
+  type DefaultTimeoutRequest,
+  type DialogEntry,
+  type DialogHandlerRequest,
+  type DomStateHint,
+  type DownloadBehaviorRequest,
+  type DownloadPolicy,
+  type DragEndpoint,
+  type DragRequest,
+  type DragResult,
+  type ElementExistsResult,
+  type ElementRequest,
+  type EmulateDeviceRequest,
+  type EmulateMediaRequest,
+  type EmulatedDevice,
+  type ExportCookiesRequest,
+  type ExportCookiesResult,
+  type ExtraHeadersResult,
+  type FrameInfo,
+  type GeolocationRequest,
+  type GeolocationResult,
+  type GetTextRequest,
+  type HarResult,
+  type HeadlessMode,
+  type HtmlRequest,
+  type ImportCookiesRequest,
+  type ImportCookiesResult,
+  type InitScriptInfo,
+  InvalidArgumentError,
+  type KeyboardRequest,
+  type KeyboardResult,
+  type LaunchSettings,
+  type LoadSessionStateRequest,
+  type LoadSessionStateResult,
+  type LocaleResult,
+  type MediaEmulation,
+  type MetricsResult,
+  type MockRouteRequest,
+  type NavigationResult,
+  type NetworkConditionsRequest,
+  type NetworkConditionsResult,
+  type NetworkIdleResult,
   type OpenTabRequest,
+  type OptionCriteria,
+  PAPER_FORMATS,
+  type PageInfo,
+  type PdfRequest,
+  type PdfResult,
+  type PinchRequest,
+  type Point,
+  ProfileLockedError,
+  type PropertyRequest,
+  type PropertyResult,
+  type RecordingResult,
+  type RouteInfo,
+  type SaveSessionStateRequest,
+  type SaveSessionStateResult,
+  type ScreencastFrame,
+  type ScreencastResult,
+  type ScreenshotRequest,
+  type ScreenshotResult,
+  type ScrollRequest,
+  type ScrollResult,
+  type SelectResult,
+  type ServerStatus,
+  SessionCrashedError,
+  type SessionIdleTime,
+  type SessionInfo,
+  SessionNotFoundError,
+  type SetViewportRequest,
+  ShuttingDownError,
+  type SnapshotRequest,
+  type SnapshotResult,
+  type StartCoverageRequest,
+  type StartHarRequest,
+  type StartRecordingRequest,
+  type StartScreencastRequest,
+  type StartTracingRequest,
+  type StopHarRequest,
+  type StopTracingRequest,
+  type StorageResult,
+  type StorageState,
+  type StorageStateOrigin,
+  type StorageType,
+  type SwipeRequest,
   type TabInfo,
-  TabNotFoundError
+  TabNotFoundError,
+  type TabTarget,
+  type TapRequest,
+  type TextResult,
+  type TouchResult,
+  type TouchTarget,
+  type TracingResult,
+  type TrafficEntry,
+  type TypeOptions,
+  type UploadBlob,
+  type UploadResult,
+  type Viewport,
+  type VisionDeficiency,
+  type WaitForDownloadResult,
+  type WaitForFunctionResult,
+  type WaitForNetworkIdleRequest,
+  type WaitForRequestRequest,
+  type WaitForResponseRequest,
+  type WaitForSelectorResult,
+  type WaitForStableRequest,
+  type WaitForStableResult,
+  type WaitForTrafficResult
 } from '../types/index.js';
-import { ensureBaseWorkingDirectory } from '../config/index.js';
+import {
+  ensureBaseWorkingDirectory,
+  getDefaultTimeout,
+  getNavigationTimeout,
+  getFfmpegPath,
+  getMaxConcurrency,
+  getPagePoolSize,
+  getQueueTimeout,
+  getSessionTtl,
+  getStatePassphrase,
+  VERSION
+} from '../config/index.js';
+import { describeNonEditable, NON_TEXT_INPUT_TYPES, setEditableValue } from './editable.js';
+import { buildEvaluateExpression, serializeInPage, withTimeout } from './evaluate.js';
+import {
+  classifyNetError,
+  getDownloadFilename,
+  getNetErrorCode,
+  isDownloadResponse,
+  isNavigationRequest,
+  resolveNavigationHeaders,
+  type WaitUntil,
+  withNavigationHeaders
+} from './navigation.js';
+import {
+  collectContent,
+  extractText,
+  NEGATIVE_HINTS,
+  normalizeText,
+  pickMainContent,
+  POSITIVE_HINTS,
+  readInnerText,
+  truncateText,
+  validateTextRequest
+} from './content.js';
+import { toAccessibilityTree } from './accessibility.js';
+import { validateCdpCommand } from './cdp.js';
+import { padClip, parseBackgroundColor, validatePadding } from './clip.js';
+import {
+  accessClipboard,
+  CLIPBOARD_READ_PERMISSIONS,
+  CLIPBOARD_WRITE_PERMISSIONS,
+  describeClipboardError
+} from './clipboard.js';
+import {
+  CONSENT_RETRY_DELAYS,
+  DEFAULT_CONSENT_RULES,
+  dismissConsentInPage,
+  validateConsentRules
+} from './consent.js';
+import {
+  cookieMatchesUrl,
+  formatCookies,
+  parseCookies,
+  resolveCookieFormat,
+  validateCookie
+} from './cookies.js';
+import { summarizeCoverage } from './coverage.js';
+import { ConsoleBuffer, parseStackLocation, toConsoleLevel } from './console.js';
+import { parseDefaultViewport, resolveDevice, resolveViewport } from './devices.js';
+import { DEFAULT_DIALOG_HANDLER, pushDialog, resolveDialogResponse } from './dialogs.js';
+import { DownloadWatcher } from './downloads.js';
+import {
+  freezeFixedElements,
+  LAZY_LOAD_DELAY,
+  MAX_LAZY_LOAD_STEPS,
+  resolveFullPageOptions,
+  restoreFixedElements,
+  scrollThroughPage
+} from './fullpage.js';
+import {
+  canonicalLocale,
+  isValidTimezone,
+  NO_MEDIA_EMULATION,
+  resolveMediaEmulation,
+  resolveNetworkConditions,
+  THROTTLED_NAVIGATION_FACTOR,
+  toAcceptLanguage,
+  toBytesPerSecond,
+  toEmulatedMedia,
+  validateCpuThrottlingRate,
+  validateGeolocation,
+  validateVisionDeficiency
+} from './emulation.js';
+import {
+  buildFrameTree,
+  describeFrames,
+  type FrameDescriptor,
+  matchFrame,
+  matchFrameUrl
+} from './frames.js';
+import { DEFAULT_MAX_BODY_SIZE, HarRecorder, toHarContent } from './har.js';
+import { validateBasicAuth, validateHeaders } from './headers.js';
+import { compileInitScript, describeInitScript, type InitScript } from './initscripts.js';
+import { toPropertyPath, validateAttributeName } from './inspect.js';
+import { resolveKeyStroke } from './keyboard.js';
+import { compileRoute, describeRoute, findRoute, type RouteRule } from './interception.js';
+import { buildLaunchArgs, validateExtensions } from './launch.js';
+import {
+  readScrollState,
+  resolveScrollMode,
+  scrollToEnd,
+  scrollUntilStable,
+  validateDrag
+} from './mouse.js';
+import { collectWebVitals, cumulativeLayoutShift } from './metrics.js';
+import { InflightRequests, resolveIdleOptions } from './network.js';
+import { applySelection, inspectSelect, matchOptions, toCriteria } from './select.js';
+import { resolveClientPath } from './paths.js';
+import { decodePng, encodePng } from './png.js';
+import { PagePool } from './pool.js';
+import { parseProxy, type ProxySettings } from './proxy.js';
+import { OperationQueue } from './queue.js';
+import { Recording, resolveRecordingOptions } from './recording.js';
+import { resolveScreencastOptions, Screencast } from './screencast.js';
+import {
+  chromeProfileOwner,
+  forgetBrowserProcess,
+  isAlive,
+  isProfileInUse,
+  killProcessTree,
+  lockProfile,
+  processGroupMemory,
+  reapOrphanedBrowsers,
+  recordBrowserProcess,
+  removeProfileLocks,
+  unlockProfile
+} from './reaper.js';
+import {
+  elementNotFoundMessage,
+  isStaleElementError,
+  toElementIndex,
+  toPiercingSelector,
+  toSelector
+} from './selectors.js';
+import { validateTimeout } from './timeouts.js';
+import { packTrace, validateCategories } from './tracing.js';
+import { swipeOffset, validatePinch, validateSwipe, validateTap } from './touch.js';
+import {
+  compileTrafficFilter,
+  decodeResponseBody,
+  nearMatches,
+  RecentTraffic,
+  resolveCaptureOptions,
+  type TrafficFilter
+} from './traffic.js';
+import { inspectFileInput, removeUploadDirs, writeBlobs } from './upload.js';
+import { toUserAgentOverride, type UserAgentOverride } from './useragent.js';
+import { diffImages, parseBaseline, resolveCompareOptions } from './visual.js';
+import {
+  decodeStorageState,
+  encodeStorageState,
+  fromStateCookie,
+  isStateOrigin,
+  toStateCookie
+} from './state.js';
+import { readStorage, removeStorage, storageOrigin, writeStorage } from './storage.js';
+import {
+  describeElementState,
+  isTimeoutError,
+  resolveStableOptions,
+  STABLE_POLL_INTERVAL,
+  StabilityTracker,
+  toPredicateExpression
+} from './waits.js';
 
 const debug = createDebug('pcs:config');
 
-puppeteer.use(StealthPlugin());
-puppeteer.use(AnonymizeUA());
+// deleteMatchingCookies treats present-but-undefined fields as filters
+function compactFilter(filter: CookieFilter): DeleteCookiesRequest {
+  const request: DeleteCookiesRequest = { name: filter.name };
+  if (filter.domain) request.domain = filter.domain;
+  if (filter.path) request.path = filter.path;
+  if (filter.url) request.url = filter.url;
+  return request;
+}
+
+// Sessions nobody touched for PCS_SESSION_TTL are closed so abandoned ones don't keep Chrome
+// alive. Short TTLs are swept more often, down to once a second.
+const SESSION_SWEEP_INTERVAL_MS = 60 * 1000;
+const MIN_SESSION_SWEEP_INTERVAL_MS = 1000;
+
+// How long a closing browser gets on shutdown before it is killed
+const BROWSER_CLOSE_TIMEOUT_MS = 3000;
+
+// Ids of sessions lost in a crash are remembered this long to explain why they're gone
+const MAX_CRASHED_SESSIONS = 1000;
+
+type PageListener = [event: keyof PageEvents, handler: (event: any) => void];
+
+// A warm page of the pool with the browser context of its own it runs in
+interface PooledPage {
+  context: BrowserContext;
+  page: Page;
+}
+
+interface Tab {
+  page: Page;
+  visible: boolean;
+  network: InflightRequests<HTTPRequest>;
+  requests: RecentTraffic<HTTPRequest>;
+  responses: RecentTraffic<HTTPResponse>;
+  listeners: PageListener[]; // added by registerTab
+  pooled?: PooledPage; // taken from the page pool, see recyclePage
+  emulated?: boolean; // viewport, geolocation, timezone or raw CDP state a reset can't undo
+  sessionId?: string;
+  openerId?: string; // page that opened this one via window.open
+  requestHandler?: (request: HTTPRequest) => void;
+  cdp?: CDPSession; // created on first use for protocol calls Puppeteer does not wrap
+  uploadDirs?: string[]; // staged base64 uploads, removed with the tab
+  locale?: string;
+  networkConditions?: NetworkConditionsResult;
+  cpuThrottlingRate?: number;
+  media?: MediaEmulation;
+  visionDeficiency?: VisionDeficiency;
+  coverage?: { js: boolean; css: boolean }; // set while coverage is collected
+  screencast?: Screencast; // set while frames are streamed
+  initScripts?: Map<string, string>; // session init script ID to the page's own identifier
+}
+
+interface Session {
+  id: string;
+  headless: boolean;
+  // null when the session shares the browser's default context
+  context: BrowserContext | null;
+  pageIds: string[];
+  activePageId: string | null;
+  createdAt: number;
+  lastUsed: number;
+  // applies to every page of the session, including pages opened later
+  interception: boolean;
+  routes: RouteRule[];
+  blockedResourceTypes: BlockableResourceType[];
+  blockedRequests: number;
+  har: HarRecorder | null;
+  console: ConsoleBuffer;
+  downloadPolicy: DownloadPolicy | null; // null keeps Chrome's default behavior
+  downloads: DownloadWatcher | null; // set while downloads are allowed
+  dialogHandler: DialogHandlerRequest;
+  dialogs: DialogEntry[];
+  extraHeaders: Record<string, string>;
+  credentials: BasicAuthRequest | null; // answers HTTP auth challenges
+  proxy: ProxySettings | null; // proxy of the session's own context, overrides the global one
+  tracing: string | null; // page being traced
+  recording: Recording | null;
+  defaultTimeout: number | null; // null falls back to the global default
+  navigationTimeout: number | null;
+  consentRules: ConsentRule[] | null; // set when cookie banners are dismissed automatically
+  dismissedConsents: number;
+  cacheEnabled: boolean;
+  ignoreHTTPSErrors: boolean; // on top of the launch-wide setting, which it can't turn off
+  initScripts: InitScript[]; // added to every page, including pages opened later
+}
+
+const stealth = StealthPlugin();
+// Its override races the first request of a page, the user agent is set in registerTab instead
+stealth.enabledEvasions.delete('user-agent-override');
+puppeteer.use(stealth);
 puppeteer.use(
   UserPreferences({
     userPrefs: {
@@ -35,411 +444,4314 @@ puppeteer.use(
         always_open_pdf_externally: true
       }
     }
-  })
-);
+  })
+);
+
+export class BrowserManager {
+  private browsers: Map<boolean, Browser | null> = new Map();
+  private userAgents: Map<boolean, UserAgentOverride | null> = new Map();
+  private launching: Map<boolean, Promise<void>> = new Map();
+  // Browsers closed on purpose, any other disconnect is a crash
+  private releasing: WeakSet<Browser> = new WeakSet();
+  private crashedSessions: Map<string, number> = new Map();
+  private disconnected: Set<boolean> = new Set(); // crashed and not relaunched yet
+  private tabs: Map<string, Tab> = new Map();
+  private sessions: Map<string, Session> = new Map();
+  // overridePermissions replaces an origin's grants, so they are accumulated per context
+  private grantedPermissions: WeakMap<BrowserContext, Map<string, Set<Permission>>> =
+    new WeakMap();
+  private frameIds: WeakMap<Frame, string> = new WeakMap();
+  private sessionSweeper: NodeJS.Timeout | null = null;
+  private chromePath: string | null = null;
+  private cdpEndpoint: string | null = null;
+  private proxy: ProxySettings | null = null;
+  private launchArgs: string[] = [];
+  private extensions: string[] = [];
+  private userDataDir: string;
+  private persistentProfile: boolean; // set with PCS_USER_DATA_DIR, never deleted
+  private profileLocked = false;
+  private processFile: string; // PIDs of the browsers launched here, see reaper.ts
+  private exitHandler: (() => void) | null = null;
+  private headless: HeadlessMode | null = null;
+  // PCS_DEFAULT_VIEWPORT, null lets new pages take the size of their window
+  private defaultViewport: Viewport | null = null;
+  private ignoreHTTPSErrors = false; // for every browser, sessions can also opt in on their own
+  private defaultTimeout = getDefaultTimeout();
+  private navigationTimeout = getNavigationTimeout();
+  private sessionTtl = getSessionTtl();
+  private operations = new OperationQueue(getMaxConcurrency(), getQueueTimeout());
+  private pagePool: PagePool<PooledPage> | null = null; // headless pages, see openTab
+
+  public getPageByTabId(tabId: string): Page | null {
+    const tab = this.tabs.get(tabId);
+    return tab ? tab.page : null;
+  }
+
+  public getVisibleByTabId(tabId: string): boolean | null {
+    const tab = this.tabs.get(tabId);
+    return tab ? tab.visible : null;
+  }
+
+  constructor(
+    chromePath?: string | null,
+    cdpEndpoint?: string | null,
+    proxy?: string | null,
+    launch?: LaunchSettings
+  ) {
+    this.chromePath = chromePath || null;
+    this.cdpEndpoint = cdpEndpoint || null;
+    this.headless = launch?.headless ?? null;
+    this.defaultViewport = launch?.viewport ? parseDefaultViewport(launch.viewport) : null;
+    this.ignoreHTTPSErrors = launch?.ignoreHTTPSErrors ?? false;
+    if (this.ignoreHTTPSErrors) {
+      logger.warn('HTTPS certificate errors are ignored, browsers accept invalid certificates');
+    }
+    this.persistentProfile = Boolean(launch?.userDataDir);
+    this.userDataDir =
+      launch?.userDataDir || path.resolve(ensureBaseWorkingDirectory(), '.browser');
+    this.processFile = path.resolve(ensureBaseWorkingDirectory(), '.browser-pids.json');
+    // --proxy-server is a launch flag, an attached browser keeps whatever it was started with
+    if (proxy && this.cdpEndpoint) {
+      debug('Ignoring the proxy, the browser at %s was not launched here', this.cdpEndpoint);
+    } else if (proxy) {
+      this.proxy = parseProxy(proxy);
+    }
+    if (this.cdpEndpoint) {
+      if (launch?.args.length || launch?.extensions.length) {
+        debug('Ignoring launch args and extensions, %s was not launched here', cdpEndpoint);
+      }
+    } else {
+      // The old headless shell has no extension support, so fail at startup, not on launch
+      this.extensions = validateExtensions(launch?.extensions ?? []);
+      if (this.extensions.length && this.headless === 'shell') {
+        throw new BrowserError(
+          'Extensions need a visible browser or the new headless mode, not PCS_HEADLESS=shell'
+        );
+      }
+      this.launchArgs = buildLaunchArgs(launch?.args ?? [], {
+        userDataDir: this.userDataDir,
+        proxyServer: this.proxy?.server ?? null,
+        extensions: this.extensions
+      });
+      debug('Chrome launch arguments: %s', this.launchArgs.join(' '));
+      const reaped = reapOrphanedBrowsers(this.processFile);
+      if (reaped.length) {
+        logger.warn('killed browsers left behind by an earlier run', { pids: reaped });
+      }
+    }
+    this.browsers.set(true, null); // headless
+    this.browsers.set(false, null); // visible
+    const poolSize = getPagePoolSize();
+    if (poolSize) {
+      this.pagePool = new PagePool(poolSize, () => this.warmPage(), ({ context }) => {
+        context.close().catch(error => debug('Failed to close a pooled page: %O', error));
+      });
+    }
+  }
+
+  async initialize(headless = true): Promise<void> {
+    await this.close();
+    await this.startBrowser(headless);
+  }
+
+  private async startBrowser(headless: boolean): Promise<void> {
+    try {
+      const browser = this.cdpEndpoint
+        ? await this.connectBrowser(this.cdpEndpoint)
+        : await this.launchBrowser(headless);
+
+      this.userAgents.set(headless, toUserAgentOverride(await browser.userAgent()));
+      this.browsers.set(headless, browser);
+      this.disconnected.delete(headless);
+      browserLaunches.inc({ browser: headless ? 'headless' : 'visible' });
+      logger.info(this.cdpEndpoint ? 'browser attached' : 'browser launched', {
+        browser: headless ? 'headless' : 'visible',
+        pid: browser.process()?.pid,
+        endpoint: this.cdpEndpoint ?? undefined
+      });
+
+      // Handle browser disconnection
+      browser.on('disconnected', () => this.handleDisconnect(browser, headless));
+      if (headless) {
+        this.pagePool?.fill();
+      }
+
+      debug('Browser initialized successfully');
+    } catch (error) {
+      if (error instanceof BrowserError) {
+        throw error;
+      }
+      throw new BrowserError(`Failed to initialize browser: ${error}`);
+    }
+  }
+
+  private async launchBrowser(headless: boolean): Promise<Browser> {
+    const executablePath = await this.getChromePath();
+    const mode = headless && this.headless === 'shell' ? 'shell' : headless;
+    debug('Launching %s (headless: %s)', executablePath, mode);
+    this.claimProfile();
+
+    // Signals are handled by the server's shutdown, Puppeteer would kill Chrome outright
+    const browser = await puppeteer.launch({
+      defaultViewport: this.defaultViewport,
+      acceptInsecureCerts: this.ignoreHTTPSErrors,
+      executablePath,
+      headless: mode,
+      args: this.launchArgs,
+      // Puppeteer disables extensions unless told otherwise
+      ignoreDefaultArgs: this.extensions.length ? ['--disable-extensions'] : false,
+      handleSIGINT: false,
+      handleSIGTERM: false,
+      handleSIGHUP: false
+    });
+    const pid = browser.process()?.pid;
+    if (pid) {
+      recordBrowserProcess(this.processFile, {
+        pid,
+        ownerPid: process.pid,
+        userDataDir: this.userDataDir,
+        startedAt: new Date().toISOString()
+      });
+      this.killBrowsersOnExit();
+    }
+    return browser;
+  }
+
+  // Chrome refuses a profile another Chrome runs on, and two servers sharing one would also
+  // reap each other's browsers, so the profile is locked for this process until it exits.
+  // Locks Chrome left behind when it was killed are removed, those of a live one are not.
+  private claimProfile(): void {
+    const owner = lockProfile(this.userDataDir);
+    if (owner !== null) {
+      throw new ProfileLockedError(this.userDataDir, owner, 'pcs');
+    }
+    if (!this.profileLocked) {
+      this.profileLocked = true;
+      process.once('exit', () => unlockProfile(this.userDataDir));
+    }
+    if (isProfileInUse(this.processFile, this.userDataDir)) {
+      return;
+    }
+
+    const chrome = chromeProfileOwner(this.userDataDir);
+    if (chrome && chrome.host === os.hostname() && isAlive(chrome.pid)) {
+      throw new ProfileLockedError(this.userDataDir, chrome.pid, 'Chrome');
+    }
+    try {
+      removeProfileLocks(this.userDataDir);
+    } catch {
+      // Windows keeps the lockfile of a running Chrome open
+      throw new ProfileLockedError(this.userDataDir, null, 'Chrome');
+    }
+  }
+
+  // Puppeteer only kills its browsers on the signals it handles, this also covers a plain
+  // process.exit and takes their renderers along
+  private killBrowsersOnExit(): void {
+    if (this.exitHandler) {
+      return;
+    }
+    this.exitHandler = () => {
+      for (const browser of this.browsers.values()) {
+        const pid = browser?.process()?.pid;
+        if (pid) {
+          killProcessTree(pid);
+          forgetBrowserProcess(this.processFile, pid);
+        }
+      }
+    };
+    process.once('exit', this.exitHandler);
+  }
+
+  // Renderers can outlive a browser that crashed or was closed, so the whole group goes
+  private reapBrowserProcess(browser: Browser): void {
+    const pid = browser.process()?.pid;
+    if (pid) {
+      killProcessTree(pid);
+      forgetBrowserProcess(this.processFile, pid);
+    }
+  }
+
+  // Waits for a free slot under PCS_MAX_CONCURRENCY, callers hold it until their operation is
+  // done so bursts of calls queue up instead of piling pages onto Chrome
+  acquireOperationSlot(operation: string): Promise<() => void> {
+    return this.operations.acquire(operation);
+  }
+
+  getStatus(): ServerStatus {
+    const browsers = [true, false].map(headless => this.getBrowserStatus(headless));
+    return {
+      healthy: browsers.every(browser => browser.state !== 'disconnected'),
+      version: VERSION,
+      uptimeMs: Math.round(process.uptime() * 1000),
+      browsers,
+      queue: this.operations.status(),
+      pagePool: this.pagePool?.status() ?? { size: 0, idle: 0, hits: 0, misses: 0 },
+      tabs: this.tabs.size,
+      sessions: this.sessions.size,
+      sessionTtl: this.sessionTtl,
+      idleSessions: this.getIdleSessions()
+    };
+  }
+
+  private getIdleSessions(now = Date.now()): SessionIdleTime[] {
+    return Array.from(this.sessions.values())
+      .sort((a, b) => a.lastUsed - b.lastUsed)
+      .map(session => {
+        const idleMs = now - session.lastUsed;
+        return {
+          sessionId: session.id,
+          idleMs,
+          expiresInMs: this.sessionTtl ? Math.max(0, this.sessionTtl - idleMs) : null
+        };
+      });
+  }
+
+  private getBrowserStatus(headless: boolean): BrowserStatus {
+    const browser = this.browsers.get(headless);
+    const pid = browser?.process()?.pid ?? null;
+    let state: BrowserState = 'idle';
+    if (browser?.connected) {
+      state = 'connected';
+    } else if (this.disconnected.has(headless)) {
+      state = 'disconnected';
+    }
+    return {
+      headless,
+      state,
+      pid,
+      memoryBytes: pid && state === 'connected' ? processGroupMemory(pid) : null
+    };
+  }
+
+  // Whether sessions and tabs that don't choose run headless, PCS_HEADLESS overrides the
+  // caller's fallback
+  getDefaultHeadless(fallback: boolean): boolean {
+    return this.headless === null ? fallback : this.headless !== false;
+  }
+
+  // Attaches to an already running Chrome. Never falls back to launching one, the caller
+  // asked for a specific browser and silently using another would be surprising.
+  private async connectBrowser(endpoint: string): Promise<Browser> {
+    const isWebSocket = endpoint.startsWith('ws://') || endpoint.startsWith('wss://');
+    try {
+      const browser = await puppeteer.connect({
+        ...(isWebSocket ? { browserWSEndpoint: endpoint } : { browserURL: endpoint }),
+        defaultViewport: this.defaultViewport,
+        acceptInsecureCerts: this.ignoreHTTPSErrors
+      });
+      debug('Connected to browser at %s', endpoint);
+      return browser;
+    } catch (error) {
+      throw new BrowserError(
+        `Failed to connect to browser at ${endpoint}: ${error}. ` +
+          'Make sure Chrome is running with --remote-debugging-port and the endpoint is reachable.'
+      );
+    }
+  }
+
+  // Tabs of a browser that went away are dropped. When nobody closed it on purpose it
+  // crashed (or the attached one went away), which takes its sessions with it; the next
+  // tab or session launches a fresh browser.
+  private handleDisconnect(browser: Browser, headless: boolean): void {
+    const crashed = !this.releasing.has(browser);
+    const child = browser.process();
+    const exit = child?.signalCode ?? child?.exitCode ?? 'unknown';
+    this.reapBrowserProcess(browser);
+
+    let tabs = 0;
+    for (const [tabId, tab] of this.tabs) {
+      if (tab.visible === headless) {
+        tabs++;
+        tab.screencast?.stop('closed');
+        this.tabs.delete(tabId);
+        if (tab.sessionId) {
+          this.detachFromSession(tab.sessionId, tabId);
+        }
+      }
+    }
+    if (this.browsers.get(headless) === browser) {
+      this.browsers.set(headless, null);
+    }
+    if (headless) {
+      this.pagePool?.drain(); // the pages went with the browser
+    }
+    if (!crashed) {
+      logger.info('browser closed', { browser: headless ? 'headless' : 'visible', tabs });
+      return;
+    }
+    this.disconnected.add(headless);
+    browserCrashes.inc({ browser: headless ? 'headless' : 'visible' });
+
+    let sessions = 0;
+    const now = Date.now();
+    for (const session of this.sessions.values()) {
+      if (session.headless === headless) {
+        sessions++;
+        this.sessions.delete(session.id);
+        session.har?.detach();
+        session.downloads?.stop();
+        this.crashedSessions.set(session.id, now);
+      }
+    }
+    for (const sessionId of this.crashedSessions.keys()) {
+      if (this.crashedSessions.size <= MAX_CRASHED_SESSIONS) break;
+      this.crashedSessions.delete(sessionId);
+    }
+    if (this.sessions.size === 0) {
+      this.stopSessionSweeper();
+    }
+
+    logger.error(this.cdpEndpoint ? 'browser disconnected' : 'browser crashed', {
+      browser: headless ? 'headless' : 'visible',
+      pid: child?.pid,
+      exit,
+      endpoint: this.cdpEndpoint ?? undefined,
+      code: 'SESSION_CRASHED',
+      sessions,
+      tabs
+    });
+  }
+
+  // An attached browser belongs to someone else, so disconnect instead of closing it
+  private async releaseBrowser(browser: Browser): Promise<void> {
+    this.releasing.add(browser);
+    if (this.cdpEndpoint) {
+      // the contexts of pooled pages would outlive the connection
+      if (this.pagePool && this.browsers.get(true) === browser) {
+        await Promise.all(
+          this.pagePool.drain().map(({ context }) => context.close().catch(() => {}))
+        );
+      }
+      await browser.disconnect();
+    } else {
+      await browser.close();
+    }
+  }
+
+  private async getChromePath(): Promise<string> {
+    if (process.env['CI'] && process.env['PUPPETEER_EXEC_PATH']) {
+      return process.env['PUPPETEER_EXEC_PATH'];
+    }
+
+    if (this.chromePath) {
+      return this.chromePath;
+    }
+
+    try {
+      const chromePath = await findChromeBrowser();
+      if (chromePath) {
+        // retain for future use until restarted
+        this.chromePath = chromePath;
+        return chromePath;
+      }
+    } catch (error) {
+      debug('Failed to find Chrome automatically: %O', error);
+    }
+
+    throw new BrowserError('Chrome executable not found. Please specify chromePath in config.');
+  }
+
+  private async ensureBrowser(headless: boolean): Promise<Browser> {
+    if (!this.browsers.get(headless)) {
+      // Calls arriving together share one launch, e.g. the retries after a crash
+      let launching = this.launching.get(headless);
+      if (!launching) {
+        launching = this.startBrowser(headless).finally(() => this.launching.delete(headless));
+        this.launching.set(headless, launching);
+      }
+      await launching;
+    }
+
+    const browser = this.browsers.get(headless);
+
+    if (!browser) {
+      throw new BrowserError('Browser not initialized');
+    }
+
+    assert(browser);
+    return browser;
+  }
+
+  private registerTab(
+    page: Page,
+    headless: boolean,
+    sessionId?: string,
+    openerId?: string
+  ): string {
+    const tabId = randomUUID();
+    // Kept so a pooled page can go to the next tab without the handlers of this one
+    const listeners: PageListener[] = [];
+    const on = <K extends keyof PageEvents>(
+      event: K,
+      handler: (event: PageEvents[K]) => void
+    ): void => {
+      page.on(event, handler as (event: any) => void);
+      listeners.push([event, handler]);
+    };
+
+    // Requests are followed from the start so a later wait knows what is already in flight
+    const network = new InflightRequests<HTTPRequest>();
+    on('request', request => network.add(request, request.url()));
+    on('requestfinished', request => network.remove(request));
+    on('requestfailed', request => network.remove(request));
+    const requests = new RecentTraffic<HTTPRequest>();
+    const responses = new RecentTraffic<HTTPResponse>();
+    on('request', request =>
+      requests.add(request, { method: request.method(), url: request.url(), status: null })
+    );
+    on('response', response =>
+      responses.add(response, {
+        method: response.request().method(),
+        url: response.url(),
+        status: response.status()
+      })
+    );
+
+    const tab: Tab = { page, visible: headless, network, requests, responses, listeners };
+    if (sessionId) tab.sessionId = sessionId;
+    if (openerId) tab.openerId = openerId;
+    this.tabs.set(tabId, tab);
+
+    // CDP commands run in order, so this lands before the first navigation of the page
+    const userAgent = this.userAgents.get(headless);
+    if (userAgent) {
+      page.setUserAgent(userAgent).catch(error =>
+        debug('Failed to set the user agent of page %s: %O', tabId, error)
+      );
+    }
+
+    if (sessionId) {
+      const session = this.sessions.get(sessionId);
+      session?.pageIds.push(tabId);
+      session?.har?.attach(page, tabId);
+      this.captureConsole(page, sessionId, tabId);
+      if (session?.downloadPolicy) {
+        this.applyDownloadBehavior(tab, session).catch(error =>
+          debug('Failed to set download behavior of page %s: %O', tabId, error)
+        );
+      }
+      if (session && Object.keys(session.extraHeaders).length > 0) {
+        page.setExtraHTTPHeaders(session.extraHeaders).catch(error =>
+          debug('Failed to set headers of page %s: %O', tabId, error)
+        );
+      }
+      if (session && !session.cacheEnabled) {
+        page.setCacheEnabled(false).catch(error =>
+          debug('Failed to disable the cache of page %s: %O', tabId, error)
+        );
+      }
+      for (const script of session?.initScripts ?? []) {
+        this.applyInitScript(tab, script).catch(error =>
+          debug('Failed to add init script %s to page %s: %O', script.id, tabId, error)
+        );
+      }
+      on('load', () => {
+        void this.dismissConsent(page, sessionId, tabId);
+      });
+      // Pages the site opens itself join the session so they can be listed and targeted
+      on('popup', popup => {
+        if (popup && this.sessions.has(sessionId)) {
+          const popupId = this.registerTab(popup, headless, sessionId, tabId);
+          this.syncInterception(popupId).catch(error =>
+            debug('Failed to intercept requests of popup %s: %O', popupId, error)
+          );
+          this.syncCertificateErrors(popupId).catch(error =>
+            debug('Failed to ignore certificate errors of popup %s: %O', popupId, error)
+          );
+        }
+      });
+    }
+
+    // Proxy credentials apply to every tab, basic auth only to the pages of its session
+    const owner = sessionId ? this.sessions.get(sessionId) : undefined;
+    this.applyDefaultTimeout(tab, owner);
+    const credentials = this.resolveCredentials(owner);
+    if (credentials) {
+      page.authenticate(credentials).catch(error =>
+        debug('Failed to set credentials of page %s: %O', tabId, error)
+      );
+    }
+
+    // Every tab answers dialogs, otherwise a stray alert blocks the page indefinitely
+    on('dialog', dialog => this.handleDialog(dialog, page, tabId, sessionId));
+
+    // A crashed renderer never answers again, closing it fails calls fast instead of hanging
+    on('error', error => {
+      logger.error('page crashed', { tabId, sessionId, url: page.url(), error });
+      page
+        .close()
+        .catch(closeError => debug('Failed to close crashed page %s: %O', tabId, closeError));
+    });
+
+    // Handle page close
+    on('close', () => {
+      tab.pooled?.context.close().catch(() => {});
+      network.clear();
+      tab.screencast?.stop('closed');
+      if (tab.uploadDirs) {
+        removeUploadDirs(tab.uploadDirs);
+      }
+      this.tabs.delete(tabId);
+      if (sessionId) {
+        this.detachFromSession(sessionId, tabId);
+      }
+    });
+
+    return tabId;
+  }
+
+  // With PCS_PAGE_POOL_SIZE headless tabs start on a warm page of the pool, each in a browser
+  // context of its own so closing the tab can reset it for the next one
+  async openTab(request: OpenTabRequest): Promise<string> {
+    const headless = request.headless ?? this.getDefaultHeadless(true);
+    const browser = await this.ensureBrowser(headless);
+
+    try {
+      const pooled =
+        headless && this.pagePool
+          ? (this.pagePool.acquire() ?? (await this.createPooledPage(browser)))
+          : null;
+      const page = pooled?.page ?? (await browser.newPage());
+      const tabId = this.registerTab(page, headless);
+      if (pooled) {
+        this.tabs.get(tabId)!.pooled = pooled;
+      }
+
+      // Navigate to URL if provided
+      if (request.url) {
+        await page.goto(request.url, { waitUntil: 'networkidle2' });
+      }
+
+      return tabId;
+    } catch (error) {
+      throw new BrowserError(`Failed to open tab: ${error}`);
+    }
+  }
+
+  private async createPooledPage(browser: Browser): Promise<PooledPage> {
+    const context = await browser.createBrowserContext();
+    try {
+      return { context, page: await context.newPage() };
+    } catch (error) {
+      await context.close().catch(() => {});
+      throw error;
+    }
+  }
+
+  // Fills the pool only while the headless browser runs, it is never launched just for that
+  private async warmPage(): Promise<PooledPage> {
+    const browser = this.browsers.get(true);
+    if (!browser?.connected) {
+      throw new BrowserError('Browser not initialized');
+    }
+    return this.createPooledPage(browser).catch(error => {
+      debug('Failed to warm a pooled page: %O', error);
+      throw error;
+    });
+  }
+
+  // Gives the page of a closed tab back to the pool. The reset covers what is kept in the
+  // browser context or the document, cookies, storage and permissions, plus the handlers of
+  // the tab. Overrides on the page itself, like an emulated viewport or network conditions,
+  // would carry over to the next tab, so those are left to the caller to close. Pages that fail
+  // to reset or find the pool full are closed here.
+  private async recyclePage(tabId: string, tab: Tab): Promise<boolean> {
+    const pooled = tab.pooled;
+    if (
+      !pooled ||
+      !this.pagePool ||
+      tab.emulated ||
+      tab.locale ||
+      tab.networkConditions ||
+      tab.cpuThrottlingRate !== undefined ||
+      tab.media ||
+      tab.visionDeficiency ||
+      tab.coverage ||
+      tab.screencast
+    ) {
+      return false;
+    }
+
+    this.tabs.delete(tabId);
+    for (const [event, handler] of tab.listeners) {
+      tab.page.off(event, handler);
+    }
+    tab.network.clear();
+    if (tab.uploadDirs) {
+      removeUploadDirs(tab.uploadDirs);
+    }
+    try {
+      const origins = new Set(tab.page.frames().map(frame => storageOrigin(frame.url())));
+      const cdp = await this.getCdpSession(tab);
+      for (const origin of origins) {
+        if (origin) {
+          await cdp.send('Storage.clearDataForOrigin', { origin, storageTypes: 'all' });
+        }
+      }
+      await cdp.detach();
+      await pooled.context.deleteCookie(...(await pooled.context.cookies()));
+      await pooled.context.clearPermissionOverrides();
+      this.grantedPermissions.delete(pooled.context);
+      await tab.page.goto('about:blank');
+      if (this.pagePool.release(pooled)) {
+        return true;
+      }
+    } catch (error) {
+      debug('Failed to reset the pooled page of tab %s: %O', tabId, error);
+    }
+    await pooled.context.close().catch(() => {});
+    return true;
+  }
+
+  async createSession(request: CreateSessionRequest = {}): Promise<SessionInfo> {
+    const headless = request.headless ?? this.getDefaultHeadless(true);
+    const browser = await this.ensureBrowser(headless);
+
+    const now = Date.now();
+    const session: Session = {
+      id: randomUUID(),
+      headless,
+      context: null,
+      pageIds: [],
+      activePageId: null,
+      createdAt: now,
+      lastUsed: now,
+      interception: false,
+      routes: [],
+      blockedResourceTypes: [],
+      blockedRequests: 0,
+      har: null,
+      console: new ConsoleBuffer(),
+      downloadPolicy: null,
+      downloads: null,
+      dialogHandler: { ...DEFAULT_DIALOG_HANDLER },
+      dialogs: [],
+      extraHeaders: {},
+      credentials: null,
+      proxy: request.proxy ? parseProxy(request.proxy) : null,
+      tracing: null,
+      recording: null,
+      defaultTimeout: null,
+      navigationTimeout: null,
+      consentRules: null,
+      dismissedConsents: 0,
+      cacheEnabled: true,
+      ignoreHTTPSErrors: request.ignoreHTTPSErrors ?? false,
+      initScripts: (request.initScripts ?? []).map(script =>
+        compileInitScript(randomUUID(), script)
+      )
+    };
+    if (request.proxyBypass && !session.proxy) {
+      throw new BrowserError('proxyBypass needs a proxy for the session');
+    }
+    if (request.consentRules && !request.autoDismissConsent) {
+      throw new BrowserError('consentRules needs autoDismissConsent for the session');
+    }
+    if (request.autoDismissConsent) {
+      const rules = validateConsentRules(request.consentRules ?? []);
+      session.consentRules = [...rules, ...DEFAULT_CONSENT_RULES];
+    }
+
+    try {
+      // A proxy of its own needs a context of its own
+      if (session.proxy) {
+        session.context = await browser.createBrowserContext({
+          proxyServer: session.proxy.server,
+          proxyBypassList: request.proxyBypass ?? []
+        });
+      } else if (request.isolated) {
+        session.context = await browser.createBrowserContext();
+      }
+      const page = await (session.context ?? browser).newPage();
+      this.sessions.set(session.id, session);
+      session.activePageId = this.registerTab(page, headless, session.id);
+      this.startSessionSweeper();
+      logger.info('session created', { sessionId: session.id, headless });
+      if (session.ignoreHTTPSErrors) {
+        logger.warn('session ignores HTTPS certificate errors', { sessionId: session.id });
+        await this.syncCertificateErrors(session.activePageId);
+      }
+
+      if (request.blockResourceTypes?.length) {
+        await this.blockResourceTypes(session.id, request.blockResourceTypes);
+      }
+
+      if (request.url) {
+        await page.goto(request.url, { waitUntil: 'networkidle2' });
+      }
+
+      return this.describeSession(session);
+    } catch (error) {
+      if (!this.sessions.has(session.id)) {
+        await session.context?.close().catch(() => {});
+      }
+      throw new BrowserError(`Failed to create session: ${error}`);
+    }
+  }
+
+  async closeSession(sessionId: string): Promise<void> {
+    const session = this.sessions.get(sessionId);
+    if (!session) {
+      throw this.sessionNotFound(sessionId);
+    }
+
+    this.sessions.delete(sessionId);
+    if (this.sessions.size === 0) {
+      this.stopSessionSweeper();
+    }
+    logger.info('session closed', { sessionId });
+
+    // Drop the routes so nothing handled by this session outlives it, even on pages of a
+    // shared browser context
+    session.interception = false;
+    session.routes = [];
+    session.blockedResourceTypes = [];
+    session.har?.detach();
+    session.har = null;
+    session.downloads?.stop();
+    session.downloads = null;
+    session.tracing = null;
+    // Finish the file while the page is still there
+    await session.recording?.stop().catch(error => debug('Failed to stop recording: %O', error));
+    session.recording = null;
+
+    try {
+      for (const pageId of session.pageIds) {
+        const tab = this.tabs.get(pageId);
+        if (tab) {
+          this.detachRequestHandler(tab);
+          await tab.page.close();
+          this.tabs.delete(pageId);
+        }
+      }
+      // Disposing the context drops its cookies, storage and cache
+      if (session.context) {
+        await session.context.close();
+      }
+      await this.closeBrowserIfUnused(session.headless);
+    } catch (error) {
+      throw new BrowserError(`Failed to close session: ${error}`);
+    }
+  }
+
+  getSessions(): SessionInfo[] {
+    return Array.from(this.sessions.values()).map(session => this.describeSession(session));
+  }
+
+  // Sets how long the session's pages wait for selectors, clicks and other Puppeteer
+  // operations, and for navigations, and what tools targeting the session fall back to
+  // without a timeout of their own. null goes back to the global default.
+  setDefaultTimeout(sessionId: string, request: DefaultTimeoutRequest): SessionInfo {
+    const session = this.getSession(sessionId);
+    const { timeout, navigationTimeout } = request;
+    if (timeout === undefined && navigationTimeout === undefined) {
+      throw new InvalidArgumentError('timeout or navigationTimeout is required');
+    }
+    if (timeout !== undefined) {
+      session.defaultTimeout = timeout === null ? null : validateTimeout(timeout);
+    }
+    if (navigationTimeout !== undefined) {
+      session.navigationTimeout =
+        navigationTimeout === null ? null : validateTimeout(navigationTimeout);
+    }
+    for (const pageId of session.pageIds) {
+      const tab = this.tabs.get(pageId);
+      if (tab) {
+        this.applyDefaultTimeout(tab, session);
+      }
+    }
+    return this.describeSession(session);
+  }
+
+  // The timeout a call falls back to: the default of the session it targets, else the global.
+  // Calls that navigate or wait for a navigation get the navigation timeout.
+  resolveTimeout(target: TabTarget, navigation = false): number {
+    const sessionId = this.resolveSessionId(target);
+    const session = sessionId ? this.sessions.get(sessionId) : undefined;
+    return navigation
+      ? (session?.navigationTimeout ?? this.navigationTimeout)
+      : (session?.defaultTimeout ?? this.defaultTimeout);
+  }
+
+  // The session a call targets, directly or through one of its pages
+  resolveSessionId(target: TabTarget): string | null {
+    const pageId = target.pageId || target.tabId;
+    return target.sessionId || (pageId ? this.tabs.get(pageId)?.sessionId : undefined) || null;
+  }
+
+  // Throttled tabs keep their longer navigation timeout
+  private applyDefaultTimeout(tab: Tab, session: Session | undefined): void {
+    const slow = tab.networkConditions !== undefined && !tab.networkConditions.offline;
+    const navigationTimeout =
+      (session?.navigationTimeout ?? this.navigationTimeout) *
+      (slow ? THROTTLED_NAVIGATION_FACTOR : 1);
+    tab.page.setDefaultTimeout(session?.defaultTimeout ?? this.defaultTimeout);
+    tab.page.setDefaultNavigationTimeout(navigationTimeout);
+    if (tab.networkConditions) {
+      tab.networkConditions.navigationTimeout = navigationTimeout;
+    }
+  }
+
+  // Maps the tabId/sessionId pair a tool was called with to the tab it should act on and
+  // marks the owning session as used
+  resolveTabId(target: TabTarget): string {
+    const pageId = target.pageId || target.tabId;
+
+    if (!target.sessionId) {
+      if (!pageId) {
+        throw new InvalidArgumentError('Either tabId, pageId or sessionId is required');
+      }
+      const sessionId = this.tabs.get(pageId)?.sessionId;
+      if (sessionId) {
+        this.touchSession(sessionId);
+      }
+      return pageId;
+    }
+
+    const session = this.getSession(target.sessionId);
+    if (pageId) {
+      if (!session.pageIds.includes(pageId)) {
+        throw new TabNotFoundError(pageId);
+      }
+      return pageId;
+    }
+
+    if (!session.activePageId) {
+      throw new BrowserError(`Session ${session.id} has no open pages`);
+    }
+    return session.activePageId;
+  }
+
+  async newPage(sessionId: string, url?: string): Promise<string> {
+    const session = this.getSession(sessionId);
+    const browser = await this.ensureBrowser(session.headless);
+
+    try {
+      const page = await (session.context ?? browser).newPage();
+      const pageId = this.registerTab(page, session.headless, session.id);
+      session.activePageId = pageId;
+      await this.syncInterception(pageId);
+      await this.syncCertificateErrors(pageId);
+
+      if (url) {
+        await page.goto(url, { waitUntil: 'networkidle2' });
+      }
+
+      return pageId;
+    } catch (error) {
+      throw new BrowserError(`Failed to open page: ${error}`);
+    }
+  }
+
+  async listPages(sessionId: string): Promise<PageInfo[]> {
+    const session = this.getSession(sessionId);
+    const pages: PageInfo[] = [];
+
+    for (const pageId of session.pageIds) {
+      const tab = this.tabs.get(pageId);
+      if (!tab) {
+        continue;
+      }
+      pages.push({
+        id: pageId,
+        url: tab.page.url(),
+        title: await tab.page.title(),
+        active: pageId === session.activePageId,
+        target: tab.openerId ? 'popup' : 'page',
+        openerId: tab.openerId ?? null
+      });
+    }
+
+    return pages;
+  }
+
+  async switchPage(sessionId: string, pageId: string): Promise<void> {
+    const session = this.getSession(sessionId);
+    const tab = this.tabs.get(pageId);
+    if (!tab || !session.pageIds.includes(pageId)) {
+      throw new TabNotFoundError(pageId);
+    }
+
+    session.activePageId = pageId;
+    try {
+      await tab.page.bringToFront();
+    } catch (error) {
+      throw new BrowserError(`Failed to switch page: ${error}`);
+    }
+  }
+
+  async closePage(sessionId: string, pageId: string): Promise<void> {
+    const session = this.getSession(sessionId);
+    const tab = this.tabs.get(pageId);
+    if (!tab || !session.pageIds.includes(pageId)) {
+      throw new TabNotFoundError(pageId);
+    }
+
+    try {
+      await tab.page.close();
+      this.tabs.delete(pageId);
+      this.detachFromSession(sessionId, pageId);
+    } catch (error) {
+      throw new BrowserError(`Failed to close page: ${error}`);
+    }
+  }
+
+  async setRequestInterception(sessionId: string, enabled: boolean): Promise<SessionInfo> {
+    const session = this.getSession(sessionId);
+    session.interception = enabled;
+
+    try {
+      for (const pageId of session.pageIds) {
+        await this.syncInterception(pageId);
+      }
+      return this.describeSession(session);
+    } catch (error) {
+      throw new BrowserError(`Failed to set request interception: ${error}`);
+    }
+  }
+
+  // Adding a route turns interception on for the session if it was off
+  async mockRoute(sessionId: string, request: MockRouteRequest): Promise<RouteInfo> {
+    const session = this.getSession(sessionId);
+    const route = compileRoute(randomUUID(), request);
+    session.routes.push(route);
+
+    if (!session.interception) {
+      await this.setRequestInterception(sessionId, true);
+    }
+    return describeRoute(route);
+  }
+
+  // Aborts matching subresources on every page of the session. An empty list stops blocking.
+  async blockResourceTypes(
+    sessionId: string,
+    types: BlockableResourceType[]
+  ): Promise<SessionInfo> {
+    const session = this.getSession(sessionId);
+    const unknown = types.filter(type => !BLOCKABLE_RESOURCE_TYPES.includes(type));
+    if (unknown.length > 0) {
+      const supported = BLOCKABLE_RESOURCE_TYPES.join(', ');
+      throw new BrowserError(`Cannot block resource types ${unknown.join(', ')}; use ${supported}`);
+    }
+    session.blockedResourceTypes = Array.from(new Set(types));
+
+    try {
+      for (const pageId of session.pageIds) {
+        await this.syncInterception(pageId);
+      }
+      return this.describeSession(session);
+    } catch (error) {
+      throw new BrowserError(`Failed to block resource types: ${error}`);
+    }
+  }
+
+  // Records the network activity of every page of the session until stopHar
+  startHar(sessionId: string, request: StartHarRequest = {}): SessionInfo {
+    const session = this.getSession(sessionId);
+    if (session.har) {
+      throw new BrowserError(`Session ${sessionId} is already recording a HAR`);
+    }
+
+    session.har = new HarRecorder({
+      captureBodies: request.captureBodies ?? true,
+      maxBodySize: request.maxBodySize ?? DEFAULT_MAX_BODY_SIZE
+    });
+    for (const pageId of session.pageIds) {
+      const tab = this.tabs.get(pageId);
+      if (tab) {
+        session.har.attach(tab.page, pageId);
+      }
+    }
+    return this.describeSession(session);
+  }
+
+  async stopHar(sessionId: string, request: StopHarRequest = {}): Promise<HarResult> {
+    const session = this.getSession(sessionId);
+    const recorder = session.har;
+    if (!recorder) {
+      throw new BrowserError(`Session ${sessionId} is not recording a HAR`);
+    }
+
+    recorder.detach();
+    session.har = null;
+    try {
+      const har = await recorder.toHar();
+      const entries = har.log.entries.length;
+      if (!request.path) {
+        return { entries, har };
+      }
+      const filePath = await this.writeArtifact(
+        Buffer.from(JSON.stringify(har, null, 2)),
+        request.path
+      );
+      return { entries, path: filePath };
+    } catch (error) {
+      throw new BrowserError(`Failed to export HAR: ${error}`);
+    }
+  }
+
+  // Records a Chrome trace of one session page until stopTracing. Chrome runs a single trace
+  // per browser, so sessions sharing a browser take turns.
+  async startTracing(sessionId: string, request: StartTracingRequest = {}): Promise<SessionInfo> {
+    const session = this.getSession(sessionId);
+    const categories = validateCategories(request.categories);
+    if (session.tracing) {
+      throw new BrowserError(`Session ${sessionId} is already tracing`);
+    }
+    for (const other of this.sessions.values()) {
+      if (other.tracing && other.headless === session.headless) {
+        throw new BrowserError(`Session ${other.id} is tracing, stop it first`);
+      }
+    }
+    const pageId = this.resolveTabId({ sessionId, pageId: request.pageId });
+    const tab = this.tabs.get(pageId);
+    if (!tab) {
+      throw new TabNotFoundError(pageId);
+    }
+
+    try {
+      await tab.page.tracing.start({
+        screenshots: request.screenshots ?? false,
+        ...(categories ? { categories } : {})
+      });
+      session.tracing = pageId;
+      return this.describeSession(session);
+    } catch (error) {
+      throw new BrowserError(`Failed to start tracing: ${error}`);
+    }
+  }
+
+  async stopTracing(sessionId: string, request: StopTracingRequest = {}): Promise<TracingResult> {
+    const session = this.getSession(sessionId);
+    const pageId = session.tracing;
+    if (!pageId) {
+      throw new BrowserError(`Session ${sessionId} is not tracing`);
+    }
+
+    session.tracing = null;
+    const tab = this.tabs.get(pageId);
+    if (!tab) {
+      throw new BrowserError(`Page ${pageId} was closed while tracing, the trace is lost`);
+    }
+    try {
+      const data = await tab.page.tracing.stop();
+      if (!data) {
+        throw new Error('the browser returned no trace');
+      }
+      if (!request.path) {
+        return packTrace(data);
+      }
+      const { size, events } = packTrace(data, Number.POSITIVE_INFINITY);
+      const filePath = await this.writeArtifact(Buffer.from(data), request.path);
+      return { size, events, path: filePath };
+    } catch (error) {
+      throw new BrowserError(`Failed to export trace: ${error}`);
+    }
+  }
+
+  // Records a video of a session page, or of the area of one element, with Puppeteer's
+  // screencast, which pipes the frames through ffmpeg (PCS_FFMPEG_PATH or the one on the
+  // PATH) into a WebM or GIF file
+  async startRecording(
+    sessionId: string,
+    request: StartRecordingRequest = {}
+  ): Promise<SessionInfo> {
+    const session = this.getSession(sessionId);
+    if (session.recording) {
+      throw new BrowserError(`Session ${sessionId} is already recording`);
+    }
+    const options = resolveRecordingOptions(
+      request,
+      path.join('recordings', `${session.id}-${Date.now()}.webm`)
+    );
+    const pageId = this.resolveTabId({ sessionId, pageId: request.pageId });
+    const tab = this.tabs.get(pageId);
+    if (!tab) {
+      throw new TabNotFoundError(pageId);
+    }
+
+    const filePath = resolveClientPath(ensureBaseWorkingDirectory(), options.path);
+    const ffmpegPath = getFfmpegPath();
+    try {
+      const crop = request.selector ? await this.recordingArea(tab.page, request.selector) : null;
+      await fs.mkdir(path.dirname(filePath), { recursive: true });
+      const recorder = await tab.page.screencast({
+        path: filePath as `${string}.${typeof options.format}`,
+        format: options.format,
+        fps: options.fps,
+        ...(crop ? { crop } : {}),
+        ...(ffmpegPath ? { ffmpegPath } : {})
+      });
+      session.recording = new Recording(recorder, pageId, filePath, options.maxDuration);
+      return this.describeSession(session);
+    } catch (error) {
+      const hint = /ENOENT/.test(String(error)) ? ', install ffmpeg or set PCS_FFMPEG_PATH' : '';
+      throw new BrowserError(`Failed to start recording: ${error}${hint}`);
+    }
+  }
+
+  // The area stays where the element was when the recording started
+  private async recordingArea(page: Page, selector: string): Promise<BoundingBox> {
+    const element = await page.$(selector);
+    const box = await element?.boundingBox();
+    await element?.dispose();
+    if (!box) {
+      throw new BrowserError((await this.elementNotFound(page.mainFrame(), selector)).message);
+    }
+    return {
+      x: Math.round(box.x),
+      y: Math.round(box.y),
+      width: Math.round(box.width),
+      height: Math.round(box.height)
+    };
+  }
+
+  async stopRecording(sessionId: string): Promise<RecordingResult> {
+    const session = this.getSession(sessionId);
+    const recording = session.recording;
+    if (!recording) {
+      throw new BrowserError(`Session ${sessionId} is not recording`);
+    }
+
+    session.recording = null;
+    try {
+      await recording.stop();
+      const { size } = await fs.stat(recording.path);
+      return {
+        path: recording.path,
+        size,
+        durationMs: recording.durationMs,
+        truncated: recording.truncated
+      };
+    } catch (error) {
+      throw new BrowserError(`Failed to stop recording: ${error}`);
+    }
+  }
+
+  // Streams JPEG frames of a tab to onFrame until stopScreencast, or until the tab or its
+  // session closes. The returned screencast's done settles with the totals either way.
+  async startScreencast(
+    tabId: string,
+    request: StartScreencastRequest,
+    onFrame: (frame: ScreencastFrame) => void
+  ): Promise<Screencast> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+    if (tab.screencast) {
+      throw new BrowserError(`Tab ${tabId} is already streaming a screencast`);
+    }
+
+    const options = resolveScreencastOptions(request);
+    try {
+      // A session of its own so the frame listener goes away with it
+      const cdp = await tab.page.createCDPSession();
+      const screencast = new Screencast(cdp, options, onFrame);
+      tab.screencast = screencast;
+      screencast.done.finally(() => {
+        if (tab.screencast === screencast) {
+          delete tab.screencast;
+        }
+      });
+      await screencast.start();
+      return screencast;
+    } catch (error) {
+      await tab.screencast?.stop();
+      throw new BrowserError(`Failed to start screencast: ${error}`);
+    }
+  }
+
+  async stopScreencast(tabId: string): Promise<ScreencastResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+    if (!tab.screencast) {
+      throw new BrowserError(`Tab ${tabId} is not streaming a screencast`);
+    }
+    return tab.screencast.stop();
+  }
+
+  // Sends the downloads of every session page, including pages opened later, to a directory
+  // of the session's own and starts watching it for finished files
+  async setDownloadBehavior(
+    sessionId: string,
+    request: DownloadBehaviorRequest = {}
+  ): Promise<SessionInfo> {
+    const session = this.getSession(sessionId);
+    const policy = request.policy ?? 'allow';
+    if (!DOWNLOAD_POLICIES.includes(policy)) {
+      throw new InvalidArgumentError(
+        `Download policy must be one of: ${DOWNLOAD_POLICIES.join(', ')}`
+      );
+    }
+
+    // A directory outside the working directory is refused before the current one is dropped
+    const directory =
+      policy === 'allow'
+        ? resolveClientPath(
+            ensureBaseWorkingDirectory(),
+            request.path || path.join('downloads', session.id)
+          )
+        : null;
+
+    try {
+      session.downloads?.stop();
+      session.downloads = null;
+      if (directory) {
+        const watcher = new DownloadWatcher(directory);
+        watcher.start();
+        session.downloads = watcher;
+      }
+      session.downloadPolicy = policy;
+
+      for (const pageId of session.pageIds) {
+        const tab = this.tabs.get(pageId);
+        if (tab) {
+          await this.applyDownloadBehavior(tab, session);
+        }
+      }
+      return this.describeSession(session);
+    } catch (error) {
+      throw new BrowserError(`Failed to set download behavior: ${error}`);
+    }
+  }
+
+  // Resolves once a download of the session has finished writing to disk. Downloads that
+  // finished since the last call are returned first, so starting the download before waiting
+  // does not lose it.
+  async waitForDownload(sessionId: string, timeout?: number): Promise<WaitForDownloadResult> {
+    const session = this.getSession(sessionId);
+    if (!session.downloads) {
+      throw new BrowserError(
+        `Downloads are not enabled for session ${sessionId}, set the download behavior first`
+      );
+    }
+    return session.downloads.next(timeout ?? session.defaultTimeout ?? this.defaultTimeout);
+  }
+
+  private async applyDownloadBehavior(tab: Tab, session: Session): Promise<void> {
+    const cdp = await this.getCdpSession(tab);
+    await cdp.send(
+      'Page.setDownloadBehavior',
+      session.downloads
+        ? { behavior: 'allow', downloadPath: session.downloads.directory }
+        : { behavior: 'deny' }
+    );
+  }
+
+  // Sends the headers with every request of the session's pages, replacing the previous
+  // ones. An empty object stops sending them.
+  async setExtraHTTPHeaders(
+    sessionId: string,
+    headers: Record<string, string>
+  ): Promise<ExtraHeadersResult> {
+    const session = this.getSession(sessionId);
+    const validated = validateHeaders(headers);
+
+    try {
+      session.extraHeaders = validated.headers;
+      for (const pageId of session.pageIds) {
+        const tab = this.tabs.get(pageId);
+        if (tab) {
+          await tab.page.setExtraHTTPHeaders(session.extraHeaders);
+        }
+      }
+      return { headers: Object.keys(validated.headers), warnings: validated.warnings };
+    } catch (error) {
+      throw new BrowserError(`Failed to set extra HTTP headers: ${error}`);
+    }
+  }
+
+  // The HTTP cache is on by default, as in Chrome. Turning it off makes every navigation
+  // load fresh from the network, which keeps tests deterministic; keeping it on saves
+  // refetching shared assets. Puppeteer bypasses the cache anyway while it intercepts
+  // requests, i.e. while the session has routes or blocks resource types.
+  async setCacheEnabled(sessionId: string, enabled: boolean): Promise<SessionInfo> {
+    const session = this.getSession(sessionId);
+    if (typeof enabled !== 'boolean') {
+      throw new InvalidArgumentError('enabled must be true or false');
+    }
+
+    try {
+      session.cacheEnabled = enabled;
+      for (const pageId of session.pageIds) {
+        const tab = this.tabs.get(pageId);
+        if (tab) {
+          await tab.page.setCacheEnabled(enabled);
+        }
+      }
+      return this.describeSession(session);
+    } catch (error) {
+      throw new BrowserError(`Failed to set the cache: ${error}`);
+    }
+  }
+
+  // Answers HTTP basic and digest auth challenges of the session's pages. Omitting the
+  // credentials stops answering them.
+  async setBasicAuth(sessionId: string, request?: BasicAuthRequest): Promise<SessionInfo> {
+    const session = this.getSession(sessionId);
+    const credentials = request ? validateBasicAuth(request) : null;
+    if (credentials && (session.proxy ?? this.proxy)?.username) {
+      throw new BrowserError(
+        `Session ${sessionId} answers auth challenges with its proxy credentials, ` +
+          'Chrome cannot use a second set for basic auth'
+      );
+    }
+
+    try {
+      session.credentials = credentials;
+      for (const pageId of session.pageIds) {
+        const tab = this.tabs.get(pageId);
+        if (tab) {
+          await tab.page.authenticate(this.resolveCredentials(session));
+        }
+      }
+      return this.describeSession(session);
+    } catch (error) {
+      throw new BrowserError(`Failed to set basic auth: ${error}`);
+    }
+  }
+
+  // Chrome answers every auth challenge of a page with the same credentials, so a proxy that
+  // needs them rules out basic auth for the sites behind it
+  private resolveCredentials(session?: Session): BasicAuthRequest | null {
+    if (session?.credentials) {
+      return session.credentials;
+    }
+    const proxy = session?.proxy ?? this.proxy;
+    return proxy?.username ? { username: proxy.username, password: proxy.password ?? '' } : null;
+  }
+
+  getConsoleLogs(sessionId: string, request: ConsoleLogsRequest = {}): ConsoleLogsResult {
+    const session = this.getSession(sessionId);
+    const result = session.console.list(request);
+    if (request.clear) {
+      session.console.clear();
+    }
+    return result;
+  }
+
+  // Buffers console output and uncaught errors of a session page so failures that never
+  // surface in a tool result can still be inspected
+  private captureConsole(page: Page, sessionId: string, pageId: string): void {
+    const record = (entry: ConsoleEntry) => this.sessions.get(sessionId)?.console.push(entry);
+
+    page.on('console', message => {
+      const location = message.location();
+      record({
+        source: 'console',
+        level: toConsoleLevel(message.type()),
+        type: message.type(),
+        text: message.text(),
+        url: location.url || null,
+        lineNumber: location.lineNumber === undefined ? null : location.lineNumber + 1,
+        columnNumber: location.columnNumber === undefined ? null : location.columnNumber + 1,
+        timestamp: new Date().toISOString(),
+        pageId
+      });
+    });
+
+    page.on('pageerror', error => {
+      const location = parseStackLocation(error.stack);
+      record({
+        source: 'pageerror',
+        level: 'error',
+        type: 'error',
+        text: error.message || String(error),
+        url: location?.url ?? null,
+        lineNumber: location?.lineNumber ?? null,
+        columnNumber: location?.columnNumber ?? null,
+        timestamp: new Date().toISOString(),
+        pageId
+      });
+    });
+  }
+
+  // Applies to dialogs opened by any page of the session from now on
+  setDialogHandler(sessionId: string, request: DialogHandlerRequest): SessionInfo {
+    const session = this.getSession(sessionId);
+    if (!DIALOG_ACTIONS.includes(request.action)) {
+      throw new InvalidArgumentError(`Dialog action must be one of: ${DIALOG_ACTIONS.join(', ')}`);
+    }
+    session.dialogHandler = { action: request.action };
+    if (request.promptText !== undefined) {
+      session.dialogHandler.promptText = request.promptText;
+    }
+    return this.describeSession(session);
+  }
+
+  // Dialogs the session's pages opened, oldest first, with how each was answered
+  getDialogs(sessionId: string, clear = false): DialogEntry[] {
+    const session = this.getSession(sessionId);
+    const dialogs = [...session.dialogs];
+    if (clear) {
+      session.dialogs = [];
+    }
+    return dialogs;
+  }
+
+  private handleDialog(dialog: Dialog, page: Page, tabId: string, sessionId?: string): void {
+    const session = sessionId ? this.sessions.get(sessionId) : undefined;
+    const handler = session?.dialogHandler ?? DEFAULT_DIALOG_HANDLER;
+    const response = resolveDialogResponse(handler, dialog.type(), dialog.defaultValue());
+    const answer = response.accept ? dialog.accept(response.promptText) : dialog.dismiss();
+    answer.catch(error =>
+      debug('Failed to answer %s dialog on page %s: %O', dialog.type(), tabId, error)
+    );
+
+    if (session) {
+      pushDialog(session.dialogs, {
+        type: dialog.type(),
+        message: dialog.message(),
+        defaultValue: dialog.defaultValue(),
+        action: response.accept ? 'accept' : 'dismiss',
+        promptText: response.promptText ?? null,
+        url: page.url(),
+        pageId: tabId,
+        timestamp: new Date().toISOString()
+      });
+    }
+  }
+
+  // Cookies of the session's browser context, optionally only those sent to one of the URLs
+  async getCookies(sessionId: string, urls: string[] = []): Promise<Cookie[]> {
+    const session = this.getSession(sessionId);
+
+    let parsed: URL[];
+    try {
+      parsed = urls.map(url => new URL(url));
+    } catch (error) {
+      throw new InvalidArgumentError(`Invalid cookie URL filter: ${error}`);
+    }
+
+    try {
+      const cookies = await this.getSessionContext(session).cookies();
+      if (parsed.length === 0) {
+        return cookies;
+      }
+      return cookies.filter(cookie => parsed.some(url => cookieMatchesUrl(cookie, url)));
+    } catch (error) {
+      throw new BrowserError(`Failed to get cookies: ${error}`);
+    }
+  }
+
+  // All cookies are validated before any is applied, so a bad one leaves the jar untouched
+  async setCookies(sessionId: string, cookies: CookieInput[]): Promise<number> {
+    const session = this.getSession(sessionId);
+    const validated = cookies.map(cookie => validateCookie(cookie));
+
+    try {
+      await this.getSessionContext(session).setCookie(...validated);
+      return validated.length;
+    } catch (error) {
+      throw new BrowserError(`Failed to set cookies: ${error}`);
+    }
+  }
+
+  // Cookies as JSON or in the cookies.txt format of curl, wget and yt-dlp. A file written to
+  // path holds credentials, so only its owner can read it.
+  async exportCookies(
+    sessionId: string,
+    request: ExportCookiesRequest = {}
+  ): Promise<ExportCookiesResult> {
+    const format = resolveCookieFormat(request.format);
+    const cookies = await this.getCookies(sessionId, request.urls);
+    const content = formatCookies(cookies, format);
+    if (!request.path) {
+      return { format, count: cookies.length, content, path: null };
+    }
+
+    try {
+      const filePath = await this.writeArtifact(Buffer.from(content), request.path, 0o600);
+      return { format, count: cookies.length, content: null, path: filePath };
+    } catch (error) {
+      throw new BrowserError(`Failed to export cookies: ${error}`);
+    }
+  }
+
+  // Adds the cookies of an export or a cookies.txt file, given as content or a path relative
+  // to the working directory. All are validated before any is applied, and those that have
+  // expired already are skipped.
+  async importCookies(
+    sessionId: string,
+    request: ImportCookiesRequest
+  ): Promise<ImportCookiesResult> {
+    const session = this.getSession(sessionId);
+    const format = resolveCookieFormat(request.format);
+    if ((request.content === undefined) === (request.path === undefined)) {
+      throw new InvalidArgumentError('content or path is required, but not both');
+    }
+
+    let text = request.content ?? '';
+    if (request.path !== undefined) {
+      const filePath = resolveClientPath(ensureBaseWorkingDirectory(), request.path);
+      try {
+        text = await fs.readFile(filePath, 'utf8');
+      } catch (error) {
+        throw new BrowserError(`Failed to read cookies file ${request.path}: ${error}`);
+      }
+    }
+    const now = Date.now() / 1000;
+    const cookies = parseCookies(text, format);
+    const fresh = cookies.filter(cookie => cookie.expires === undefined || cookie.expires > now);
+    const validated = fresh.map(cookie => validateCookie(cookie));
+
+    try {
+      if (validated.length) {
+        await this.getSessionContext(session).setCookie(...validated);
+      }
+      return { count: validated.length, expired: cookies.length - fresh.length };
+    } catch (error) {
+      throw new BrowserError(`Failed to import cookies: ${error}`);
+    }
+  }
+
+  // Deletes cookies matching the filters, or every cookie of the context with all set.
+  // Returns how many cookies were removed.
+  async deleteCookies(sessionId: string, filters: CookieFilter[], all = false): Promise<number> {
+    const session = this.getSession(sessionId);
+    const context = this.getSessionContext(session);
+
+    try {
+      const before = await context.cookies();
+      if (all) {
+        await context.deleteCookie(...before);
+      } else if (filters.length > 0) {
+        await context.deleteMatchingCookies(...filters.map(filter => compactFilter(filter)));
+      }
+      return before.length - (await context.cookies()).length;
+    } catch (error) {
+      throw new BrowserError(`Failed to delete cookies: ${error}`);
+    }
+  }
+
+  // Writes the session's cookies and the localStorage of the origins its pages are on in
+  // Playwright's storageState format, so a login can outlive the server. The file holds
+  // credentials: it is only readable by the owner and encrypted when there is a passphrase.
+  async saveSessionState(
+    sessionId: string,
+    request: SaveSessionStateRequest = {}
+  ): Promise<SaveSessionStateResult> {
+    const session = this.getSession(sessionId);
+    const passphrase = request.passphrase || getStatePassphrase();
+
+    try {
+      const cookies = await this.getSessionContext(session).cookies();
+      const origins = new Map<string, StorageStateOrigin>();
+      for (const pageId of session.pageIds) {
+        const page = this.tabs.get(pageId)?.page;
+        const origin = page && storageOrigin(page.url());
+        if (!page || !origin || !isStateOrigin(origin) || origins.has(origin)) {
+          continue;
+        }
+        const items = await page.evaluate(readStorage, 'local', []);
+        const localStorage = Object.entries(items).map(([name, value]) => ({ name, value }));
+        origins.set(origin, { origin, localStorage });
+      }
+
+      const state: StorageState = {
+        cookies: cookies.map(cookie => toStateCookie(cookie)),
+        origins: Array.from(origins.values())
+      };
+      const filePath = await this.writeArtifact(
+        encodeStorageState(state, passphrase),
+        request.path || path.join('state', `${session.id}.json`),
+        0o600
+      );
+      return {
+        path: filePath,
+        cookies: state.cookies.length,
+        origins: state.origins.length,
+        encrypted: Boolean(passphrase)
+      };
+    } catch (error) {
+      throw new BrowserError(`Failed to save session state: ${error}`);
+    }
+  }
+
+  // Creates a session from a file saved by saveSessionState or Playwright. It is isolated
+  // unless asked otherwise, so the restored login does not leak into other sessions, and
+  // only opens the URL once the state is in place.
+  async loadSessionState(request: LoadSessionStateRequest): Promise<LoadSessionStateResult> {
+    const { path: filePath, passphrase, url, ...options } = request;
+    const resolved = resolveClientPath(ensureBaseWorkingDirectory(), filePath);
+    let state: StorageState;
+    try {
+      const file = await fs.readFile(resolved);
+      state = decodeStorageState(file, passphrase || getStatePassphrase());
+    } catch (error) {
+      throw error instanceof BrowserError
+        ? error
+        : new BrowserError(`Failed to read state file ${filePath}: ${error}`);
+    }
+    const cookies = state.cookies.map(cookie => validateCookie(fromStateCookie(cookie)));
+
+    const info = await this.createSession({ ...options, isolated: options.isolated ?? true });
+    const session = this.getSession(info.id);
+    try {
+      const context = this.getSessionContext(session);
+      if (cookies.length) {
+        await context.setCookie(...cookies);
+      }
+      await this.restoreLocalStorage(context, state.origins);
+      const page = session.activePageId ? this.tabs.get(session.activePageId)?.page : undefined;
+      if (url && page) {
+        await page.goto(url, { waitUntil: 'networkidle2' });
+      }
+      return {
+        session: this.describeSession(session),
+        cookies: cookies.length,
+        origins: state.origins.length
+      };
+    } catch (error) {
+      await this.closeSession(session.id).catch(() => {});
+      throw new BrowserError(`Failed to load session state: ${error}`);
+    }
+  }
+
+  // localStorage can only be written from a document of its origin, so a scratch page visits
+  // each origin with every request answered by a blank page and nothing reaching the site
+  private async restoreLocalStorage(
+    context: BrowserContext,
+    origins: StorageStateOrigin[]
+  ): Promise<void> {
+    const wanted = origins.filter(
+      ({ origin, localStorage }) => isStateOrigin(origin) && localStorage.length > 0
+    );
+    if (!wanted.length) {
+      return;
+    }
+
+    const page = await context.newPage();
+    try {
+      await page.setRequestInterception(true);
+      page.on('request', request => {
+        request
+          .respond({ status: 200, contentType: 'text/html', body: '<html></html>' })
+          .catch(() => {});
+      });
+      for (const { origin, localStorage } of wanted) {
+        await page.goto(origin);
+        const items = Object.fromEntries(localStorage.map(({ name, value }) => [name, value]));
+        await page.evaluate(writeStorage, 'local', items);
+      }
+    } finally {
+      await page.close().catch(() => {});
+    }
+  }
+
+  private getSessionContext(session: Session): BrowserContext {
+    if (session.context) {
+      return session.context;
+    }
+    const browser = this.browsers.get(session.headless);
+    if (!browser) {
+      throw new BrowserError(`Browser of session ${session.id} is not running`);
+    }
+    return browser.defaultBrowserContext();
+  }
+
+  // Runs a script in every new document of the session's pages, frames included, before the
+  // page's own scripts. Pages already open get it from their next navigation on, pages
+  // opened later from the start. Returns its ID for removeInitScripts.
+  async addInitScript(sessionId: string, request: AddInitScriptRequest): Promise<InitScriptInfo> {
+    const session = this.getSession(sessionId);
+    const script = compileInitScript(randomUUID(), request);
+    session.initScripts.push(script);
+
+    try {
+      for (const pageId of session.pageIds) {
+        const tab = this.tabs.get(pageId);
+        if (tab) {
+          await this.applyInitScript(tab, script);
+        }
+      }
+      return describeInitScript(script);
+    } catch (error) {
+      throw new BrowserError(`Failed to add init script: ${error}`);
+    }
+  }
+
+  // Removes one init script, or all of them without a script ID. Documents a script already
+  // ran in keep what it did until they reload. Returns how many were removed.
+  async removeInitScripts(sessionId: string, scriptId?: string): Promise<number> {
+    const session = this.getSession(sessionId);
+    const removed = session.initScripts.filter(script => !scriptId || script.id === scriptId);
+    session.initScripts = session.initScripts.filter(script => !removed.includes(script));
+
+    try {
+      for (const pageId of session.pageIds) {
+        const tab = this.tabs.get(pageId);
+        for (const script of removed) {
+          const identifier = tab?.initScripts?.get(script.id);
+          if (tab && identifier) {
+            tab.initScripts?.delete(script.id);
+            await tab.page.removeScriptToEvaluateOnNewDocument(identifier);
+          }
+        }
+      }
+      return removed.length;
+    } catch (error) {
+      throw new BrowserError(`Failed to remove init scripts: ${error}`);
+    }
+  }
+
+  private async applyInitScript(tab: Tab, script: InitScript): Promise<void> {
+    const { identifier } = await tab.page.evaluateOnNewDocument(script.source);
+    tab.initScripts ??= new Map();
+    tab.initScripts.set(script.id, identifier);
+  }
+
+  // Removes one route, or all of them without a route ID. Returns how many were removed.
+  unroute(sessionId: string, routeId?: string): number {
+    const session = this.getSession(sessionId);
+    const before = session.routes.length;
+    session.routes = routeId ? session.routes.filter(route => route.id !== routeId) : [];
+    return before - session.routes.length;
+  }
+
+  // Brings a session page in line with the session's interception and blocking settings
+  private async syncInterception(pageId: string): Promise<void> {
+    const tab = this.tabs.get(pageId);
+    const session = tab?.sessionId ? this.sessions.get(tab.sessionId) : undefined;
+    if (!tab || !session) {
+      return;
+    }
+
+    const intercept = session.interception || session.blockedResourceTypes.length > 0;
+    if (intercept && !tab.requestHandler) {
+      const sessionId = session.id;
+      tab.requestHandler = request => this.handleInterceptedRequest(sessionId, request);
+      tab.page.on('request', tab.requestHandler);
+      await tab.page.setRequestInterception(true);
+    } else if (!intercept && tab.requestHandler) {
+      this.detachRequestHandler(tab);
+      await tab.page.setRequestInterception(false);
+    }
+  }
+
+  // Chrome checks certificates per page, so each page of a session that accepts invalid ones
+  // is told before it navigates. Browsers launched with PCS_IGNORE_HTTPS_ERRORS need nothing.
+  private async syncCertificateErrors(pageId: string): Promise<void> {
+    const tab = this.tabs.get(pageId);
+    const session = tab?.sessionId ? this.sessions.get(tab.sessionId) : undefined;
+    if (!tab || !session?.ignoreHTTPSErrors || this.ignoreHTTPSErrors) {
+      return;
+    }
+    const cdp = await this.getCdpSession(tab);
+    await cdp.send('Security.setIgnoreCertificateErrors', { ignore: true });
+  }
+
+  private detachRequestHandler(tab: Tab): void {
+    if (tab.requestHandler) {
+      tab.page.off('request', tab.requestHandler);
+      delete tab.requestHandler;
+    }
+  }
+
+  private handleInterceptedRequest(sessionId: string, request: HTTPRequest): void {
+    if (request.isInterceptResolutionHandled()) {
+      return;
+    }
+
+    const session = this.sessions.get(sessionId);
+    const route =
+      session?.interception === true
+        ? findRoute(session.routes, request.url(), request.method())
+        : null;
+    if (route) {
+      route.hits++;
+    }
+
+    // An explicit route beats the resource type filter, so a blocked stylesheet can still
+    // be mocked
+    const blocked =
+      !route &&
+      session !== undefined &&
+      (session.blockedResourceTypes as string[]).includes(request.resourceType());
+
+    let resolution: Promise<void>;
+    if (blocked && session) {
+      session.blockedRequests++;
+      resolution = request.abort('blockedbyclient');
+    } else if (route?.action === 'block') {
+      resolution = request.abort('blockedbyclient');
+    } else if (route?.action === 'fulfill') {
+      resolution = request.respond(route.response);
+    } else {
+      resolution = request.continue();
+    }
+    resolution.catch(error => debug('Failed to resolve request %s: %O', request.url(), error));
+  }
+
+  async evictIdleSessions(now = Date.now()): Promise<string[]> {
+    const evicted: string[] = [];
+    if (!this.sessionTtl) {
+      return evicted;
+    }
+    for (const session of Array.from(this.sessions.values())) {
+      const idleMs = now - session.lastUsed;
+      if (idleMs < this.sessionTtl) {
+        continue;
+      }
+      logger.info('closing idle session', {
+        sessionId: session.id,
+        idleMs,
+        sessionTtl: this.sessionTtl
+      });
+      sessionsExpired.inc();
+      try {
+        await this.closeSession(session.id);
+      } catch (error) {
+        debug('Failed to close idle session %s: %O', session.id, error);
+      }
+      evicted.push(session.id);
+    }
+    return evicted;
+  }
+
+  private getSession(sessionId: string): Session {
+    const session = this.sessions.get(sessionId);
+    if (!session) {
+      throw this.sessionNotFound(sessionId);
+    }
+    session.lastUsed = Date.now();
+    return session;
+  }
+
+  private sessionNotFound(sessionId: string): SessionNotFoundError {
+    const crashedAt = this.crashedSessions.get(sessionId);
+    return crashedAt === undefined
+      ? new SessionNotFoundError(sessionId)
+      : new SessionCrashedError(sessionId, new Date(crashedAt));
+  }
+
+  private touchSession(sessionId: string): void {
+    const session = this.sessions.get(sessionId);
+    if (session) {
+      session.lastUsed = Date.now();
+    }
+  }
+
+  private detachFromSession(sessionId: string, pageId: string): void {
+    const session = this.sessions.get(sessionId);
+    if (!session) {
+      return;
+    }
+    session.pageIds = session.pageIds.filter(id => id !== pageId);
+    if (session.activePageId === pageId) {
+      session.activePageId = session.pageIds[session.pageIds.length - 1] ?? null;
+    }
+  }
+
+  private describeSession(session: Session): SessionInfo {
+    return {
+      id: session.id,
+      headless: session.headless,
+      isolated: session.context !== null,
+      pageIds: [...session.pageIds],
+      activePageId: session.activePageId,
+      createdAt: new Date(session.createdAt).toISOString(),
+      lastUsed: new Date(session.lastUsed).toISOString(),
+      interception: session.interception,
+      routes: session.routes.map(describeRoute),
+      blockedResourceTypes: [...session.blockedResourceTypes],
+      blockedRequests: session.blockedRequests,
+      recordingHar: session.har !== null,
+      tracingPageId: session.tracing,
+      recordingPageId: session.recording?.pageId ?? null,
+      downloadDirectory: session.downloads?.directory ?? null,
+      dialogHandler: { ...session.dialogHandler },
+      extraHeaders: Object.keys(session.extraHeaders),
+      proxy: (session.proxy ?? this.proxy)?.server ?? null,
+      basicAuthUsername: session.credentials?.username ?? null,
+      defaultTimeout: session.defaultTimeout ?? this.defaultTimeout,
+      navigationTimeout: session.navigationTimeout ?? this.navigationTimeout,
+      autoDismissConsent: session.consentRules !== null,
+      dismissedConsents: session.dismissedConsents,
+      cacheEnabled: session.cacheEnabled,
+      ignoreHTTPSErrors: session.ignoreHTTPSErrors || this.ignoreHTTPSErrors,
+      initScripts: session.initScripts.map(describeInitScript)
+    };
+  }
+
+  // Best-effort, a banner no rule finds or that ignores the click is left where it is
+  private async dismissConsent(page: Page, sessionId: string, tabId: string): Promise<void> {
+    for (const delay of CONSENT_RETRY_DELAYS) {
+      if (delay > 0) {
+        await new Promise(resolve => setTimeout(resolve, delay));
+      }
+      const session = this.sessions.get(sessionId);
+      if (!session?.consentRules || page.isClosed()) {
+        return;
+      }
+      for (const frame of page.frames()) {
+        const rule = await frame
+          .evaluate(dismissConsentInPage, session.consentRules)
+          .catch(error => debug('Failed to look for a consent banner in %s: %O', tabId, error));
+        if (rule) {
+          session.dismissedConsents++;
+          debug('Dismissed the %s consent banner of page %s', rule, tabId);
+          return;
+        }
+      }
+    }
+  }
+
+  private startSessionSweeper(): void {
+    if (this.sessionSweeper || !this.sessionTtl) {
+      return;
+    }
+    const interval = Math.max(
+      MIN_SESSION_SWEEP_INTERVAL_MS,
+      Math.min(SESSION_SWEEP_INTERVAL_MS, this.sessionTtl)
+    );
+    this.sessionSweeper = setInterval(() => {
+      void this.evictIdleSessions();
+    }, interval);
+    // never keep the process alive just to sweep
+    this.sessionSweeper.unref();
+  }
+
+  private stopSessionSweeper(): void {
+    if (this.sessionSweeper) {
+      clearInterval(this.sessionSweeper);
+      this.sessionSweeper = null;
+    }
+  }
+
+  // close the browser if no tabs are left, a page pool keeps the headless one warm
+  private async closeBrowserIfUnused(headless: boolean): Promise<void> {
+    const anyTabsLeft = Array.from(this.tabs.values()).some(t => t.visible === headless);
+    if (!anyTabsLeft && !(headless && this.pagePool)) {
+      const browser = this.browsers.get(headless);
+      if (browser) {
+        await this.releaseBrowser(browser);
+        this.browsers.set(headless, null);
+      }
+    }
+  }
+
+  // With returnPartialOnTimeout a page that never reaches waitUntil, usually because a
+  // third-party resource hangs, is reported as a partial load instead of failing, since it
+  // is often usable already. referer and headers only go with this navigation's request.
+  async navigateTab(
+    tabId: string,
+    url: string,
+    options?: {
+      waitUntil?: WaitUntil | undefined;
+      timeout?: number | undefined;
+      returnPartialOnTimeout?: boolean | undefined;
+      referer?: string | undefined;
+      headers?: Record<string, string> | undefined;
+    }
+  ): Promise<NavigationResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+    const navigation = resolveNavigationHeaders(options ?? {});
+    const warnings = navigation.warnings.length ? { warnings: navigation.warnings } : {};
+
+    const page = tab.page;
+    // goto() returns no response when the navigation is aborted, so track the main frame's
+    // last navigation response to report status and headers for downloads and errors
+    let mainResponse = null as HTTPResponse | null;
+    const onResponse = (response: HTTPResponse) => {
+      const request = response.request();
+      if (request.isNavigationRequest() && request.frame() === page.mainFrame()) {
+        mainResponse = response;
+      }
+    };
+    page.on('response', onResponse);
+    let stopSendingHeaders: (() => Promise<void>) | null = null;
+
+    try {
+      const gotoOptions: any = { waitUntil: options?.waitUntil || 'networkidle2' };
+      if (options?.timeout !== undefined) gotoOptions.timeout = options.timeout;
+      if (navigation.referer !== null) gotoOptions.referer = navigation.referer;
+      if (Object.keys(navigation.headers).length > 0) {
+        stopSendingHeaders = await this.sendNavigationHeaders(tab, url, navigation.headers);
+      }
+
+      const response = (await page.goto(url, gotoOptions)) ?? mainResponse;
+      return {
+        outcome: 'loaded',
+        url: page.url(),
+        status: response ? response.status() : null,
+        headers: response ? response.headers() : {},
+        ...warnings
+      };
+    } catch (error) {
+      if (options?.returnPartialOnTimeout && isTimeoutError(error)) {
+        const readyState = await page
+          .evaluate(() => String((globalThis as any).document.readyState))
+          .catch(() => null);
+        return {
+          outcome: 'loaded',
+          url: page.url(),
+          status: mainResponse ? mainResponse.status() : null,
+          headers: mainResponse ? mainResponse.headers() : {},
+          partial: true,
+          readyState,
+          ...warnings
+        };
+      }
+
+      const errorCode = getNetErrorCode(error);
+      if (!errorCode) {
+        const throttled = tab.networkConditions?.throttled
+          ? ` (network throttled to ${tab.networkConditions.preset ?? 'custom conditions'})`
+          : '';
+        throw new BrowserError(`Failed to navigate tab: ${error}${throttled}`);
+      }
+
+      const headers = mainResponse ? mainResponse.headers() : {};
+      const status = mainResponse ? mainResponse.status() : null;
+      if (errorCode === 'ERR_ABORTED' && mainResponse && isDownloadResponse(status, headers)) {
+        return {
+          outcome: 'download',
+          url: mainResponse.url(),
+          status,
+          headers,
+          filename: getDownloadFilename(headers, mainResponse.url()),
+          ...warnings
+        };
+      }
+
+      const errorCategory = classifyNetError(errorCode);
+      return { outcome: 'error', url, status, headers, errorCode, errorCategory, ...warnings };
+    } finally {
+      page.off('response', onResponse);
+      await stopSendingHeaders?.();
+    }
+  }
+
+  // Adds headers to the request of the navigation to url alone. Unlike setExtraHTTPHeaders
+  // they never reach its subresources, frames or later navigations. Returns what stops it.
+  private async sendNavigationHeaders(
+    tab: Tab,
+    url: string,
+    headers: Record<string, string>
+  ): Promise<() => Promise<void>> {
+    const cdp = await this.getCdpSession(tab);
+    let sent = false;
+    const onPaused = (event: Protocol.Fetch.RequestPausedEvent) => {
+      const matches = !sent && isNavigationRequest(event.request.url, url);
+      sent ||= matches;
+      cdp
+        .send(
+          'Fetch.continueRequest',
+          matches
+            ? {
+                requestId: event.requestId,
+                headers: withNavigationHeaders(event.request.headers, headers)
+              }
+            : { requestId: event.requestId }
+        )
+        .catch(error => debug('Failed to continue request %s: %O', event.request.url, error));
+    };
+    await cdp.send('Fetch.enable', {
+      patterns: [{ urlPattern: '*', resourceType: 'Document', requestStage: 'Request' }]
+    });
+    cdp.on('Fetch.requestPaused', onPaused);
+    return async () => {
+      cdp.off('Fetch.requestPaused', onPaused);
+      await cdp
+        .send('Fetch.disable')
+        .catch(error => debug('Failed to stop adding navigation headers: %O', error));
+    };
+  }
+
+  async screenshotTab(tabId: string, fullPage = false): Promise<string> {
+    const result = await this.captureScreenshot(tabId, { fullPage });
+    return result.data as string;
+  }
+
+  async captureScreenshot(tabId: string, request: ScreenshotRequest): Promise<ScreenshotResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const element = Boolean(request.selector || request.xpath);
+    const modes = [request.fullPage, element, request.clip].filter(Boolean);
+    if (modes.length > 1) {
+      throw new BrowserError('Only one of fullPage, selector or xpath, or clip can be set');
+    }
+
+    const format = request.format ?? 'png';
+    if (request.quality !== undefined && format === 'png') {
+      throw new BrowserError('quality is only supported for jpeg and webp screenshots');
+    }
+    const current = tab.visionDeficiency ?? 'none';
+    const visionDeficiency =
+      request.visionDeficiency === undefined
+        ? current
+        : validateVisionDeficiency(request.visionDeficiency);
+    const fullPage = request.fullPage ? resolveFullPageOptions(request) : null;
+    if (request.padding !== undefined) {
+      if (!element) {
+        throw new BrowserError('padding is only supported for element screenshots');
+      }
+      validatePadding(request.padding);
+    }
+    if (request.background !== undefined && request.omitBackground) {
+      throw new BrowserError('Only one of background or omitBackground can be set');
+    }
+    const background =
+      request.background === undefined ? null : parseBackgroundColor(request.background);
+    const selector = element ? toSelector(request) : null;
+    const index = toElementIndex(request);
+
+    try {
+      const options: any = { type: format, encoding: 'base64' };
+      if (request.quality !== undefined) options.quality = request.quality;
+      if (request.omitBackground !== undefined) options.omitBackground = request.omitBackground;
+
+      // Emulated for this capture only, the tab's own emulation comes back afterwards
+      if (visionDeficiency !== current) {
+        await tab.page.emulateVisionDeficiency(visionDeficiency);
+      }
+      const cdp = background ? await this.getCdpSession(tab) : null;
+      await cdp?.send('Emulation.setDefaultBackgroundColorOverride', { color: background! });
+      let data: string;
+      try {
+        if (selector) {
+          const frame = await this.resolveFrame(tab.page, request.frame);
+          const handle = await this.findElement(frame, selector, index);
+          try {
+            await handle.scrollIntoView();
+            await (await this.waitForMatch(frame, selector, index, { visible: true }))?.dispose();
+            if (request.padding) {
+              data = (await tab.page.screenshot({
+                ...options,
+                clip: await this.paddedClip(tab.page, handle, request.padding),
+                captureBeyondViewport: true
+              })) as string;
+            } else {
+              data = (await handle.screenshot(options)) as string;
+            }
+          } finally {
+            await handle.dispose().catch(() => {});
+          }
+        } else if (fullPage) {
+          data = await this.captureFullPage(tab.page, options, fullPage);
+        } else {
+          if (request.clip) options.clip = request.clip;
+          data = (await tab.page.screenshot(options)) as string;
+        }
+      } finally {
+        if (visionDeficiency !== current) {
+          await tab.page.emulateVisionDeficiency(current).catch(error =>
+            debug('Failed to restore vision deficiency of %s: %O', tabId, error)
+          );
+        }
+        await cdp
+          ?.send('Emulation.setDefaultBackgroundColorOverride', {})
+          .catch(error => debug('Failed to restore the background of %s: %O', tabId, error));
+      }
+
+      const mimeType = `image/${format}`;
+      if (request.encoding !== 'file') {
+        return { mimeType, data };
+      }
+
+      const filePath = await this.writeArtifact(
+        Buffer.from(data, 'base64'),
+        request.path || path.join('screenshots', `${tabId}-${Date.now()}.${format}`)
+      );
+      return { mimeType, path: filePath };
+    } catch (error) {
+      throw new BrowserError(`Failed to take screenshot: ${error}`);
+    }
+  }
+
+  // The element's box grown by padding, in the page coordinates clip is in. The box is
+  // relative to the top-level viewport even for elements in iframes.
+  private async paddedClip(page: Page, handle: ElementHandle, padding: number): Promise<ClipRect> {
+    const box = await handle.boundingBox();
+    if (!box) {
+      throw new BrowserError('The element has no box to capture, it is not rendered');
+    }
+    const { scroll, size } = await page.evaluate(() => {
+      const scope = globalThis as any;
+      const root = scope.document.documentElement;
+      return {
+        scroll: { x: scope.scrollX, y: scope.scrollY },
+        size: { width: root.scrollWidth, height: root.scrollHeight }
+      };
+    });
+    return padClip({ ...box, x: box.x + scroll.x, y: box.y + scroll.y }, padding, size);
+  }
+
+  // Lazy content is loaded by scrolling through the page first, and fixed or sticky elements
+  // are frozen in place or hidden so they show once instead of over every part of the page.
+  // The page gets its styles and scroll position back afterwards.
+  private async captureFullPage(
+    page: Page,
+    options: any,
+    { fixedElements, loadLazyContent }: ReturnType<typeof resolveFullPageOptions>
+  ): Promise<string> {
+    if (fixedElements === 'keep' && !loadLazyContent) {
+      return (await page.screenshot({ ...options, fullPage: true })) as string;
+    }
+    const scroll = await page.evaluate(() => {
+      const scope = globalThis as any;
+      return { x: scope.scrollX as number, y: scope.scrollY as number };
+    });
+    try {
+      if (loadLazyContent) {
+        await page.evaluate(scrollThroughPage, LAZY_LOAD_DELAY, MAX_LAZY_LOAD_STEPS);
+      }
+      if (fixedElements !== 'keep') {
+        await page.evaluate(freezeFixedElements, fixedElements);
+      }
+      return (await page.screenshot({ ...options, fullPage: true })) as string;
+    } finally {
+      await page
+        .evaluate(restoreFixedElements, scroll)
+        .catch(error => debug('Failed to restore the page after a screenshot: %O', error));
+    }
+  }
+
+  // Diffs a PNG screenshot of the page, an element or a region against a baseline PNG. Images
+  // of different sizes fail without a pixel diff.
+  async compareScreenshot(
+    tabId: string,
+    request: CompareScreenshotRequest
+  ): Promise<CompareScreenshotResult> {
+    if (!this.tabs.has(tabId)) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const options = resolveCompareOptions(request);
+    try {
+      const source = parseBaseline(request.baseline);
+      const baseline = decodePng(
+        'data' in source
+          ? source.data
+          : await fs.readFile(resolveClientPath(ensureBaseWorkingDirectory(), source.path))
+      );
+      const screenshot = await this.captureScreenshot(tabId, {
+        fullPage: request.fullPage,
+        selector: request.selector,
+        clip: request.clip,
+        format: 'png'
+      });
+      const actual = decodePng(Buffer.from(screenshot.data ?? '', 'base64'));
+
+      const sizes = {
+        width: actual.width,
+        height: actual.height,
+        baselineWidth: baseline.width,
+        baselineHeight: baseline.height
+      };
+      if (actual.width !== baseline.width || actual.height !== baseline.height) {
+        return {
+          passed: false,
+          ...sizes,
+          sizeMismatch: true,
+          diffPixels: actual.width * actual.height,
+          antialiasedPixels: 0,
+          diffPercentage: 100
+        };
+      }
+
+      const diff = diffImages(baseline, actual, options);
+      const diffPercentage =
+        Math.round((diff.diffPixels / (actual.width * actual.height)) * 1000000) / 10000;
+      const result: CompareScreenshotResult = {
+        passed: diffPercentage <= options.maxDiffPercentage,
+        ...sizes,
+        sizeMismatch: false,
+        diffPixels: diff.diffPixels,
+        antialiasedPixels: diff.antialiasedPixels,
+        diffPercentage
+      };
+      if (request.diffImage === 'base64') {
+        result.diff = encodePng(diff.image).toString('base64');
+      } else if (request.diffImage === 'file') {
+        result.diffPath = await this.writeArtifact(
+          encodePng(diff.image),
+          request.diffPath || path.join('diffs', `${tabId}-${Date.now()}.png`)
+        );
+      }
+      return result;
+    } catch (error) {
+      throw new BrowserError(`Failed to compare screenshot: ${error}`);
+    }
+  }
+
+  async generatePdf(tabId: string, request: PdfRequest): Promise<PdfResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const format = request.format?.toLowerCase();
+    if (format && !(PAPER_FORMATS as readonly string[]).includes(format)) {
+      throw new InvalidArgumentError(`format must be one of: ${PAPER_FORMATS.join(', ')}`);
+    }
+
+    const emulatePrintMedia = request.emulatePrintMedia ?? true;
+    try {
+      const options: any = {};
+      if (format) options.format = format;
+      if (request.landscape !== undefined) options.landscape = request.landscape;
+      if (request.margin) options.margin = request.margin;
+      if (request.printBackground !== undefined) options.printBackground = request.printBackground;
+      if (request.scale !== undefined) options.scale = request.scale;
+      if (request.headerTemplate !== undefined || request.footerTemplate !== undefined) {
+        options.displayHeaderFooter = true;
+        // Chrome renders its own default for whichever template is left out
+        options.headerTemplate = request.headerTemplate ?? '<span></span>';
+        options.footerTemplate = request.footerTemplate ?? '<span></span>';
+      }
+
+      // printToPDF lays out with print styles unless screen media is emulated. The tab's
+      // color scheme and motion emulation stay in effect and its media type comes back after.
+      const emulation = tab.media ?? NO_MEDIA_EMULATION;
+      await this.applyMedia(tab, {
+        ...emulation,
+        mediaType: emulatePrintMedia ? 'print' : 'screen'
+      });
+      let pdf: Uint8Array;
+      try {
+        pdf = await tab.page.pdf(options);
+      } finally {
+        await this.applyMedia(tab, emulation);
+      }
+
+      if (request.encoding !== 'file') {
+        return { mimeType: 'application/pdf', data: Buffer.from(pdf).toString('base64') };
+      }
+
+      const filePath = await this.writeArtifact(
+        Buffer.from(pdf),
+        request.path || path.join('pdfs', `${tabId}-${Date.now()}.pdf`)
+      );
+      return { mimeType: 'application/pdf', path: filePath };
+    } catch (error) {
+      throw new BrowserError(`Failed to generate PDF: ${error}`);
+    }
+  }
+
+  // Saves the page as a single MHTML file with its images, stylesheets and frames inlined, so
+  // it opens offline in Chrome as it looked when captured
+  async saveSnapshot(tabId: string, request: SnapshotRequest = {}): Promise<SnapshotResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      const cdp = await this.getCdpSession(tab);
+      const { data } = await cdp.send('Page.captureSnapshot', { format: 'mhtml' });
+      const archive = Buffer.from(data);
+      if (request.encoding !== 'file') {
+        return {
+          mimeType: 'multipart/related',
+          size: archive.length,
+          data: archive.toString('base64')
+        };
+      }
+
+      const filePath = await this.writeArtifact(
+        archive,
+        request.path || path.join('snapshots', `${tabId}-${Date.now()}.mhtml`)
+      );
+      return { mimeType: 'multipart/related', size: archive.length, path: filePath };
+    } catch (error) {
+      throw new BrowserError(`Failed to save snapshot: ${error}`);
+    }
+  }
+
+  // Relative paths land in the base working directory, paths leaving it are refused
+  private async writeArtifact(data: Buffer, filePath: string, mode?: number): Promise<string> {
+    const resolved = resolveClientPath(ensureBaseWorkingDirectory(), filePath);
+    await fs.mkdir(path.dirname(resolved), { recursive: true });
+    await fs.writeFile(resolved, data, mode === undefined ? {} : { mode });
+    return resolved;
+  }
+
+  // Returns the element's bounding box at click time
+  async clickElement(
+    tabId: string,
+    selector: string,
+    waitForNavigation = false,
+    options: ClickOptions = {}
+  ): Promise<BoundingBox | null> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      const frame = await this.resolveFrame(tab.page, options.frame);
+      if (waitForNavigation) {
+        const [, box] = await Promise.all([
+          frame.waitForNavigation({ waitUntil: 'networkidle2' }),
+          this.clickWithRetry(frame, selector, options)
+        ]);
+        return box;
+      }
+      return await this.clickWithRetry(frame, selector, options);
+    } catch (error) {
+      throw new BrowserError(`Failed to click element: ${error}`);
+    }
+  }
+
+  // Dynamic pages often swap a node out between lookup and click, so stale-element errors
+  // look the element up again until the timeout runs out instead of failing right away
+  private async clickWithRetry(
+    frame: Frame,
+    selector: string,
+    options: ClickOptions
+  ): Promise<BoundingBox | null> {
+    const deadline = Date.now() + (options.timeout ?? frame.page().getDefaultTimeout());
+    const remaining = () => Math.max(deadline - Date.now(), 1);
+
+    for (;;) {
+      try {
+        const index = options.index ?? 0;
+        const visible = { visible: true, timeout: remaining() };
+        const element = options.waitForVisible
+          ? await this.waitForMatch(frame, selector, index, visible)
+          : await this.queryElement(frame, selector, index);
+        if (!element) {
+          throw await this.elementNotFound(frame, selector, index);
+        }
+
+        try {
+          if (options.waitForEnabled) {
+            await frame.waitForFunction(
+              (el: any) => !el.disabled && el.getAttribute('aria-disabled') !== 'true',
+              { timeout: remaining() },
+              element
+            );
+          }
+          await element.scrollIntoView();
+          const box = await element.boundingBox();
+          await element.click({
+            button: options.button ?? 'left',
+            count: options.clickCount ?? 1
+          });
+          return box;
+        } finally {
+          await element.dispose().catch(() => {});
+        }
+      } catch (error) {
+        if (!isStaleElementError(error) || Date.now() >= deadline) {
+          throw error;
+        }
+        debug('Retrying click on %s after stale element: %O', selector, error);
+        await new Promise(resolve => setTimeout(resolve, 100));
+      }
+    }
+  }
+
+  // Scrolls the element into view and leaves the mouse over its center, which opens hover
+  // menus and tooltips until the mouse moves again
+  async hoverElement(
+    tabId: string,
+    selector: string,
+    frameTarget?: string,
+    index = 0
+  ): Promise<BoundingBox | null> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      const frame = await this.resolveFrame(tab.page, frameTarget);
+      const element = await this.findElement(frame, selector, index);
+      try {
+        await element.hover();
+        return await element.boundingBox();
+      } finally {
+        await element.dispose().catch(() => {});
+      }
+    } catch (error) {
+      throw new BrowserError(`Failed to hover element: ${error}`);
+    }
+  }
+
+  // Scrolls the window of the page, or of the frame, in one of the modes of ScrollRequest
+  async scrollPage(tabId: string, request: ScrollRequest): Promise<ScrollResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const mode = resolveScrollMode(request);
+    try {
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      if (mode === 'element') {
+        const selector = toSelector(request);
+        const element = await this.findElement(frame, selector, toElementIndex(request));
+        try {
+          await element.scrollIntoView();
+        } finally {
+          await element.dispose().catch(() => {});
+        }
+      } else if (mode === 'pixels') {
+        await frame.evaluate(
+          (x: number, y: number) =>
+            (globalThis as any).scrollBy({ left: x, top: y, behavior: 'instant' }),
+          request.x ?? 0,
+          request.y ?? 0
+        );
+      } else {
+        const progress = await scrollUntilStable(
+          {
+            scrollToEnd: () => frame.evaluate(scrollToEnd),
+            waitForGrowth: async (height, timeout) => {
+              if (timeout > 0) {
+                await frame
+                  .waitForFunction(
+                    (previous: number) => {
+                      const { document } = globalThis as any;
+                      const root = document.scrollingElement ?? document.documentElement;
+                      return root.scrollHeight > previous;
+                    },
+                    { timeout, polling: 100 },
+                    height
+                  )
+                  .catch(error => {
+                    if (!isTimeoutError(error)) throw error;
+                  });
+              }
+              return (await frame.evaluate(readScrollState)).scrollHeight;
+            }
+          },
+          request.maxIterations,
+          request.idleTimeout
+        );
+        return { ...(await frame.evaluate(readScrollState)), ...progress };
+      }
+      return await frame.evaluate(readScrollState);
+    } catch (error) {
+      throw new BrowserError(`Failed to scroll: ${error}`);
+    }
+  }
+
+  // Presses the mouse at one end, moves there in steps and releases it at the other. This
+  // drives sliders, sortable lists, maps and canvas drawing, which follow mouse events.
+  // Elements are grabbed at their center; points are viewport coordinates of the tab.
+  async dragElement(tabId: string, request: DragRequest): Promise<DragResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    validateDrag(request);
+    try {
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      // only the source is scrolled into view so the drop target does not move it away again
+      const from = await this.resolveDragPoint(frame, request.from, true);
+      const to = await this.resolveDragPoint(frame, request.to, false);
+      const { mouse } = tab.page;
+      await mouse.move(from.x, from.y);
+      await mouse.down();
+      try {
+        await mouse.move(to.x, to.y, { steps: request.steps ?? 10 });
+      } finally {
+        await mouse.up();
+      }
+      return { from, to };
+    } catch (error) {
+      throw new BrowserError(`Failed to drag: ${error}`);
+    }
+  }
+
+  private async resolveDragPoint(
+    frame: Frame,
+    endpoint: DragEndpoint,
+    scrollIntoView: boolean
+  ): Promise<Point> {
+    if (!endpoint.selector && !endpoint.xpath) {
+      return { x: endpoint.x as number, y: endpoint.y as number };
+    }
+
+    const selector = toSelector(endpoint);
+    const element = await this.findElement(frame, selector, toElementIndex(endpoint));
+    try {
+      if (scrollIntoView) {
+        await element.scrollIntoView();
+      }
+      const box = await element.boundingBox();
+      if (!box) {
+        throw new Error(`Element ${selector} is not visible`);
+      }
+      return { x: box.x + box.width / 2, y: box.y + box.height / 2 };
+    } finally {
+      await element.dispose().catch(() => {});
+    }
+  }
+
+  // Touch gestures are synthesized by Chrome's input pipeline like a real finger, so touch
+  // handlers, native scrolling and pinch-zoom respond where mouse events do nothing
+  async tap(tabId: string, request: TapRequest): Promise<TouchResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    validateTap(request);
+    const point = await this.resolveTouchPoint(tabId, tab, request);
+    try {
+      const cdp = await this.getCdpSession(tab);
+      await cdp.send('Input.synthesizeTapGesture', {
+        ...point,
+        duration: request.duration ?? 50,
+        tapCount: request.count ?? 1,
+        gestureSourceType: 'touch'
+      });
+      return { point };
+    } catch (error) {
+      throw new BrowserError(`Failed to tap: ${error}`);
+    }
+  }
+
+  // Carousels, drawers and pull-to-refresh follow the finger, and the page itself scrolls
+  // against it. The swipe flings no further once the finger is lifted.
+  async swipe(tabId: string, request: SwipeRequest): Promise<TouchResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    validateSwipe(request);
+    const point = await this.resolveTouchPoint(tabId, tab, request);
+    const offset = swipeOffset(request.direction, request.distance);
+    try {
+      const cdp = await this.getCdpSession(tab);
+      await cdp.send('Input.synthesizeScrollGesture', {
+        ...point,
+        xDistance: offset.x,
+        yDistance: offset.y,
+        speed: request.speed ?? 800,
+        preventFling: true,
+        gestureSourceType: 'touch'
+      });
+      return { point, to: { x: point.x + offset.x, y: point.y + offset.y } };
+    } catch (error) {
+      throw new BrowserError(`Failed to swipe: ${error}`);
+    }
+  }
+
+  // Two fingers move apart from or towards the point, e.g. to zoom a map around it
+  async pinch(tabId: string, request: PinchRequest): Promise<TouchResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    validatePinch(request);
+    const point = await this.resolveTouchPoint(tabId, tab, request);
+    try {
+      const cdp = await this.getCdpSession(tab);
+      await cdp.send('Input.synthesizePinchGesture', {
+        ...point,
+        scaleFactor: request.scale,
+        relativeSpeed: request.speed ?? 800,
+        gestureSourceType: 'touch'
+      });
+      return { point };
+    } catch (error) {
+      throw new BrowserError(`Failed to pinch: ${error}`);
+    }
+  }
+
+  // Pages without touch emulation ignore touch input or treat it as a mouse, and a gesture
+  // that did nothing would pass silently, so tools refuse to run until touch is on
+  private async resolveTouchPoint(tabId: string, tab: Tab, target: TouchTarget): Promise<Point> {
+    if (!tab.page.viewport()?.hasTouch) {
+      throw new InvalidArgumentError(
+        `Touch must be enabled on tab ${tabId} first, emulate a touch device or set hasTouch`
+      );
+    }
+    try {
+      const frame = await this.resolveFrame(tab.page, target.frame);
+      return await this.resolveDragPoint(frame, target, true);
+    } catch (error) {
+      throw new BrowserError(`Failed to find the touch target: ${error}`);
+    }
+  }
+
+  // Types key by key so key handlers, autocompletes and debounced inputs see every stroke
+  async typeText(
+    tabId: string,
+    selector: string,
+    text: string,
+    options: TypeOptions = {}
+  ): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      const frame = await this.resolveFrame(tab.page, options.frame);
+      const element = await this.findEditable(frame, selector, options.index);
+      try {
+        await element.focus();
+        if (options.clear) {
+          await element.evaluate(setEditableValue, '');
+        }
+        await element.type(text, { delay: options.delay ?? 0 });
+        if (options.afterKey) {
+          await tab.page.keyboard.press(options.afterKey);
+        }
+      } finally {
+        await element.dispose().catch(() => {});
+      }
+    } catch (error) {
+      throw new BrowserError(`Failed to type text: ${error}`);
+    }
+  }
+
+  // Keys go to whatever has focus, so focus an element first to type into it
+  async pressKeys(tabId: string, request: KeyboardRequest): Promise<KeyboardResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const { action, keys, delay, commands } = resolveKeyStroke(request);
+    const { keyboard } = tab.page;
+    try {
+      if (action === 'down') {
+        for (const key of keys) await keyboard.down(key);
+      } else if (action === 'up') {
+        for (const key of [...keys].reverse()) await keyboard.up(key);
+      } else {
+        const modifiers = keys.slice(0, -1);
+        for (const modifier of modifiers) await keyboard.down(modifier);
+        try {
+          await keyboard.press(keys[keys.length - 1]!, { delay, commands });
+        } finally {
+          for (const modifier of [...modifiers].reverse()) await keyboard.up(modifier);
+        }
+      }
+      return { action, keys };
+    } catch (error) {
+      throw new BrowserError(`Failed to press keys: ${error}`);
+    }
+  }
+
+  // Sets the value in one step, replacing whatever the field held
+  async fillField(
+    tabId: string,
+    selector: string,
+    value: string,
+    frameTarget?: string,
+    index = 0
+  ): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      const frame = await this.resolveFrame(tab.page, frameTarget);
+      const element = await this.findEditable(frame, selector, index);
+      try {
+        await element.focus();
+        await element.evaluate(setEditableValue, value);
+      } finally {
+        await element.dispose().catch(() => {});
+      }
+    } catch (error) {
+      throw new BrowserError(`Failed to fill field: ${error}`);
+    }
+  }
+
+  private async findEditable(frame: Frame, selector: string, index = 0): Promise<ElementHandle> {
+    const element = await this.findElement(frame, selector, index);
+
+    const reason = await element.evaluate(describeNonEditable, NON_TEXT_INPUT_TYPES);
+    if (reason) {
+      await element.dispose().catch(() => {});
+      throw new Error(`Element ${selector} is not editable: ${reason}`);
+    }
+    return element;
+  }
+
+  // Attaches files on disk and base64 blobs to a file input. Puppeteer fires the input and
+  // change events so the page registers the selection. Blobs are staged in a temporary
+  // directory; the browser only reads the files when the form is sent, so the directory is
+  // kept until the tab closes, or removed right away when attaching fails.
+  async uploadFile(
+    tabId: string,
+    selector: string,
+    paths: string[] = [],
+    blobs: UploadBlob[] = [],
+    frameTarget?: string,
+    index = 0
+  ): Promise<UploadResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const count = paths.length + blobs.length;
+    if (!count) {
+      throw new InvalidArgumentError('At least one file path or base64 file is required');
+    }
+    const resolved = paths.map(filePath =>
+      resolveClientPath(ensureBaseWorkingDirectory(), filePath)
+    );
+    for (const filePath of resolved) {
+      const stat = await fs.stat(filePath).catch(() => null);
+      if (!stat?.isFile()) {
+        throw new BrowserError(`File not found: ${filePath}`);
+      }
+    }
+
+    let staged: { dir: string; paths: string[] } | null = null;
+    let attached = false;
+    try {
+      const frame = await this.resolveFrame(tab.page, frameTarget);
+      const element = await this.findElement(frame, selector, index);
+      try {
+        const state = await element.evaluate(inspectFileInput);
+        if (!state.isFileInput) {
+          throw new Error(`Element ${selector} is not a file input, it is ${state.description}`);
+        }
+        if (state.disabled) {
+          throw new Error(`File input ${selector} is disabled`);
+        }
+        if (count > 1 && !state.multiple) {
+          throw new Error(
+            `File input ${selector} has no multiple attribute but ${count} files were given`
+          );
+        }
+
+        staged = blobs.length ? await writeBlobs(blobs) : null;
+        const files = [...resolved, ...(staged?.paths ?? [])];
+        await element.uploadFile(...files);
+        attached = true;
+        if (staged) {
+          (tab.uploadDirs ??= []).push(staged.dir);
+        }
+        return { files: files.map(filePath => path.basename(filePath)) };
+      } finally {
+        await element.dispose().catch(() => {});
+      }
+    } catch (error) {
+      throw new BrowserError(`Failed to upload file: ${error}`);
+    } finally {
+      if (staged && !attached) {
+        await removeUploadDirs([staged.dir]);
+      }
+    }
+  }
+
+  // Selects options of a <select> by value, label or index and returns the chosen ones
+  async selectOption(
+    tabId: string,
+    selector: string,
+    criteria: OptionCriteria,
+    frameTarget?: string
+  ): Promise<SelectResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    toCriteria(criteria);
+    try {
+      const frame = await this.resolveFrame(tab.page, frameTarget);
+      const element = await this.findElement(frame, selector);
+      try {
+        const state = await element.evaluate(inspectSelect);
+        if (!state.isSelect) {
+          throw new Error(`Element ${selector} is not a select, it is ${state.description}`);
+        }
+        if (state.disabled) {
+          throw new Error(`Select ${selector} is disabled`);
+        }
+        const indexes = matchOptions(state, criteria, selector);
+        await element.evaluate(applySelection, indexes);
+        return { selected: state.options.filter(option => indexes.includes(option.index)) };
+      } finally {
+        await element.dispose().catch(() => {});
+      }
+    } catch (error) {
+      throw new BrowserError(`Failed to select option: ${error}`);
+    }
+  }
+
+  async evaluateScript(tabId: string, script: string): Promise<any> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      return await tab.page.evaluate(script);
+    } catch (error) {
+      throw new BrowserError(`Failed to evaluate script: ${error}`);
+    }
+  }
+
+  // Runs a function body with JSON arguments and returns its JSON-serializable result. The
+  // page's default timeout applies unless a timeout is given.
+  async evaluateFunction(
+    tabId: string,
+    body: string,
+    args: unknown[] = [],
+    timeout?: number
+  ): Promise<unknown> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const limit = timeout ?? tab.page.getDefaultTimeout();
+    try {
+      const serialized = await withTimeout(
+        (async () => {
+          const handle = await tab.page.evaluateHandle(buildEvaluateExpression(body, args));
+          try {
+            return await handle.evaluate(serializeInPage);
+          } finally {
+            await handle.dispose().catch(() => {});
+          }
+        })(),
+        limit,
+        `Evaluation timed out after ${limit}ms`
+      );
+      if (serialized.error) {
+        throw new Error(`Result is not JSON-serializable: ${serialized.error}`);
+      }
+      return serialized.json === undefined ? null : JSON.parse(serialized.json);
+    } catch (error) {
+      throw new BrowserError(`Failed to evaluate function: ${error}`);
+    }
+  }
+
+  async closeTab(tabId: string): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      if (await this.recyclePage(tabId, tab)) {
+        return;
+      }
+      await tab.page.close();
+      this.tabs.delete(tabId);
+      await this.closeBrowserIfUnused(tab.visible);
+    } catch (error) {
+      throw new BrowserError(`Failed to close tab: ${error}`);
+    }
+  }
+
+  async bringToFront(tabId: string): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      await tab.page.bringToFront();
+    } catch (error) {
+      throw new BrowserError(`Failed to bring tab to front: ${error}`);
+    }
+  }
+
+  async focusElement(
+    tabId: string,
+    selector: string,
+    frameTarget?: string,
+    index = 0
+  ): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      const frame = await this.resolveFrame(tab.page, frameTarget);
+      const element = await this.findElement(frame, selector, index);
+      try {
+        await element.focus();
+      } finally {
+        await element.dispose().catch(() => {});
+      }
+    } catch (error) {
+      throw new BrowserError(`Failed to focus element: ${error}`);
+    }
+  }
+
+  async goBack(tabId: string): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      await tab.page.goBack({ waitUntil: 'networkidle2' });
+    } catch (error) {
+      throw new BrowserError(`Failed to go back: ${error}`);
+    }
+  }
+
+  async goForward(tabId: string): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      await tab.page.goForward({ waitUntil: 'networkidle2' });
+    } catch (error) {
+      throw new BrowserError(`Failed to go forward: ${error}`);
+    }
+  }
+
+  async reloadTab(tabId: string, waitUntil?: string): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      await tab.page.reload({ waitUntil: (waitUntil || 'networkidle2') as any });
+    } catch (error) {
+      throw new BrowserError(`Failed to reload tab: ${error}`);
+    }
+  }
+
+  // Times out with a structured result rather than an error so callers can see what the
+  // page looked like when the wait gave up
+  async waitForSelector(
+    tabId: string,
+    selector: string,
+    options: {
+      timeout?: number;
+      visible?: boolean;
+      hidden?: boolean;
+      frame?: string;
+      index?: number;
+    } = {}
+  ): Promise<WaitForSelectorResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const { frame: frameTarget, index = 0, ...waitOptions } = options;
+    const started = Date.now();
+    let frame = tab.page.mainFrame();
+    try {
+      frame = await this.resolveFrame(tab.page, frameTarget);
+      const element = await this.waitForMatch(frame, selector, index, waitOptions);
+      const boundingBox = element ? await element.boundingBox() : null;
+      await element?.dispose().catch(() => {});
+      return {
+        found: element !== null,
+        timedOut: false,
+        elapsedMs: Date.now() - started,
+        boundingBox
+      };
+    } catch (error) {
+      if (!isTimeoutError(error)) {
+        throw new BrowserError(`Failed to wait for selector: ${error}`);
+      }
+      return {
+        found: false,
+        timedOut: true,
+        elapsedMs: Date.now() - started,
+        boundingBox: null,
+        hint: await this.describeDomState(frame, selector)
+      };
+    }
+  }
+
+  // Polls the bounding box of an element until it stops moving and resizing, so a click
+  // lands where the element ends up once an animation is over. The element is looked up
+  // again on every poll, which also follows one that is re-rendered or appears late. Like
+  // waitForSelector a timeout is a result, not an error.
+  async waitForStable(tabId: string, request: WaitForStableRequest): Promise<WaitForStableResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const selector = toSelector(request);
+    const index = toElementIndex(request);
+    const { tolerance, stableTime, timeout } = resolveStableOptions({
+      ...request,
+      timeout: request.timeout ?? tab.page.getDefaultTimeout()
+    });
+    const tracker = new StabilityTracker(tolerance, stableTime);
+    const started = Date.now();
+    try {
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      for (;;) {
+        const element = await this.queryElement(frame, selector, index);
+        const box = element ? await element.boundingBox() : null;
+        await element?.dispose().catch(() => {});
+        const boundingBox = box && { x: box.x, y: box.y, width: box.width, height: box.height };
+
+        const now = Date.now();
+        const stable = tracker.update(boundingBox, now);
+        if (stable || (timeout && now - started >= timeout)) {
+          return {
+            stable,
+            timedOut: !stable,
+            elapsedMs: now - started,
+            boundingBox,
+            moves: tracker.moves
+          };
+        }
+        await new Promise(resolve => setTimeout(resolve, STABLE_POLL_INTERVAL));
+      }
+    } catch (error) {
+      throw new BrowserError(`Failed to wait for a stable element: ${error}`);
+    }
+  }
+
+  // Element tools act on the index-th match in document order, the first by default
+  private async queryElement(
+    frame: Frame,
+    selector: string,
+    index = 0
+  ): Promise<ElementHandle | null> {
+    if (index === 0) {
+      return frame.$(selector);
+    }
+    const matches = await frame.$$(selector);
+    const element = matches[index] ?? null;
+    await Promise.all(
+      matches.filter(match => match !== element).map(match => match.dispose().catch(() => {}))
+    );
+    return element;
+  }
+
+  private async findElement(frame: Frame, selector: string, index = 0): Promise<ElementHandle> {
+    const element = await this.queryElement(frame, selector, index);
+    if (!element) {
+      throw await this.elementNotFound(frame, selector, index);
+    }
+    return element;
+  }
+
+  // Puppeteer only waits for the first match, a later one is polled for
+  private async waitForMatch(
+    frame: Frame,
+    selector: string,
+    index: number,
+    options: { timeout?: number; visible?: boolean; hidden?: boolean }
+  ): Promise<ElementHandle | null> {
+    if (index === 0) {
+      return frame.waitForSelector(selector, options);
+    }
+    const timeout = options.timeout ?? frame.page().getDefaultTimeout();
+    const deadline = Date.now() + timeout;
+    for (;;) {
+      const element = await this.queryElement(frame, selector, index);
+      if (options.hidden) {
+        if (!element || (await element.isHidden())) {
+          await element?.dispose().catch(() => {});
+          return null;
+        }
+      } else if (element && (!options.visible || (await element.isVisible()))) {
+        return element;
+      }
+      await element?.dispose().catch(() => {});
+      if (timeout > 0 && Date.now() >= deadline) {
+        throw new TimeoutError(
+          `Waiting for match ${index} of selector \`${selector}\` failed: ${timeout}ms exceeded`
+        );
+      }
+      await new Promise(resolve => setTimeout(resolve, 100));
+    }
+  }
+
+  // Says how many elements matched when the index was past them, otherwise counts what the
+  // selector would match across shadow roots, so a miss caused by a shadow boundary says how
+  // to pierce it
+  private async elementNotFound(frame: Frame, selector: string, index = 0): Promise<Error> {
+    const matches = index > 0 ? await this.countMatches(frame, selector) : 0;
+    if (matches > 0) {
+      return new Error(elementNotFoundMessage(selector, { index, matches }));
+    }
+    const piercing = toPiercingSelector(selector);
+    const shadowMatches = piercing ? await this.countMatches(frame, piercing) : 0;
+    return new Error(elementNotFoundMessage(selector, { shadowMatches }));
+  }
+
+  private async countMatches(frame: Frame, selector: string): Promise<number> {
+    try {
+      const matches = await frame.$$(selector);
+      await Promise.all(matches.map(match => match.dispose().catch(() => {})));
+      return matches.length;
+    } catch (error) {
+      debug('Failed to count the matches of %s: %O', selector, error);
+      return 0;
+    }
+  }
+
+  private async describeDomState(frame: Frame, selector: string): Promise<DomStateHint> {
+    const hint: DomStateHint = {
+      url: frame.url(),
+      readyState: 'unknown',
+      matchCount: 0,
+      shadowMatchCount: 0,
+      firstMatch: null
+    };
+    try {
+      hint.readyState = String(await frame.evaluate('document.readyState'));
+      const matches = await frame.$$(selector);
+      hint.matchCount = matches.length;
+      if (matches[0]) {
+        hint.firstMatch = await matches[0].evaluate(describeElementState);
+      }
+      await Promise.all(matches.map(match => match.dispose().catch(() => {})));
+      const piercing = matches.length === 0 ? toPiercingSelector(selector) : null;
+      if (piercing) {
+        hint.shadowMatchCount = await this.countMatches(frame, piercing);
+      }
+    } catch (error) {
+      debug('Failed to describe DOM state for %s: %O', selector, error);
+    }
+    return hint;
+  }
+
+  // Accepts an expression or function source, polled until it returns a truthy value
+  async waitForFunction(
+    tabId: string,
+    fn: string,
+    options: { timeout?: number; polling?: 'raf' | 'mutation' | number; frame?: string } = {}
+  ): Promise<WaitForFunctionResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const { frame: frameTarget, ...waitOptions } = options;
+    const started = Date.now();
+    try {
+      const frame = await this.resolveFrame(tab.page, frameTarget);
+      const handle = await frame.waitForFunction(toPredicateExpression(fn), waitOptions);
+      try {
+        const serialized = await handle.evaluate(serializeInPage);
+        return {
+          satisfied: true,
+          timedOut: false,
+          elapsedMs: Date.now() - started,
+          value: serialized.json === undefined ? null : JSON.parse(serialized.json)
+        };
+      } finally {
+        await handle.dispose().catch(() => {});
+      }
+    } catch (error) {
+      if (!isTimeoutError(error)) {
+        throw new BrowserError(`Failed to wait for function: ${error}`);
+      }
+      return { satisfied: false, timedOut: true, elapsedMs: Date.now() - started, value: null };
+    }
+  }
+
+  async waitForNavigation(
+    tabId: string,
+    options?: { timeout?: number; waitUntil?: string }
+  ): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      const waitOptions: any = {};
+      if (options?.timeout) waitOptions.timeout = options.timeout;
+      if (options?.waitUntil) waitOptions.waitUntil = options.waitUntil;
+      else waitOptions.waitUntil = 'networkidle2';
+
+      await tab.page.waitForNavigation(waitOptions);
+    } catch (error) {
+      throw new BrowserError(`Failed to wait for navigation: ${error}`);
+    }
+  }
+
+  async listFrames(tabId: string): Promise<FrameInfo> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const tree = buildFrameTree(this.getFrameDescriptors(tab.page));
+    if (!tree) {
+      throw new BrowserError(`Tab ${tabId} has no main frame`);
+    }
+    return tree;
+  }
+
+  // The main frame unless a target is given, see FrameTarget. A target that names no frame
+  // is tried as the selector of an iframe element in any frame before falling back to
+  // matching part of the frame URL.
+  private async resolveFrame(page: Page, target?: string): Promise<Frame> {
+    if (!target) {
+      return page.mainFrame();
+    }
+
+    const frames = this.getFrameDescriptors(page);
+    const match = matchFrame(frames, target);
+    if (match) {
+      return match.frame;
+    }
+    for (const { frame } of frames) {
+      const element = await frame.$(target).catch(() => null);
+      if (!element) {
+        continue;
+      }
+      try {
+        const content = await element.contentFrame();
+        if (content) {
+          return content;
+        }
+      } finally {
+        await element.dispose().catch(() => {});
+      }
+    }
+    const partial = matchFrameUrl(frames, target);
+    if (partial) {
+      return partial.frame;
+    }
+    throw new Error(`No frame matches ${target}, frames: ${describeFrames(frames)}`);
+  }
+
+  private getFrameDescriptors(page: Page): Array<FrameDescriptor & { frame: Frame }> {
+    return page
+      .frames()
+      .filter(frame => !frame.isDetached())
+      .map(frame => {
+        const parent = frame.parentFrame();
+        return {
+          frame,
+          id: this.getFrameId(frame),
+          name: frame.name(),
+          url: frame.url(),
+          parentId: parent ? this.getFrameId(parent) : null
+        };
+      });
+  }
+
+  // Puppeteer exposes no frame ID, so frames get one the first time they are seen
+  private getFrameId(frame: Frame): string {
+    let id = this.frameIds.get(frame);
+    if (!id) {
+      id = randomUUID();
+      this.frameIds.set(frame, id);
+    }
+    return id;
+  }
+
+  // Independent of navigation, for pages that fetch data after a click. Long-polling and
+  // streaming requests never finish, so allow for them with maxInflightRequests.
+  async waitForNetworkIdle(
+    tabId: string,
+    request: WaitForNetworkIdleRequest = {}
+  ): Promise<NetworkIdleResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
 
-class BrowserManager {
-  private browsers: Map<boolean, Browser | null> = new Map();
-  private tabs: Map<string, { page: Page; visible: boolean }> = new Map();
-  private chromePath: string | null = null;
+    const { idleTime, maxInflight, timeout } = resolveIdleOptions({
+      ...request,
+      timeout: request.timeout ?? tab.page.getDefaultTimeout()
+    });
+    return tab.network.waitForIdle(idleTime, maxInflight, timeout);
+  }
 
-  public getPageByTabId(tabId: string): Page | null {
+  // Waits for the next request matching the filter, or finds one the page already sent
+  // within lookback, e.g. to check that a click called the expected API
+  async waitForRequest(
+    tabId: string,
+    request: WaitForRequestRequest
+  ): Promise<WaitForTrafficResult> {
     const tab = this.tabs.get(tabId);
-    return tab ? tab.page : null;
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const filter = compileTrafficFilter(request);
+    return this.waitForTraffic(tab, tab.requests, filter, request, async match => ({
+      method: match.method(),
+      url: match.url(),
+      status: null,
+      resourceType: match.resourceType(),
+      headers: match.headers(),
+      ...(request.includeBody ? await this.readPostData(match) : {})
+    }));
   }
 
-  public getVisibleByTabId(tabId: string): boolean | null {
+  async waitForResponse(
+    tabId: string,
+    request: WaitForResponseRequest
+  ): Promise<WaitForTrafficResult> {
     const tab = this.tabs.get(tabId);
-    return tab ? tab.visible : null;
-  }
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
 
-  constructor(chromePath?: string | null) {
-    this.chromePath = chromePath || null;
-    this.browsers.set(true, null); // headless
-    this.browsers.set(false, null); // visible
+    const filter = compileTrafficFilter(request);
+    return this.waitForTraffic(tab, tab.responses, filter, request, async match => ({
+      method: match.request().method(),
+      url: match.url(),
+      status: match.status(),
+      statusText: match.statusText(),
+      resourceType: match.request().resourceType(),
+      headers: match.headers(),
+      ...(request.includeBody ? await this.readResponseBody(match) : {})
+    }));
   }
 
-  async initialize(headless = true): Promise<void> {
-    const cwd = ensureBaseWorkingDirectory();
-    try {
-      await this.close();
-      const executablePath = await this.getChromePath();
-      const args = [
-        '--no-sandbox',
-        '--disable-setuid-sandbox',
-        '--disable-dev-shm-usage',
-        '--disable-accelerated-2d-canvas',
-        '--no-first-run',
-        '--no-zygote',
-        '--disable-gpu',
-        '--mute-audio',
-        `--user-data-dir=${path.resolve(cwd, '.browser')}`
-      ];
-
-      const browser = await puppeteer.launch({
-        defaultViewport: null,
-        executablePath,
-        headless,
-        args
-      });
-
-      this.browsers.set(headless, browser);
+  // The body of a response the page fetched, such as the JSON a single-page app renders
+  // from, which is often easier to use than the DOM built from it
+  async captureResponseBody(
+    tabId: string,
+    request: CaptureResponseBodyRequest
+  ): Promise<WaitForTrafficResult<CapturedResponse>> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
 
-      // Handle browser disconnection
-      browser.on('disconnected', () => {
-        debug('Browser disconnected, clearing tabs');
-        for (const [tabId, tab] of this.tabs) {
-          if (tab.visible === headless) {
-            this.tabs.delete(tabId);
-          }
-        }
-        this.browsers.set(headless, null);
-      });
+    const filter = compileTrafficFilter(request);
+    const options = resolveCaptureOptions(request);
+    return this.waitForTraffic(tab, tab.responses, filter, request, async match => ({
+      method: match.request().method(),
+      url: match.url(),
+      status: match.status(),
+      ...decodeResponseBody(await match.buffer(), match.headers()['content-type'] ?? '', options)
+    }));
+  }
 
-      debug('Browser initialized successfully');
+  private async waitForTraffic<T, E>(
+    tab: Tab,
+    traffic: RecentTraffic<T>,
+    filter: TrafficFilter,
+    request: WaitForRequestRequest,
+    describe: (match: T) => Promise<E>
+  ): Promise<WaitForTrafficResult<E>> {
+    const timeout = validateTimeout(request.timeout ?? tab.page.getDefaultTimeout());
+    const startedAt = Date.now();
+    const since = startedAt - (request.lookback ?? 0);
+    const match = await traffic.waitFor(filter, since, timeout);
+    const elapsedMs = Date.now() - startedAt;
+    if (!match) {
+      const seen = nearMatches(filter, traffic.seenSince(since));
+      return { matched: false, timedOut: true, elapsedMs, entry: null, nearMatches: seen };
+    }
+    try {
+      const entry = await describe(match);
+      return { matched: true, timedOut: false, elapsedMs, entry, nearMatches: [] };
     } catch (error) {
-      throw new BrowserError(`Failed to initialize browser: ${error}`);
+      throw new BrowserError(`Failed to read the matched traffic: ${error}`);
     }
   }
 
-  private async getChromePath(): Promise<string> {
-    if (process.env['CI'] && process.env['PUPPETEER_EXEC_PATH']) {
-      return process.env['PUPPETEER_EXEC_PATH'];
+  private async readPostData(request: HTTPRequest): Promise<Partial<TrafficEntry>> {
+    const body = request.hasPostData() ? await request.fetchPostData() : undefined;
+    return body === undefined ? {} : { body };
+  }
+
+  // Bodies are kept the way HAR files keep them, text as is and anything else as base64.
+  // Redirects and requests that failed have none.
+  private async readResponseBody(response: HTTPResponse): Promise<Partial<TrafficEntry>> {
+    const mimeType = response.headers()['content-type'] ?? '';
+    const body = await response.buffer().catch(() => null);
+    if (!body) {
+      return {};
     }
+    const content = toHarContent(body, mimeType, DEFAULT_MAX_BODY_SIZE);
+    const entry: Partial<TrafficEntry> = { body: content.text ?? '' };
+    if (content.encoding) entry.bodyEncoding = content.encoding;
+    if (content._truncated) entry.bodyTruncated = true;
+    return entry;
+  }
 
-    if (this.chromePath) {
-      return this.chromePath;
+  async getTabUrl(tabId: string): Promise<string> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
     }
 
     try {
-      const chromePath = await findChromeBrowser();
-      if (chromePath) {
-        // retain for future use until restarted
-        this.chromePath = chromePath;
-        return chromePath;
-      }
+      return tab.page.url();
     } catch (error) {
-      debug('Failed to find Chrome automatically: %O', error);
+      throw new BrowserError(`Failed to get tab URL: ${error}`);
     }
+  }
 
-    throw new BrowserError('Chrome executable not found. Please specify chromePath in config.');
+  // The DOM as it is now, after scripts ran, rather than the HTML the server sent
+  async getTabHtml(tabId: string, request: HtmlRequest = {}): Promise<string> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    try {
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      if (!request.selector && !request.xpath) {
+        return await frame.content();
+      }
+      const selector = toSelector(request);
+      const element = await this.findElement(frame, selector, toElementIndex(request));
+      try {
+        return await element.evaluate((el: any) => String(el.outerHTML));
+      } finally {
+        await element.dispose().catch(() => {});
+      }
+    } catch (error) {
+      throw new BrowserError(`Failed to get tab HTML: ${error}`);
+    }
   }
 
-  async openTab(request: OpenTabRequest): Promise<string> {
-    const headless = request.headless ?? true;
+  // Records which JavaScript and CSS the tab runs and applies until stopCoverage
+  async startCoverage(
+    tabId: string,
+    request: StartCoverageRequest = {}
+  ): Promise<{ js: boolean; css: boolean }> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
 
-    if (!this.browsers.get(headless)) {
-      await this.initialize(headless);
+    if (tab.coverage) {
+      throw new BrowserError(`Tab ${tabId} is already collecting coverage`);
+    }
+    const js = request.js ?? true;
+    const css = request.css ?? true;
+    if (!js && !css) {
+      throw new BrowserError('Enable js, css or both to collect coverage');
     }
 
-    const browser = this.browsers.get(headless);
+    const options = { resetOnNavigation: request.resetOnNavigation ?? true };
+    try {
+      if (js) await tab.page.coverage.startJSCoverage(options);
+      if (css) await tab.page.coverage.startCSSCoverage(options);
+      tab.coverage = { js, css };
+      return { js, css };
+    } catch (error) {
+      throw new BrowserError(`Failed to start coverage: ${error}`);
+    }
+  }
 
-    if (!browser) {
-      throw new BrowserError('Browser not initialized');
+  // Per-file used and unused bytes since startCoverage, with the ranges when detailed
+  async stopCoverage(tabId: string, detailed = false): Promise<CoverageResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
     }
 
-    assert(browser);
+    const coverage = tab.coverage;
+    if (!coverage) {
+      throw new BrowserError(`Tab ${tabId} is not collecting coverage, start it first`);
+    }
 
     try {
-      const page = await browser.newPage();
-      const tabId = randomUUID();
+      const [js, css] = await Promise.all([
+        coverage.js ? tab.page.coverage.stopJSCoverage() : null,
+        coverage.css ? tab.page.coverage.stopCSSCoverage() : null
+      ]);
+      return summarizeCoverage(js, css, detailed);
+    } catch (error) {
+      throw new BrowserError(`Failed to stop coverage: ${error}`);
+    } finally {
+      delete tab.coverage;
+    }
+  }
 
-      this.tabs.set(tabId, { page, visible: headless });
+  // Core Web Vitals and timings of the current document along with Chrome's runtime counters.
+  // LCP and CLS keep changing while the page loads, so call it once the page has settled.
+  async getMetrics(tabId: string): Promise<MetricsResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
 
-      // Navigate to URL if provided
-      if (request.url) {
-        await page.goto(request.url, { waitUntil: 'networkidle2' });
+    try {
+      const [metrics, raw] = await Promise.all([
+        tab.page.metrics(),
+        tab.page.evaluate(collectWebVitals)
+      ]);
+      const runtime: Record<string, number> = {};
+      for (const [name, value] of Object.entries(metrics)) {
+        if (typeof value === 'number') runtime[name] = value;
       }
+      return {
+        url: tab.page.url(),
+        vitals: {
+          ttfb: raw.ttfb,
+          fcp: raw.fcp,
+          lcp: raw.lcp,
+          cls: raw.layoutShifts ? cumulativeLayoutShift(raw.layoutShifts) : null,
+          domContentLoaded: raw.domContentLoaded,
+          load: raw.load
+        },
+        runtime
+      };
+    } catch (error) {
+      throw new BrowserError(`Failed to get metrics: ${error}`);
+    }
+  }
 
-      // Handle page close
-      page.on('close', () => {
-        this.tabs.delete(tabId);
-      });
+  // The accessibility tree as assistive technology sees it, of the whole page or below one
+  // element. interestingOnly (the default) drops nodes without semantics such as wrapper divs.
+  async getAccessibilitySnapshot(
+    tabId: string,
+    request: AccessibilityRequest = {}
+  ): Promise<AccessibilitySnapshotResult> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
 
-      return tabId;
+    try {
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      const inIframe = frame !== tab.page.mainFrame();
+      let root: ElementHandle | null = null;
+      if (request.selector || request.xpath) {
+        root = await this.findElement(frame, toSelector(request), toElementIndex(request));
+      } else if (inIframe) {
+        root = await frame.$(':root');
+      }
+
+      try {
+        const snapshot = await tab.page.accessibility.snapshot({
+          interestingOnly: request.interestingOnly ?? true,
+          // nodes of an iframe are only found when the iframe trees are included
+          includeIframes: inIframe,
+          ...(root ? { root } : {})
+        });
+        if (!snapshot) {
+          return { snapshot: null, nodes: 0 };
+        }
+        const { tree, nodes } = toAccessibilityTree(snapshot);
+        return { snapshot: tree, nodes };
+      } finally {
+        await root?.dispose().catch(() => {});
+      }
     } catch (error) {
-      throw new BrowserError(`Failed to open tab: ${error}`);
+      throw new BrowserError(`Failed to get accessibility snapshot: ${error}`);
     }
   }
 
-  async navigateTab(tabId: string, url: string): Promise<void> {
+  // Rendered text of an element, or in readability mode the main content of the page
+  // without navigation, ads and other boilerplate
+  async getText(tabId: string, request: GetTextRequest = {}): Promise<TextResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const { mode, maxLength } = validateTextRequest(request);
     try {
-      await tab.page.goto(url, { waitUntil: 'networkidle2' });
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      const selector = request.selector || request.xpath ? toSelector(request) : 'body';
+      const root = await this.findElement(frame, selector, toElementIndex(request));
+      let raw: string;
+      try {
+        raw = mode === 'raw' ? await root.evaluate(readInnerText) : await this.readMainText(root);
+      } finally {
+        await root.dispose().catch(() => {});
+      }
+      const text = normalizeText(raw);
+      return {
+        mode,
+        title: await frame.evaluate(() => String((globalThis as any).document.title)),
+        url: frame.url(),
+        ...truncateText(text, maxLength),
+        length: text.length
+      };
     } catch (error) {
-      throw new BrowserError(`Failed to navigate tab: ${error}`);
+      throw new BrowserError(`Failed to get text: ${error}`);
     }
   }
 
-  async screenshotTab(tabId: string, fullPage = false): Promise<string> {
+  async getAttribute(tabId: string, request: AttributeRequest): Promise<AttributeResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const name = validateAttributeName(request.name);
+    const selector = toSelector(request);
     try {
-      const screenshot = await tab.page.screenshot({
-        type: 'png',
-        fullPage,
-        encoding: 'base64'
-      });
-      return screenshot as string;
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      const element = await this.findElement(frame, selector, toElementIndex(request));
+      try {
+        const value = await element.evaluate((el: any, n: string) => el.getAttribute(n), name);
+        return { name, value };
+      } finally {
+        await element.dispose().catch(() => {});
+      }
     } catch (error) {
-      throw new BrowserError(`Failed to take screenshot: ${error}`);
+      throw new BrowserError(`Failed to get attribute: ${error}`);
     }
   }
 
-  async clickElement(tabId: string, selector: string, waitForNavigation = false): Promise<void> {
+  // The live value, which for form fields is what the user typed rather than the attribute
+  async getProperty(tabId: string, request: PropertyRequest): Promise<PropertyResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const path = toPropertyPath(request.name);
+    const selector = toSelector(request);
     try {
-      if (waitForNavigation) {
-        await Promise.all([
-          tab.page.waitForNavigation({ waitUntil: 'networkidle2' }),
-          tab.page.click(selector)
-        ]);
-      } else {
-        await tab.page.click(selector);
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      const element = await this.findElement(frame, selector, toElementIndex(request));
+      try {
+        const handle = await element.evaluateHandle(
+          (el: any, keys: string[]) => keys.reduce((value, key) => value?.[key], el),
+          path
+        );
+        try {
+          const serialized = await handle.evaluate(serializeInPage);
+          if (serialized.error) {
+            throw new Error(`${request.name} is not JSON-serializable: ${serialized.error}`);
+          }
+          const value = serialized.json === undefined ? null : JSON.parse(serialized.json);
+          return { name: request.name, value };
+        } finally {
+          await handle.dispose().catch(() => {});
+        }
+      } finally {
+        await element.dispose().catch(() => {});
       }
     } catch (error) {
-      throw new BrowserError(`Failed to click element: ${error}`);
+      throw new BrowserError(`Failed to get property: ${error}`);
     }
   }
 
-  async hoverElement(tabId: string, selector: string): Promise<void> {
+  async getBoundingBox(tabId: string, request: ElementRequest): Promise<BoundingBoxResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const selector = toSelector(request);
     try {
-      await tab.page.hover(selector);
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      const element = await this.findElement(frame, selector, toElementIndex(request));
+      try {
+        const box = await element.boundingBox();
+        return {
+          boundingBox: box && { x: box.x, y: box.y, width: box.width, height: box.height },
+          visible: await element.isVisible(),
+          inViewport: box !== null && (await element.isIntersectingViewport())
+        };
+      } finally {
+        await element.dispose().catch(() => {});
+      }
     } catch (error) {
-      throw new BrowserError(`Failed to hover element: ${error}`);
+      throw new BrowserError(`Failed to get bounding box: ${error}`);
     }
   }
 
-  async fillField(tabId: string, selector: string, value: string): Promise<void> {
+  // Checks the DOM as it is now without waiting, waitForSelector is the waiting variant
+  async countElements(tabId: string, request: CountElementsRequest): Promise<CountElementsResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const selector = toSelector(request);
     try {
-      await tab.page.type(selector, value);
+      const frame = await this.resolveFrame(tab.page, request.frame);
+      const matches = await frame.$$(selector);
+      try {
+        if (!request.visible) {
+          return { count: matches.length };
+        }
+        const visible = await Promise.all(matches.map(match => match.isVisible()));
+        return { count: visible.filter(Boolean).length };
+      } finally {
+        await Promise.all(matches.map(match => match.dispose().catch(() => {})));
+      }
     } catch (error) {
-      throw new BrowserError(`Failed to fill field: ${error}`);
+      throw new BrowserError(`Failed to count elements: ${error}`);
+    }
+  }
+
+  async elementExists(tabId: string, request: CountElementsRequest): Promise<ElementExistsResult> {
+    const { count } = await this.countElements(tabId, request);
+    return { exists: count > 0, count };
+  }
+
+  private async readMainText(root: ElementHandle): Promise<string> {
+    const content = await root.evaluateHandle(collectContent);
+    try {
+      const index = pickMainContent(await content.evaluate(collected => collected.stats));
+      const main: JSHandle =
+        index === null
+          ? root
+          : await content.evaluateHandle((collected, i: number) => collected.nodes[i], index);
+      try {
+        return await main.evaluate(extractText, POSITIVE_HINTS.source, NEGATIVE_HINTS.source);
+      } finally {
+        if (main !== root) await main.dispose().catch(() => {});
+      }
+    } finally {
+      await content.dispose().catch(() => {});
     }
   }
 
-  async selectOption(tabId: string, selector: string, value: string): Promise<void> {
+  async getStorage(
+    tabId: string,
+    type: StorageType,
+    keys: string[] = []
+  ): Promise<StorageResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const origin = this.getStorageOrigin(tabId, tab.page);
     try {
-      await tab.page.select(selector, value);
+      const items = await tab.page.evaluate(readStorage, type, keys);
+      return { origin, type, items };
     } catch (error) {
-      throw new BrowserError(`Failed to select option: ${error}`);
+      throw new BrowserError(`Failed to get ${type}Storage: ${error}`);
     }
   }
 
-  async evaluateScript(tabId: string, script: string): Promise<any> {
+  async setStorage(
+    tabId: string,
+    type: StorageType,
+    items: Record<string, string>
+  ): Promise<number> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    this.getStorageOrigin(tabId, tab.page);
     try {
-      return await tab.page.evaluate(script);
+      return await tab.page.evaluate(writeStorage, type, items);
     } catch (error) {
-      throw new BrowserError(`Failed to evaluate script: ${error}`);
+      throw new BrowserError(`Failed to set ${type}Storage: ${error}`);
     }
   }
 
-  async closeTab(tabId: string): Promise<void> {
+  // Removes the given keys, or every item when no keys are given
+  async clearStorage(tabId: string, type: StorageType, keys: string[] = []): Promise<number> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    this.getStorageOrigin(tabId, tab.page);
     try {
-      await tab.page.close();
-      this.tabs.delete(tabId);
-      // close the browser if no tabs are left
-      const headless = tab.visible;
-      const anyTabsLeft = Array.from(this.tabs.values()).some(t => t.visible === headless);
-      if (!anyTabsLeft) {
-        const browser = this.browsers.get(headless);
-        if (browser) {
-          await browser.close();
-          this.browsers.set(headless, null);
+      return await tab.page.evaluate(removeStorage, type, keys);
+    } catch (error) {
+      throw new BrowserError(`Failed to clear ${type}Storage: ${error}`);
+    }
+  }
+
+  // Storage belongs to the origin of the loaded document, which does not exist until the
+  // tab has navigated somewhere
+  private getStorageOrigin(tabId: string, page: Page): string {
+    const url = page.url();
+    const origin = storageOrigin(url);
+    if (!origin) {
+      throw new BrowserError(
+        `Tab ${tabId} has no origin with storage (at ${url}), navigate to a page first`
+      );
+    }
+    return origin;
+  }
+
+  // Applies viewport, scale factor, touch and user agent of a preset or custom device in one
+  // go. Pages lay out for the new viewport right away, but some sites only pick a mobile
+  // layout on load, so emulate before navigating or reload afterwards.
+  async emulateDevice(tabId: string, request: EmulateDeviceRequest): Promise<EmulatedDevice> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    const device = resolveDevice(request);
+    tab.emulated = true;
+    try {
+      await tab.page.setViewport(device.viewport);
+      if (device.userAgent) {
+        await tab.page.setUserAgent({ userAgent: device.userAgent });
+        // a new user agent override drops the Accept-Language that came with the old one
+        if (tab.locale) {
+          await this.applyLocale(tab, tab.locale);
         }
       }
+      return device;
     } catch (error) {
-      throw new BrowserError(`Failed to close tab: ${error}`);
+      throw new BrowserError(`Failed to emulate device: ${error}`);
     }
   }
 
-  async bringToFront(tabId: string): Promise<void> {
+  // Resizes the viewport on its own, keeping touch, user agent and the rest of an emulated
+  // device. The size stays for the tab, across navigations, until it is set again.
+  async setViewport(tabId: string, request: SetViewportRequest): Promise<Viewport> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const viewport = resolveViewport(request, tab.page.viewport());
+    tab.emulated = true;
     try {
-      await tab.page.bringToFront();
+      await tab.page.setViewport(viewport);
+      return viewport;
     } catch (error) {
-      throw new BrowserError(`Failed to bring tab to front: ${error}`);
+      throw new BrowserError(`Failed to set viewport: ${error}`);
     }
   }
 
-  async focusElement(tabId: string, selector: string): Promise<void> {
+  // Grants the geolocation permission to the origin under test, the page's own by default,
+  // and reports the given position to it
+  async setGeolocation(tabId: string, request: GeolocationRequest): Promise<GeolocationResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    validateGeolocation(request);
+    const url = request.origin ?? tab.page.url();
+    const origin = storageOrigin(url);
+    if (!origin) {
+      throw new BrowserError(
+        `No origin to grant geolocation to (at ${url}), navigate to a page first or pass origin`
+      );
+    }
+
+    const { latitude, longitude, accuracy = 0 } = request;
+    tab.emulated = true;
     try {
-      await tab.page.$eval(selector, (el: any) => el.focus());
+      await this.grantPermissions(tab.page, origin, ['geolocation']);
+      await tab.page.setGeolocation({ latitude, longitude, accuracy });
+      return { latitude, longitude, accuracy, origin };
     } catch (error) {
-      throw new BrowserError(`Failed to focus element: ${error}`);
+      throw new BrowserError(`Failed to set geolocation: ${error}`);
     }
   }
 
-  async goBack(tabId: string): Promise<void> {
+  async readClipboard(tabId: string): Promise<ClipboardResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const origin = this.getClipboardOrigin(tab.page);
     try {
-      await tab.page.goBack({ waitUntil: 'networkidle2' });
+      await this.grantPermissions(tab.page, origin, CLIPBOARD_READ_PERMISSIONS);
+      return { origin, text: await this.useClipboard(tab.page, null) };
     } catch (error) {
-      throw new BrowserError(`Failed to go back: ${error}`);
+      throw new BrowserError(`Failed to read the clipboard: ${error}`);
     }
   }
 
-  async goForward(tabId: string): Promise<void> {
+  async writeClipboard(tabId: string, text: string): Promise<ClipboardResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    if (typeof text !== 'string') {
+      throw new InvalidArgumentError('Clipboard text must be a string');
+    }
+    const origin = this.getClipboardOrigin(tab.page);
     try {
-      await tab.page.goForward({ waitUntil: 'networkidle2' });
+      await this.grantPermissions(tab.page, origin, CLIPBOARD_WRITE_PERMISSIONS);
+      return { origin, text: await this.useClipboard(tab.page, text) };
     } catch (error) {
-      throw new BrowserError(`Failed to go forward: ${error}`);
+      throw new BrowserError(`Failed to write the clipboard: ${error}`);
     }
   }
 
-  async reloadTab(tabId: string, waitUntil?: string): Promise<void> {
+  // Access is granted to the page's own origin only, like a user accepting the prompt
+  private getClipboardOrigin(page: Page): string {
+    const origin = storageOrigin(page.url());
+    if (!origin) {
+      throw new BrowserError(
+        `No origin to grant clipboard access to (at ${page.url()}), navigate to a page first`
+      );
+    }
+    return origin;
+  }
+
+  private async useClipboard(page: Page, text: string | null): Promise<string> {
+    // The clipboard only serves the focused page
+    await page.bringToFront();
+    const outcome = await page.evaluate(accessClipboard, text);
+    if ('error' in outcome) {
+      throw new Error(describeClipboardError(outcome, page.url()));
+    }
+    return outcome.text;
+  }
+
+  // Omitting the timezone restores the system one
+  async setTimezone(tabId: string, timezoneId?: string): Promise<void> {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
+    }
+
+    if (timezoneId && !isValidTimezone(timezoneId)) {
+      throw new InvalidArgumentError(
+        `Invalid timezone ${timezoneId}, expected an IANA ID like Europe/Berlin`
+      );
+    }
+
+    tab.emulated = true;
+    try {
+      await tab.page.emulateTimezone(timezoneId || undefined);
+    } catch (error) {
+      throw new BrowserError(`Failed to set timezone: ${error}`);
+    }
+  }
+
+  // Emulates dark mode, reduced motion or print media. Fields left out keep their current
+  // emulation, so the color scheme can change without resetting the media type.
+  async emulateMedia(tabId: string, request: EmulateMediaRequest): Promise<MediaEmulation> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const emulation = resolveMediaEmulation(tab.media ?? NO_MEDIA_EMULATION, request);
     try {
-      await tab.page.reload({ waitUntil: (waitUntil || 'networkidle2') as any });
+      await this.applyMedia(tab, emulation);
+      if (Object.values(emulation).some(value => value !== null)) {
+        tab.media = emulation;
+      } else {
+        delete tab.media;
+      }
+      return emulation;
     } catch (error) {
-      throw new BrowserError(`Failed to reload tab: ${error}`);
+      throw new BrowserError(`Failed to emulate media: ${error}`);
     }
   }
 
-  async waitForSelector(
+  // Renders the page as people with the deficiency see it, screenshots included. It lasts
+  // until reset with none or the page closes, as every page does with its session.
+  async emulateVisionDeficiency(
     tabId: string,
-    selector: string,
-    options?: { timeout?: number; visible?: boolean }
-  ): Promise<void> {
+    type: VisionDeficiency
+  ): Promise<{ visionDeficiency: VisionDeficiency }> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const visionDeficiency = validateVisionDeficiency(type);
     try {
-      await tab.page.waitForSelector(selector, options);
+      await tab.page.emulateVisionDeficiency(visionDeficiency);
+      if (visionDeficiency === 'none') {
+        delete tab.visionDeficiency;
+      } else {
+        tab.visionDeficiency = visionDeficiency;
+      }
+      return { visionDeficiency };
     } catch (error) {
-      throw new BrowserError(`Failed to wait for selector: ${error}`);
+      throw new BrowserError(`Failed to emulate vision deficiency: ${error}`);
     }
   }
 
-  async waitForFunction(tabId: string, fn: string, options?: { timeout?: number }): Promise<void> {
+  // One protocol call for the media type and features, Puppeteer's emulateMediaType and
+  // emulateMediaFeatures would each reset what the other set
+  private async applyMedia(tab: Tab, emulation: MediaEmulation): Promise<void> {
+    const cdp = await this.getCdpSession(tab);
+    await cdp.send('Emulation.setEmulatedMedia', toEmulatedMedia(emulation));
+  }
+
+  // Overrides the locale used by Intl and navigator.language along with the Accept-Language
+  // header. Omitting the locale removes the override.
+  async setLocale(tabId: string, locale?: string): Promise<LocaleResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const canonical = locale ? canonicalLocale(locale) : null;
     try {
-      await tab.page.waitForFunction(fn, options);
+      await this.applyLocale(tab, canonical);
+      if (canonical) {
+        tab.locale = canonical;
+      } else {
+        delete tab.locale;
+      }
+      return { locale: canonical, acceptLanguage: canonical && toAcceptLanguage(canonical) };
     } catch (error) {
-      throw new BrowserError(`Failed to wait for function: ${error}`);
+      throw new BrowserError(`Failed to set locale: ${error}`);
     }
   }
 
-  async waitForNavigation(
+  // Throttles the tab's connection or takes it offline, and stretches the default navigation
+  // timeout so slow loads do not fail early. Without a preset or values the throttling and
+  // the longer timeout are removed.
+  async setNetworkConditions(
     tabId: string,
-    options?: { timeout?: number; waitUntil?: string }
-  ): Promise<void> {
+    request: NetworkConditionsRequest
+  ): Promise<NetworkConditionsResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const conditions = resolveNetworkConditions(request);
     try {
-      const waitOptions: any = {};
-      if (options?.timeout) waitOptions.timeout = options.timeout;
-      if (options?.waitUntil) waitOptions.waitUntil = options.waitUntil;
-      else waitOptions.waitUntil = 'networkidle2';
+      await tab.page.emulateNetworkConditions(
+        conditions && {
+          offline: conditions.offline,
+          download: toBytesPerSecond(conditions.downloadKbps),
+          upload: toBytesPerSecond(conditions.uploadKbps),
+          latency: conditions.latency
+        }
+      );
+      // offline requests fail right away, only a slow connection needs more time
+      const slow = conditions !== null && !conditions.offline;
+      const owner = tab.sessionId ? this.sessions.get(tab.sessionId) : undefined;
+      const navigationTimeout =
+        (owner?.navigationTimeout ?? this.navigationTimeout) *
+        (slow ? THROTTLED_NAVIGATION_FACTOR : 1);
+      tab.page.setDefaultNavigationTimeout(navigationTimeout);
 
-      await tab.page.waitForNavigation(waitOptions);
+      const result: NetworkConditionsResult = conditions
+        ? { ...conditions, navigationTimeout }
+        : {
+            preset: null,
+            throttled: false,
+            offline: false,
+            downloadKbps: null,
+            uploadKbps: null,
+            latency: 0,
+            navigationTimeout
+          };
+      if (conditions) {
+        tab.networkConditions = result;
+      } else {
+        delete tab.networkConditions;
+      }
+      return result;
     } catch (error) {
-      throw new BrowserError(`Failed to wait for navigation: ${error}`);
+      throw new BrowserError(`Failed to set network conditions: ${error}`);
     }
   }
 
-  async getTabUrl(tabId: string): Promise<string> {
+  // Slows the tab's CPU down by the rate, 1 restores full speed. The emulation ends with the
+  // tab, so closing it or its session resets it.
+  async setCpuThrottling(tabId: string, rate: number): Promise<CpuThrottlingResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    validateCpuThrottlingRate(rate);
     try {
-      return tab.page.url();
+      await tab.page.emulateCPUThrottling(rate === 1 ? null : rate);
+      if (rate === 1) {
+        delete tab.cpuThrottlingRate;
+      } else {
+        tab.cpuThrottlingRate = rate;
+      }
+      return { rate };
     } catch (error) {
-      throw new BrowserError(`Failed to get tab URL: ${error}`);
+      throw new BrowserError(`Failed to set CPU throttling: ${error}`);
+    }
+  }
+
+  getCpuThrottling(tabId: string): CpuThrottlingResult {
+    const tab = this.tabs.get(tabId);
+    if (!tab) {
+      throw new TabNotFoundError(tabId);
     }
+    return { rate: tab.cpuThrottlingRate ?? 1 };
   }
 
-  async getTabHtml(tabId: string): Promise<string> {
+  // Sends a protocol command as is over the tab's CDP session, for what no tool wraps yet.
+  // Only reachable with --enable-raw-cdp, it can do anything DevTools can.
+  async sendCdpCommand(tabId: string, request: CdpCommandRequest): Promise<CdpCommandResult> {
     const tab = this.tabs.get(tabId);
     if (!tab) {
       throw new TabNotFoundError(tabId);
     }
 
+    const { method, params } = validateCdpCommand(request);
+    tab.emulated = true;
     try {
-      return await tab.page.content();
+      const cdp = await this.getCdpSession(tab);
+      const result = await cdp.send(method as any, params);
+      return { method, result };
     } catch (error) {
-      throw new BrowserError(`Failed to get tab HTML: ${error}`);
+      throw new BrowserError(`CDP command ${method} failed: ${error}`);
     }
   }
 
+  private async applyLocale(tab: Tab, locale: string | null): Promise<void> {
+    const cdp = await this.getCdpSession(tab);
+    await cdp.send('Emulation.setLocaleOverride', locale ? { locale } : {});
+    // Accept-Language can only be overridden together with the user agent, so keep the
+    // current one
+    const userAgent: string = await tab.page.evaluate(
+      () => (globalThis as any).navigator.userAgent
+    );
+    // along with the client hints of the default one, which would be dropped otherwise
+    const defaults = this.userAgents.get(tab.visible);
+    const override = defaults?.userAgent === userAgent ? defaults : { userAgent };
+    await cdp.send(
+      'Network.setUserAgentOverride',
+      locale ? { ...override, acceptLanguage: toAcceptLanguage(locale) } : override
+    );
+  }
+
+  private async getCdpSession(tab: Tab): Promise<CDPSession> {
+    tab.cdp ??= await tab.page.createCDPSession();
+    return tab.cdp;
+  }
+
+  private async grantPermissions(
+    page: Page,
+    origin: string,
+    permissions: Permission[]
+  ): Promise<void> {
+    const context = page.browserContext();
+    let origins = this.grantedPermissions.get(context);
+    if (!origins) {
+      origins = new Map();
+      this.grantedPermissions.set(context, origins);
+    }
+    const granted = origins.get(origin) ?? new Set<Permission>();
+    for (const permission of permissions) {
+      granted.add(permission);
+    }
+    origins.set(origin, granted);
+    await context.overridePermissions(origin, Array.from(granted));
+  }
+
   async getTabs(): Promise<TabInfo[]> {
     const tabs: TabInfo[] = [];
 
-    for (const [tabId, { page }] of this.tabs) {
+    for (const [tabId, { page, sessionId }] of this.tabs) {
       tabs.push({
         id: tabId,
         url: page.url(),
         title: await page.title(),
-        headless: false // We'll track this if needed
+        headless: false, // We'll track this if needed
+        ...(sessionId ? { sessionId } : {})
       });
     }
 
@@ -457,11 +4769,13 @@ class BrowserManager {
           this.tabs.delete(tabId);
         }
       }
+      this.sessions.clear();
+      this.stopSessionSweeper();
 
       // Close all browsers
       for (const [headless, browser] of this.browsers) {
         if (browser) {
-          await browser.close();
+          await this.releaseBrowser(browser);
           this.browsers.set(headless, null);
         }
       }
@@ -473,19 +4787,49 @@ class BrowserManager {
   async close(waitPostClose = 250): Promise<void> {
     for (const [headless, browser] of this.browsers) {
       if (browser) {
-        await browser.close();
+        await this.releaseBrowser(browser);
         this.browsers.set(headless, null);
       }
     }
+    await removeUploadDirs(Array.from(this.tabs.values()).flatMap(tab => tab.uploadDirs ?? []));
     this.tabs.clear();
+    this.sessions.clear();
+    this.stopSessionSweeper();
     await new Promise(resolve => setTimeout(resolve, waitPostClose));
   }
 
+  // Stops taking operations, gives the running ones timeout ms to finish and then closes every
+  // session and browser, so Chrome exits on its own and releases its profile
+  async shutdown(timeout: number): Promise<void> {
+    this.operations.close(new ShuttingDownError());
+    if (!(await this.operations.idle(timeout))) {
+      const { running } = this.operations.status();
+      logger.warn('closing the browsers with operations still running', { running });
+    }
+    for (const sessionId of Array.from(this.sessions.keys())) {
+      await this.closeSession(sessionId).catch(error =>
+        debug('Failed to close session %s on shutdown: %O', sessionId, error)
+      );
+    }
+
+    const closed = await Promise.race([
+      this.close(0).then(() => true),
+      new Promise<boolean>(resolve => setTimeout(resolve, BROWSER_CLOSE_TIMEOUT_MS, false))
+    ]);
+    if (!closed) {
+      logger.warn('browser did not close in time, killing it');
+      this.exitHandler?.();
+    }
+  }
+
+  // A profile set with PCS_USER_DATA_DIR is kept, it is meant to outlive the server
   async cleanBrowserData(): Promise<void> {
     await this.close();
-    await fs
-      .rm(path.join(ensureBaseWorkingDirectory(), '.browser'), { recursive: true, force: true })
-      .catch(() => {});
+    if (this.persistentProfile) {
+      logger.warn('keeping the persistent profile', { userDataDir: this.userDataDir });
+      return;
+    }
+    await fs.rm(this.userDataDir, { recursive: true, force: true }).catch(() => {});
   }
 
   updateChromePath(chromePath: string | null): void {
@@ -494,5 +4838,10 @@ class BrowserManager {
 }
 
 export const BrowserManagerSingleton = memoize(
-  (chromePath?: string | null) => new BrowserManager(chromePath)
+  (
+    chromePath?: string | null,
+    cdpEndpoint?: string | null,
+    proxy?: string | null,
+    launch?: LaunchSettings
+  ) => new BrowserManager(chromePath, cdpEndpoint, proxy, launch)
 );
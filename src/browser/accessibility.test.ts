@@ -0,0 +1,44 @@
+import type { SerializedAXNode } from 'puppeteer-core';
+import { describe, expect, it } from 'vitest';
+import { toAccessibilityTree } from './accessibility.js';
+
+// Puppeteer leaves unset properties undefined and adds an elementHandle accessor
+function node(fields: { role: string; [property: string]: unknown }): SerializedAXNode {
+  return { ...fields, elementHandle: async () => null } as SerializedAXNode;
+}
+
+describe('Accessibility helpers', () => {
+  describe('toAccessibilityTree', () => {
+    it('should copy the tree without accessors and unset properties', () => {
+      const snapshot = node({
+        role: 'RootWebArea',
+        name: 'Checkout',
+        children: [
+          node({ role: 'heading', name: 'Payment', level: 2 }),
+          node({ role: 'checkbox', name: 'Save card', checked: 'mixed', disabled: undefined }),
+          node({ role: 'button', name: 'Pay', focused: true })
+        ]
+      });
+
+      const { tree, nodes } = toAccessibilityTree(snapshot);
+      expect(nodes).toBe(4);
+      expect(JSON.parse(JSON.stringify(tree))).toEqual(tree);
+      expect(tree).toEqual({
+        role: 'RootWebArea',
+        name: 'Checkout',
+        children: [
+          { role: 'heading', name: 'Payment', level: 2 },
+          { role: 'checkbox', name: 'Save card', checked: 'mixed' },
+          { role: 'button', name: 'Pay', focused: true }
+        ]
+      });
+    });
+
+    it('should leave out empty child lists', () => {
+      expect(toAccessibilityTree(node({ role: 'link', name: 'Home', children: [] }))).toEqual({
+        tree: { role: 'link', name: 'Home' },
+        nodes: 1
+      });
+    });
+  });
+});
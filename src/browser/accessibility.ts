@@ -0,0 +1,25 @@
+import type { SerializedAXNode } from 'puppeteer-core';
+import type { AccessibilityNode } from '../types/index.js';
+
+// Copies the snapshot into plain JSON, dropping the elementHandle accessor and unset
+// properties, and counts the nodes along the way
+export function toAccessibilityTree(root: SerializedAXNode): {
+  tree: AccessibilityNode;
+  nodes: number;
+} {
+  let nodes = 0;
+  const convert = (node: SerializedAXNode): AccessibilityNode => {
+    nodes++;
+    const result: AccessibilityNode = { role: node.role };
+    for (const [key, value] of Object.entries(node)) {
+      if (key === 'role' || key === 'children' || value === undefined) continue;
+      if (typeof value === 'function') continue;
+      result[key] = value;
+    }
+    if (node.children?.length) {
+      result.children = node.children.map(convert);
+    }
+    return result;
+  };
+  return { tree: convert(root), nodes };
+}
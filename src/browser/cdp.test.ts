@@ -0,0 +1,25 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { validateCdpCommand } from './cdp.js';
+
+describe('validateCdpCommand', () => {
+  it('should default the params to an empty object', () => {
+    expect(validateCdpCommand({ method: 'Animation.getPlaybackRate' })).toEqual({
+      method: 'Animation.getPlaybackRate',
+      params: {}
+    });
+    expect(validateCdpCommand({ method: 'DOM.getDocument', params: { depth: 1 } })).toEqual({
+      method: 'DOM.getDocument',
+      params: { depth: 1 }
+    });
+  });
+
+  it('should reject malformed methods and params', () => {
+    expect(() => validateCdpCommand({ method: 'getDocument' })).toThrow(BrowserError);
+    expect(() => validateCdpCommand({ method: 'DOM.getDocument()' })).toThrow(/Invalid CDP/);
+    expect(() => validateCdpCommand({} as any)).toThrow(/Invalid CDP/);
+    expect(() => validateCdpCommand({ method: 'DOM.enable', params: [] as any })).toThrow(
+      /must be an object/
+    );
+  });
+});
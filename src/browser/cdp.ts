@@ -0,0 +1,20 @@
+import { type CdpCommandRequest, InvalidArgumentError } from '../types/index.js';
+
+// Domain.command as the protocol names them, e.g. Animation.setPlaybackRate
+const METHOD = /^[A-Z][A-Za-z]*\.[a-z][A-Za-z]*$/;
+
+export function validateCdpCommand(request: CdpCommandRequest): {
+  method: string;
+  params: Record<string, unknown>;
+} {
+  const { method, params = {} } = request;
+  if (typeof method !== 'string' || !METHOD.test(method)) {
+    throw new InvalidArgumentError(
+      `Invalid CDP method: ${JSON.stringify(method)}, expected Domain.command`
+    );
+  }
+  if (typeof params !== 'object' || params === null || Array.isArray(params)) {
+    throw new InvalidArgumentError('CDP params must be an object');
+  }
+  return { method, params };
+}
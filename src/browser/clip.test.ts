@@ -0,0 +1,52 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { padClip, parseBackgroundColor, validatePadding } from './clip.js';
+
+describe('Element screenshot clips', () => {
+  const page = { width: 1280, height: 3000 };
+
+  it('should grow the box by the padding on every side', () => {
+    expect(padClip({ x: 100, y: 200, width: 50, height: 20 }, 16, page)).toEqual({
+      x: 84,
+      y: 184,
+      width: 82,
+      height: 52
+    });
+    expect(padClip({ x: 100, y: 200, width: 50, height: 20 }, 0, page)).toEqual({
+      x: 100,
+      y: 200,
+      width: 50,
+      height: 20
+    });
+  });
+
+  it('should stop the padding at the edges of the page', () => {
+    expect(padClip({ x: 4, y: 0, width: 1270, height: 40 }, 20, page)).toEqual({
+      x: 0,
+      y: 0,
+      width: 1280,
+      height: 60
+    });
+    // an element hanging off the page keeps its own size
+    expect(padClip({ x: 1200, y: 2990, width: 200, height: 20 }, 10, page)).toEqual({
+      x: 1190,
+      y: 2980,
+      width: 210,
+      height: 30
+    });
+  });
+
+  it('should only take non-negative padding', () => {
+    expect(() => validatePadding(8)).not.toThrow();
+    expect(() => validatePadding(-1)).toThrow(BrowserError);
+    expect(() => validatePadding(Number.NaN)).toThrow(/padding/);
+  });
+
+  it('should parse hex background colors', () => {
+    expect(parseBackgroundColor('#ffffff')).toEqual({ r: 255, g: 255, b: 255, a: 1 });
+    expect(parseBackgroundColor('#0f08')).toEqual({ r: 0, g: 255, b: 0, a: 0.53 });
+    expect(parseBackgroundColor('#1E293B80')).toEqual({ r: 30, g: 41, b: 59, a: 0.5 });
+    expect(() => parseBackgroundColor('white')).toThrow(/hex color/);
+    expect(() => parseBackgroundColor('#12345')).toThrow(BrowserError);
+  });
+});
@@ -0,0 +1,45 @@
+import type { Protocol } from 'puppeteer-core';
+import { type ClipRect, InvalidArgumentError } from '../types/index.js';
+
+const HEX_COLOR = /^#([0-9a-f]{3,4}|[0-9a-f]{6}|[0-9a-f]{8})$/i;
+
+export function validatePadding(padding: number): void {
+  if (typeof padding !== 'number' || !(padding >= 0) || !Number.isFinite(padding)) {
+    throw new InvalidArgumentError('padding must be a non-negative number of pixels');
+  }
+}
+
+// #rgb, #rgba, #rrggbb or #rrggbbaa, as the color Chrome paints behind a page that has no
+// background of its own
+export function parseBackgroundColor(color: string): Protocol.DOM.RGBA {
+  const match = HEX_COLOR.exec(typeof color === 'string' ? color : '');
+  if (!match) {
+    throw new InvalidArgumentError(`background must be a hex color like #ffffff, got ${color}`);
+  }
+  let hex = match[1]!;
+  if (hex.length <= 4) {
+    hex = Array.from(hex, digit => digit + digit).join('');
+  }
+  const channel = (index: number) => Number.parseInt(hex.slice(index * 2, index * 2 + 2), 16);
+  return {
+    r: channel(0),
+    g: channel(1),
+    b: channel(2),
+    a: hex.length === 8 ? Math.round((channel(3) / 255) * 100) / 100 : 1
+  };
+}
+
+// The box of an element in page coordinates grown by padding on every side. The padding
+// stops at the edges of the page, so an element against one gets less on that side, while
+// parts outside the viewport are fine since the capture goes beyond it.
+export function padClip(
+  box: ClipRect,
+  padding: number,
+  page: { width: number; height: number }
+): ClipRect {
+  const x = Math.max(0, box.x - padding);
+  const y = Math.max(0, box.y - padding);
+  const right = Math.min(Math.max(page.width, box.x + box.width), box.x + box.width + padding);
+  const bottom = Math.min(Math.max(page.height, box.y + box.height), box.y + box.height + padding);
+  return { x, y, width: right - x, height: bottom - y };
+}
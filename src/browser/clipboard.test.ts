@@ -0,0 +1,49 @@
+import { afterEach, describe, expect, it } from 'vitest';
+import { accessClipboard, describeClipboardError } from './clipboard.js';
+
+describe('Clipboard', () => {
+  const globals = globalThis as any;
+  const original = Object.getOwnPropertyDescriptor(globalThis, 'navigator');
+
+  const fakeClipboard = (clipboard: object, isSecureContext = true) => {
+    Object.defineProperty(globalThis, 'navigator', { value: { clipboard }, configurable: true });
+    globals.isSecureContext = isSecureContext;
+  };
+
+  afterEach(() => {
+    if (original) Object.defineProperty(globalThis, 'navigator', original);
+    delete globals.isSecureContext;
+  });
+
+  it('should read and write text', async () => {
+    let stored = 'copied link';
+    fakeClipboard({
+      readText: async () => stored,
+      writeText: async (text: string) => {
+        stored = text;
+      }
+    });
+
+    expect(await accessClipboard(null)).toEqual({ text: 'copied link' });
+    expect(await accessClipboard('pasted')).toEqual({ text: 'pasted' });
+    expect(stored).toBe('pasted');
+  });
+
+  it('should report why the clipboard is unavailable', async () => {
+    fakeClipboard({}, false);
+    const insecure = await accessClipboard(null);
+    expect(insecure).toMatchObject({ name: 'InsecureContext' });
+    expect(describeClipboardError(insecure as any, 'http://example.com/')).toMatch(/HTTPS/);
+
+    const denied = new Error('Document is not focused.');
+    denied.name = 'NotAllowedError';
+    fakeClipboard({
+      readText: async () => {
+        throw denied;
+      }
+    });
+    const outcome = await accessClipboard(null);
+    expect(outcome).toEqual({ error: 'Document is not focused.', name: 'NotAllowedError' });
+    expect(describeClipboardError(outcome as any, 'https://example.com/')).toMatch(/headless/);
+  });
+});
@@ -0,0 +1,45 @@
+import type { Permission } from 'puppeteer-core';
+
+export const CLIPBOARD_READ_PERMISSIONS: Permission[] = ['clipboard-read'];
+export const CLIPBOARD_WRITE_PERMISSIONS: Permission[] = [
+  'clipboard-write',
+  'clipboard-sanitized-write'
+];
+
+export type ClipboardOutcome = { text: string } | { error: string; name: string };
+
+// Runs in the page: reads the clipboard when text is null, writes text to it otherwise.
+// Failures are returned rather than thrown so their DOMException name survives.
+export async function accessClipboard(text: string | null): Promise<ClipboardOutcome> {
+  const scope: any = globalThis;
+  const clipboard = scope.isSecureContext ? scope.navigator?.clipboard : undefined;
+  if (!clipboard) {
+    return { error: 'navigator.clipboard is undefined', name: 'InsecureContext' };
+  }
+  try {
+    if (text === null) {
+      return { text: String(await clipboard.readText()) };
+    }
+    await clipboard.writeText(text);
+    return { text };
+  } catch (error: any) {
+    return { error: String(error?.message ?? error), name: String(error?.name ?? 'Error') };
+  }
+}
+
+// Spells out the limits the browser puts on the clipboard, which its own messages leave out
+export function describeClipboardError(
+  outcome: { error: string; name: string },
+  url: string
+): string {
+  if (outcome.name === 'InsecureContext') {
+    return `The clipboard is not available at ${url}, it needs a secure page (HTTPS or localhost)`;
+  }
+  if (outcome.name === 'NotAllowedError') {
+    return (
+      `${outcome.error}. Chrome only lets a focused page use the clipboard, and the old ` +
+      'headless shell has no clipboard at all, use the new headless mode or a headed browser'
+    );
+  }
+  return `${outcome.name}: ${outcome.error}`;
+}
@@ -0,0 +1,81 @@
+import { afterEach, describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { DEFAULT_CONSENT_RULES, dismissConsentInPage, validateConsentRules } from './consent.js';
+
+describe('Consent banners', () => {
+  const globals = globalThis as any;
+
+  // Enough of the DOM for the rules, buttons match the selectors listed for them
+  const fakeButton = (options: {
+    selectors?: string[];
+    text?: string;
+    visible?: boolean;
+    inBanner?: boolean;
+  }) => ({
+    tagName: 'BUTTON',
+    textContent: options.text ?? '',
+    clicks: 0,
+    selectors: options.selectors ?? [],
+    checkVisibility: () => options.visible ?? true,
+    closest: () => (options.inBanner ? {} : null),
+    getRootNode: () => ({}),
+    click() {
+      this.clicks++;
+    }
+  });
+
+  const fakeDocument = (buttons: ReturnType<typeof fakeButton>[]) => {
+    globals.document = {
+      querySelectorAll: (selector: string) =>
+        selector.startsWith('button,')
+          ? buttons
+          : buttons.filter(button => button.selectors.includes(selector))
+    };
+  };
+
+  afterEach(() => {
+    delete globals.document;
+  });
+
+  it('should keep the built-in rules valid', () => {
+    expect(validateConsentRules(DEFAULT_CONSENT_RULES)).toEqual(DEFAULT_CONSENT_RULES);
+    expect(DEFAULT_CONSENT_RULES.map(rule => rule.name)).toContain('OneTrust');
+    expect(DEFAULT_CONSENT_RULES.map(rule => rule.name)).toContain('Cookiebot');
+  });
+
+  it('should reject malformed user rules', () => {
+    expect(() => validateConsentRules({})).toThrow(BrowserError);
+    expect(() => validateConsentRules([{ selectors: ['#ok'] }])).toThrow(/must have a name/);
+    expect(() => validateConsentRules([{ name: 'Mine' }])).toThrow(/selectors or texts/);
+    expect(() => validateConsentRules([{ name: 'Mine', texts: [1] }])).toThrow(/as strings/);
+  });
+
+  it('should click the first visible button a rule finds', () => {
+    const hidden = fakeButton({ selectors: ['#onetrust-accept-btn-handler'], visible: false });
+    const shown = fakeButton({ selectors: ['#didomi-notice-agree-button'] });
+    fakeDocument([hidden, shown]);
+
+    expect(dismissConsentInPage(DEFAULT_CONSENT_RULES)).toBe('Didomi');
+    expect(hidden.clicks).toBe(0);
+    expect(shown.clicks).toBe(1);
+  });
+
+  it('should only trust generic labels inside a consent banner', () => {
+    const invite = fakeButton({ text: 'Accept' });
+    fakeDocument([invite]);
+    expect(dismissConsentInPage(DEFAULT_CONSENT_RULES)).toBeNull();
+
+    const banner = fakeButton({ text: '  Accept   all ', inBanner: true });
+    fakeDocument([invite, banner]);
+    expect(dismissConsentInPage(DEFAULT_CONSENT_RULES)).toBe('Generic');
+    expect(invite.clicks).toBe(0);
+    expect(banner.clicks).toBe(1);
+  });
+
+  it('should try user rules first', () => {
+    const button = fakeButton({ selectors: ['#my-accept'] });
+    fakeDocument([button]);
+    const rules = [{ name: 'Mine', selectors: ['#my-accept'] }, ...DEFAULT_CONSENT_RULES];
+    expect(dismissConsentInPage(rules)).toBe('Mine');
+  });
+});
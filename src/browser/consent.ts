@@ -0,0 +1,156 @@
+import { type ConsentRule, InvalidArgumentError } from '../types/index.js';
+
+// Accept buttons of popular consent management platforms. Banners are injected after the
+// page loaded, often a while after, so they are looked for again after these delays.
+export const CONSENT_RETRY_DELAYS = [0, 500, 1500, 3000];
+
+// Where a generic accept label is trusted to belong to a consent banner
+const CONSENT_CONTAINERS = [
+  '[id*="cookie" i]',
+  '[class*="cookie" i]',
+  '[id*="consent" i]',
+  '[class*="consent" i]',
+  '[id*="gdpr" i]',
+  '[class*="gdpr" i]',
+  '[aria-label*="cookie" i]',
+  '[aria-label*="consent" i]'
+].join(', ');
+
+export const DEFAULT_CONSENT_RULES: ConsentRule[] = [
+  { name: 'OneTrust', selectors: ['#onetrust-accept-btn-handler', '.onetrust-accept-btn-handler'] },
+  {
+    name: 'Cookiebot',
+    selectors: [
+      '#CybotCookiebotDialogBodyLevelButtonLevelOptinAllowAll',
+      '#CybotCookiebotDialogBodyButtonAccept'
+    ]
+  },
+  { name: 'Didomi', selectors: ['#didomi-notice-agree-button'] },
+  { name: 'Quantcast', selectors: ['.qc-cmp2-summary-buttons button[mode="primary"]'] },
+  { name: 'TrustArc', selectors: ['#truste-consent-button'] },
+  { name: 'Usercentrics', selectors: ['[data-testid="uc-accept-all-button"]'] },
+  { name: 'Google Funding Choices', selectors: ['.fc-cta-consent'] },
+  { name: 'Osano', selectors: ['.osano-cm-accept-all', '.cc-btn.cc-allow'] },
+  { name: 'CookieYes', selectors: ['.cky-btn-accept'] },
+  { name: 'Complianz', selectors: ['.cmplz-accept'] },
+  { name: 'Iubenda', selectors: ['.iubenda-cs-accept-btn'] },
+  { name: 'Borlabs', selectors: ['a._brlbs-btn-accept-all'] },
+  { name: 'Termly', selectors: ['[data-tid="banner-accept"]'] },
+  { name: 'Axeptio', selectors: ['#axeptio_btn_acceptAll'] },
+  {
+    name: 'Generic',
+    texts: [
+      'Accept all',
+      'Accept all cookies',
+      'Accept cookies',
+      'Allow all',
+      'Allow all cookies',
+      'I agree',
+      'I accept',
+      'Agree',
+      'Accept',
+      'Alle akzeptieren',
+      'Akzeptieren',
+      'Tout accepter',
+      'Accepter',
+      'Aceptar todo',
+      'Aceptar',
+      'Accetta tutto',
+      'Accetta',
+      'Alles accepteren',
+      'Aceitar todos'
+    ],
+    within: CONSENT_CONTAINERS
+  }
+];
+
+export function validateConsentRules(rules: unknown): ConsentRule[] {
+  if (!Array.isArray(rules)) {
+    throw new InvalidArgumentError('consentRules must be a list of rules');
+  }
+  const isStrings = (value: unknown) =>
+    value === undefined ||
+    (Array.isArray(value) && value.every(item => typeof item === 'string' && item));
+  return rules.map((rule, i) => {
+    if (typeof rule?.name !== 'string' || !rule.name) {
+      throw new InvalidArgumentError(`Consent rule ${i} must have a name`);
+    }
+    if (!isStrings(rule.selectors) || !isStrings(rule.texts)) {
+      throw new InvalidArgumentError(
+        `Consent rule ${rule.name} must have selectors and texts as strings`
+      );
+    }
+    if (!rule.selectors?.length && !rule.texts?.length) {
+      throw new InvalidArgumentError(`Consent rule ${rule.name} must have selectors or texts`);
+    }
+    if (rule.within !== undefined && typeof rule.within !== 'string') {
+      throw new InvalidArgumentError(
+        `Consent rule ${rule.name} must have a within selector string`
+      );
+    }
+    return rule as ConsentRule;
+  });
+}
+
+// Runs in every frame of the page. Clicks the first visible accept button a rule finds,
+// looking into open shadow roots too since some platforms render the banner in one, and
+// returns the name of that rule.
+export function dismissConsentInPage(rules: ConsentRule[]): string | null {
+  const scope: any = globalThis;
+  const queryAll = (root: any, selector: string): any[] => {
+    const found: any[] = [];
+    const visit = (node: any) => {
+      found.push(...node.querySelectorAll(selector));
+      for (const el of node.querySelectorAll('*')) {
+        if (el.shadowRoot) visit(el.shadowRoot);
+      }
+    };
+    try {
+      visit(root);
+    } catch {
+      // an invalid selector of a user rule matches nothing
+    }
+    return found;
+  };
+  const isVisible = (el: any) =>
+    typeof el.checkVisibility === 'function'
+      ? el.checkVisibility({ checkOpacity: true, checkVisibilityCSS: true })
+      : el.getClientRects().length > 0;
+  const isWithin = (el: any, selector: string) => {
+    try {
+      return Boolean(el.closest(selector) ?? el.getRootNode()?.host?.closest(selector));
+    } catch {
+      return false;
+    }
+  };
+  const labelOf = (el: any) =>
+    String(el.value !== undefined && el.tagName === 'INPUT' ? el.value : el.textContent)
+      .replace(/\s+/g, ' ')
+      .trim()
+      .toLowerCase();
+
+  for (const rule of rules) {
+    for (const selector of rule.selectors ?? []) {
+      const button = queryAll(scope.document, selector).find(isVisible);
+      if (button) {
+        button.click();
+        return rule.name;
+      }
+    }
+    if (rule.texts?.length) {
+      const texts = new Set(rule.texts.map(text => text.toLowerCase()));
+      const candidates = queryAll(
+        scope.document,
+        'button, [role="button"], a, input[type="button"], input[type="submit"]'
+      );
+      const button = candidates.find(
+        el => texts.has(labelOf(el)) && (!rule.within || isWithin(el, rule.within)) && isVisible(el)
+      );
+      if (button) {
+        button.click();
+        return rule.name;
+      }
+    }
+  }
+  return null;
+}
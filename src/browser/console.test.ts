@@ -0,0 +1,75 @@
+import { describe, expect, it } from 'vitest';
+import type { ConsoleEntry, ConsoleLevel } from '../types/index.js';
+import { ConsoleBuffer, parseStackLocation, toConsoleLevel } from './console.js';
+
+function entry(level: ConsoleLevel, text: string, pageId = 'page-1'): ConsoleEntry {
+  return {
+    source: 'console',
+    level,
+    type: 'log',
+    text,
+    url: null,
+    lineNumber: null,
+    columnNumber: null,
+    timestamp: new Date(0).toISOString(),
+    pageId
+  };
+}
+
+describe('Console helpers', () => {
+  describe('toConsoleLevel', () => {
+    it('should map console methods to levels', () => {
+      expect(toConsoleLevel('warn')).toBe('warning');
+      expect(toConsoleLevel('assert')).toBe('error');
+      expect(toConsoleLevel('debug')).toBe('debug');
+      expect(toConsoleLevel('table')).toBe('info');
+    });
+  });
+
+  describe('parseStackLocation', () => {
+    it('should take the first frame with a location', () => {
+      const stack = 'TypeError: x is undefined\n    at render (https://example.com/app.js:12:34)';
+      expect(parseStackLocation(stack)).toEqual({
+        url: 'https://example.com/app.js',
+        lineNumber: 12,
+        columnNumber: 34
+      });
+    });
+
+    it('should return null without a location', () => {
+      expect(parseStackLocation('Error: boom')).toBe(null);
+      expect(parseStackLocation(undefined)).toBe(null);
+    });
+  });
+
+  describe('ConsoleBuffer', () => {
+    it('should drop the oldest entries once full', () => {
+      const buffer = new ConsoleBuffer(2);
+      buffer.push(entry('info', 'one'));
+      buffer.push(entry('info', 'two'));
+      buffer.push(entry('info', 'three'));
+      const result = buffer.list();
+      expect(result.entries.map(e => e.text)).toEqual(['two', 'three']);
+      expect(result.dropped).toBe(1);
+    });
+
+    it('should filter by minimum level and page', () => {
+      const buffer = new ConsoleBuffer();
+      buffer.push(entry('debug', 'noise'));
+      buffer.push(entry('warning', 'careful'));
+      buffer.push(entry('error', 'broken', 'page-2'));
+      expect(buffer.list({ level: 'warning' }).entries.map(e => e.text)).toEqual([
+        'careful',
+        'broken'
+      ]);
+      expect(buffer.list({ pageId: 'page-2' }).entries.map(e => e.text)).toEqual(['broken']);
+    });
+
+    it('should empty on clear', () => {
+      const buffer = new ConsoleBuffer();
+      buffer.push(entry('info', 'one'));
+      buffer.clear();
+      expect(buffer.list()).toEqual({ entries: [], dropped: 0 });
+    });
+  });
+});
@@ -0,0 +1,75 @@
+import {
+  CONSOLE_LEVELS,
+  type ConsoleEntry,
+  type ConsoleLevel,
+  type ConsoleLogsRequest,
+  type ConsoleLogsResult
+} from '../types/index.js';
+
+// Oldest entries are dropped first once a session holds this many
+export const CONSOLE_BUFFER_SIZE = 1000;
+
+// Puppeteer console message types collapsed to the levels callers filter on
+export function toConsoleLevel(type: string): ConsoleLevel {
+  switch (type) {
+    case 'error':
+    case 'assert':
+      return 'error';
+    case 'warn':
+    case 'warning':
+      return 'warning';
+    case 'debug':
+    case 'trace':
+      return 'debug';
+    default:
+      return 'info';
+  }
+}
+
+// Uncaught errors carry their location in the stack rather than as separate fields. Takes
+// the first frame that has a URL, line and column.
+export function parseStackLocation(
+  stack: string | undefined
+): { url: string; lineNumber: number; columnNumber: number } | null {
+  const match = stack?.match(/((?:https?|file|blob|data|chrome-extension):[^\s)]+):(\d+):(\d+)/);
+  if (!match?.[1]) {
+    return null;
+  }
+  return { url: match[1], lineNumber: Number(match[2]), columnNumber: Number(match[3]) };
+}
+
+export class ConsoleBuffer {
+  private entries: ConsoleEntry[] = [];
+  private dropped = 0;
+  private capacity: number;
+
+  constructor(capacity = CONSOLE_BUFFER_SIZE) {
+    this.capacity = capacity;
+  }
+
+  push(entry: ConsoleEntry): void {
+    this.entries.push(entry);
+    if (this.entries.length > this.capacity) {
+      this.entries.shift();
+      this.dropped++;
+    }
+  }
+
+  // Entries at or above the given level, optionally for one page
+  list(filter: ConsoleLogsRequest = {}): ConsoleLogsResult {
+    const minimum = filter.level ? CONSOLE_LEVELS.indexOf(filter.level) : 0;
+    return {
+      entries: this.entries.filter(
+        entry =>
+          CONSOLE_LEVELS.indexOf(entry.level) >= minimum &&
+          (!filter.pageId || entry.pageId === filter.pageId)
+      ),
+      dropped: this.dropped
+    };
+  }
+
+  clear(): void {
+    this.entries = [];
+    this.dropped = 0;
+  }
+}
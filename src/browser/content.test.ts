@@ -0,0 +1,87 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import {
+  type ContentStats,
+  normalizeText,
+  pickMainContent,
+  truncateText,
+  validateTextRequest
+} from './content.js';
+
+// A blog post page: a menu, the article, and a comment section under the same body
+const stats: ContentStats = {
+  nodes: [
+    { tag: 'div', classAndId: 'site-nav ', textLength: 400, linkTextLength: 380 },
+    { tag: 'body', classAndId: ' ', textLength: 5000, linkTextLength: 600 },
+    { tag: 'article', classAndId: 'post ', textLength: 3800, linkTextLength: 100 },
+    { tag: 'div', classAndId: 'comments ', textLength: 800, linkTextLength: 50 }
+  ],
+  paragraphs: [
+    { length: 40, commas: 0, ancestors: [0, 1] },
+    { length: 900, commas: 6, ancestors: [2, 1] },
+    { length: 1200, commas: 9, ancestors: [2, 1] },
+    { length: 700, commas: 4, ancestors: [2, 1] },
+    { length: 300, commas: 3, ancestors: [3, 1] },
+    { length: 250, commas: 2, ancestors: [3, 1] }
+  ]
+};
+
+describe('Content helpers', () => {
+  describe('pickMainContent', () => {
+    it('should pick the article over menus and comments', () => {
+      expect(pickMainContent(stats)).toBe(2);
+    });
+
+    it('should discount link-heavy containers', () => {
+      const links: ContentStats = {
+        nodes: [
+          { tag: 'div', classAndId: 'list ', textLength: 1000, linkTextLength: 990 },
+          { tag: 'div', classAndId: ' ', textLength: 300, linkTextLength: 0 }
+        ],
+        paragraphs: [
+          { length: 1000, commas: 10, ancestors: [0] },
+          { length: 300, commas: 1, ancestors: [1] }
+        ]
+      };
+      expect(pickMainContent(links)).toBe(1);
+    });
+
+    it('should return null without prose', () => {
+      expect(pickMainContent({ nodes: [], paragraphs: [] })).toBeNull();
+    });
+  });
+
+  describe('normalizeText', () => {
+    it('should collapse whitespace but keep paragraph breaks', () => {
+      expect(normalizeText('  Title \n\n\n\n  First   para here.\t\n \nSecond \n')).toBe(
+        'Title\n\nFirst para here.\n\nSecond'
+      );
+    });
+  });
+
+  describe('truncateText', () => {
+    it('should cut at a word boundary near the limit', () => {
+      expect(truncateText('The quick brown fox jumps over', 18)).toEqual({
+        text: 'The quick brown',
+        truncated: true
+      });
+    });
+
+    it('should cut mid-word without a nearby boundary', () => {
+      expect(truncateText('abcdefghij', 4)).toEqual({ text: 'abcd', truncated: true });
+      expect(truncateText('short', 10)).toEqual({ text: 'short', truncated: false });
+    });
+  });
+
+  describe('validateTextRequest', () => {
+    it('should apply defaults', () => {
+      expect(validateTextRequest({})).toEqual({ mode: 'raw', maxLength: 50000 });
+    });
+
+    it('should reject unknown modes and bad limits', () => {
+      expect(() => validateTextRequest({ mode: 'markdown' as any })).toThrow(BrowserError);
+      expect(() => validateTextRequest({ maxLength: 0 })).toThrow(/maxLength/);
+      expect(() => validateTextRequest({ maxLength: Number.NaN })).toThrow(/maxLength/);
+    });
+  });
+});
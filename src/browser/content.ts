@@ -0,0 +1,221 @@
+import {
+  type GetTextRequest,
+  InvalidArgumentError,
+  TEXT_MODES,
+  type TextMode
+} from '../types/index.js';
+
+export const DEFAULT_MAX_TEXT_LENGTH = 50000;
+
+export interface ContentNodeStats {
+  tag: string;
+  classAndId: string;
+  textLength: number;
+  linkTextLength: number;
+}
+
+export interface ContentStats {
+  nodes: ContentNodeStats[];
+  // blocks of text with the indexes of their parent and grandparent in nodes
+  paragraphs: Array<{ length: number; commas: number; ancestors: number[] }>;
+}
+
+// Class and ID hints in the spirit of Mozilla Readability
+export const POSITIVE_HINTS = /article|body|content|entry|h-entry|main|page|post|text|blog|story/i;
+export const NEGATIVE_HINTS =
+  /banner|breadcrumb|combx|comment|community|cookie|disqus|extra|footer|gdpr|header|menu|modal|nav|newsletter|pager|pagination|popup|promo|related|remark|replies|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe|supplemental|widget|\bads?\b/i;
+
+const TAG_SCORES: Record<string, number> = {
+  article: 10,
+  main: 10,
+  section: 5,
+  div: 5,
+  pre: 3,
+  td: 3,
+  blockquote: 3,
+  address: -3,
+  dd: -3,
+  dl: -3,
+  dt: -3,
+  form: -3,
+  li: -3,
+  ol: -3,
+  ul: -3,
+  h1: -5,
+  h2: -5,
+  h3: -5,
+  h4: -5,
+  h5: -5,
+  h6: -5,
+  th: -5
+};
+
+export function validateTextRequest(request: GetTextRequest): {
+  mode: TextMode;
+  maxLength: number;
+} {
+  const mode = request.mode ?? 'raw';
+  if (!TEXT_MODES.includes(mode)) {
+    throw new InvalidArgumentError(`Text mode must be one of: ${TEXT_MODES.join(', ')}`);
+  }
+  const maxLength = request.maxLength ?? DEFAULT_MAX_TEXT_LENGTH;
+  if (!Number.isInteger(maxLength) || maxLength < 1) {
+    throw new InvalidArgumentError('maxLength must be a positive number of characters');
+  }
+  return { mode, maxLength };
+}
+
+// Scores the parents of every text block by how much prose they hold, the way Readability
+// does: commas and length count for the parent and half for the grandparent, class names
+// and tags nudge the score, and link-heavy containers such as menus are discounted.
+// Returns the index of the best node, or null when the page has no prose at all.
+export function pickMainContent(stats: ContentStats): number | null {
+  const scores = new Map<number, number>();
+  for (const { length, commas, ancestors } of stats.paragraphs) {
+    const score = 1 + commas + Math.min(Math.floor(length / 100), 3);
+    ancestors.forEach((index, level) => {
+      scores.set(index, (scores.get(index) ?? 0) + score / (level + 1));
+    });
+  }
+
+  let best: number | null = null;
+  let bestScore = -Infinity;
+  for (const [index, base] of scores) {
+    const node = stats.nodes[index];
+    if (!node) continue;
+    let score = base + (TAG_SCORES[node.tag] ?? 0);
+    if (POSITIVE_HINTS.test(node.classAndId)) score += 25;
+    if (NEGATIVE_HINTS.test(node.classAndId)) score -= 25;
+    const linkDensity = node.textLength ? node.linkTextLength / node.textLength : 0;
+    score *= 1 - linkDensity;
+    if (score > bestScore) {
+      best = index;
+      bestScore = score;
+    }
+  }
+  return best;
+}
+
+// Collapses the whitespace of rendered text while keeping paragraph breaks
+export function normalizeText(text: string): string {
+  return text
+    .replace(/[^\S\n]+/g, ' ')
+    .replace(/ ?\n ?/g, '\n')
+    .replace(/\n{3,}/g, '\n\n')
+    .trim();
+}
+
+// Cuts at a word boundary when there is one close to the limit
+export function truncateText(
+  text: string,
+  maxLength: number
+): { text: string; truncated: boolean } {
+  if (text.length <= maxLength) {
+    return { text, truncated: false };
+  }
+  const cut = text.slice(0, maxLength);
+  const boundary = cut.search(/\s\S*$/);
+  return {
+    text: (boundary > maxLength * 0.8 ? cut.slice(0, boundary) : cut).trimEnd(),
+    truncated: true
+  };
+}
+
+// The functions below run in the page
+
+export function readInnerText(el: any): string {
+  return String(el.innerText ?? el.textContent ?? '');
+}
+
+// Gathers the statistics pickMainContent scores, keeping the nodes themselves in the page so
+// the chosen one can be extracted without marking up the DOM
+export function collectContent(root: any): { nodes: any[]; stats: ContentStats } {
+  const nodes: any[] = [];
+  const indexes = new Map<any, number>();
+  const stats: ContentStats = { nodes: [], paragraphs: [] };
+  const textOf = (el: any) => String(el.textContent ?? '').replace(/\s+/g, ' ').trim();
+  const indexOf = (el: any): number => {
+    let index = indexes.get(el);
+    if (index === undefined) {
+      index = nodes.length;
+      nodes.push(el);
+      indexes.set(el, index);
+      stats.nodes.push({
+        tag: String(el.tagName).toLowerCase(),
+        classAndId: `${el.getAttribute('class') ?? ''} ${el.id ?? ''}`,
+        textLength: textOf(el).length,
+        linkTextLength: Array.from(el.querySelectorAll('a'), (a: any) => textOf(a).length).reduce(
+          (sum: number, length: number) => sum + length,
+          0
+        )
+      });
+    }
+    return index;
+  };
+
+  for (const block of root.querySelectorAll('p, pre, td, blockquote')) {
+    const text = textOf(block);
+    const parent = block.parentElement;
+    if (text.length < 25 || !parent || !root.contains(parent)) {
+      continue;
+    }
+    const ancestors = [indexOf(parent)];
+    const grandparent = parent.parentElement;
+    if (grandparent && root.contains(grandparent)) {
+      ancestors.push(indexOf(grandparent));
+    }
+    stats.paragraphs.push({ length: text.length, commas: text.split(',').length - 1, ancestors });
+  }
+  return { nodes, stats };
+}
+
+// Text of the element without navigation, forms, scripts and other boilerplate, with a blank
+// line between blocks. Works on a copy, so the page is left untouched.
+export function extractText(el: any, positiveHints: string, negativeHints: string): string {
+  const clone = el.cloneNode(true);
+  const boilerplate =
+    'script, style, noscript, template, svg, canvas, iframe, nav, aside, footer, form, ' +
+    'button, select, dialog, [hidden], [aria-hidden="true"], [role="navigation"], ' +
+    '[role="banner"], [role="contentinfo"], [role="complementary"]';
+  for (const node of clone.querySelectorAll(boilerplate)) {
+    node.remove();
+  }
+  const positive = new RegExp(positiveHints, 'i');
+  const negative = new RegExp(negativeHints, 'i');
+  for (const node of clone.querySelectorAll('[class], [id]')) {
+    const hints = `${node.getAttribute('class') ?? ''} ${node.id ?? ''}`;
+    if (negative.test(hints) && !positive.test(hints)) {
+      node.remove();
+    }
+  }
+
+  const blocks = new Set(
+    (
+      'address article blockquote dd div dl dt figcaption figure h1 h2 h3 h4 h5 h6 header hr ' +
+      'li main ol p pre section table tr ul'
+    ).split(' ')
+  );
+  const parts: string[] = [];
+  const walk = (node: any) => {
+    if (node.nodeType === 3) {
+      parts.push(String(node.textContent));
+      return;
+    }
+    if (node.nodeType !== 1) {
+      return;
+    }
+    const tag = String(node.tagName).toLowerCase();
+    if (tag === 'br') {
+      parts.push('\n');
+      return;
+    }
+    const block = blocks.has(tag);
+    if (block) parts.push('\n\n');
+    for (const child of node.childNodes) {
+      walk(child);
+    }
+    if (block) parts.push('\n\n');
+  };
+  walk(clone);
+  return parts.join('');
+}
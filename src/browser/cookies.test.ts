@@ -0,0 +1,198 @@
+import type { Cookie } from 'puppeteer-core';
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import {
+  cookieMatchesUrl,
+  formatCookies,
+  parseCookies,
+  parseNetscapeCookies,
+  resolveCookieFormat,
+  toNetscapeCookies,
+  validateCookie
+} from './cookies.js';
+
+function cookie(overrides: Partial<Cookie>): Cookie {
+  return {
+    name: 'sid',
+    value: '1',
+    domain: 'example.com',
+    path: '/',
+    expires: -1,
+    size: 4,
+    httpOnly: false,
+    secure: false,
+    session: true,
+    ...overrides
+  };
+}
+
+describe('Cookie helpers', () => {
+  describe('validateCookie', () => {
+    it('should default the path and keep given attributes', () => {
+      expect(
+        validateCookie({ name: 'sid', value: 'abc', domain: '.example.com', httpOnly: true })
+      ).toEqual({ name: 'sid', value: 'abc', domain: '.example.com', path: '/', httpOnly: true });
+    });
+
+    it('should derive domain and secure from a url', () => {
+      const result = validateCookie({ name: 'sid', value: 'abc', url: 'https://app.example.com/' });
+      expect(result.domain).toBe('app.example.com');
+      expect(result.secure).toBe(true);
+    });
+
+    it('should normalize sameSite and require secure for None', () => {
+      const base = { name: 'sid', value: 'abc', domain: 'example.com' };
+      expect(validateCookie({ ...base, sameSite: 'lax' }).sameSite).toBe('Lax');
+      expect(() => validateCookie({ ...base, sameSite: 'None' })).toThrow(BrowserError);
+      expect(() => validateCookie({ ...base, sameSite: 'Sometimes' })).toThrow(BrowserError);
+    });
+
+    it('should enforce cookie prefix rules', () => {
+      expect(() =>
+        validateCookie({ name: '__Secure-id', value: '1', domain: 'example.com' })
+      ).toThrow('__Secure-');
+      expect(() =>
+        validateCookie({ name: '__Host-id', value: '1', domain: '.example.com', secure: true })
+      ).toThrow('__Host-');
+      const scoped = { name: '__Host-id', value: '1', domain: 'example.com', path: '/app' };
+      expect(() => validateCookie({ ...scoped, secure: true })).toThrow('__Host-');
+      expect(
+        validateCookie({ name: '__Host-id', value: '1', domain: 'example.com', secure: true }).name
+      ).toBe('__Host-id');
+    });
+
+    it('should reject malformed names and values', () => {
+      expect(() => validateCookie({ name: 'a b', value: '1', domain: 'example.com' })).toThrow();
+      expect(() => validateCookie({ name: 'a', value: '1;2', domain: 'example.com' })).toThrow();
+      expect(() => validateCookie({ name: 'a', value: '1' })).toThrow('domain or url');
+    });
+  });
+
+  describe('cookieMatchesUrl', () => {
+    it('should match host-only cookies exactly', () => {
+      expect(cookieMatchesUrl(cookie({}), new URL('http://example.com/'))).toBe(true);
+      expect(cookieMatchesUrl(cookie({}), new URL('http://www.example.com/'))).toBe(false);
+    });
+
+    it('should match domain cookies on subdomains', () => {
+      const domainCookie = cookie({ domain: '.example.com' });
+      expect(cookieMatchesUrl(domainCookie, new URL('http://www.example.com/'))).toBe(true);
+      expect(cookieMatchesUrl(domainCookie, new URL('http://badexample.com/'))).toBe(false);
+    });
+
+    it('should respect path and secure', () => {
+      const scoped = cookie({ path: '/app', secure: true });
+      expect(cookieMatchesUrl(scoped, new URL('https://example.com/app/page'))).toBe(true);
+      expect(cookieMatchesUrl(scoped, new URL('https://example.com/application'))).toBe(false);
+      expect(cookieMatchesUrl(scoped, new URL('http://example.com/app'))).toBe(false);
+    });
+  });
+
+  describe('toNetscapeCookies', () => {
+    it('should write a cookies.txt line per cookie', () => {
+      const text = toNetscapeCookies([
+        cookie({ domain: '.example.com', secure: true, expires: 1893456000.5, session: false }),
+        cookie({ name: 'token', value: 'abc', path: '/app', httpOnly: true })
+      ]);
+
+      expect(text).toBe(
+        '# Netscape HTTP Cookie File\n' +
+          '.example.com\tTRUE\t/\tTRUE\t1893456000\tsid\t1\n' +
+          '#HttpOnly_example.com\tFALSE\t/app\tFALSE\t0\ttoken\tabc\n'
+      );
+    });
+  });
+
+  describe('parseNetscapeCookies', () => {
+    it('should read what curl writes, httpOnly lines included', () => {
+      const cookies = parseNetscapeCookies(
+        '# Netscape HTTP Cookie File\r\n' +
+          '# https://curl.se/docs/http-cookies.html\r\n' +
+          '\r\n' +
+          'example.com\tTRUE\t/\tTRUE\t1893456000\tsid\t1\r\n' +
+          '#HttpOnly_app.example.com\tFALSE\t/app\tFALSE\t0\ttoken\r\n'
+      );
+
+      expect(cookies).toEqual([
+        {
+          name: 'sid',
+          value: '1',
+          domain: '.example.com',
+          path: '/',
+          secure: true,
+          httpOnly: false,
+          expires: 1893456000
+        },
+        {
+          name: 'token',
+          value: '',
+          domain: 'app.example.com',
+          path: '/app',
+          secure: false,
+          httpOnly: true
+        }
+      ]);
+    });
+
+    it('should name the line of a malformed entry', () => {
+      expect(() => parseNetscapeCookies('example.com TRUE / TRUE 0 sid 1')).toThrow(
+        'Line 1 of the cookies file must have 7 tab-separated fields'
+      );
+      expect(() => parseNetscapeCookies('\nexample.com\tyes\t/\tTRUE\t0\tsid\t1')).toThrow(
+        'Line 2 of the cookies file: include subdomains must be TRUE or FALSE'
+      );
+      expect(() => parseNetscapeCookies('example.com\tTRUE\t/\tTRUE\tsoon\tsid\t1')).toThrow(
+        'Line 1 of the cookies file: expiry must be a Unix timestamp in seconds'
+      );
+    });
+
+    it('should read back what toNetscapeCookies writes', () => {
+      const original = [
+        cookie({ domain: '.example.com', expires: 1893456000, session: false, httpOnly: true })
+      ];
+
+      const [parsed] = parseNetscapeCookies(toNetscapeCookies(original));
+
+      expect(validateCookie(parsed!)).toEqual({
+        name: 'sid',
+        value: '1',
+        domain: '.example.com',
+        path: '/',
+        secure: false,
+        httpOnly: true,
+        expires: 1893456000
+      });
+    });
+  });
+
+  describe('formatCookies and parseCookies', () => {
+    it('should round-trip cookies as JSON without the expiry of session cookies', () => {
+      const text = formatCookies([cookie({ sameSite: 'Strict' })], 'json');
+
+      expect(parseCookies(text, 'json')).toEqual([
+        {
+          name: 'sid',
+          value: '1',
+          domain: 'example.com',
+          path: '/',
+          httpOnly: false,
+          secure: false,
+          sameSite: 'Strict'
+        }
+      ]);
+    });
+
+    it('should reject JSON that is no list of cookies', () => {
+      expect(() => parseCookies('{"cookies": []}', 'json')).toThrow(
+        'Invalid cookies JSON: expected a list of cookies'
+      );
+      expect(() => parseCookies('sid=1', 'json')).toThrow(BrowserError);
+    });
+
+    it('should only accept the known formats', () => {
+      expect(resolveCookieFormat(undefined)).toBe('json');
+      expect(resolveCookieFormat('netscape')).toBe('netscape');
+      expect(() => resolveCookieFormat('har')).toThrow('format must be one of: json, netscape');
+    });
+  });
+});
@@ -0,0 +1,213 @@
+import type { Cookie, CookieData, CookieSameSite } from 'puppeteer-core';
+import {
+  BrowserError,
+  COOKIE_FORMATS,
+  type CookieFormat,
+  type CookieInput,
+  InvalidArgumentError,
+  type StorageStateCookie
+} from '../types/index.js';
+import { fromStateCookie, toStateCookie } from './state.js';
+
+const SAME_SITE_VALUES: CookieSameSite[] = ['Strict', 'Lax', 'None'];
+
+// RFC 6265 token separators; values may not contain the ; Chrome would split on
+const NAME_SEPARATORS = /[\s()<>@,;:\\"/[\]?={}]/;
+
+function hasControlCharacters(text: string): boolean {
+  return Array.from(text).some(char => {
+    const code = char.charCodeAt(0);
+    return code < 0x20 || code === 0x7f;
+  });
+}
+
+// Checks a cookie the way Chrome would before storing it, so a bad cookie fails loudly
+// instead of being dropped silently
+export function validateCookie(input: CookieInput): CookieData {
+  const label = input.name ? `Cookie ${input.name}` : 'Cookie';
+  if (!input.name || NAME_SEPARATORS.test(input.name) || hasControlCharacters(input.name)) {
+    throw new BrowserError(`${label} has an invalid name`);
+  }
+  if (
+    typeof input.value !== 'string' ||
+    input.value.includes(';') ||
+    hasControlCharacters(input.value)
+  ) {
+    throw new BrowserError(`${label} has an invalid value`);
+  }
+
+  let domain = input.domain;
+  let secure = input.secure;
+  if (!domain && input.url) {
+    try {
+      const url = new URL(input.url);
+      domain = url.hostname;
+      secure ??= url.protocol === 'https:';
+    } catch {
+      throw new BrowserError(`${label} has an invalid url ${input.url}`);
+    }
+  }
+  if (!domain) {
+    throw new BrowserError(`${label} needs a domain or url`);
+  }
+
+  const path = input.path ?? '/';
+  if (!path.startsWith('/')) {
+    throw new BrowserError(`${label} path must start with /`);
+  }
+
+  let sameSite: CookieSameSite | undefined;
+  if (input.sameSite !== undefined) {
+    const requested = input.sameSite.toLowerCase();
+    sameSite = SAME_SITE_VALUES.find(value => value.toLowerCase() === requested);
+    if (!sameSite) {
+      throw new InvalidArgumentError(
+        `${label} sameSite must be one of: ${SAME_SITE_VALUES.join(', ')}`
+      );
+    }
+    if (sameSite === 'None' && !secure) {
+      throw new InvalidArgumentError(`${label} with sameSite None must be secure`);
+    }
+  }
+
+  if (input.name.startsWith('__Secure-') && !secure) {
+    throw new InvalidArgumentError(`${label} uses the __Secure- prefix and must be secure`);
+  }
+  if (input.name.startsWith('__Host-')) {
+    if (!secure || path !== '/' || domain.startsWith('.')) {
+      throw new InvalidArgumentError(
+        `${label} uses the __Host- prefix and must be secure, host-only and have path /`
+      );
+    }
+  }
+
+  if (input.expires !== undefined && !Number.isFinite(input.expires)) {
+    throw new InvalidArgumentError(`${label} expires must be a Unix timestamp in seconds`);
+  }
+
+  const cookie: CookieData = { name: input.name, value: input.value, domain, path };
+  if (secure !== undefined) cookie.secure = secure;
+  if (input.httpOnly !== undefined) cookie.httpOnly = input.httpOnly;
+  if (sameSite) cookie.sameSite = sameSite;
+  if (input.expires !== undefined) cookie.expires = input.expires;
+  return cookie;
+}
+
+// Whether a browser would send the cookie with a request to the URL
+export function cookieMatchesUrl(cookie: Cookie, url: URL): boolean {
+  const domain = cookie.domain.replace(/^\./, '');
+  const host = url.hostname;
+  const domainMatches = cookie.domain.startsWith('.')
+    ? host === domain || host.endsWith(`.${domain}`)
+    : host === domain;
+  const pathMatches =
+    url.pathname === cookie.path ||
+    url.pathname.startsWith(cookie.path.endsWith('/') ? cookie.path : `${cookie.path}/`);
+  return domainMatches && pathMatches && (!cookie.secure || url.protocol === 'https:');
+}
+
+export function resolveCookieFormat(format: string | undefined): CookieFormat {
+  const resolved = (format ?? 'json') as CookieFormat;
+  if (!COOKIE_FORMATS.includes(resolved)) {
+    throw new InvalidArgumentError(`format must be one of: ${COOKIE_FORMATS.join(', ')}`);
+  }
+  return resolved;
+}
+
+export function formatCookies(cookies: Cookie[], format: CookieFormat): string {
+  if (format === 'netscape') {
+    return toNetscapeCookies(cookies);
+  }
+  return `${JSON.stringify(cookies.map(cookie => toStateCookie(cookie)), null, 2)}\n`;
+}
+
+export function parseCookies(text: string, format: CookieFormat): CookieInput[] {
+  if (format === 'netscape') {
+    return parseNetscapeCookies(text);
+  }
+  let cookies: unknown;
+  try {
+    cookies = JSON.parse(text);
+  } catch (error) {
+    throw new InvalidArgumentError(`Invalid cookies JSON: ${error}`);
+  }
+  if (!Array.isArray(cookies)) {
+    throw new InvalidArgumentError('Invalid cookies JSON: expected a list of cookies');
+  }
+  return cookies.map((cookie: StorageStateCookie) =>
+    fromStateCookie({ ...cookie, expires: cookie.expires ?? -1 })
+  );
+}
+
+const NETSCAPE_HEADER = '# Netscape HTTP Cookie File';
+// curl's convention for httpOnly cookies, readers that don't know it skip them as comments
+const HTTP_ONLY_PREFIX = '#HttpOnly_';
+
+// One line per cookie with tab-separated domain, whether subdomains match, path, secure,
+// expiry in Unix seconds (0 for session cookies), name and value. SameSite has no field.
+export function toNetscapeCookies(cookies: Cookie[]): string {
+  const lines = cookies.map(cookie => {
+    const domain = `${cookie.httpOnly ? HTTP_ONLY_PREFIX : ''}${cookie.domain}`;
+    const expires = cookie.session || cookie.expires < 0 ? 0 : Math.floor(cookie.expires);
+    return [
+      domain,
+      cookie.domain.startsWith('.') ? 'TRUE' : 'FALSE',
+      cookie.path,
+      cookie.secure ? 'TRUE' : 'FALSE',
+      expires,
+      cookie.name,
+      cookie.value
+    ].join('\t');
+  });
+  return `${[NETSCAPE_HEADER, ...lines].join('\n')}\n`;
+}
+
+function parseFlag(value: string, field: string, line: number): boolean {
+  const upper = value.toUpperCase();
+  if (upper !== 'TRUE' && upper !== 'FALSE') {
+    throw new InvalidArgumentError(
+      `Line ${line} of the cookies file: ${field} must be TRUE or FALSE`
+    );
+  }
+  return upper === 'TRUE';
+}
+
+// Reads what curl, wget, yt-dlp and browser extensions write. Blank lines and comments are
+// skipped, a missing value is an empty one.
+export function parseNetscapeCookies(text: string): CookieInput[] {
+  const cookies: CookieInput[] = [];
+  text.split(/\r?\n/).forEach((raw, index) => {
+    const line = index + 1;
+    const httpOnly = raw.startsWith(HTTP_ONLY_PREFIX);
+    const entry = httpOnly ? raw.slice(HTTP_ONLY_PREFIX.length) : raw;
+    if (!entry.trim() || (!httpOnly && entry.startsWith('#'))) {
+      return;
+    }
+    const fields = entry.split('\t');
+    if (fields.length !== 6 && fields.length !== 7) {
+      throw new InvalidArgumentError(
+        `Line ${line} of the cookies file must have 7 tab-separated fields`
+      );
+    }
+    const [domain = '', subdomains = '', path = '', secure = '', expires = '', name = ''] = fields;
+    const includeSubdomains = parseFlag(subdomains, 'include subdomains', line);
+    const expiry = Number(expires);
+    if (!/^\d+$/.test(expires.trim()) || !Number.isSafeInteger(expiry)) {
+      throw new InvalidArgumentError(
+        `Line ${line} of the cookies file: expiry must be a Unix timestamp in seconds`
+      );
+    }
+    const host = domain.replace(/^\./, '');
+    const cookie: CookieInput = {
+      name,
+      value: fields[6] ?? '',
+      domain: includeSubdomains ? `.${host}` : host,
+      path,
+      secure: parseFlag(secure, 'secure', line),
+      httpOnly
+    };
+    if (expiry > 0) cookie.expires = expiry;
+    cookies.push(cookie);
+  });
+  return cookies;
+}
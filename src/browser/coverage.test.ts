@@ -0,0 +1,92 @@
+import { describe, expect, it } from 'vitest';
+import { invertRanges, mergeRanges, summarizeCoverage } from './coverage.js';
+
+describe('Coverage helpers', () => {
+  describe('mergeRanges', () => {
+    it('should merge overlapping and touching ranges and clamp them', () => {
+      expect(
+        mergeRanges(
+          [
+            { start: 10, end: 20 },
+            { start: 0, end: 5 },
+            { start: 5, end: 8 },
+            { start: 15, end: 30 },
+            { start: 95, end: 120 }
+          ],
+          100
+        )
+      ).toEqual([
+        { start: 0, end: 8 },
+        { start: 10, end: 30 },
+        { start: 95, end: 100 }
+      ]);
+    });
+  });
+
+  describe('invertRanges', () => {
+    it('should return the gaps between used ranges', () => {
+      expect(
+        invertRanges(
+          [
+            { start: 0, end: 5 },
+            { start: 10, end: 20 }
+          ],
+          30
+        )
+      ).toEqual([
+        { start: 5, end: 10 },
+        { start: 20, end: 30 }
+      ]);
+    });
+  });
+
+  describe('summarizeCoverage', () => {
+    const js = [
+      {
+        url: 'https://example.com/vendor.js',
+        text: 'x'.repeat(100),
+        ranges: [{ start: 0, end: 25 }]
+      },
+      { url: 'https://example.com/app.js', text: 'x'.repeat(10), ranges: [{ start: 0, end: 10 }] }
+    ];
+    const css = [
+      {
+        url: 'https://example.com/site.css',
+        text: 'y'.repeat(40),
+        ranges: [{ start: 10, end: 20 }]
+      }
+    ];
+
+    it('should total each type and sort files by unused bytes', () => {
+      const result = summarizeCoverage(js, css);
+      expect(result.js).toEqual({ totalBytes: 110, usedBytes: 35, percentage: 31.82 });
+      expect(result.css).toEqual({ totalBytes: 40, usedBytes: 10, percentage: 25 });
+      expect(result.files.map(file => file.url)).toEqual([
+        'https://example.com/vendor.js',
+        'https://example.com/site.css',
+        'https://example.com/app.js'
+      ]);
+      expect(result.files[0]).not.toHaveProperty('usedRanges');
+    });
+
+    it('should include ranges when detailed', () => {
+      const [file] = summarizeCoverage(null, css, true).files;
+      expect(file).toMatchObject({
+        type: 'css',
+        usedRanges: [{ start: 10, end: 20 }],
+        unusedRanges: [
+          { start: 0, end: 10 },
+          { start: 20, end: 40 }
+        ]
+      });
+    });
+
+    it('should report types that were not collected as null', () => {
+      expect(summarizeCoverage(null, [])).toEqual({
+        js: null,
+        css: { totalBytes: 0, usedBytes: 0, percentage: 100 },
+        files: []
+      });
+    });
+  });
+});
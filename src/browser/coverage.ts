@@ -0,0 +1,86 @@
+import type {
+  CoverageFile,
+  CoverageRange,
+  CoverageResult,
+  CoverageSummary
+} from '../types/index.js';
+
+export interface CoverageInput {
+  url: string;
+  text: string;
+  ranges: CoverageRange[];
+}
+
+// Sorts and merges overlapping or touching ranges, clamped to the file
+export function mergeRanges(ranges: CoverageRange[], length: number): CoverageRange[] {
+  const sorted = ranges
+    .map(({ start, end }) => ({ start: Math.max(0, start), end: Math.min(length, end) }))
+    .filter(range => range.end > range.start)
+    .sort((a, b) => a.start - b.start);
+  const merged: CoverageRange[] = [];
+  for (const range of sorted) {
+    const last = merged[merged.length - 1];
+    if (last && range.start <= last.end) {
+      last.end = Math.max(last.end, range.end);
+    } else {
+      merged.push({ ...range });
+    }
+  }
+  return merged;
+}
+
+// The gaps between used ranges
+export function invertRanges(used: CoverageRange[], length: number): CoverageRange[] {
+  const unused: CoverageRange[] = [];
+  let position = 0;
+  for (const range of used) {
+    if (range.start > position) {
+      unused.push({ start: position, end: range.start });
+    }
+    position = range.end;
+  }
+  if (position < length) {
+    unused.push({ start: position, end: length });
+  }
+  return unused;
+}
+
+function summarize(totalBytes: number, usedBytes: number): CoverageSummary {
+  const percentage = totalBytes ? Math.round((usedBytes / totalBytes) * 10000) / 100 : 100;
+  return { totalBytes, usedBytes, percentage };
+}
+
+// Sizes count characters of the source, as Chrome reports the ranges in them
+export function summarizeCoverage(
+  js: CoverageInput[] | null,
+  css: CoverageInput[] | null,
+  detailed = false
+): CoverageResult {
+  const files: CoverageFile[] = [];
+  const collect = (entries: CoverageInput[], type: 'js' | 'css'): CoverageSummary => {
+    let total = 0;
+    let used = 0;
+    for (const entry of entries) {
+      const length = entry.text.length;
+      const usedRanges = mergeRanges(entry.ranges, length);
+      const usedBytes = usedRanges.reduce((sum, range) => sum + range.end - range.start, 0);
+      const file: CoverageFile = { url: entry.url, type, ...summarize(length, usedBytes) };
+      if (detailed) {
+        file.usedRanges = usedRanges;
+        file.unusedRanges = invertRanges(usedRanges, length);
+      }
+      files.push(file);
+      total += length;
+      used += usedBytes;
+    }
+    return summarize(total, used);
+  };
+
+  const result: CoverageResult = {
+    js: js ? collect(js, 'js') : null,
+    css: css ? collect(css, 'css') : null,
+    files
+  };
+  files.sort((a, b) => b.totalBytes - b.usedBytes - (a.totalBytes - a.usedBytes));
+  return result;
+}
@@ -0,0 +1,133 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import {
+  DEVICES,
+  findDevice,
+  parseDefaultViewport,
+  resolveDevice,
+  resolveViewport
+} from './devices.js';
+
+describe('Device helpers', () => {
+  describe('DEVICES', () => {
+    it('should describe every device in portrait', () => {
+      for (const [name, device] of Object.entries(DEVICES)) {
+        expect(device.height, name).toBeGreaterThan(device.width);
+        expect(device.userAgent, name).toMatch(/^Mozilla\/5\.0 /);
+      }
+    });
+  });
+
+  describe('findDevice', () => {
+    it('should match names case-insensitively', () => {
+      expect(findDevice(' iphone 14 ')?.[0]).toBe('iPhone 14');
+      expect(findDevice('Nokia 3310')).toBeNull();
+    });
+  });
+
+  describe('resolveDevice', () => {
+    it('should apply a preset', () => {
+      expect(resolveDevice({ device: 'Pixel 7' })).toEqual({
+        name: 'Pixel 7',
+        userAgent: DEVICES['Pixel 7']?.userAgent,
+        viewport: {
+          width: 412,
+          height: 839,
+          deviceScaleFactor: 2.625,
+          isMobile: true,
+          hasTouch: true,
+          isLandscape: false
+        }
+      });
+    });
+
+    it('should swap dimensions in landscape', () => {
+      const { viewport } = resolveDevice({ device: 'iPhone 14', landscape: true });
+      expect(viewport).toMatchObject({ width: 663, height: 390, isLandscape: true });
+    });
+
+    it('should let explicit fields override the preset', () => {
+      const device = resolveDevice({ device: 'iPad', hasTouch: false, userAgent: 'Custom' });
+      expect(device.viewport.hasTouch).toBe(false);
+      expect(device.userAgent).toBe('Custom');
+    });
+
+    it('should build a custom device from explicit fields', () => {
+      expect(resolveDevice({ width: 1280, height: 800 })).toEqual({
+        name: null,
+        userAgent: null,
+        viewport: {
+          width: 1280,
+          height: 800,
+          deviceScaleFactor: 1,
+          isMobile: false,
+          hasTouch: false,
+          isLandscape: false
+        }
+      });
+    });
+
+    it('should reject unknown devices and missing dimensions', () => {
+      expect(() => resolveDevice({ device: 'Nokia 3310' })).toThrow(BrowserError);
+      expect(() => resolveDevice({ width: 1280 })).toThrow('width and height');
+      expect(() => resolveDevice({ width: 1280, height: 800, deviceScaleFactor: 0 })).toThrow(
+        'deviceScaleFactor'
+      );
+    });
+  });
+
+  describe('resolveViewport', () => {
+    const phone = resolveDevice({ device: 'Pixel 7', landscape: true }).viewport;
+
+    it('should keep what the request leaves out', () => {
+      expect(resolveViewport({ width: 1280, height: 720 }, null)).toEqual({
+        width: 1280,
+        height: 720,
+        deviceScaleFactor: 1,
+        isMobile: false,
+        hasTouch: false,
+        isLandscape: false
+      });
+      expect(resolveViewport({ width: 800, height: 600, isMobile: false }, phone)).toMatchObject({
+        deviceScaleFactor: 2.625,
+        isMobile: false,
+        hasTouch: true,
+        isLandscape: true
+      });
+    });
+
+    it('should reject sizes Chrome cannot render', () => {
+      expect(() => resolveViewport({ width: 0, height: 600 }, null)).toThrow(BrowserError);
+      expect(() => resolveViewport({ width: 800.5, height: 600 }, null)).toThrow(/width/);
+      expect(() => resolveViewport({ width: 800, height: 20000 }, null)).toThrow(/height/);
+      expect(() =>
+        resolveViewport({ width: 8000, height: 600, deviceScaleFactor: 3 }, null)
+      ).toThrow(/device pixels/);
+      const unscaled = { width: 800, height: 600, deviceScaleFactor: 0 };
+      expect(() => resolveViewport(unscaled, null)).toThrow(/deviceScaleFactor/);
+    });
+  });
+
+  describe('parseDefaultViewport', () => {
+    it('should read the size and optional scale factor', () => {
+      expect(parseDefaultViewport('1920x1080')).toEqual({
+        width: 1920,
+        height: 1080,
+        deviceScaleFactor: 1,
+        isMobile: false,
+        hasTouch: false,
+        isLandscape: false
+      });
+      expect(parseDefaultViewport('1280x720@2')).toMatchObject({ deviceScaleFactor: 2 });
+      expect(parseDefaultViewport(' 390x844@1.5 ')).toMatchObject({ deviceScaleFactor: 1.5 });
+    });
+
+    it('should fail on malformed values', () => {
+      expect(() => parseDefaultViewport('1920*1080')).toThrow(/WIDTHxHEIGHT/);
+      expect(() => parseDefaultViewport('1920x')).toThrow(BrowserError);
+      expect(() => parseDefaultViewport('1920x1080@')).toThrow(/WIDTHxHEIGHT/);
+      expect(() => parseDefaultViewport('0x1080')).toThrow(/Invalid PCS_DEFAULT_VIEWPORT 0x1080/);
+      expect(() => parseDefaultViewport('16000x1080@2')).toThrow(/device pixels/);
+    });
+  });
+});
@@ -0,0 +1,235 @@
+import {
+  BrowserError,
+  type EmulateDeviceRequest,
+  type EmulatedDevice,
+  InvalidArgumentError,
+  type SetViewportRequest,
+  type Viewport
+} from '../types/index.js';
+
+export interface DeviceProfile {
+  userAgent: string;
+  width: number; // CSS pixels in portrait orientation
+  height: number;
+  deviceScaleFactor: number;
+  isMobile: boolean;
+  hasTouch: boolean;
+}
+
+const IOS_16_SAFARI =
+  'Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1';
+const IOS_17_SAFARI =
+  'Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1';
+const IPADOS_SAFARI =
+  'Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1';
+
+// Device descriptors in the shape of Puppeteer's KnownDevices, with the viewport the browser
+// leaves for the page. Landscape variants are derived by swapping width and height, so add
+// new devices here in portrait.
+export const DEVICES: Record<string, DeviceProfile> = {
+  'iPhone SE': {
+    userAgent: IOS_16_SAFARI,
+    width: 375,
+    height: 667,
+    deviceScaleFactor: 2,
+    isMobile: true,
+    hasTouch: true
+  },
+  'iPhone 14': {
+    userAgent: IOS_16_SAFARI,
+    width: 390,
+    height: 663,
+    deviceScaleFactor: 3,
+    isMobile: true,
+    hasTouch: true
+  },
+  'iPhone 14 Pro Max': {
+    userAgent: IOS_16_SAFARI,
+    width: 430,
+    height: 739,
+    deviceScaleFactor: 3,
+    isMobile: true,
+    hasTouch: true
+  },
+  'iPhone 15': {
+    userAgent: IOS_17_SAFARI,
+    width: 393,
+    height: 659,
+    deviceScaleFactor: 3,
+    isMobile: true,
+    hasTouch: true
+  },
+  'iPhone 15 Pro': {
+    userAgent: IOS_17_SAFARI,
+    width: 393,
+    height: 659,
+    deviceScaleFactor: 3,
+    isMobile: true,
+    hasTouch: true
+  },
+  'Pixel 5': {
+    userAgent:
+      'Mozilla/5.0 (Linux; Android 11; Pixel 5) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/99.0.4812.0 Mobile Safari/537.36',
+    width: 393,
+    height: 851,
+    deviceScaleFactor: 3,
+    isMobile: true,
+    hasTouch: true
+  },
+  'Pixel 7': {
+    userAgent:
+      'Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36',
+    width: 412,
+    height: 839,
+    deviceScaleFactor: 2.625,
+    isMobile: true,
+    hasTouch: true
+  },
+  'Galaxy S9+': {
+    userAgent:
+      'Mozilla/5.0 (Linux; Android 8.0.0; SM-G965U Build/R16NW) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/63.0.3239.111 Mobile Safari/537.36',
+    width: 320,
+    height: 658,
+    deviceScaleFactor: 4.5,
+    isMobile: true,
+    hasTouch: true
+  },
+  'Galaxy Tab S4': {
+    userAgent:
+      'Mozilla/5.0 (Linux; Android 8.1.0; SM-T837A) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.80 Safari/537.36',
+    width: 712,
+    height: 1138,
+    deviceScaleFactor: 2.25,
+    isMobile: true,
+    hasTouch: true
+  },
+  iPad: {
+    userAgent: IPADOS_SAFARI,
+    width: 810,
+    height: 1080,
+    deviceScaleFactor: 2,
+    isMobile: true,
+    hasTouch: true
+  },
+  'iPad Mini': {
+    userAgent: IPADOS_SAFARI,
+    width: 768,
+    height: 1024,
+    deviceScaleFactor: 2,
+    isMobile: true,
+    hasTouch: true
+  },
+  'iPad Pro 11': {
+    userAgent: IPADOS_SAFARI,
+    width: 834,
+    height: 1194,
+    deviceScaleFactor: 2,
+    isMobile: true,
+    hasTouch: true
+  }
+};
+
+export const DEVICE_NAMES = Object.keys(DEVICES);
+
+// Names are matched case-insensitively so "iphone 14" finds "iPhone 14"
+export function findDevice(name: string): [string, DeviceProfile] | null {
+  const wanted = name.trim().toLowerCase();
+  const match = DEVICE_NAMES.find(deviceName => deviceName.toLowerCase() === wanted);
+  return match ? [match, DEVICES[match] as DeviceProfile] : null;
+}
+
+// Combines the named preset, if any, with explicitly given fields, which take precedence.
+// Without a preset width and height are required.
+export function resolveDevice(request: EmulateDeviceRequest): EmulatedDevice {
+  let name: string | null = null;
+  let preset: Partial<DeviceProfile> = {};
+  if (request.device) {
+    const found = findDevice(request.device);
+    if (!found) {
+      throw new BrowserError(
+        `Unknown device ${request.device}, known devices: ${DEVICE_NAMES.join(', ')}`
+      );
+    }
+    [name, preset] = found;
+  }
+
+  const width = request.width ?? preset.width;
+  const height = request.height ?? preset.height;
+  if (!width || !height || width < 1 || height < 1) {
+    throw new BrowserError('A device name or a positive width and height are required');
+  }
+  const deviceScaleFactor = request.deviceScaleFactor ?? preset.deviceScaleFactor ?? 1;
+  if (!(deviceScaleFactor > 0)) {
+    throw new InvalidArgumentError('deviceScaleFactor must be greater than 0');
+  }
+
+  const landscape = request.landscape ?? false;
+  const viewport: EmulatedDevice['viewport'] = {
+    width: Math.round(landscape ? Math.max(width, height) : width),
+    height: Math.round(landscape ? Math.min(width, height) : height),
+    deviceScaleFactor,
+    isMobile: request.isMobile ?? preset.isMobile ?? false,
+    hasTouch: request.hasTouch ?? preset.hasTouch ?? false,
+    isLandscape: landscape
+  };
+  return { name, userAgent: request.userAgent ?? preset.userAgent ?? null, viewport };
+}
+
+// Chrome cannot paint a surface larger than its maximum texture size in either direction,
+// screenshots of a bigger viewport come back blank or cut off
+export const MAX_VIEWPORT_SIZE = 16384;
+
+export function resolveViewport(request: SetViewportRequest, current: Viewport | null): Viewport {
+  for (const key of ['width', 'height'] as const) {
+    const value = request[key];
+    if (!Number.isInteger(value) || value < 1 || value > MAX_VIEWPORT_SIZE) {
+      throw new InvalidArgumentError(
+        `Viewport ${key} must be an integer from 1 to ${MAX_VIEWPORT_SIZE}`
+      );
+    }
+  }
+  const deviceScaleFactor = request.deviceScaleFactor ?? current?.deviceScaleFactor ?? 1;
+  if (!(deviceScaleFactor > 0) || deviceScaleFactor > 10) {
+    throw new InvalidArgumentError('deviceScaleFactor must be greater than 0 and at most 10');
+  }
+  const scaled = Math.max(request.width, request.height) * deviceScaleFactor;
+  if (scaled > MAX_VIEWPORT_SIZE) {
+    throw new InvalidArgumentError(
+      `Viewport must be at most ${MAX_VIEWPORT_SIZE} device pixels across, ` +
+        `this one is ${Math.round(scaled)} with deviceScaleFactor ${deviceScaleFactor}`
+    );
+  }
+  return {
+    width: request.width,
+    height: request.height,
+    deviceScaleFactor,
+    isMobile: request.isMobile ?? current?.isMobile ?? false,
+    hasTouch: current?.hasTouch ?? false,
+    isLandscape: current?.isLandscape ?? false
+  };
+}
+
+const VIEWPORT_SIZE = /^(\d+)x(\d+)(?:@(\d+(?:\.\d+)?))?$/;
+
+// The viewport pages start with, from PCS_DEFAULT_VIEWPORT: WIDTHxHEIGHT with an optional
+// @SCALE device scale factor, e.g. 1920x1080@2. Checked at startup so a typo fails there.
+export function parseDefaultViewport(value: string): Viewport {
+  const match = VIEWPORT_SIZE.exec(value.trim());
+  if (!match) {
+    throw new InvalidArgumentError(
+      'PCS_DEFAULT_VIEWPORT must be WIDTHxHEIGHT or WIDTHxHEIGHT@SCALE, ' +
+        `e.g. 1920x1080@2, got ${value}`
+    );
+  }
+  const [, width, height, scale] = match;
+  try {
+    return resolveViewport(
+      { width: Number(width), height: Number(height), deviceScaleFactor: Number(scale ?? 1) },
+      null
+    );
+  } catch (error) {
+    throw new InvalidArgumentError(
+      `Invalid PCS_DEFAULT_VIEWPORT ${value}: ${(error as Error).message}`
+    );
+  }
+}
@@ -0,0 +1,54 @@
+import { describe, expect, it } from 'vitest';
+import type { DialogEntry } from '../types/index.js';
+import {
+  DEFAULT_DIALOG_HANDLER,
+  DIALOG_BUFFER_SIZE,
+  pushDialog,
+  resolveDialogResponse
+} from './dialogs.js';
+
+describe('Dialog helpers', () => {
+  describe('resolveDialogResponse', () => {
+    it('should dismiss by default', () => {
+      expect(resolveDialogResponse(DEFAULT_DIALOG_HANDLER, 'confirm', '')).toEqual({
+        accept: false
+      });
+    });
+
+    it('should accept without text unless the dialog is a prompt', () => {
+      const handler = { action: 'accept' as const, promptText: 'Ada' };
+      expect(resolveDialogResponse(handler, 'alert', '')).toEqual({ accept: true });
+      expect(resolveDialogResponse(handler, 'prompt', 'Guest')).toEqual({
+        accept: true,
+        promptText: 'Ada'
+      });
+    });
+
+    it("should fall back to the prompt's default value", () => {
+      expect(resolveDialogResponse({ action: 'accept' }, 'prompt', 'Guest')).toEqual({
+        accept: true,
+        promptText: 'Guest'
+      });
+    });
+  });
+
+  describe('pushDialog', () => {
+    it('should keep only the latest dialogs', () => {
+      const dialogs: DialogEntry[] = [];
+      for (let i = 0; i < DIALOG_BUFFER_SIZE + 5; i++) {
+        pushDialog(dialogs, {
+          type: 'alert',
+          message: `dialog ${i}`,
+          defaultValue: '',
+          action: 'dismiss',
+          promptText: null,
+          url: 'https://example.com/',
+          pageId: 'page',
+          timestamp: new Date().toISOString()
+        });
+      }
+      expect(dialogs).toHaveLength(DIALOG_BUFFER_SIZE);
+      expect(dialogs[0]?.message).toBe('dialog 5');
+    });
+  });
+});
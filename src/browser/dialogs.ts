@@ -0,0 +1,31 @@
+import type { DialogEntry, DialogHandlerRequest } from '../types/index.js';
+
+// Older dialogs are dropped once a session has recorded this many
+export const DIALOG_BUFFER_SIZE = 100;
+
+// Dismissing is the safe default: an unanswered dialog blocks the page and every call that
+// waits on it
+export const DEFAULT_DIALOG_HANDLER: DialogHandlerRequest = { action: 'dismiss' };
+
+// How to answer a dialog of the given type. Prompts get the configured text, or their own
+// default value when none is set; other dialog types take no text.
+export function resolveDialogResponse(
+  handler: DialogHandlerRequest,
+  type: string,
+  defaultValue: string
+): { accept: boolean; promptText?: string } {
+  if (handler.action !== 'accept') {
+    return { accept: false };
+  }
+  if (type !== 'prompt') {
+    return { accept: true };
+  }
+  return { accept: true, promptText: handler.promptText ?? defaultValue };
+}
+
+export function pushDialog(dialogs: DialogEntry[], entry: DialogEntry): void {
+  dialogs.push(entry);
+  if (dialogs.length > DIALOG_BUFFER_SIZE) {
+    dialogs.shift();
+  }
+}
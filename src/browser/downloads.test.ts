@@ -0,0 +1,69 @@
+import fs from 'node:fs';
+import os from 'node:os';
+import path from 'node:path';
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import { DownloadWatcher, isCompleteDownload } from './downloads.js';
+
+describe('Download helpers', () => {
+  describe('isCompleteDownload', () => {
+    it('should skip partial files and reserved names', () => {
+      expect(isCompleteDownload('report.csv.crdownload', 10, ['report.csv.crdownload'])).toBe(
+        false
+      );
+      expect(isCompleteDownload('report.csv', 0, ['report.csv', 'report.csv.crdownload'])).toBe(
+        false
+      );
+      expect(isCompleteDownload('report.csv', 0, ['report.csv', 'Unconfirmed 1.crdownload'])).toBe(
+        false
+      );
+    });
+
+    it('should accept finished files', () => {
+      expect(isCompleteDownload('report.csv', 42, ['report.csv'])).toBe(true);
+      expect(isCompleteDownload('empty.txt', 0, ['empty.txt'])).toBe(true);
+    });
+  });
+
+  describe('DownloadWatcher', () => {
+    let directory: string;
+    let watcher: DownloadWatcher;
+
+    beforeEach(() => {
+      directory = fs.mkdtempSync(path.join(os.tmpdir(), 'pcs-downloads-test-'));
+      fs.writeFileSync(path.join(directory, 'old.txt'), 'existing');
+      watcher = new DownloadWatcher(directory);
+      watcher.start();
+    });
+
+    afterEach(() => {
+      watcher.stop();
+      fs.rmSync(directory, { recursive: true, force: true });
+    });
+
+    it('should report a download once it is renamed into place', async () => {
+      const partial = path.join(directory, 'report.csv.crdownload');
+      fs.writeFileSync(partial, 'a,b\n1,2\n');
+      setTimeout(() => fs.renameSync(partial, path.join(directory, 'report.csv')), 50);
+
+      const result = await watcher.next(5000);
+      expect(result.timedOut).toBe(false);
+      expect(result.download).toMatchObject({ filename: 'report.csv', size: 8 });
+    });
+
+    it('should queue downloads that finished before waiting', async () => {
+      fs.writeFileSync(path.join(directory, 'first.txt'), '1');
+      const result = await watcher.next(1000);
+      expect(result.download?.filename).toBe('first.txt');
+    });
+
+    it('should ignore files present before watching and time out', async () => {
+      fs.writeFileSync(path.join(directory, 'slow.zip.crdownload'), 'partial');
+      const result = await watcher.next(100);
+      expect(result).toMatchObject({
+        download: null,
+        timedOut: true,
+        inProgress: ['slow.zip.crdownload']
+      });
+    });
+  });
+});
@@ -0,0 +1,144 @@
+import fs, { type FSWatcher } from 'node:fs';
+import path from 'node:path';
+import type { DownloadInfo, WaitForDownloadResult } from '../types/index.js';
+
+// Chrome writes a download to <name>.crdownload (or "Unconfirmed <id>.crdownload") and
+// renames it once the last byte is on disk
+const PARTIAL_SUFFIX = '.crdownload';
+const SCAN_INTERVAL_MS = 250;
+
+interface Waiter {
+  resolve: (download: DownloadInfo | null) => void;
+  timer: NodeJS.Timeout | null;
+}
+
+// Whether a file in the download directory is a finished download. Chrome reserves the final
+// name with an empty file while the data still goes to the .crdownload file.
+export function isCompleteDownload(name: string, size: number, names: string[]): boolean {
+  if (name.endsWith(PARTIAL_SUFFIX) || name.startsWith('.')) {
+    return false;
+  }
+  if (names.includes(`${name}${PARTIAL_SUFFIX}`)) {
+    return false;
+  }
+  return size > 0 || !names.some(other => other.endsWith(PARTIAL_SUFFIX));
+}
+
+// Watches a download directory and queues downloads as they complete, so a download that
+// finishes before anyone waits for it is still reported. Files present when watching starts
+// are ignored.
+export class DownloadWatcher {
+  readonly directory: string;
+  private known: Set<string> = new Set();
+  private completed: DownloadInfo[] = [];
+  private waiters: Waiter[] = [];
+  private watcher: FSWatcher | null = null;
+  private interval: NodeJS.Timeout | null = null;
+
+  constructor(directory: string) {
+    this.directory = directory;
+  }
+
+  start(): void {
+    fs.mkdirSync(this.directory, { recursive: true });
+    for (const name of fs.readdirSync(this.directory)) {
+      if (!name.endsWith(PARTIAL_SUFFIX)) {
+        this.known.add(name);
+      }
+    }
+    this.watcher = fs.watch(this.directory, () => this.scan());
+    this.watcher.on('error', () => {
+      // the interval below keeps scanning when the platform drops watch events
+    });
+    this.interval = setInterval(() => this.scan(), SCAN_INTERVAL_MS);
+    this.interval.unref();
+  }
+
+  stop(): void {
+    this.watcher?.close();
+    this.watcher = null;
+    if (this.interval) {
+      clearInterval(this.interval);
+      this.interval = null;
+    }
+    for (const waiter of this.waiters.splice(0)) {
+      if (waiter.timer) clearTimeout(waiter.timer);
+      waiter.resolve(null);
+    }
+  }
+
+  // Names of downloads Chrome is still writing
+  inProgress(): string[] {
+    try {
+      return fs.readdirSync(this.directory).filter(name => name.endsWith(PARTIAL_SUFFIX));
+    } catch {
+      return [];
+    }
+  }
+
+  // Resolves with the oldest completed download nobody has waited for yet, or null on timeout
+  // (0 waits forever)
+  async next(timeout: number): Promise<WaitForDownloadResult> {
+    const startedAt = Date.now();
+    this.scan();
+    const download =
+      this.completed.shift() ??
+      (await new Promise<DownloadInfo | null>(resolve => {
+        const waiter: Waiter = { resolve, timer: null };
+        if (timeout > 0) {
+          waiter.timer = setTimeout(() => {
+            this.waiters = this.waiters.filter(other => other !== waiter);
+            resolve(null);
+          }, timeout);
+        }
+        this.waiters.push(waiter);
+      }));
+    return {
+      download,
+      timedOut: download === null,
+      elapsedMs: Date.now() - startedAt,
+      inProgress: download ? [] : this.inProgress()
+    };
+  }
+
+  private scan(): void {
+    let names: string[];
+    try {
+      names = fs.readdirSync(this.directory);
+    } catch {
+      return;
+    }
+    for (const name of names) {
+      if (this.known.has(name)) {
+        continue;
+      }
+      const filePath = path.join(this.directory, name);
+      let stat: fs.Stats;
+      try {
+        stat = fs.statSync(filePath);
+      } catch {
+        continue;
+      }
+      if (!stat.isFile() || !isCompleteDownload(name, stat.size, names)) {
+        continue;
+      }
+      this.known.add(name);
+      this.deliver({
+        path: filePath,
+        filename: name,
+        size: stat.size,
+        completedAt: new Date().toISOString()
+      });
+    }
+  }
+
+  private deliver(download: DownloadInfo): void {
+    const waiter = this.waiters.shift();
+    if (waiter) {
+      if (waiter.timer) clearTimeout(waiter.timer);
+      waiter.resolve(download);
+    } else {
+      this.completed.push(download);
+    }
+  }
+}
@@ -0,0 +1,39 @@
+import { describe, expect, it } from 'vitest';
+import { describeNonEditable, NON_TEXT_INPUT_TYPES } from './editable.js';
+
+describe('Editable helpers', () => {
+  describe('describeNonEditable', () => {
+    it('should accept text inputs and textareas', () => {
+      expect(describeNonEditable({ tagName: 'INPUT', type: 'email' }, NON_TEXT_INPUT_TYPES)).toBe(
+        null
+      );
+      expect(describeNonEditable({ tagName: 'TEXTAREA' }, NON_TEXT_INPUT_TYPES)).toBe(null);
+    });
+
+    it('should accept contenteditable elements', () => {
+      expect(
+        describeNonEditable({ tagName: 'DIV', isContentEditable: true }, NON_TEXT_INPUT_TYPES)
+      ).toBe(null);
+    });
+
+    it('should reject non-text inputs', () => {
+      expect(
+        describeNonEditable({ tagName: 'INPUT', type: 'checkbox' }, NON_TEXT_INPUT_TYPES)
+      ).toContain('not a text input');
+    });
+
+    it('should reject disabled and read-only fields', () => {
+      const input = { tagName: 'INPUT', type: 'text', disabled: true };
+      expect(describeNonEditable(input, NON_TEXT_INPUT_TYPES)).toContain('disabled');
+      expect(
+        describeNonEditable({ tagName: 'TEXTAREA', readOnly: true }, NON_TEXT_INPUT_TYPES)
+      ).toContain('read-only');
+    });
+
+    it('should reject other elements', () => {
+      expect(describeNonEditable({ tagName: 'DIV' }, NON_TEXT_INPUT_TYPES)).toContain(
+        'not an input'
+      );
+    });
+  });
+});
@@ -0,0 +1,47 @@
+// Page-side helpers for text entry. They run inside the browser through evaluate, so they
+// only use what is available there and take the element as an untyped argument.
+
+export const NON_TEXT_INPUT_TYPES = [
+  'button',
+  'checkbox',
+  'color',
+  'file',
+  'hidden',
+  'image',
+  'radio',
+  'range',
+  'reset',
+  'submit'
+];
+
+// Returns null when the element accepts text, otherwise the reason it does not
+export function describeNonEditable(el: any, nonTextTypes: string[]): string | null {
+  const tag = String(el.tagName).toLowerCase();
+  if (tag === 'textarea' || tag === 'input') {
+    if (tag === 'input' && nonTextTypes.includes(String(el.type).toLowerCase())) {
+      return `<input type="${el.type}"> is not a text input`;
+    }
+    if (el.disabled) return `<${tag}> is disabled`;
+    if (el.readOnly) return `<${tag}> is read-only`;
+    return null;
+  }
+  return el.isContentEditable ? null : `<${tag}> is not an input, textarea or contenteditable`;
+}
+
+// Sets the value the way a framework-controlled input expects it: through the native setter
+// so React and friends notice, followed by input and change events
+export function setEditableValue(el: any, value: string): void {
+  if (el.isContentEditable && el.tagName !== 'INPUT' && el.tagName !== 'TEXTAREA') {
+    el.textContent = value;
+  } else {
+    const proto = Object.getPrototypeOf(el);
+    const setter = Object.getOwnPropertyDescriptor(proto, 'value')?.set;
+    if (setter) {
+      setter.call(el, value);
+    } else {
+      el.value = value;
+    }
+  }
+  el.dispatchEvent(new Event('input', { bubbles: true }));
+  el.dispatchEvent(new Event('change', { bubbles: true }));
+}
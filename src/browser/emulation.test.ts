@@ -0,0 +1,169 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import {
+  canonicalLocale,
+  isValidTimezone,
+  NO_MEDIA_EMULATION,
+  resolveMediaEmulation,
+  resolveNetworkConditions,
+  toAcceptLanguage,
+  toBytesPerSecond,
+  toEmulatedMedia,
+  validateCpuThrottlingRate,
+  validateGeolocation,
+  validateVisionDeficiency
+} from './emulation.js';
+
+describe('Emulation helpers', () => {
+  describe('validateGeolocation', () => {
+    it('should accept coordinates in range', () => {
+      expect(() => validateGeolocation({ latitude: 52.52, longitude: 13.405 })).not.toThrow();
+      expect(() =>
+        validateGeolocation({ latitude: -90, longitude: 180, accuracy: 10 })
+      ).not.toThrow();
+    });
+
+    it('should reject coordinates out of range', () => {
+      expect(() => validateGeolocation({ latitude: 91, longitude: 0 })).toThrow(BrowserError);
+      expect(() => validateGeolocation({ latitude: 0, longitude: -181 })).toThrow('longitude');
+      expect(() => validateGeolocation({ latitude: 0, longitude: 0, accuracy: -1 })).toThrow(
+        'accuracy'
+      );
+    });
+  });
+
+  describe('isValidTimezone', () => {
+    it('should recognize IANA timezone IDs', () => {
+      expect(isValidTimezone('Europe/Berlin')).toBe(true);
+      expect(isValidTimezone('America/New_York')).toBe(true);
+      expect(isValidTimezone('Mars/Olympus_Mons')).toBe(false);
+    });
+  });
+
+  describe('canonicalLocale', () => {
+    it('should normalize language tags', () => {
+      expect(canonicalLocale('de-de')).toBe('de-DE');
+      expect(canonicalLocale('ja')).toBe('ja');
+    });
+
+    it('should reject malformed tags', () => {
+      expect(() => canonicalLocale('not a locale')).toThrow(BrowserError);
+    });
+  });
+
+  describe('toAcceptLanguage', () => {
+    it('should add the bare language after a regional locale', () => {
+      expect(toAcceptLanguage('de-DE')).toBe('de-DE,de;q=0.9');
+      expect(toAcceptLanguage('fr')).toBe('fr');
+    });
+  });
+
+  describe('resolveNetworkConditions', () => {
+    it('should apply presets', () => {
+      expect(resolveNetworkConditions({ preset: 'slow-3g' })).toEqual({
+        preset: 'slow-3g',
+        throttled: true,
+        offline: false,
+        downloadKbps: 400,
+        uploadKbps: 400,
+        latency: 2000
+      });
+      expect(resolveNetworkConditions({ preset: 'offline' })).toMatchObject({
+        offline: true,
+        downloadKbps: null
+      });
+    });
+
+    it('should let custom values override the preset', () => {
+      expect(resolveNetworkConditions({ preset: 'fast-3g', latency: 100 })).toMatchObject({
+        preset: 'fast-3g',
+        downloadKbps: 1440,
+        latency: 100
+      });
+      expect(resolveNetworkConditions({ downloadKbps: 1000 })).toMatchObject({
+        preset: null,
+        downloadKbps: 1000,
+        uploadKbps: null,
+        latency: 0
+      });
+    });
+
+    it('should remove throttling without a preset or values', () => {
+      expect(resolveNetworkConditions({})).toBeNull();
+      expect(resolveNetworkConditions({ preset: 'offline', offline: false })).toBeNull();
+    });
+
+    it('should reject unknown presets and bad values', () => {
+      expect(() => resolveNetworkConditions({ preset: '2g' as any })).toThrow(BrowserError);
+      expect(() => resolveNetworkConditions({ downloadKbps: 0 })).toThrow(/downloadKbps/);
+      expect(() => resolveNetworkConditions({ latency: -5 })).toThrow(/latency/);
+    });
+  });
+
+  describe('toBytesPerSecond', () => {
+    it('should convert kilobits and map no limit to -1', () => {
+      expect(toBytesPerSecond(400)).toBe(50000);
+      expect(toBytesPerSecond(null)).toBe(-1);
+    });
+  });
+
+  describe('validateCpuThrottlingRate', () => {
+    it('should accept rates of at least 1', () => {
+      expect(() => validateCpuThrottlingRate(1)).not.toThrow();
+      expect(() => validateCpuThrottlingRate(4.5)).not.toThrow();
+    });
+
+    it('should reject rates below 1', () => {
+      expect(() => validateCpuThrottlingRate(0.5)).toThrow(BrowserError);
+      expect(() => validateCpuThrottlingRate(Number.POSITIVE_INFINITY)).toThrow(/at least 1/);
+      expect(() => validateCpuThrottlingRate('4' as any)).toThrow(/at least 1/);
+    });
+  });
+
+  describe('validateVisionDeficiency', () => {
+    it('should accept the deficiencies Chrome emulates', () => {
+      expect(validateVisionDeficiency('deuteranopia')).toBe('deuteranopia');
+      expect(validateVisionDeficiency('none')).toBe('none');
+      expect(() => validateVisionDeficiency('colorblind')).toThrow(BrowserError);
+      expect(() => validateVisionDeficiency(undefined)).toThrow(/one of: none, blurredVision/);
+    });
+  });
+
+  describe('resolveMediaEmulation', () => {
+    it('should keep fields that are left out and clear null ones', () => {
+      const dark = resolveMediaEmulation(NO_MEDIA_EMULATION, {
+        colorScheme: 'dark',
+        mediaType: 'print'
+      });
+      expect(dark).toEqual({ colorScheme: 'dark', reducedMotion: null, mediaType: 'print' });
+      expect(resolveMediaEmulation(dark, { reducedMotion: 'reduce', mediaType: null })).toEqual({
+        colorScheme: 'dark',
+        reducedMotion: 'reduce',
+        mediaType: null
+      });
+    });
+
+    it('should reject unknown values', () => {
+      expect(() =>
+        resolveMediaEmulation(NO_MEDIA_EMULATION, { colorScheme: 'sepia' as any })
+      ).toThrow(BrowserError);
+      expect(() => resolveMediaEmulation(NO_MEDIA_EMULATION, { mediaType: 'tv' as any })).toThrow(
+        /screen, print/
+      );
+    });
+  });
+
+  describe('toEmulatedMedia', () => {
+    it('should send every feature, empty where the operating system decides', () => {
+      expect(
+        toEmulatedMedia({ colorScheme: 'dark', reducedMotion: null, mediaType: null })
+      ).toEqual({
+        media: '',
+        features: [
+          { name: 'prefers-color-scheme', value: 'dark' },
+          { name: 'prefers-reduced-motion', value: '' }
+        ]
+      });
+    });
+  });
+});
@@ -0,0 +1,178 @@
+import {
+  COLOR_SCHEMES,
+  type EmulateMediaRequest,
+  type GeolocationRequest,
+  InvalidArgumentError,
+  MEDIA_TYPES,
+  type MediaEmulation,
+  NETWORK_PRESETS,
+  type NetworkConditionsRequest,
+  type NetworkConditionsResult,
+  type NetworkPreset,
+  REDUCED_MOTION_VALUES,
+  VISION_DEFICIENCIES,
+  type VisionDeficiency
+} from '../types/index.js';
+
+// Throttled pages load several times slower, so navigations get that much longer by default
+export const THROTTLED_NAVIGATION_FACTOR = 4;
+
+type Throughput = Pick<NetworkConditionsResult, 'downloadKbps' | 'uploadKbps' | 'latency'>;
+
+// Same values as the Chrome DevTools presets
+const PRESETS: Record<NetworkPreset, Throughput & { offline: boolean }> = {
+  'slow-3g': { downloadKbps: 400, uploadKbps: 400, latency: 2000, offline: false },
+  'fast-3g': { downloadKbps: 1440, uploadKbps: 675, latency: 562.5, offline: false },
+  offline: { downloadKbps: null, uploadKbps: null, latency: 0, offline: true }
+};
+
+export function validateGeolocation(request: GeolocationRequest): void {
+  const { latitude, longitude, accuracy } = request;
+  if (typeof latitude !== 'number' || !(latitude >= -90 && latitude <= 90)) {
+    throw new InvalidArgumentError('latitude must be a number between -90 and 90');
+  }
+  if (typeof longitude !== 'number' || !(longitude >= -180 && longitude <= 180)) {
+    throw new InvalidArgumentError('longitude must be a number between -180 and 180');
+  }
+  if (accuracy !== undefined && !(accuracy >= 0)) {
+    throw new InvalidArgumentError('accuracy must be a non-negative number of meters');
+  }
+}
+
+// IANA time zone IDs as Chrome's ICU knows them, e.g. "Europe/Berlin"
+export function isValidTimezone(timezoneId: string): boolean {
+  try {
+    new Intl.DateTimeFormat('en-US', { timeZone: timezoneId });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+// Normalizes a BCP 47 tag such as "de-de" to "de-DE" and rejects malformed ones
+export function canonicalLocale(locale: string): string {
+  try {
+    const [canonical] = Intl.getCanonicalLocales(locale);
+    if (canonical) {
+      return canonical;
+    }
+  } catch {
+    // reported below
+  }
+  throw new InvalidArgumentError(`Invalid locale ${locale}, expected a BCP 47 tag like en-US`);
+}
+
+// Region-specific locales also accept the bare language, as browsers send it
+export function toAcceptLanguage(locale: string): string {
+  const language = locale.split('-')[0] as string;
+  return language === locale ? locale : `${locale},${language};q=0.9`;
+}
+
+// Merges a preset with custom values. Returns null when nothing is throttled, which removes
+// the emulation.
+export function resolveNetworkConditions(
+  request: NetworkConditionsRequest
+): Omit<NetworkConditionsResult, 'navigationTimeout'> | null {
+  const { preset, downloadKbps, uploadKbps, latency, offline } = request;
+  if (preset !== undefined && !NETWORK_PRESETS.includes(preset)) {
+    throw new InvalidArgumentError(`Network preset must be one of: ${NETWORK_PRESETS.join(', ')}`);
+  }
+  for (const [name, value] of Object.entries({ downloadKbps, uploadKbps })) {
+    if (value !== undefined && (typeof value !== 'number' || !(value > 0))) {
+      throw new InvalidArgumentError(`${name} must be a positive number of kilobits per second`);
+    }
+  }
+  if (latency !== undefined && (typeof latency !== 'number' || !(latency >= 0))) {
+    throw new InvalidArgumentError('latency must be a non-negative number of milliseconds');
+  }
+
+  const base = preset
+    ? PRESETS[preset]
+    : { downloadKbps: null, uploadKbps: null, latency: 0, offline: false };
+  const conditions = {
+    preset: preset ?? null,
+    offline: offline ?? base.offline,
+    downloadKbps: downloadKbps ?? base.downloadKbps,
+    uploadKbps: uploadKbps ?? base.uploadKbps,
+    latency: latency ?? base.latency
+  };
+  const throttled =
+    conditions.offline ||
+    conditions.downloadKbps !== null ||
+    conditions.uploadKbps !== null ||
+    conditions.latency > 0;
+  return throttled ? { ...conditions, throttled } : null;
+}
+
+// 1 is full speed, 4 runs the page four times slower as on a mid-range phone
+export function validateCpuThrottlingRate(rate: number): void {
+  if (typeof rate !== 'number' || !Number.isFinite(rate) || rate < 1) {
+    throw new InvalidArgumentError('CPU throttling rate must be a number of at least 1');
+  }
+}
+
+export function validateVisionDeficiency(type: unknown): VisionDeficiency {
+  if (!VISION_DEFICIENCIES.includes(type as VisionDeficiency)) {
+    throw new InvalidArgumentError(
+      `Vision deficiency must be one of: ${VISION_DEFICIENCIES.join(', ')}`
+    );
+  }
+  return type as VisionDeficiency;
+}
+
+export const NO_MEDIA_EMULATION: MediaEmulation = {
+  colorScheme: null,
+  reducedMotion: null,
+  mediaType: null
+};
+
+// Applies the request on top of the current emulation
+export function resolveMediaEmulation(
+  current: MediaEmulation,
+  request: EmulateMediaRequest
+): MediaEmulation {
+  const pick = <T extends string>(
+    name: string,
+    value: T | null | undefined,
+    allowed: readonly T[],
+    previous: T | null
+  ): T | null => {
+    if (value === undefined) {
+      return previous;
+    }
+    if (value !== null && !allowed.includes(value)) {
+      throw new InvalidArgumentError(`${name} must be one of: ${allowed.join(', ')}`);
+    }
+    return value;
+  };
+  return {
+    colorScheme: pick('colorScheme', request.colorScheme, COLOR_SCHEMES, current.colorScheme),
+    reducedMotion: pick(
+      'reducedMotion',
+      request.reducedMotion,
+      REDUCED_MOTION_VALUES,
+      current.reducedMotion
+    ),
+    mediaType: pick('mediaType', request.mediaType, MEDIA_TYPES, current.mediaType)
+  };
+}
+
+// Chrome takes the media type and features in one call that replaces the previous ones, an
+// empty value leaves the feature to the operating system
+export function toEmulatedMedia(emulation: MediaEmulation): {
+  media: string;
+  features: { name: string; value: string }[];
+} {
+  return {
+    media: emulation.mediaType ?? '',
+    features: [
+      { name: 'prefers-color-scheme', value: emulation.colorScheme ?? '' },
+      { name: 'prefers-reduced-motion', value: emulation.reducedMotion ?? '' }
+    ]
+  };
+}
+
+// Chrome takes bytes per second, with -1 for no limit
+export function toBytesPerSecond(kbps: number | null): number {
+  return kbps === null ? -1 : (kbps * 1000) / 8;
+}
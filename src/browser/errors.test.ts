@@ -0,0 +1,93 @@
+import { describe, expect, it } from 'vitest';
+import {
+  BrowserError,
+  InvalidArgumentError,
+  OperationTimeoutError,
+  ProfileLockedError,
+  SessionCrashedError,
+  SessionNotFoundError,
+  TabNotFoundError
+} from '../types/index.js';
+import { toToolError } from './errors.js';
+import { elementNotFoundMessage } from './selectors.js';
+
+describe('Tool errors', () => {
+  it('should map the typed errors', () => {
+    expect(toToolError(new TabNotFoundError('t1'))).toEqual({
+      code: 'TAB_NOT_FOUND',
+      message: 'Tab with ID t1 not found'
+    });
+    expect(toToolError(new SessionNotFoundError('s1')).code).toBe('SESSION_NOT_FOUND');
+    expect(toToolError(new SessionCrashedError('s1', new Date())).code).toBe('SESSION_CRASHED');
+    expect(toToolError(new InvalidArgumentError('URL is required'))).toEqual({
+      code: 'INVALID_ARGUMENT',
+      message: 'URL is required'
+    });
+    expect(toToolError(new OperationTimeoutError('browser_click', 100, 120))).toMatchObject({
+      code: 'TIMEOUT',
+      details: { operation: 'browser_click', timeout: 100, elapsedMs: 120 }
+    });
+    expect(toToolError(new ProfileLockedError('/data/profile', 42, 'Chrome'))).toMatchObject({
+      code: 'PROFILE_LOCKED',
+      message: expect.stringContaining('another Chrome (pid 42)'),
+      details: { userDataDir: '/data/profile', pid: 42 }
+    });
+  });
+
+  it('should keep the code of validation errors wrapped by an operation', () => {
+    const invalid = new InvalidArgumentError('index must be a non-negative integer');
+    const wrapped = new BrowserError(`Failed to click element: ${invalid}`);
+    expect(toToolError(wrapped).code).toBe('INVALID_ARGUMENT');
+  });
+
+  it('should classify Puppeteer failures by their message', () => {
+    const cases: Array<[string, string]> = [
+      ['Waiting for selector `#go` failed: Waiting failed: 500ms exceeded', 'TIMEOUT'],
+      ['No frame matches "checkout"', 'FRAME_NOT_FOUND'],
+      ['Attempted to use detached Frame', 'DETACHED_FRAME'],
+      ['Node is detached from document', 'STALE_ELEMENT'],
+      ['Protocol error (Runtime.callFunctionOn): Target closed', 'TARGET_CLOSED'],
+      ['SyntaxError: Unexpected token', 'BROWSER_ERROR'],
+      ['Failed to evaluate script: SyntaxError: Invalid or unexpected token', 'BROWSER_ERROR'],
+      ['Protocol error (Page.printToPDF): Printing failed', 'BROWSER_ERROR']
+    ];
+    for (const [message, code] of cases) {
+      expect(toToolError(new BrowserError(message)).code).toBe(code);
+    }
+    expect(toToolError(new Error('boom')).code).toBe('INTERNAL_ERROR');
+    expect(toToolError('boom')).toEqual({ code: 'INTERNAL_ERROR', message: 'boom' });
+  });
+
+  it('should add details when the message names them', () => {
+    expect(toToolError(new BrowserError('No element found for selector: #buy'))).toEqual({
+      code: 'SELECTOR_NOT_FOUND',
+      message: 'No element found for selector: #buy',
+      details: { selector: '#buy' }
+    });
+    const message = elementNotFoundMessage('#buy', { shadowMatches: 2 });
+    const shadowed = new BrowserError(`Failed to click: ${message}`);
+    expect(toToolError(shadowed)).toMatchObject({
+      code: 'SELECTOR_NOT_FOUND',
+      details: { selector: '#buy', shadowMatches: 2 }
+    });
+    expect(
+      toToolError(new BrowserError(elementNotFoundMessage('li', { index: 3, matches: 2 })))
+    ).toMatchObject({ details: { selector: 'li' } });
+    expect(
+      toToolError(new BrowserError('net::ERR_NAME_NOT_RESOLVED at https://nope.invalid'))
+    ).toEqual({
+      code: 'NAVIGATION_FAILED',
+      message: 'net::ERR_NAME_NOT_RESOLVED at https://nope.invalid',
+      details: {
+        netError: 'ERR_NAME_NOT_RESOLVED',
+        netErrorCategory: 'dns',
+        url: 'https://nope.invalid'
+      }
+    });
+    expect(
+      toToolError(new BrowserError('Failed to open tab: Error: net::ERR_HTTP2_PROTOCOL_ERROR'))
+    ).toMatchObject({
+      details: { netError: 'ERR_HTTP2_PROTOCOL_ERROR', netErrorCategory: 'response' }
+    });
+  });
+});
@@ -0,0 +1,91 @@
+import {
+  BrowserError,
+  type ErrorCode,
+  InvalidArgumentError,
+  OperationTimeoutError,
+  ProfileLockedError,
+  SessionCrashedError,
+  SessionNotFoundError,
+  ShuttingDownError,
+  TabNotFoundError,
+  type ToolError
+} from '../types/index.js';
+import { classifyNetError, getNetErrorCode } from './navigation.js';
+import { isStaleElementError } from './selectors.js';
+
+// Most failures reach the caller as a BrowserError wrapping Puppeteer's message, so they are
+// told apart by what the message says. Earlier patterns win. Validation failures are typed
+// instead, and a wrapped one is recognized by the class name its message embeds.
+const MESSAGE_CODES: Array<[RegExp, ErrorCode]> = [
+  [/\bInvalidArgumentError: /, 'INVALID_ARGUMENT'],
+  [/TimeoutError|timed out|\d+ ?ms exceeded/i, 'TIMEOUT'],
+  [/No frame matches/, 'FRAME_NOT_FOUND'],
+  [/detached Frame|frame (got|was) detached|Navigating frame was detached/i, 'DETACHED_FRAME'],
+  [/No element (found for|matches) selector/, 'SELECTOR_NOT_FOUND'],
+  [/net::ERR_|Failed to navigate|Navigation failed/, 'NAVIGATION_FAILED'],
+  [/Target closed|Session closed|Connection closed|browser has disconnected/i, 'TARGET_CLOSED'],
+  [/Chrome executable not found|Failed to (initialize|connect to) browser/, 'BROWSER_UNAVAILABLE']
+];
+
+// The selector of a SELECTOR_NOT_FOUND message without what elementNotFoundMessage adds,
+// and how many elements behind shadow roots match it when it counted them
+const SELECTOR_DETAILS = new RegExp(
+  'No element (?:found for selector:|matches selector) (.+?)' +
+    '(?:, (\\d+) match(?:es are| is) inside.*|, index \\d+ is out of range.*)?$',
+  'm'
+);
+
+function messageDetails(code: ErrorCode, message: string): Record<string, unknown> | null {
+  if (code === 'SELECTOR_NOT_FOUND') {
+    const found = message.match(SELECTOR_DETAILS);
+    if (!found) {
+      return null;
+    }
+    const shadowMatches = found[2] ? { shadowMatches: Number(found[2]) } : {};
+    return { selector: found[1], ...shadowMatches };
+  }
+  if (code === 'NAVIGATION_FAILED') {
+    const netError = getNetErrorCode(message);
+    if (!netError) {
+      return null;
+    }
+    const url = message.match(/net::ERR_[A-Z0-9_]+ at (\S+)/)?.[1];
+    return { netError, netErrorCategory: classifyNetError(netError), ...(url && { url }) };
+  }
+  return null;
+}
+
+// Maps anything a tool throws to the structured error clients branch on
+export function toToolError(error: unknown): ToolError {
+  if (error instanceof OperationTimeoutError) {
+    return error.toJSON();
+  }
+  const message = error instanceof Error ? error.message : String(error);
+  if (error instanceof InvalidArgumentError) {
+    return { code: error.code, message };
+  }
+  if (error instanceof SessionCrashedError) {
+    return { code: error.code, message };
+  }
+  if (error instanceof SessionNotFoundError) {
+    return { code: 'SESSION_NOT_FOUND', message };
+  }
+  if (error instanceof TabNotFoundError) {
+    return { code: 'TAB_NOT_FOUND', message };
+  }
+  if (error instanceof ShuttingDownError) {
+    return { code: error.code, message };
+  }
+  if (error instanceof ProfileLockedError) {
+    const details = { userDataDir: error.userDataDir, pid: error.pid };
+    return { code: error.code, message, details };
+  }
+
+  let code = MESSAGE_CODES.find(([pattern]) => pattern.test(message))?.[1];
+  if (!code && isStaleElementError(error)) {
+    code = 'STALE_ELEMENT';
+  }
+  code ??= error instanceof BrowserError ? 'BROWSER_ERROR' : 'INTERNAL_ERROR';
+  const details = messageDetails(code, message);
+  return details ? { code, message, details } : { code, message };
+}
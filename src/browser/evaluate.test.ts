@@ -0,0 +1,46 @@
+import { describe, expect, it } from 'vitest';
+import { buildEvaluateExpression, serializeInPage, withTimeout } from './evaluate.js';
+
+describe('Evaluate helpers', () => {
+  describe('buildEvaluateExpression', () => {
+    it('should bind arguments to args', async () => {
+      const expression = buildEvaluateExpression('return args[0] + args[1];', [2, 3]);
+      expect(await (0, eval)(expression)).toBe(5);
+    });
+
+    it('should allow await in the body', async () => {
+      const expression = buildEvaluateExpression('return await Promise.resolve("done");', []);
+      expect(await (0, eval)(expression)).toBe('done');
+    });
+  });
+
+  describe('serializeInPage', () => {
+    it('should serialize plain values', () => {
+      expect(serializeInPage({ a: [1, 'two', null] })).toEqual({ json: '{"a":[1,"two",null]}' });
+    });
+
+    it('should return nothing for undefined', () => {
+      expect(serializeInPage(undefined)).toEqual({});
+    });
+
+    it('should reject functions and say where they are', () => {
+      expect(serializeInPage({ handler: () => {} }).error).toBe('a function at "handler"');
+    });
+
+    it('should reject circular structures', () => {
+      const value: any = {};
+      value.self = value;
+      expect(serializeInPage(value).error).toMatch(/circular/i);
+    });
+  });
+
+  describe('withTimeout', () => {
+    it('should resolve when the promise settles in time', async () => {
+      await expect(withTimeout(Promise.resolve(1), 1000, 'too slow')).resolves.toBe(1);
+    });
+
+    it('should reject when the timeout elapses', async () => {
+      await expect(withTimeout(new Promise(() => {}), 10, 'too slow')).rejects.toThrow('too slow');
+    });
+  });
+});
@@ -0,0 +1,58 @@
+export interface SerializedResult {
+  json?: string;
+  error?: string;
+}
+
+// Wraps a function body so it runs as an async function with the given arguments bound to
+// `args`. The arguments are inlined as a JSON literal, which is also valid JavaScript.
+export function buildEvaluateExpression(body: string, args: unknown[]): string {
+  return `(async function (...args) {\n${body}\n}).apply(globalThis, ${JSON.stringify(args)})`;
+}
+
+// Runs in the page against the value the function returned. Values JSON cannot represent
+// faithfully are reported instead of being silently turned into {} or dropped.
+export function serializeInPage(value: any): SerializedResult {
+  const scope: any = globalThis;
+  const describe = (v: any): string | null => {
+    if (typeof v === 'function') return 'a function';
+    if (typeof v === 'symbol') return 'a symbol';
+    if (typeof v === 'bigint') return 'a BigInt';
+    if (scope.Node && v instanceof scope.Node) return `a DOM node (${v.nodeName})`;
+    if (scope.Window && v instanceof scope.Window) return 'the window object';
+    if (v instanceof Map) return 'a Map';
+    if (v instanceof Set) return 'a Set';
+    if (v instanceof Promise) return 'a Promise';
+    return null;
+  };
+
+  if (value === undefined) {
+    return {};
+  }
+
+  let problem = null as string | null;
+  try {
+    const json = JSON.stringify(value, (key, v) => {
+      const kind = describe(v);
+      if (kind && !problem) {
+        problem = key ? `${kind} at "${key}"` : kind;
+      }
+      return v;
+    });
+    return problem ? { error: problem } : { json };
+  } catch (error) {
+    return { error: problem ?? (error instanceof Error ? error.message : String(error)) };
+  }
+}
+
+// Rejects once the timeout elapses. A timeout of 0 disables it, as it does in Puppeteer.
+export function withTimeout<T>(promise: Promise<T>, timeout: number, message: string): Promise<T> {
+  if (timeout <= 0) {
+    return promise;
+  }
+
+  let timer: ReturnType<typeof setTimeout> | undefined;
+  const expired = new Promise<never>((_, reject) => {
+    timer = setTimeout(() => reject(new Error(message)), timeout);
+  });
+  return Promise.race([promise, expired]).finally(() => clearTimeout(timer));
+}
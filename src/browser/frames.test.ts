@@ -0,0 +1,73 @@
+import { describe, expect, it } from 'vitest';
+import {
+  buildFrameTree,
+  describeFrames,
+  type FrameDescriptor,
+  matchFrame,
+  matchFrameUrl
+} from './frames.js';
+
+const frames: FrameDescriptor[] = [
+  { id: 'main', name: '', url: 'https://shop.example/checkout', parentId: null },
+  {
+    id: 'pay',
+    name: 'payment',
+    url: 'https://js.stripe.com/v3/elements-inner.html#key=1',
+    parentId: 'main'
+  },
+  { id: 'card', name: 'card', url: 'https://js.stripe.com/v3/card.html', parentId: 'pay' },
+  { id: 'ads', name: '', url: 'https://ads.example/slot', parentId: 'main' }
+];
+
+describe('Frame helpers', () => {
+  describe('matchFrame', () => {
+    it('should match by ID, name or exact URL', () => {
+      expect(matchFrame(frames, 'card')?.id).toBe('card');
+      expect(matchFrame(frames, 'payment')?.id).toBe('pay');
+      expect(matchFrame(frames, 'https://ads.example/slot')?.id).toBe('ads');
+    });
+
+    it('should prefer IDs over names', () => {
+      const named = [...frames, { id: 'x', name: 'ads', url: 'about:blank', parentId: 'main' }];
+      expect(matchFrame(named, 'ads')?.id).toBe('ads');
+    });
+
+    it('should match URL globs', () => {
+      expect(matchFrame(frames, 'https://js.stripe.com/**')?.id).toBe('pay');
+      expect(matchFrame(frames, 'https://*.example/slot')?.id).toBe('ads');
+    });
+
+    it('should leave partial URLs to matchFrameUrl', () => {
+      expect(matchFrame(frames, 'js.stripe.com')).toBeNull();
+      expect(matchFrameUrl(frames, 'card.html')?.id).toBe('card');
+      expect(matchFrameUrl(frames, 'nothing')).toBeNull();
+    });
+  });
+
+  describe('buildFrameTree', () => {
+    it('should nest frames under the main frame', () => {
+      const tree = buildFrameTree(frames);
+      expect(tree?.id).toBe('main');
+      expect(tree?.children.map(child => child.id)).toEqual(['pay', 'ads']);
+      expect(tree?.children[0]?.children[0]).toEqual({
+        id: 'card',
+        name: 'card',
+        url: 'https://js.stripe.com/v3/card.html',
+        parentId: 'pay',
+        children: []
+      });
+    });
+
+    it('should return null without frames', () => {
+      expect(buildFrameTree([])).toBeNull();
+    });
+  });
+
+  describe('describeFrames', () => {
+    it('should list URLs with names', () => {
+      expect(describeFrames(frames.slice(2))).toBe(
+        'https://js.stripe.com/v3/card.html (card), https://ads.example/slot'
+      );
+    });
+  });
+});
@@ -0,0 +1,54 @@
+import type { FrameInfo } from '../types/index.js';
+import { globToRegExp } from './interception.js';
+
+export interface FrameDescriptor {
+  id: string;
+  name: string;
+  url: string;
+  parentId: string | null;
+}
+
+// Finds the frame a DOM tool should run in by ID, name or URL, in that order of precedence.
+// URLs match exactly first, then as a glob when the target has a `*`, then as a substring, so
+// "checkout.stripe.com" finds the payment iframe. Frames are searched in document order and
+// the first match wins. Returns null so the caller can try the target as an iframe selector.
+export function matchFrame<T extends FrameDescriptor>(frames: T[], target: string): T | null {
+  const exact =
+    frames.find(frame => frame.id === target) ??
+    frames.find(frame => frame.name === target) ??
+    frames.find(frame => frame.url === target);
+  if (exact) {
+    return exact;
+  }
+  if (target.includes('*')) {
+    const matcher = globToRegExp(target);
+    return frames.find(frame => matcher.test(frame.url)) ?? null;
+  }
+  return null;
+}
+
+export function matchFrameUrl<T extends FrameDescriptor>(frames: T[], target: string): T | null {
+  return frames.find(frame => frame.url.includes(target)) ?? null;
+}
+
+// Nests the flat frame list under the main frame, the one without a parent
+export function buildFrameTree(frames: FrameDescriptor[]): FrameInfo | null {
+  const nodes = new Map<string, FrameInfo>();
+  for (const { id, name, url, parentId } of frames) {
+    nodes.set(id, { id, name, url, parentId, children: [] });
+  }
+  let root: FrameInfo | null = null;
+  for (const node of nodes.values()) {
+    const parent = node.parentId ? nodes.get(node.parentId) : undefined;
+    if (parent) {
+      parent.children.push(node);
+    } else {
+      root ??= node;
+    }
+  }
+  return root;
+}
+
+export function describeFrames(frames: FrameDescriptor[]): string {
+  return frames.map(frame => (frame.name ? `${frame.url} (${frame.name})` : frame.url)).join(', ');
+}
@@ -0,0 +1,126 @@
+import { afterEach, describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import {
+  freezeFixedElements,
+  resolveFullPageOptions,
+  restoreFixedElements,
+  scrollThroughPage
+} from './fullpage.js';
+
+describe('Full page screenshots', () => {
+  const globals = globalThis as any;
+
+  // Boxes are in page coordinates, offset is where the containing block of a fixed element
+  // puts it once it is absolute
+  const fakeElement = (
+    position: string,
+    box: { top: number; left?: number; width?: number },
+    options: { style?: string; offset?: number } = {}
+  ) => {
+    const properties = new Map<string, string>();
+    const element = {
+      position,
+      attribute: options.style ?? null,
+      properties,
+      style: { setProperty: (name: string, value: string) => properties.set(name, value) },
+      getAttribute: () => element.attribute,
+      setAttribute: (_name: string, value: string) => {
+        element.attribute = value;
+        properties.clear();
+      },
+      removeAttribute: () => {
+        element.attribute = null;
+        properties.clear();
+      },
+      getBoundingClientRect: () => {
+        const absolute = properties.get('position') === 'absolute';
+        const offset = absolute ? (options.offset ?? 0) : 0;
+        return {
+          top: (absolute ? Number.parseFloat(properties.get('top')!) : box.top) + offset,
+          left: (absolute ? Number.parseFloat(properties.get('left')!) : (box.left ?? 0)) + offset,
+          width: box.width ?? 100
+        };
+      }
+    };
+    return element;
+  };
+
+  const fakePage = (elements: ReturnType<typeof fakeElement>[], scrollHeight = 3000) => {
+    const scrolls: number[] = [];
+    globals.innerHeight = 800;
+    globals.scrollY = 0;
+    globals.scrollX = 0;
+    globals.scrollTo = ({ top }: { top: number }) => {
+      globals.scrollY = top;
+      scrolls.push(top);
+    };
+    globals.scrollBy = ({ top }: { top: number }) => {
+      globals.scrollY = Math.min(globals.scrollY + top, scrollHeight - 800);
+      scrolls.push(globals.scrollY);
+    };
+    globals.getComputedStyle = (element: { position: string }) => ({
+      position: element.position
+    });
+    globals.document = {
+      documentElement: { scrollHeight },
+      querySelectorAll: () => elements
+    };
+    return scrolls;
+  };
+
+  afterEach(() => {
+    for (const key of ['innerHeight', 'scrollY', 'scrollX', 'scrollTo', 'scrollBy']) {
+      delete globals[key];
+    }
+    delete globals.getComputedStyle;
+    delete globals.document;
+  });
+
+  it('should freeze fixed elements by default and check the mode', () => {
+    expect(resolveFullPageOptions({})).toEqual({ fixedElements: 'freeze', loadLazyContent: true });
+    expect(resolveFullPageOptions({ fixedElements: 'keep', loadLazyContent: false })).toEqual({
+      fixedElements: 'keep',
+      loadLazyContent: false
+    });
+    expect(() => resolveFullPageOptions({ fixedElements: 'float' as any })).toThrow(BrowserError);
+  });
+
+  it('should scroll down a viewport at a time and back to the top', async () => {
+    const scrolls = fakePage([], 2000);
+    expect(await scrollThroughPage(0, 50)).toBe(2);
+    expect(scrolls).toEqual([800, 1200, 0]);
+
+    fakePage([], 100000);
+    expect(await scrollThroughPage(0, 3)).toBe(3);
+  });
+
+  it('should pin headers to the top and bars to the bottom of the page', () => {
+    const header = fakeElement('fixed', { top: 0 }, { offset: 10 });
+    const footer = fakeElement('fixed', { top: 740, left: 20 });
+    const sticky = fakeElement('sticky', { top: 100 }, { style: 'color: red' });
+    const plain = fakeElement('static', { top: 200 });
+    fakePage([header, footer, sticky, plain]);
+
+    expect(freezeFixedElements('freeze')).toBe(3);
+    expect(header.getBoundingClientRect().top).toBe(0);
+    expect(footer.getBoundingClientRect()).toMatchObject({ top: 2940, left: 20 });
+    expect(sticky.properties.get('position')).toBe('static');
+    expect(plain.properties.size).toBe(0);
+
+    globals.scrollY = 0;
+    restoreFixedElements({ x: 0, y: 500 });
+    expect(header.attribute).toBeNull();
+    expect(sticky.attribute).toBe('color: red');
+    expect(globals.scrollY).toBe(500);
+  });
+
+  it('should hide fixed and sticky elements in hide mode', () => {
+    const header = fakeElement('fixed', { top: 0 });
+    const sticky = fakeElement('sticky', { top: 100 });
+    fakePage([header, sticky]);
+
+    expect(freezeFixedElements('hide')).toBe(2);
+    expect(header.properties.get('visibility')).toBe('hidden');
+    expect(sticky.properties.get('visibility')).toBe('hidden');
+  });
+});
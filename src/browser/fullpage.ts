@@ -0,0 +1,109 @@
+import {
+  FIXED_ELEMENT_MODES,
+  type FixedElementMode,
+  InvalidArgumentError,
+  type ScreenshotRequest
+} from '../types/index.js';
+
+// Long enough for lazy images and infinite lists to start loading once scrolled into view
+export const LAZY_LOAD_DELAY = 100;
+// Pages that keep growing are captured as far as this many viewports down
+export const MAX_LAZY_LOAD_STEPS = 50;
+
+export function resolveFullPageOptions(request: ScreenshotRequest): {
+  fixedElements: FixedElementMode;
+  loadLazyContent: boolean;
+} {
+  const fixedElements = request.fixedElements ?? 'freeze';
+  if (!FIXED_ELEMENT_MODES.includes(fixedElements)) {
+    throw new InvalidArgumentError(
+      `fixedElements must be one of: ${FIXED_ELEMENT_MODES.join(', ')}`
+    );
+  }
+  return { fixedElements, loadLazyContent: request.loadLazyContent ?? true };
+}
+
+// The functions below run in the page
+
+// Scrolls down a viewport at a time so content that loads when it comes into view is there
+// for the capture, then back to the top. Stops at the end of the page, when scrolling gets
+// nowhere, or after maxSteps. Resolves with the number of steps taken.
+export async function scrollThroughPage(delay: number, maxSteps: number): Promise<number> {
+  const scope = globalThis as any;
+  const root = scope.document.scrollingElement ?? scope.document.documentElement;
+  let steps = 0;
+  while (steps < maxSteps && scope.scrollY + scope.innerHeight < root.scrollHeight) {
+    const before = scope.scrollY;
+    scope.scrollBy({ top: scope.innerHeight, behavior: 'instant' });
+    if (scope.scrollY === before) {
+      break;
+    }
+    steps++;
+    await new Promise(resolve => setTimeout(resolve, delay));
+  }
+  scope.scrollTo({ top: 0, left: 0, behavior: 'instant' });
+  return steps;
+}
+
+// Fixed elements turn absolute where they are with the page scrolled to the top, so a header
+// shows once above the content instead of over it. Those in the lower half of the viewport,
+// like cookie bars and footers, go to the bottom of the page. Sticky elements stay in their
+// place in the flow. hide hides both instead. restoreFixedElements undoes either.
+export function freezeFixedElements(mode: 'freeze' | 'hide'): number {
+  const scope = globalThis as any;
+  const { document, innerHeight } = scope;
+  const root = document.scrollingElement ?? document.documentElement;
+  scope.scrollTo({ top: 0, left: 0, behavior: 'instant' });
+
+  const frozen: Array<[any, string | null]> = [];
+  const fixed: Array<[any, { top: number; left: number; width: number }]> = [];
+  for (const element of Array.from(document.querySelectorAll('*')) as any[]) {
+    const { position } = scope.getComputedStyle(element);
+    if (position !== 'fixed' && position !== 'sticky') {
+      continue;
+    }
+    frozen.push([element, element.getAttribute('style')]);
+    if (mode === 'hide') {
+      element.style.setProperty('visibility', 'hidden', 'important');
+    } else if (position === 'sticky') {
+      element.style.setProperty('position', 'static', 'important');
+    } else {
+      const rect = element.getBoundingClientRect();
+      const top =
+        rect.top >= innerHeight / 2 ? root.scrollHeight - (innerHeight - rect.top) : rect.top;
+      fixed.push([element, { top, left: rect.left, width: rect.width }]);
+    }
+  }
+
+  // Measured before any of them moves, then corrected for the box they are positioned in
+  for (const [element, target] of fixed) {
+    const { style } = element;
+    style.setProperty('position', 'absolute', 'important');
+    style.setProperty('top', `${target.top}px`, 'important');
+    style.setProperty('left', `${target.left}px`, 'important');
+    style.setProperty('bottom', 'auto', 'important');
+    style.setProperty('right', 'auto', 'important');
+    style.setProperty('width', `${target.width}px`, 'important');
+    const rect = element.getBoundingClientRect();
+    style.setProperty('top', `${2 * target.top - rect.top}px`, 'important');
+    style.setProperty('left', `${2 * target.left - rect.left}px`, 'important');
+  }
+
+  // kept on the page for restoreFixedElements, module constants don't reach the page
+  scope.__pcsFrozenElements = frozen;
+  return frozen.length;
+}
+
+export function restoreFixedElements(scroll: { x: number; y: number }): void {
+  const scope = globalThis as any;
+  const frozen: Array<[any, string | null]> = scope.__pcsFrozenElements ?? [];
+  for (const [element, style] of frozen) {
+    if (style === null) {
+      element.removeAttribute('style');
+    } else {
+      element.setAttribute('style', style);
+    }
+  }
+  delete scope.__pcsFrozenElements;
+  scope.scrollTo({ top: scroll.y, left: scroll.x, behavior: 'instant' });
+}
@@ -0,0 +1,114 @@
+import { describe, expect, it } from 'vitest';
+import {
+  isTextMimeType,
+  toHarContent,
+  toHarHeaders,
+  toHarQueryString,
+  toHarTimings,
+  totalTime
+} from './har.js';
+
+describe('HAR helpers', () => {
+  describe('toHarHeaders', () => {
+    it('should split repeated headers joined with newlines', () => {
+      expect(toHarHeaders({ 'set-cookie': 'a=1\nb=2', 'content-type': 'text/html' })).toEqual([
+        { name: 'set-cookie', value: 'a=1' },
+        { name: 'set-cookie', value: 'b=2' },
+        { name: 'content-type', value: 'text/html' }
+      ]);
+    });
+  });
+
+  describe('toHarQueryString', () => {
+    it('should list query parameters in order', () => {
+      expect(toHarQueryString('https://example.com/?q=shoes&page=2')).toEqual([
+        { name: 'q', value: 'shoes' },
+        { name: 'page', value: '2' }
+      ]);
+    });
+  });
+
+  describe('toHarTimings', () => {
+    it('should convert resource timing phases', () => {
+      const timings = toHarTimings(
+        {
+          dnsStart: 1,
+          dnsEnd: 5,
+          connectStart: 5,
+          connectEnd: 20,
+          sslStart: 10,
+          sslEnd: 20,
+          sendStart: 21,
+          sendEnd: 22,
+          receiveHeadersEnd: 50
+        },
+        80
+      );
+      expect(timings).toEqual({
+        blocked: 1,
+        dns: 4,
+        connect: 15,
+        ssl: 10,
+        send: 1,
+        wait: 28,
+        receive: 30
+      });
+      expect(totalTime(timings)).toBe(79);
+    });
+
+    it('should mark skipped phases with -1', () => {
+      const timings = toHarTimings(
+        {
+          dnsStart: -1,
+          dnsEnd: -1,
+          connectStart: -1,
+          connectEnd: -1,
+          sslStart: -1,
+          sslEnd: -1,
+          sendStart: 2,
+          sendEnd: 3,
+          receiveHeadersEnd: 10
+        },
+        12
+      );
+      expect(timings.dns).toBe(-1);
+      expect(timings.connect).toBe(-1);
+      expect(timings.blocked).toBe(2);
+    });
+
+    it('should attribute everything to wait without timing data', () => {
+      expect(totalTime(toHarTimings(null, 42))).toBe(42);
+    });
+  });
+
+  describe('toHarContent', () => {
+    it('should keep text bodies as text', () => {
+      expect(toHarContent(Buffer.from('{"ok":true}'), 'application/json', 1024)).toEqual({
+        size: 11,
+        mimeType: 'application/json',
+        text: '{"ok":true}'
+      });
+    });
+
+    it('should base64 encode binary bodies', () => {
+      const content = toHarContent(Buffer.from([0xff, 0xd8]), 'image/jpeg', 1024);
+      expect(content.encoding).toBe('base64');
+      expect(content.text).toBe('/9g=');
+    });
+
+    it('should truncate bodies over the cap and flag them', () => {
+      const content = toHarContent(Buffer.from('abcdef'), 'text/plain', 3);
+      expect(content).toEqual({ size: 6, mimeType: 'text/plain', text: 'abc', _truncated: true });
+    });
+  });
+
+  describe('isTextMimeType', () => {
+    it('should recognize textual types', () => {
+      expect(isTextMimeType('text/html; charset=utf-8')).toBe(true);
+      expect(isTextMimeType('application/ld+json')).toBe(true);
+      expect(isTextMimeType('application/javascript')).toBe(true);
+      expect(isTextMimeType('image/svg+xml')).toBe(true);
+      expect(isTextMimeType('font/woff2')).toBe(false);
+    });
+  });
+});
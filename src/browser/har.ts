@@ -0,0 +1,343 @@
+import type { HTTPRequest, Page } from 'puppeteer-core';
+import { VERSION } from '../config/index.js';
+
+// Subsets of the HAR 1.2 format (http://www.softwareishard.com/blog/har-12-spec/) that the
+// recorder fills in. Custom fields are prefixed with an underscore, as the spec requires.
+export interface HarHeader {
+  name: string;
+  value: string;
+}
+
+export interface HarTimings {
+  blocked: number;
+  dns: number;
+  connect: number;
+  ssl: number;
+  send: number;
+  wait: number;
+  receive: number;
+}
+
+export interface HarContent {
+  size: number;
+  mimeType: string;
+  text?: string;
+  encoding?: 'base64';
+  _truncated?: boolean;
+}
+
+export interface HarEntry {
+  pageref: string;
+  startedDateTime: string;
+  time: number;
+  request: {
+    method: string;
+    url: string;
+    httpVersion: string;
+    cookies: [];
+    headers: HarHeader[];
+    queryString: HarHeader[];
+    postData?: { mimeType: string; text: string };
+    headersSize: -1;
+    bodySize: number;
+  };
+  response: {
+    status: number;
+    statusText: string;
+    httpVersion: string;
+    cookies: [];
+    headers: HarHeader[];
+    content: HarContent;
+    redirectURL: string;
+    headersSize: -1;
+    bodySize: number;
+    _error?: string;
+  };
+  cache: Record<string, never>;
+  timings: HarTimings;
+  serverIPAddress?: string;
+  _resourceType: string;
+  _fromCache?: boolean;
+}
+
+export interface HarPage {
+  startedDateTime: string;
+  id: string;
+  title: string;
+  pageTimings: Record<string, never>;
+}
+
+export interface Har {
+  log: {
+    version: '1.2';
+    creator: { name: string; version: string };
+    pages: HarPage[];
+    entries: HarEntry[];
+  };
+}
+
+export interface HarOptions {
+  captureBodies: boolean;
+  maxBodySize: number; // bytes kept per response body
+}
+
+// Protocol.Network.ResourceTiming: requestTime is in seconds, the rest are millisecond
+// offsets from it and -1 when the phase did not happen
+export interface ResourceTiming {
+  dnsStart: number;
+  dnsEnd: number;
+  connectStart: number;
+  connectEnd: number;
+  sslStart: number;
+  sslEnd: number;
+  sendStart: number;
+  sendEnd: number;
+  receiveHeadersEnd: number;
+}
+
+export const DEFAULT_MAX_BODY_SIZE = 1024 * 1024;
+
+// Puppeteer joins repeated response headers with newlines
+export function toHarHeaders(headers: Record<string, string>): HarHeader[] {
+  return Object.entries(headers).flatMap(([name, value]) =>
+    String(value)
+      .split('\n')
+      .map(part => ({ name, value: part }))
+  );
+}
+
+export function toHarQueryString(url: string): HarHeader[] {
+  try {
+    return Array.from(new URL(url).searchParams, ([name, value]) => ({ name, value }));
+  } catch {
+    return [];
+  }
+}
+
+// totalMs is the wall-clock time from request to completion, which bounds the receive phase
+export function toHarTimings(timing: ResourceTiming | null, totalMs: number): HarTimings {
+  if (!timing) {
+    return { blocked: -1, dns: -1, connect: -1, ssl: -1, send: 0, wait: totalMs, receive: 0 };
+  }
+
+  const phase = (start: number, end: number) => (start >= 0 && end >= start ? end - start : -1);
+  const firstStart = [timing.dnsStart, timing.connectStart, timing.sendStart].find(t => t >= 0);
+  return {
+    blocked: firstStart ?? -1,
+    dns: phase(timing.dnsStart, timing.dnsEnd),
+    connect: phase(timing.connectStart, timing.connectEnd),
+    ssl: phase(timing.sslStart, timing.sslEnd),
+    send: Math.max(timing.sendEnd - timing.sendStart, 0),
+    wait: Math.max(timing.receiveHeadersEnd - timing.sendEnd, 0),
+    receive: Math.max(totalMs - timing.receiveHeadersEnd, 0)
+  };
+}
+
+// ssl is already part of connect, so it is left out of the total
+export function totalTime(timings: HarTimings): number {
+  const { blocked, dns, connect, send, wait, receive } = timings;
+  return [blocked, dns, connect, send, wait, receive]
+    .filter(value => value > 0)
+    .reduce((sum, value) => sum + value, 0);
+}
+
+export function isTextMimeType(mimeType: string): boolean {
+  return /^text\/|[/+](json|xml|javascript|ecmascript)|x-www-form-urlencoded|svg/i.test(mimeType);
+}
+
+export function toHarContent(body: Buffer, mimeType: string, maxBodySize: number): HarContent {
+  const content: HarContent = { size: body.length, mimeType };
+  const kept = body.length > maxBodySize ? body.subarray(0, maxBodySize) : body;
+  if (isTextMimeType(mimeType)) {
+    content.text = kept.toString('utf8');
+  } else {
+    content.text = kept.toString('base64');
+    content.encoding = 'base64';
+  }
+  if (kept.length < body.length) {
+    content._truncated = true;
+  }
+  return content;
+}
+
+interface PendingRequest {
+  entry: HarEntry;
+  startedAt: number;
+}
+
+// Records the network activity of one or more pages. Response bodies are read as requests
+// finish, so toHar waits for reads that are still in flight.
+export class HarRecorder {
+  private pages: Array<{ page: Page; info: HarPage }> = [];
+  private entries: HarEntry[] = [];
+  private pending: Map<HTTPRequest, PendingRequest> = new Map();
+  private bodyReads: Set<Promise<void>> = new Set();
+  private detachers: Array<() => void> = [];
+  private options: HarOptions;
+
+  constructor(options: HarOptions) {
+    this.options = options;
+  }
+
+  attach(page: Page, pageId: string): void {
+    this.pages.push({
+      page,
+      info: {
+        startedDateTime: new Date().toISOString(),
+        id: pageId,
+        title: page.url(),
+        pageTimings: {}
+      }
+    });
+
+    const onRequest = (request: HTTPRequest) => this.onRequest(request, pageId);
+    const onFinished = (request: HTTPRequest) => this.onFinished(request);
+    const onFailed = (request: HTTPRequest) => this.onFailed(request);
+    page.on('request', onRequest);
+    page.on('requestfinished', onFinished);
+    page.on('requestfailed', onFailed);
+    this.detachers.push(() => {
+      page.off('request', onRequest);
+      page.off('requestfinished', onFinished);
+      page.off('requestfailed', onFailed);
+    });
+  }
+
+  detach(): void {
+    for (const detach of this.detachers) {
+      detach();
+    }
+    this.detachers = [];
+  }
+
+  get entryCount(): number {
+    return this.entries.length;
+  }
+
+  async toHar(): Promise<Har> {
+    await Promise.allSettled(Array.from(this.bodyReads));
+
+    const pages = this.pages.map(({ page, info }) => ({
+      ...info,
+      title: page.isClosed() ? info.title : page.url()
+    }));
+    const entries = [...this.entries].sort((a, b) =>
+      a.startedDateTime.localeCompare(b.startedDateTime)
+    );
+    return {
+      log: {
+        version: '1.2',
+        creator: { name: 'puppeteer-command-server', version: VERSION },
+        pages,
+        entries
+      }
+    };
+  }
+
+  private onRequest(request: HTTPRequest, pageId: string): void {
+    const headers = request.headers();
+    const postData = request.postData();
+    const entry: HarEntry = {
+      pageref: pageId,
+      startedDateTime: new Date().toISOString(),
+      time: 0,
+      request: {
+        method: request.method(),
+        url: request.url(),
+        httpVersion: '',
+        cookies: [],
+        headers: toHarHeaders(headers),
+        queryString: toHarQueryString(request.url()),
+        headersSize: -1,
+        bodySize: postData ? Buffer.byteLength(postData) : 0
+      },
+      response: {
+        status: 0,
+        statusText: '',
+        httpVersion: '',
+        cookies: [],
+        headers: [],
+        content: { size: 0, mimeType: '' },
+        redirectURL: '',
+        headersSize: -1,
+        bodySize: -1
+      },
+      cache: {},
+      timings: toHarTimings(null, 0),
+      _resourceType: request.resourceType()
+    };
+    if (postData) {
+      entry.request.postData = {
+        mimeType: headers['content-type'] ?? '',
+        text: postData
+      };
+    }
+    this.pending.set(request, { entry, startedAt: Date.now() });
+  }
+
+  private onFinished(request: HTTPRequest): void {
+    const pending = this.take(request);
+    if (!pending) {
+      return;
+    }
+
+    const { entry } = pending;
+    const response = request.response();
+    if (response) {
+      const headers = response.headers();
+      const length = Number(headers['content-length']);
+      entry.response = {
+        ...entry.response,
+        status: response.status(),
+        statusText: response.statusText(),
+        headers: toHarHeaders(headers),
+        content: { size: 0, mimeType: headers['content-type'] ?? '' },
+        redirectURL: headers['location'] ?? '',
+        bodySize: Number.isFinite(length) ? length : -1
+      };
+      const ip = response.remoteAddress().ip;
+      if (ip) entry.serverIPAddress = ip;
+      if (response.fromCache()) entry._fromCache = true;
+    }
+    entry.timings = toHarTimings(response?.timing() ?? null, Date.now() - pending.startedAt);
+    entry.time = totalTime(entry.timings);
+    this.entries.push(entry);
+
+    const isRedirect = entry.response.status >= 300 && entry.response.status < 400;
+    if (response && this.options.captureBodies && !isRedirect) {
+      const read = response
+        .buffer()
+        .then(body => {
+          entry.response.content = toHarContent(
+            body,
+            entry.response.content.mimeType,
+            this.options.maxBodySize
+          );
+        })
+        .catch(() => {
+          // bodies of evicted or streamed resources are not available, headers still are
+        })
+        .finally(() => this.bodyReads.delete(read));
+      this.bodyReads.add(read);
+    }
+  }
+
+  private onFailed(request: HTTPRequest): void {
+    const pending = this.take(request);
+    if (!pending) {
+      return;
+    }
+
+    const { entry } = pending;
+    entry.response._error = request.failure()?.errorText ?? 'Request failed';
+    entry.timings = toHarTimings(null, Date.now() - pending.startedAt);
+    entry.time = totalTime(entry.timings);
+    this.entries.push(entry);
+  }
+
+  private take(request: HTTPRequest): PendingRequest | undefined {
+    const pending = this.pending.get(request);
+    this.pending.delete(request);
+    return pending;
+  }
+}
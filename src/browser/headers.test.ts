@@ -0,0 +1,50 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { validateBasicAuth, validateHeaders } from './headers.js';
+
+describe('Header helpers', () => {
+  describe('validateHeaders', () => {
+    it('should keep custom headers as given', () => {
+      expect(
+        validateHeaders({ Authorization: 'Bearer abc', 'X-Feature-Flag': 'new-checkout' })
+      ).toEqual({
+        headers: { Authorization: 'Bearer abc', 'X-Feature-Flag': 'new-checkout' },
+        warnings: []
+      });
+    });
+
+    it('should leave out restricted headers with a warning', () => {
+      const result = validateHeaders({ Host: 'example.com', cookie: 'a=1', 'X-Env': 'staging' });
+      expect(result).toEqual({
+        headers: { 'X-Env': 'staging' },
+        warnings: [
+          'Host was ignored: it is derived from the request URL',
+          'cookie was ignored: it comes from the cookie jar, set cookies on the session instead'
+        ]
+      });
+    });
+
+    it('should reject invalid names and values', () => {
+      expect(() => validateHeaders({ 'X Env': 'a' })).toThrow(BrowserError);
+      expect(() => validateHeaders({ 'X-Env': 'a\r\nHost: evil' })).toThrow(/single-line/);
+      expect(() => validateHeaders({ 'X-Env': 1 as any })).toThrow(/single-line/);
+      expect(() => validateHeaders({ 'X-Env': 'a', 'x-env': 'b' })).toThrow(/more than once/);
+      expect(() => validateHeaders(['X-Env'] as any)).toThrow(/must be an object/);
+    });
+  });
+
+  describe('validateBasicAuth', () => {
+    it('should accept a username and password', () => {
+      expect(validateBasicAuth({ username: 'qa', password: '' })).toEqual({
+        username: 'qa',
+        password: ''
+      });
+    });
+
+    it('should reject missing or malformed credentials', () => {
+      expect(() => validateBasicAuth({} as any)).toThrow(/username is required/);
+      expect(() => validateBasicAuth({ username: 'a:b', password: 'x' })).toThrow(/colon/);
+      expect(() => validateBasicAuth({ username: 'qa' } as any)).toThrow(/password/);
+    });
+  });
+});
@@ -0,0 +1,70 @@
+import { type BasicAuthRequest, BrowserError, InvalidArgumentError } from '../types/index.js';
+
+// RFC 9110 token characters
+const HEADER_NAME = /^[!#$%&'*+\-.^_`|~0-9A-Za-z]+$/;
+
+// Headers Chrome's network stack sets itself, so an override never reaches the server
+const RESTRICTED_HEADERS: Record<string, string> = {
+  'accept-encoding': 'the browser negotiates it',
+  connection: 'the browser manages it',
+  'content-length': 'it is computed from the request body',
+  cookie: 'it comes from the cookie jar, set cookies on the session instead',
+  expect: 'the browser manages it',
+  host: 'it is derived from the request URL',
+  'keep-alive': 'the browser manages it',
+  'proxy-connection': 'the browser manages it',
+  te: 'the browser manages it',
+  trailer: 'the browser manages it',
+  'transfer-encoding': 'the browser manages it',
+  upgrade: 'the browser manages it'
+};
+
+// Validates headers to send with every request. Restricted headers are left out with a
+// warning rather than failing the whole call.
+export function validateHeaders(headers: Record<string, string>): {
+  headers: Record<string, string>;
+  warnings: string[];
+} {
+  if (typeof headers !== 'object' || headers === null || Array.isArray(headers)) {
+    throw new InvalidArgumentError('headers must be an object of header names to values');
+  }
+
+  const accepted: Record<string, string> = {};
+  const seen = new Set<string>();
+  const warnings: string[] = [];
+  for (const [name, value] of Object.entries(headers)) {
+    if (!HEADER_NAME.test(name)) {
+      throw new InvalidArgumentError(`Invalid header name: ${JSON.stringify(name)}`);
+    }
+    if (typeof value !== 'string' || /[\r\n\0]/.test(value)) {
+      throw new InvalidArgumentError(`Header ${name} must be a single-line string`);
+    }
+    const lower = name.toLowerCase();
+    if (seen.has(lower)) {
+      throw new BrowserError(`Header ${name} is set more than once`);
+    }
+    seen.add(lower);
+
+    const reason = RESTRICTED_HEADERS[lower];
+    if (reason) {
+      warnings.push(`${name} was ignored: ${reason}`);
+    } else {
+      accepted[name] = value;
+    }
+  }
+  return { headers: accepted, warnings };
+}
+
+export function validateBasicAuth(request: BasicAuthRequest): BasicAuthRequest {
+  if (typeof request.username !== 'string' || !request.username) {
+    throw new InvalidArgumentError('username is required');
+  }
+  // RFC 7617 joins the pair with a colon, so the user ID cannot contain one
+  if (request.username.includes(':')) {
+    throw new BrowserError('username cannot contain a colon');
+  }
+  if (typeof request.password !== 'string') {
+    throw new InvalidArgumentError('password must be a string');
+  }
+  return { username: request.username, password: request.password };
+}
@@ -0,0 +1,40 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { compileInitScript, describeInitScript } from './initscripts.js';
+
+describe('Init script helpers', () => {
+  describe('compileInitScript', () => {
+    it('should keep the source as it is', () => {
+      const script = compileInitScript('s1', {
+        script: 'var flags = { beta: true };',
+        name: 'flags'
+      });
+
+      expect(script).toEqual({
+        id: 's1',
+        name: 'flags',
+        size: 27,
+        source: 'var flags = { beta: true };'
+      });
+      expect(describeInitScript(script)).toEqual({ id: 's1', name: 'flags', size: 27 });
+    });
+
+    it('should require a script', () => {
+      expect(() => compileInitScript('s1', { script: '  ' })).toThrow('script is required');
+      expect(() => compileInitScript('s1', {} as any)).toThrow('script is required');
+    });
+
+    it('should reject syntax errors without running the script', () => {
+      expect(() => compileInitScript('s1', { script: 'window.x = ;' })).toThrow(
+        'Invalid init script: SyntaxError'
+      );
+      expect(() => compileInitScript('s1', { script: 'throw new Error("boom")' })).not.toThrow();
+    });
+
+    it('should reject a name that is no string', () => {
+      expect(() => compileInitScript('s1', { script: 'x()', name: 1 as any })).toThrow(
+        BrowserError
+      );
+    });
+  });
+});
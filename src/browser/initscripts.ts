@@ -0,0 +1,32 @@
+import { Script } from 'node:vm';
+import {
+  type AddInitScriptRequest,
+  type InitScriptInfo,
+  InvalidArgumentError
+} from '../types/index.js';
+
+export interface InitScript extends InitScriptInfo {
+  source: string;
+}
+
+// Compiled, not run, so a syntax error fails the call instead of every page silently. The
+// source is kept as it is: top-level declarations become globals of the page, like those of
+// a classic <script>.
+export function compileInitScript(id: string, request: AddInitScriptRequest): InitScript {
+  if (typeof request.script !== 'string' || !request.script.trim()) {
+    throw new InvalidArgumentError('script is required');
+  }
+  if (request.name !== undefined && typeof request.name !== 'string') {
+    throw new InvalidArgumentError('name must be a string');
+  }
+  try {
+    new Script(request.script, { filename: request.name ?? 'init script' });
+  } catch (error) {
+    throw new InvalidArgumentError(`Invalid init script: ${error}`);
+  }
+  return { id, name: request.name ?? null, size: request.script.length, source: request.script };
+}
+
+export function describeInitScript(script: InitScript): InitScriptInfo {
+  return { id: script.id, name: script.name, size: script.size };
+}
@@ -0,0 +1,21 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { toPropertyPath, validateAttributeName } from './inspect.js';
+
+describe('Element inspection', () => {
+  it('should accept attribute names HTML allows', () => {
+    expect(validateAttributeName('aria-expanded')).toBe('aria-expanded');
+    expect(validateAttributeName('data-test_id')).toBe('data-test_id');
+    expect(() => validateAttributeName('')).toThrow(/is required/);
+    expect(() => validateAttributeName('a b')).toThrow(BrowserError);
+    expect(() => validateAttributeName('x="1"')).toThrow(/Invalid attribute name/);
+  });
+
+  it('should split property paths into identifiers', () => {
+    expect(toPropertyPath('checked')).toEqual(['checked']);
+    expect(toPropertyPath('validity.valid')).toEqual(['validity', 'valid']);
+    expect(() => toPropertyPath(undefined)).toThrow(/is required/);
+    expect(() => toPropertyPath('value.')).toThrow(/dotted path/);
+    expect(() => toPropertyPath('constructor["x"]')).toThrow(BrowserError);
+  });
+});
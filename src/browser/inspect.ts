@@ -0,0 +1,32 @@
+import { InvalidArgumentError } from '../types/index.js';
+
+// Characters HTML does not allow in attribute names
+const INVALID_ATTRIBUTE_NAME = /[\s"'>/=]/;
+
+const IDENTIFIER = /^[A-Za-z_$][\w$]*$/;
+
+export function validateAttributeName(name: string | undefined): string {
+  if (!name) {
+    throw new InvalidArgumentError('Attribute name is required');
+  }
+  if (INVALID_ATTRIBUTE_NAME.test(name)) {
+    throw new InvalidArgumentError(`Invalid attribute name ${JSON.stringify(name)}`);
+  }
+  return name;
+}
+
+// A property of the element, or a dotted path into the objects it holds such as
+// validity.valid or dataset.userId. Only identifiers are allowed, so reading it runs no code
+// of the caller's.
+export function toPropertyPath(name: string | undefined): string[] {
+  if (!name) {
+    throw new InvalidArgumentError('Property name is required');
+  }
+  const path = name.split('.');
+  if (!path.every(key => IDENTIFIER.test(key))) {
+    throw new InvalidArgumentError(
+      `Invalid property name ${JSON.stringify(name)}, expected a dotted path such as validity.valid`
+    );
+  }
+  return path;
+}
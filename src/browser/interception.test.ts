@@ -0,0 +1,66 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { compileRoute, findRoute, globToRegExp } from './interception.js';
+
+describe('Interception helpers', () => {
+  describe('globToRegExp', () => {
+    it('should match a single path segment with *', () => {
+      const pattern = globToRegExp('https://example.com/*/users');
+      expect(pattern.test('https://example.com/api/users')).toBe(true);
+      expect(pattern.test('https://example.com/api/v1/users')).toBe(false);
+    });
+
+    it('should match across segments with **', () => {
+      expect(globToRegExp('**/analytics/**').test('https://cdn.example.com/analytics/a.js')).toBe(
+        true
+      );
+    });
+
+    it('should keep ? and dots literal', () => {
+      const pattern = globToRegExp('https://example.com/search?q=*');
+      expect(pattern.test('https://example.com/search?q=shoes')).toBe(true);
+      expect(pattern.test('https://exampleXcom/search?q=shoes')).toBe(false);
+    });
+
+    it('should support alternatives', () => {
+      const pattern = globToRegExp('**/*.{png,jpg}');
+      expect(pattern.test('https://example.com/a.jpg')).toBe(true);
+      expect(pattern.test('https://example.com/a.gif')).toBe(false);
+    });
+  });
+
+  describe('compileRoute', () => {
+    it('should default to fulfilling with an empty 200 response', () => {
+      const route = compileRoute('1', { url: '**/api' });
+      expect(route.action).toBe('fulfill');
+      expect(route.response).toEqual({ status: 200, headers: {}, body: '' });
+    });
+
+    it('should send non-string bodies as JSON', () => {
+      const route = compileRoute('1', { url: '**/api', body: { ok: true } });
+      expect(route.response.body).toBe('{"ok":true}');
+      expect(route.response.contentType).toBe('application/json');
+    });
+
+    it('should reject invalid regular expressions', () => {
+      expect(() => compileRoute('1', { url: '(', regex: true })).toThrow(BrowserError);
+    });
+  });
+
+  describe('findRoute', () => {
+    it('should prefer the most recently added route', () => {
+      const routes = [
+        compileRoute('block', { url: '**', action: 'block' }),
+        compileRoute('mock', { url: '**/api/**' })
+      ];
+      expect(findRoute(routes, 'https://example.com/api/users', 'GET')?.id).toBe('mock');
+      expect(findRoute(routes, 'https://example.com/logo.png', 'GET')?.id).toBe('block');
+    });
+
+    it('should honor the method matcher', () => {
+      const routes = [compileRoute('post', { url: '**/api', method: 'post' })];
+      expect(findRoute(routes, 'https://example.com/api', 'POST')?.id).toBe('post');
+      expect(findRoute(routes, 'https://example.com/api', 'GET')).toBe(null);
+    });
+  });
+});
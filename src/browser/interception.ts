@@ -0,0 +1,113 @@
+import {
+  InvalidArgumentError,
+  type MockRouteRequest,
+  ROUTE_ACTIONS,
+  type RouteInfo
+} from '../types/index.js';
+
+export interface RouteRule extends RouteInfo {
+  matcher: RegExp;
+  response: MockResponse;
+}
+
+export interface MockResponse {
+  status: number;
+  headers: Record<string, string>;
+  contentType?: string;
+  body: string;
+}
+
+// Converts a URL glob to an anchored regular expression. `**` matches anything, `*` matches
+// anything but a slash and `{a,b}` matches either alternative. `?` stays literal because it
+// is far more common in URLs as the query separator than as a wildcard.
+export function globToRegExp(glob: string): RegExp {
+  let source = '';
+  let inGroup = false;
+  for (let i = 0; i < glob.length; i++) {
+    const char = glob[i] as string;
+    if (char === '*') {
+      if (glob[i + 1] === '*') {
+        source += '.*';
+        i++;
+      } else {
+        source += '[^/]*';
+      }
+    } else if (char === '{') {
+      inGroup = true;
+      source += '(?:';
+    } else if (char === '}' && inGroup) {
+      inGroup = false;
+      source += ')';
+    } else if (char === ',' && inGroup) {
+      source += '|';
+    } else {
+      source += char.replace(/[.+?^${}()|[\]\\/]/g, '\\$&');
+    }
+  }
+  return new RegExp(`^${source}$`);
+}
+
+export function compileRoute(id: string, request: MockRouteRequest): RouteRule {
+  if (!request.url) {
+    throw new InvalidArgumentError('A URL pattern is required');
+  }
+
+  const action = request.action ?? 'fulfill';
+  if (!ROUTE_ACTIONS.includes(action)) {
+    throw new InvalidArgumentError(`Route action must be one of: ${ROUTE_ACTIONS.join(', ')}`);
+  }
+
+  let matcher: RegExp;
+  try {
+    matcher = request.regex ? new RegExp(request.url) : globToRegExp(request.url);
+  } catch (error) {
+    throw new InvalidArgumentError(`Invalid URL pattern ${request.url}: ${error}`);
+  }
+
+  const isJson = request.body !== undefined && typeof request.body !== 'string';
+  const response: MockResponse = {
+    status: request.status ?? 200,
+    headers: request.headers ?? {},
+    body: isJson ? JSON.stringify(request.body) : ((request.body as string | undefined) ?? '')
+  };
+  const contentType = request.contentType ?? (isJson ? 'application/json' : undefined);
+  if (contentType) {
+    response.contentType = contentType;
+  }
+
+  return {
+    id,
+    url: request.url,
+    regex: request.regex ?? false,
+    method: request.method ? request.method.toUpperCase() : null,
+    action,
+    hits: 0,
+    matcher,
+    response
+  };
+}
+
+// The most recently added route wins so a specific mock can override a broad block
+export function findRoute(routes: RouteRule[], url: string, method: string): RouteRule | null {
+  for (let i = routes.length - 1; i >= 0; i--) {
+    const route = routes[i] as RouteRule;
+    if (route.method && route.method !== method.toUpperCase()) {
+      continue;
+    }
+    if (route.matcher.test(url)) {
+      return route;
+    }
+  }
+  return null;
+}
+
+export function describeRoute(route: RouteRule): RouteInfo {
+  return {
+    id: route.id,
+    url: route.url,
+    regex: route.regex,
+    method: route.method,
+    action: route.action,
+    hits: route.hits
+  };
+}
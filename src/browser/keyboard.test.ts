@@ -0,0 +1,52 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { parseKeyCombination, resolveKeyStroke, toKeyInput } from './keyboard.js';
+
+describe('Keyboard', () => {
+  it('should spell key names the way Puppeteer does', () => {
+    expect(toKeyInput('Enter')).toBe('Enter');
+    expect(toKeyInput('arrowdown')).toBe('ArrowDown');
+    expect(toKeyInput('f12')).toBe('F12');
+    expect(toKeyInput('Numpad7')).toBe('Numpad7');
+    expect(toKeyInput('Esc')).toBe('Escape');
+    expect(toKeyInput('Ctrl')).toBe('Control');
+    expect(toKeyInput('a')).toBe('a');
+    expect(toKeyInput('A')).toBe('A');
+  });
+
+  it('should reject unknown keys with a suggestion', () => {
+    expect(() => toKeyInput('Entr')).toThrow(BrowserError);
+    expect(() => toKeyInput('Entr')).toThrow(/Did you mean Enter\?/);
+    expect(() => toKeyInput('Hyper')).toThrow(/expected a key name/);
+    expect(() => toKeyInput('F25')).toThrow(/Unknown key "F25"/);
+  });
+
+  it('should split shortcuts into keys', () => {
+    expect(parseKeyCombination('Control+Shift+K')).toEqual(['Control', 'Shift', 'K']);
+    expect(parseKeyCombination('Control++')).toEqual(['Control', '+']);
+    expect(parseKeyCombination('+')).toEqual(['+']);
+    expect(() => parseKeyCombination('')).toThrow(/is required/);
+    expect(() => parseKeyCombination('Control++A')).toThrow(/Invalid key combination/);
+  });
+
+  it('should only hold modifiers for a shortcut', () => {
+    expect(resolveKeyStroke({ key: 'Escape' })).toEqual({
+      action: 'press',
+      keys: ['Escape'],
+      delay: 0,
+      commands: []
+    });
+    expect(() => resolveKeyStroke({ key: 'a+b' })).toThrow(/Invalid shortcut/);
+    expect(resolveKeyStroke({ key: 'a+b', action: 'down' }).keys).toEqual(['a', 'b']);
+    expect(() => resolveKeyStroke({ key: 'a', action: 'tap' as never })).toThrow(/one of: /);
+    expect(() => resolveKeyStroke({ key: 'a', delay: -1 })).toThrow(/delay/);
+  });
+
+  it('should send the editing command of common shortcuts', () => {
+    expect(resolveKeyStroke({ key: 'Control+A' }).commands).toEqual(['SelectAll']);
+    expect(resolveKeyStroke({ key: 'Meta+KeyV' }).commands).toEqual(['Paste']);
+    expect(resolveKeyStroke({ key: 'Control+Shift+z' }).commands).toEqual(['Redo']);
+    expect(resolveKeyStroke({ key: 'Shift+A' }).commands).toEqual([]);
+    expect(resolveKeyStroke({ key: 'Control+A', action: 'down' }).commands).toEqual([]);
+  });
+});
@@ -0,0 +1,227 @@
+import type { KeyInput } from 'puppeteer-core';
+import {
+  InvalidArgumentError,
+  KEYBOARD_ACTIONS,
+  type KeyboardAction,
+  type KeyboardRequest
+} from '../types/index.js';
+
+// Puppeteer knows the key and code of every name in its US keyboard layout, these are the
+// names of that layout which are not a single character
+const range = (prefix: string, from: number, to: number) =>
+  Array.from({ length: to - from + 1 }, (_, i) => `${prefix}${from + i}`);
+
+const MODIFIER_KEYS = [
+  'Shift',
+  'ShiftLeft',
+  'ShiftRight',
+  'Control',
+  'ControlLeft',
+  'ControlRight',
+  'Alt',
+  'AltLeft',
+  'AltRight',
+  'AltGraph',
+  'Meta',
+  'MetaLeft',
+  'MetaRight'
+];
+
+const NAMED_KEYS = [
+  ...MODIFIER_KEYS,
+  'Enter',
+  'Tab',
+  'Space',
+  'Backspace',
+  'Delete',
+  'Escape',
+  'Insert',
+  'Home',
+  'End',
+  'PageUp',
+  'PageDown',
+  'ArrowUp',
+  'ArrowDown',
+  'ArrowLeft',
+  'ArrowRight',
+  'CapsLock',
+  'NumLock',
+  'ScrollLock',
+  'Pause',
+  'PrintScreen',
+  'ContextMenu',
+  'Help',
+  ...range('F', 1, 24),
+  ...range('Digit', 0, 9),
+  ...'ABCDEFGHIJKLMNOPQRSTUVWXYZ'.split('').map(letter => `Key${letter}`),
+  ...range('Numpad', 0, 9),
+  'NumpadAdd',
+  'NumpadSubtract',
+  'NumpadMultiply',
+  'NumpadDivide',
+  'NumpadDecimal',
+  'NumpadEnter',
+  'NumpadEqual',
+  'Semicolon',
+  'Equal',
+  'Comma',
+  'Minus',
+  'Period',
+  'Slash',
+  'Backquote',
+  'BracketLeft',
+  'Backslash',
+  'BracketRight',
+  'Quote',
+  'AudioVolumeMute',
+  'AudioVolumeDown',
+  'AudioVolumeUp',
+  'MediaTrackNext',
+  'MediaTrackPrevious',
+  'MediaStop',
+  'MediaPlayPause'
+];
+
+// Characters that have a key of their own or a key with Shift on a US keyboard
+const PRINTABLE_KEYS = new Set(
+  'abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789' +
+    ' `~!@#$%^&*()-_=+[{]}\\|;:\'",<.>/?'
+);
+
+// Names people and other tools use for keys the layout calls differently
+const KEY_ALIASES: Record<string, string> = {
+  ctrl: 'Control',
+  cmd: 'Meta',
+  command: 'Meta',
+  super: 'Meta',
+  win: 'Meta',
+  option: 'Alt',
+  esc: 'Escape',
+  return: 'Enter',
+  del: 'Delete',
+  ins: 'Insert',
+  up: 'ArrowUp',
+  down: 'ArrowDown',
+  left: 'ArrowLeft',
+  right: 'ArrowRight',
+  pgup: 'PageUp',
+  pgdn: 'PageDown',
+  plus: '+'
+};
+
+const CANONICAL_KEYS = new Map(NAMED_KEYS.map(name => [name.toLowerCase(), name]));
+
+// Chrome leaves shortcuts like Meta+A on macOS to the browser UI, which CDP input never
+// reaches, so the editing command is sent with the key for it to work on every platform
+const EDITING_COMMANDS: Record<string, string> = {
+  a: 'SelectAll',
+  c: 'Copy',
+  x: 'Cut',
+  v: 'Paste',
+  z: 'Undo',
+  y: 'Redo'
+};
+
+export interface KeyStroke {
+  action: KeyboardAction;
+  keys: KeyInput[];
+  delay: number;
+  commands: string[];
+}
+
+function distance(a: string, b: string): number {
+  let previous = Array.from({ length: b.length + 1 }, (_, i) => i);
+  for (let i = 1; i <= a.length; i++) {
+    const current = [i];
+    for (let j = 1; j <= b.length; j++) {
+      const cost = a[i - 1] === b[j - 1] ? 0 : 1;
+      current[j] = Math.min(current[j - 1]! + 1, previous[j]! + 1, previous[j - 1]! + cost);
+    }
+    previous = current;
+  }
+  return previous[b.length]!;
+}
+
+function suggestKey(name: string): string | null {
+  const lower = name.toLowerCase();
+  let best: string | null = null;
+  let bestDistance = 3;
+  for (const candidate of [...CANONICAL_KEYS.keys(), ...Object.keys(KEY_ALIASES)]) {
+    const d = distance(lower, candidate);
+    if (d < bestDistance) {
+      best = CANONICAL_KEYS.get(candidate) ?? KEY_ALIASES[candidate] ?? null;
+      bestDistance = d;
+    }
+  }
+  return best;
+}
+
+// A key name as Puppeteer spells it. Single characters are case-sensitive, since a and A are
+// different keys, names are not.
+export function toKeyInput(name: string): KeyInput {
+  if (PRINTABLE_KEYS.has(name)) {
+    return name as KeyInput;
+  }
+  const lower = name.toLowerCase();
+  const key = CANONICAL_KEYS.get(lower) ?? KEY_ALIASES[lower];
+  if (key) {
+    return key as KeyInput;
+  }
+  const suggestion = suggestKey(name);
+  throw new InvalidArgumentError(
+    `Unknown key ${JSON.stringify(name)}, expected a key name such as Enter, Escape, ArrowDown, ` +
+      `F5, Numpad1 or a single character${suggestion ? `. Did you mean ${suggestion}?` : ''}`
+  );
+}
+
+// Control+Shift+K is held down left to right and released right to left. A trailing + is
+// the plus key itself, as in Control++.
+export function parseKeyCombination(combination: string): KeyInput[] {
+  if (!combination) {
+    throw new InvalidArgumentError('Key is required');
+  }
+  const names = combination.length > 1 ? combination.split(/\+(?!$)/) : [combination];
+  return names.map(name => {
+    if (!name) {
+      throw new InvalidArgumentError(`Invalid key combination ${JSON.stringify(combination)}`);
+    }
+    return toKeyInput(name);
+  });
+}
+
+function isModifierKey(key: string): boolean {
+  return MODIFIER_KEYS.includes(key);
+}
+
+export function resolveKeyStroke(request: KeyboardRequest): KeyStroke {
+  const action = request.action ?? 'press';
+  if (!KEYBOARD_ACTIONS.includes(action)) {
+    throw new InvalidArgumentError(
+      `Keyboard action must be one of: ${KEYBOARD_ACTIONS.join(', ')}`
+    );
+  }
+  const delay = request.delay ?? 0;
+  if (!Number.isFinite(delay) || delay < 0) {
+    throw new InvalidArgumentError('Keyboard delay must be a non-negative number of milliseconds');
+  }
+
+  const keys = parseKeyCombination(request.key);
+  const modifiers = keys.slice(0, -1);
+  const key = keys[keys.length - 1]!;
+  if (action === 'press' && !modifiers.every(isModifierKey)) {
+    throw new InvalidArgumentError(
+      `Invalid shortcut ${JSON.stringify(request.key)}, only modifiers such as Control, Shift, ` +
+        'Alt and Meta can be held with another key'
+    );
+  }
+
+  const letter = /^Key[A-Z]$/.test(key) ? key.slice(3).toLowerCase() : key.toLowerCase();
+  let command = action === 'press' ? EDITING_COMMANDS[letter] : undefined;
+  if (!command || !modifiers.some(modifier => /^(Control|Meta)/.test(modifier))) {
+    return { action, keys, delay, commands: [] };
+  }
+  if (command === 'Undo' && modifiers.some(modifier => modifier.startsWith('Shift'))) {
+    command = 'Redo';
+  }
+  return { action, keys, delay, commands: [command] };
+}
@@ -0,0 +1,73 @@
+import fs from 'node:fs';
+import os from 'node:os';
+import path from 'node:path';
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { buildLaunchArgs, DEFAULT_LAUNCH_ARGS, validateExtensions } from './launch.js';
+
+describe('Launch args', () => {
+  const options = { userDataDir: '/data/.pcs/.browser', proxyServer: null, extensions: [] };
+
+  it('should start from the defaults and add the profile', () => {
+    expect(buildLaunchArgs([], options)).toEqual([
+      ...DEFAULT_LAUNCH_ARGS,
+      '--user-data-dir=/data/.pcs/.browser'
+    ]);
+  });
+
+  it('should add, replace and drop flags', () => {
+    const args = buildLaunchArgs(
+      [' --window-size=1920,1080 ', '--mute-audio=false', '!--disable-gpu', '--lang=de-DE'],
+      { ...options, proxyServer: 'http://proxy.example.com:8080' }
+    );
+    expect(args).not.toContain('--disable-gpu');
+    expect(args).toContain('--mute-audio=false');
+    expect(args).not.toContain('--mute-audio');
+    expect(args.slice(-4)).toEqual([
+      '--window-size=1920,1080',
+      '--lang=de-DE',
+      '--user-data-dir=/data/.pcs/.browser',
+      '--proxy-server=http://proxy.example.com:8080'
+    ]);
+  });
+
+  it('should reject malformed flags', () => {
+    expect(() => buildLaunchArgs(['window-size=10,10'], options)).toThrow(BrowserError);
+    expect(() => buildLaunchArgs(['--lang=de\n--evil'], options)).toThrow(/single-line/);
+    expect(() => buildLaunchArgs([42 as any], options)).toThrow(/Invalid launch arg/);
+    expect(() => buildLaunchArgs('--lang=de' as any, options)).toThrow(/list/);
+  });
+
+  it('should reject flags the server manages', () => {
+    expect(() => buildLaunchArgs(['--user-data-dir=/tmp/x'], options)).toThrow(/not allowed/);
+    expect(() => buildLaunchArgs(['--headless=new'], options)).toThrow(/PCS_HEADLESS/);
+    expect(() => buildLaunchArgs(['--proxy-server=x:1'], options)).toThrow(/PCS_PROXY/);
+    expect(() => buildLaunchArgs(['!--remote-debugging-port'], options)).toThrow(/not allowed/);
+    expect(() => buildLaunchArgs(['--load-extension=/x'], options)).toThrow(/PCS_EXTENSIONS/);
+  });
+
+  it('should load only the given extensions', () => {
+    const args = buildLaunchArgs([], { ...options, extensions: ['/ext/a', '/ext/b'] });
+    expect(args.slice(-2)).toEqual([
+      '--load-extension=/ext/a,/ext/b',
+      '--disable-extensions-except=/ext/a,/ext/b'
+    ]);
+  });
+
+  it('should accept extension directories with a manifest only', () => {
+    const root = fs.mkdtempSync(path.join(os.tmpdir(), 'pcs-extensions-'));
+    try {
+      const extension = path.join(root, 'adblock');
+      fs.mkdirSync(extension);
+      fs.writeFileSync(path.join(extension, 'manifest.json'), '{"manifest_version":3}');
+      fs.writeFileSync(path.join(root, 'file.crx'), '');
+
+      expect(validateExtensions([extension])).toEqual([extension]);
+      expect(() => validateExtensions([root])).toThrow(/manifest.json/);
+      expect(() => validateExtensions([path.join(root, 'file.crx')])).toThrow(/directory/);
+      expect(() => validateExtensions([path.join(root, 'missing')])).toThrow(BrowserError);
+    } finally {
+      fs.rmSync(root, { recursive: true, force: true });
+    }
+  });
+});
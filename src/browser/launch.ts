@@ -0,0 +1,95 @@
+import fs from 'node:fs';
+import path from 'node:path';
+import { BrowserError, InvalidArgumentError } from '../types/index.js';
+
+// Flags every launched browser gets unless the extra launch args drop or override them
+export const DEFAULT_LAUNCH_ARGS = [
+  '--no-sandbox',
+  '--disable-setuid-sandbox',
+  '--disable-dev-shm-usage',
+  '--disable-accelerated-2d-canvas',
+  '--no-first-run',
+  '--no-zygote',
+  '--disable-gpu',
+  '--mute-audio'
+];
+
+// Flags the server sets itself, with what to use instead
+const MANAGED_FLAGS: Record<string, string> = {
+  '--user-data-dir': 'set PCS_USER_DATA_DIR instead',
+  '--headless': 'set PCS_HEADLESS instead',
+  '--proxy-server': 'set PCS_PROXY instead',
+  '--load-extension': 'set PCS_EXTENSIONS instead',
+  '--disable-extensions-except': 'set PCS_EXTENSIONS instead',
+  '--remote-debugging-port': 'puppeteer talks to the browser over its own connection',
+  '--remote-debugging-pipe': 'puppeteer talks to the browser over its own connection'
+};
+
+const FLAG = /^--[a-z0-9][a-z0-9-]*(=.*)?$/i;
+
+const flagName = (arg: string) => arg.split('=', 1)[0] ?? arg;
+
+export interface LaunchArgsOptions {
+  userDataDir: string;
+  proxyServer: string | null;
+  extensions: string[]; // unpacked extension directories, checked by validateExtensions
+}
+
+// Chrome only loads an unpacked extension from a directory with a manifest.json, and
+// silently skips one it can't. The flags take comma separated lists.
+export function validateExtensions(dirs: string[]): string[] {
+  return dirs.map(dir => {
+    const resolved = path.resolve(dir);
+    if (resolved.includes(',')) {
+      throw new BrowserError(`Extension directory ${resolved} must not contain a comma`);
+    }
+    if (!fs.statSync(resolved, { throwIfNoEntry: false })?.isDirectory()) {
+      throw new InvalidArgumentError(`Extension ${resolved} must be a directory`);
+    }
+    if (!fs.existsSync(path.join(resolved, 'manifest.json'))) {
+      throw new InvalidArgumentError(`Extension ${resolved} must have a manifest.json`);
+    }
+    return resolved;
+  });
+}
+
+// Validates extra flags and merges them into the defaults. A flag replaces a default of the
+// same name and `!--flag` drops one, e.g. `!--disable-gpu` for hardware accelerated pages.
+export function buildLaunchArgs(extra: string[], options: LaunchArgsOptions): string[] {
+  if (!Array.isArray(extra)) {
+    throw new InvalidArgumentError('Launch args must be a list of Chrome flags');
+  }
+
+  const args = new Map(DEFAULT_LAUNCH_ARGS.map(arg => [flagName(arg), arg]));
+  for (const value of extra) {
+    const arg = typeof value === 'string' ? value.trim() : '';
+    const drop = arg.startsWith('!');
+    const flag = drop ? arg.slice(1) : arg;
+    if (!FLAG.test(flag) || /[\0-\x1f]/.test(flag)) {
+      throw new InvalidArgumentError(
+        `Invalid launch arg ${JSON.stringify(value)}, expected a single-line --flag or --flag=value`
+      );
+    }
+    const name = flagName(flag);
+    const reason = MANAGED_FLAGS[name];
+    if (reason) {
+      throw new InvalidArgumentError(`Launch arg ${name} is not allowed: ${reason}`);
+    }
+    if (drop) {
+      args.delete(name);
+    } else {
+      args.set(name, flag);
+    }
+  }
+
+  args.set('--user-data-dir', `--user-data-dir=${options.userDataDir}`);
+  if (options.proxyServer) {
+    args.set('--proxy-server', `--proxy-server=${options.proxyServer}`);
+  }
+  if (options.extensions.length) {
+    const list = options.extensions.join(',');
+    args.set('--load-extension', `--load-extension=${list}`);
+    args.set('--disable-extensions-except', `--disable-extensions-except=${list}`);
+  }
+  return [...args.values()];
+}
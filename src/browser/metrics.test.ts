@@ -0,0 +1,30 @@
+import { describe, expect, it } from 'vitest';
+import { cumulativeLayoutShift } from './metrics.js';
+
+const shift = (startTime: number, value: number, hadRecentInput = false) => ({
+  startTime,
+  value,
+  hadRecentInput
+});
+
+describe('Metrics helpers', () => {
+  describe('cumulativeLayoutShift', () => {
+    it('should be 0 without layout shifts', () => {
+      expect(cumulativeLayoutShift([])).toBe(0);
+    });
+
+    it('should count the worst session window', () => {
+      const shifts = [shift(0, 0.1), shift(500, 0.05), shift(3000, 0.2), shift(3800, 0.1)];
+      expect(cumulativeLayoutShift(shifts)).toBe(0.3);
+    });
+
+    it('should end a session window after five seconds', () => {
+      const shifts = Array.from({ length: 7 }, (_, i) => shift(i * 900, 0.1));
+      expect(cumulativeLayoutShift(shifts)).toBe(0.6);
+    });
+
+    it('should leave out shifts after user input', () => {
+      expect(cumulativeLayoutShift([shift(0, 0.1), shift(200, 0.5, true)])).toBe(0.1);
+    });
+  });
+});
@@ -0,0 +1,90 @@
+export interface LayoutShift {
+  value: number;
+  startTime: number;
+  hadRecentInput: boolean;
+}
+
+export interface RawVitals {
+  ttfb: number | null;
+  fcp: number | null;
+  lcp: number | null;
+  layoutShifts: LayoutShift[] | null; // null when the browser cannot observe layout shifts
+  domContentLoaded: number | null;
+  load: number | null;
+}
+
+// CLS as Core Web Vitals defines it: shifts are grouped into session windows that end after
+// a second without shifts or five seconds in total, and the worst window counts. Shifts
+// right after user input are expected and left out.
+export function cumulativeLayoutShift(shifts: LayoutShift[]): number {
+  let worst = 0;
+  let current = 0;
+  let windowStart = 0;
+  let previous = 0;
+  for (const shift of [...shifts].sort((a, b) => a.startTime - b.startTime)) {
+    if (shift.hadRecentInput) continue;
+    if (
+      current > 0 &&
+      shift.startTime - previous < 1000 &&
+      shift.startTime - windowStart < 5000
+    ) {
+      current += shift.value;
+    } else {
+      current = shift.value;
+      windowStart = shift.startTime;
+    }
+    previous = shift.startTime;
+    worst = Math.max(worst, current);
+  }
+  return Math.round(worst * 10000) / 10000;
+}
+
+// The functions below run in the page
+
+// Reads the paint and navigation timings, and picks up LCP and layout shifts through
+// buffered PerformanceObservers since those entries are not in the performance timeline
+export async function collectWebVitals(): Promise<RawVitals> {
+  const perf = (globalThis as any).performance;
+  const observe = (type: string) =>
+    new Promise<any[] | null>(resolve => {
+      try {
+        const entries: any[] = [];
+        const observer = new (globalThis as any).PerformanceObserver((list: any) => {
+          entries.push(...list.getEntries());
+        });
+        observer.observe({ type, buffered: true });
+        // buffered entries are delivered asynchronously
+        setTimeout(() => {
+          entries.push(...observer.takeRecords());
+          observer.disconnect();
+          resolve(entries);
+        }, 50);
+      } catch {
+        resolve(null);
+      }
+    });
+
+  const [largestPaints, shifts] = await Promise.all([
+    observe('largest-contentful-paint'),
+    observe('layout-shift')
+  ]);
+  const navigation = perf.getEntriesByType('navigation')[0];
+  const firstPaint = perf.getEntriesByName('first-contentful-paint')[0];
+  const lastPaint = largestPaints?.[largestPaints.length - 1];
+  const round = (value: number | undefined) =>
+    typeof value === 'number' && value > 0 ? Math.round(value * 10) / 10 : null;
+  return {
+    ttfb: round(navigation?.responseStart),
+    fcp: round(firstPaint?.startTime),
+    lcp: round(lastPaint?.startTime),
+    layoutShifts: shifts
+      ? shifts.map((shift: any) => ({
+          value: shift.value,
+          startTime: shift.startTime,
+          hadRecentInput: shift.hadRecentInput
+        }))
+      : null,
+    domContentLoaded: round(navigation?.domContentLoadedEventEnd),
+    load: round(navigation?.loadEventEnd)
+  };
+}
@@ -0,0 +1,83 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { resolveScrollMode, scrollUntilStable, validateDrag } from './mouse.js';
+
+// A feed that grows by one page per jump to the bottom until it runs out of items
+function fakeFeed(pages: number) {
+  let height = 1000;
+  let loaded = 1;
+  const timeouts: number[] = [];
+  return {
+    timeouts,
+    get height() {
+      return height;
+    },
+    scrollToEnd: async () => height,
+    waitForGrowth: async (previous: number, timeout: number) => {
+      timeouts.push(timeout);
+      if (loaded < pages) {
+        loaded++;
+        height = previous + 1000;
+      }
+      return height;
+    }
+  };
+}
+
+describe('Mouse helpers', () => {
+  describe('resolveScrollMode', () => {
+    it('should pick the mode from the request', () => {
+      expect(resolveScrollMode({ selector: '#footer' })).toBe('element');
+      expect(resolveScrollMode({ xpath: '//footer' })).toBe('element');
+      expect(resolveScrollMode({ y: -200 })).toBe('pixels');
+      expect(resolveScrollMode({ toBottom: true, maxIterations: 5 })).toBe('bottom');
+    });
+
+    it('should require exactly one mode', () => {
+      expect(() => resolveScrollMode({})).toThrow(BrowserError);
+      expect(() => resolveScrollMode({ selector: '#a', toBottom: true })).toThrow(/exactly one/);
+    });
+
+    it('should reject bad numbers', () => {
+      expect(() => resolveScrollMode({ x: Number.NaN })).toThrow(/pixels/);
+      expect(() => resolveScrollMode({ toBottom: true, maxIterations: 0 })).toThrow(/at least 1/);
+      expect(() => resolveScrollMode({ toBottom: true, idleTimeout: -1 })).toThrow(/idleTimeout/);
+    });
+  });
+
+  describe('validateDrag', () => {
+    it('should accept elements and points', () => {
+      expect(() =>
+        validateDrag({ from: { selector: '.card' }, to: { x: 300, y: 40 }, steps: 5 })
+      ).not.toThrow();
+    });
+
+    it('should reject incomplete endpoints', () => {
+      expect(() => validateDrag({ from: { x: 10 }, to: { selector: '#b' } })).toThrow(
+        /from needs a selector/
+      );
+      expect(() => validateDrag({ from: { selector: '#a' } } as any)).toThrow(/to is required/);
+      expect(() => validateDrag({ from: { xpath: '//a' }, to: { x: 1, y: 1 }, steps: 0 })).toThrow(
+        /steps/
+      );
+    });
+  });
+
+  describe('scrollUntilStable', () => {
+    it('should stop once the page stops growing', async () => {
+      const feed = fakeFeed(3);
+      expect(await scrollUntilStable(feed, 20, 500)).toEqual({
+        iterations: 3,
+        heightStable: true
+      });
+      expect(feed.height).toBe(3000);
+      expect(feed.timeouts).toEqual([500, 500, 500]);
+    });
+
+    it('should give up after maxIterations', async () => {
+      const feed = fakeFeed(Infinity);
+      expect(await scrollUntilStable(feed, 4)).toEqual({ iterations: 4, heightStable: false });
+      expect(feed.timeouts).toEqual([1000, 1000, 1000, 1000]);
+    });
+  });
+});
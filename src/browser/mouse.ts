@@ -0,0 +1,95 @@
+import {
+  BrowserError,
+  type DragEndpoint,
+  type DragRequest,
+  InvalidArgumentError,
+  type ScrollRequest
+} from '../types/index.js';
+
+export type ScrollMode = 'element' | 'pixels' | 'bottom';
+
+export interface ScrollState {
+  scrollX: number;
+  scrollY: number;
+  scrollHeight: number;
+  viewportHeight: number;
+}
+
+export const DEFAULT_SCROLL_ITERATIONS = 20;
+export const DEFAULT_SCROLL_IDLE_TIMEOUT = 1000;
+
+export function resolveScrollMode(request: ScrollRequest): ScrollMode {
+  const modes: ScrollMode[] = [];
+  if (request.selector || request.xpath) modes.push('element');
+  if (request.x !== undefined || request.y !== undefined) modes.push('pixels');
+  if (request.toBottom) modes.push('bottom');
+  if (modes.length !== 1) {
+    throw new BrowserError('Give exactly one of selector or xpath, x and y pixels, or toBottom');
+  }
+  if ([request.x, request.y].some(value => value !== undefined && !Number.isFinite(value))) {
+    throw new InvalidArgumentError('x and y must be numbers of pixels');
+  }
+  if (request.maxIterations !== undefined && !(request.maxIterations >= 1)) {
+    throw new InvalidArgumentError('maxIterations must be at least 1');
+  }
+  if (request.idleTimeout !== undefined && !(request.idleTimeout >= 0)) {
+    throw new InvalidArgumentError('idleTimeout must be a non-negative number of milliseconds');
+  }
+  return modes[0] as ScrollMode;
+}
+
+export function validateDrag(request: DragRequest): void {
+  validateDragEndpoint(request.from, 'from');
+  validateDragEndpoint(request.to, 'to');
+  if (request.steps !== undefined && !(Number.isInteger(request.steps) && request.steps >= 1)) {
+    throw new InvalidArgumentError('steps must be a positive integer');
+  }
+}
+
+function validateDragEndpoint(endpoint: DragEndpoint | undefined, label: string): void {
+  if (!endpoint || typeof endpoint !== 'object') {
+    throw new InvalidArgumentError(`Drag ${label} is required`);
+  }
+  if (endpoint.selector || endpoint.xpath) {
+    return;
+  }
+  if (!Number.isFinite(endpoint.x) || !Number.isFinite(endpoint.y)) {
+    throw new BrowserError(`Drag ${label} needs a selector, an xpath, or x and y coordinates`);
+  }
+}
+
+// Infinite feeds load more content when the end of the page comes into view, so after every
+// jump to the bottom this waits up to idleTimeout for the page to grow. It stops once the
+// height holds still, or after maxIterations jumps for feeds that never end.
+export async function scrollUntilStable(
+  page: {
+    scrollToEnd: () => Promise<number>; // resolves with the scroll height before growing
+    waitForGrowth: (height: number, timeout: number) => Promise<number>;
+  },
+  maxIterations = DEFAULT_SCROLL_ITERATIONS,
+  idleTimeout = DEFAULT_SCROLL_IDLE_TIMEOUT
+): Promise<{ iterations: number; heightStable: boolean }> {
+  for (let iterations = 1; iterations <= maxIterations; iterations++) {
+    const height = await page.scrollToEnd();
+    const grown = await page.waitForGrowth(height, idleTimeout);
+    if (grown <= height) {
+      return { iterations, heightStable: true };
+    }
+  }
+  return { iterations: maxIterations, heightStable: false };
+}
+
+// The functions below run in the page
+
+export function readScrollState(): ScrollState {
+  const { document, scrollX, scrollY, innerHeight } = globalThis as any;
+  const root = document.scrollingElement ?? document.documentElement;
+  return { scrollX, scrollY, scrollHeight: root.scrollHeight, viewportHeight: innerHeight };
+}
+
+export function scrollToEnd(): number {
+  const { document } = globalThis as any;
+  const height = (document.scrollingElement ?? document.documentElement).scrollHeight;
+  (globalThis as any).scrollTo({ top: height, behavior: 'instant' });
+  return height;
+}
@@ -0,0 +1,181 @@
+import { describe, expect, it } from 'vitest';
+import {
+  classifyNetError,
+  getDownloadFilename,
+  getNetErrorCode,
+  isDownloadResponse,
+  isNavigationRequest,
+  resolveNavigationHeaders,
+  toNavigationError,
+  withNavigationHeaders
+} from './navigation.js';
+
+describe('Navigation helpers', () => {
+  describe('getNetErrorCode', () => {
+    it('should extract the net error code from a navigation error', () => {
+      const error = new Error('net::ERR_NAME_NOT_RESOLVED at https://nope.invalid');
+
+      expect(getNetErrorCode(error)).toBe('ERR_NAME_NOT_RESOLVED');
+    });
+
+    it('should return null for non network errors', () => {
+      expect(getNetErrorCode(new Error('Navigation timeout of 30000 ms exceeded'))).toBeNull();
+    });
+  });
+
+  describe('classifyNetError', () => {
+    it('should tell the kinds of net errors apart', () => {
+      expect(classifyNetError('ERR_NAME_NOT_RESOLVED')).toBe('dns');
+      expect(classifyNetError('ERR_CONNECTION_REFUSED')).toBe('connection');
+      expect(classifyNetError('ERR_PROXY_CONNECTION_FAILED')).toBe('connection');
+      expect(classifyNetError('ERR_CERT_AUTHORITY_INVALID')).toBe('certificate');
+      expect(classifyNetError('ERR_SSL_PROTOCOL_ERROR')).toBe('certificate');
+      expect(classifyNetError('ERR_BLOCKED_BY_CLIENT')).toBe('blocked');
+      expect(classifyNetError('ERR_ABORTED')).toBe('aborted');
+      expect(classifyNetError('ERR_HTTP2_PROTOCOL_ERROR')).toBe('response');
+      expect(classifyNetError('ERR_TOO_MANY_REDIRECTS')).toBe('response');
+      expect(classifyNetError('ERR_UNKNOWN_URL_SCHEME')).toBe('url');
+      expect(classifyNetError('ERR_FAILED')).toBe('other');
+    });
+
+    it('should describe a failed navigation like a thrown one', () => {
+      const error = toNavigationError({
+        outcome: 'error',
+        url: 'https://self-signed.example',
+        status: null,
+        headers: {},
+        errorCode: 'ERR_CERT_AUTHORITY_INVALID',
+        errorCategory: 'certificate'
+      });
+      expect(error).toEqual({
+        code: 'NAVIGATION_FAILED',
+        message: 'net::ERR_CERT_AUTHORITY_INVALID at https://self-signed.example',
+        details: {
+          netError: 'ERR_CERT_AUTHORITY_INVALID',
+          netErrorCategory: 'certificate',
+          url: 'https://self-signed.example',
+          status: null
+        }
+      });
+    });
+  });
+
+  describe('resolveNavigationHeaders', () => {
+    it('should pass the referrer and validated headers on', () => {
+      const navigation = resolveNavigationHeaders({
+        referer: 'https://example.com/gallery',
+        headers: { 'X-Token': 'abc', Cookie: 'a=1' }
+      });
+
+      expect(navigation.referer).toBe('https://example.com/gallery');
+      expect(navigation.headers).toEqual({ 'X-Token': 'abc' });
+      expect(navigation.warnings).toHaveLength(1);
+    });
+
+    it('should send a Referer header as the referrer', () => {
+      const navigation = resolveNavigationHeaders({ headers: { referer: 'https://a.test/' } });
+
+      expect(navigation).toEqual({ referer: 'https://a.test/', headers: {}, warnings: [] });
+    });
+
+    it('should reject a referrer given twice or that is no http URL', () => {
+      const headers = { Referer: 'https://b.test/' };
+      expect(() => resolveNavigationHeaders({ referer: 'https://a.test/', headers })).toThrow(
+        'A Referer header is not allowed along with referer'
+      );
+      expect(() => resolveNavigationHeaders({ referer: 'example.com' })).toThrow(
+        'referer must be an absolute http or https URL'
+      );
+      expect(() => resolveNavigationHeaders({ referer: 'file:///etc/passwd' })).toThrow(
+        'referer must be an absolute http or https URL'
+      );
+    });
+  });
+
+  describe('isNavigationRequest', () => {
+    it('should match the request URL without the fragment', () => {
+      expect(isNavigationRequest('https://example.com/', 'https://example.com')).toBe(true);
+      expect(isNavigationRequest('https://example.com/a?b=1', 'https://example.com/a?b=1#c')).toBe(
+        true
+      );
+    });
+
+    it('should not match other documents or invalid URLs', () => {
+      expect(isNavigationRequest('https://example.com/a', 'https://example.com/')).toBe(false);
+      expect(isNavigationRequest('https://example.com/', 'not a url')).toBe(false);
+    });
+  });
+
+  describe('withNavigationHeaders', () => {
+    it('should replace request headers of the same name whatever their case', () => {
+      const headers = withNavigationHeaders(
+        { Accept: 'text/html', 'accept-language': 'en' },
+        { 'Accept-Language': 'de', 'X-Token': 'abc' }
+      );
+
+      expect(headers).toEqual([
+        { name: 'Accept', value: 'text/html' },
+        { name: 'Accept-Language', value: 'de' },
+        { name: 'X-Token', value: 'abc' }
+      ]);
+    });
+  });
+
+  describe('isDownloadResponse', () => {
+    it('should treat attachments as downloads', () => {
+      expect(isDownloadResponse(200, { 'content-disposition': 'attachment; filename=a.zip' })).toBe(
+        true
+      );
+    });
+
+    it('should not treat failed responses as downloads', () => {
+      expect(isDownloadResponse(404, {})).toBe(false);
+      expect(isDownloadResponse(null, {})).toBe(false);
+    });
+
+    it('should treat content the browser cannot render as a download', () => {
+      expect(isDownloadResponse(200, { 'content-type': 'application/zip' })).toBe(true);
+      expect(isDownloadResponse(200, { 'content-type': 'application/octet-stream' })).toBe(true);
+    });
+
+    it('should not treat aborted renderable responses as downloads', () => {
+      const html = { 'content-type': 'text/html; charset=utf-8' };
+      expect(isDownloadResponse(200, html)).toBe(false);
+      expect(isDownloadResponse(200, { 'content-type': 'application/json' })).toBe(false);
+      expect(isDownloadResponse(200, { 'content-type': 'image/svg+xml' })).toBe(false);
+      expect(isDownloadResponse(204, {})).toBe(false);
+    });
+  });
+
+  describe('getDownloadFilename', () => {
+    it('should prefer the encoded filename', () => {
+      const headers = {
+        'content-disposition': `attachment; filename="plain.txt"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`
+      };
+
+      expect(getDownloadFilename(headers, 'https://example.com/file')).toBe('résumé.pdf');
+    });
+
+    it('should read quoted and bare filenames', () => {
+      expect(
+        getDownloadFilename(
+          { 'content-disposition': 'attachment; filename="report.csv"' },
+          'https://example.com/x'
+        )
+      ).toBe('report.csv');
+      expect(
+        getDownloadFilename(
+          { 'content-disposition': 'attachment; filename=data.bin' },
+          'https://example.com/x'
+        )
+      ).toBe('data.bin');
+    });
+
+    it('should fall back to the URL path', () => {
+      expect(getDownloadFilename({}, 'https://example.com/files/archive.tar.gz?x=1')).toBe(
+        'archive.tar.gz'
+      );
+      expect(getDownloadFilename({}, 'https://example.com/')).toBeNull();
+    });
+  });
+});
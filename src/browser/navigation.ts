@@ -0,0 +1,172 @@
+import type { Protocol } from 'puppeteer-core';
+import {
+  InvalidArgumentError,
+  type NavigationResult,
+  type NetErrorCategory,
+  type ToolError
+} from '../types/index.js';
+import { validateHeaders } from './headers.js';
+
+export const WAIT_UNTIL_VALUES = [
+  'load',
+  'domcontentloaded',
+  'networkidle0',
+  'networkidle2'
+] as const;
+
+export type WaitUntil = (typeof WAIT_UNTIL_VALUES)[number];
+
+// Extracts the Chromium net error code (e.g. ERR_NAME_NOT_RESOLVED) from a navigation error
+export function getNetErrorCode(error: unknown): string | null {
+  const message = error instanceof Error ? error.message : String(error);
+  const match = message.match(/net::(ERR_[A-Z0-9_]+)/);
+  return match?.[1] ?? null;
+}
+
+// Codes as listed in Chromium's net/base/net_error_list.h, matched by prefix where a whole
+// family means the same thing. Earlier rules win.
+const NET_ERROR_RULES: Array<[RegExp, NetErrorCategory]> = [
+  [/^ERR_(NAME_NOT_RESOLVED|NAME_RESOLUTION_FAILED|DNS_)/, 'dns'],
+  [/^ERR_(CERT_|SSL_|BAD_SSL_CLIENT_AUTH_CERT)/, 'certificate'],
+  [/^ERR_(BLOCKED_BY_|ACCESS_DENIED|UNSAFE_PORT|NETWORK_ACCESS_DENIED)/, 'blocked'],
+  [/^ERR_ABORTED$/, 'aborted'],
+  [/^ERR_(INVALID_URL|UNKNOWN_URL_SCHEME|DISALLOWED_URL_SCHEME)$/, 'url'],
+  [/^ERR_(CONNECTION_|ADDRESS_|INTERNET_DISCONNECTED|NETWORK_CHANGED|TIMED_OUT)/, 'connection'],
+  [/^ERR_(PROXY_|TUNNEL_|SOCKET_)/, 'connection'],
+  [/^ERR_(EMPTY_RESPONSE|INVALID_RESPONSE|INVALID_HTTP_RESPONSE|HTTP)/, 'response'],
+  [/^ERR_(TOO_MANY_REDIRECTS|CONTENT_|RESPONSE_HEADERS_)/, 'response']
+];
+
+export function classifyNetError(code: string): NetErrorCategory {
+  return NET_ERROR_RULES.find(([pattern]) => pattern.test(code))?.[1] ?? 'other';
+}
+
+// The NAVIGATION_FAILED error of a navigation the browser blocked or failed, worded like the
+// one Puppeteer throws for the other tools that navigate
+export function toNavigationError(result: NavigationResult): ToolError {
+  const netError = result.errorCode ?? 'ERR_FAILED';
+  return {
+    code: 'NAVIGATION_FAILED',
+    message: `net::${netError} at ${result.url}`,
+    details: {
+      netError,
+      netErrorCategory: result.errorCategory ?? classifyNetError(netError),
+      url: result.url,
+      status: result.status
+    }
+  };
+}
+
+// The referrer and headers of a single navigation. A Referer among the headers is sent as the
+// referrer, since Chrome sets that header itself from the one the navigation carries.
+export function resolveNavigationHeaders(request: {
+  referer?: string | undefined;
+  headers?: Record<string, string> | undefined;
+}): { referer: string | null; headers: Record<string, string>; warnings: string[] } {
+  const { headers, warnings } = request.headers
+    ? validateHeaders(request.headers)
+    : { headers: {}, warnings: [] };
+  let referer = request.referer ?? null;
+  const refererHeader = Object.keys(headers).find(name => name.toLowerCase() === 'referer');
+  if (refererHeader) {
+    if (referer !== null) {
+      throw new InvalidArgumentError('A Referer header is not allowed along with referer');
+    }
+    referer = headers[refererHeader]!;
+    delete headers[refererHeader];
+  }
+  if (referer !== null && !/^https?:$/.test(parseUrl(referer)?.protocol ?? '')) {
+    throw new InvalidArgumentError('referer must be an absolute http or https URL');
+  }
+  return { referer, headers, warnings };
+}
+
+function parseUrl(url: string): URL | null {
+  try {
+    return new URL(url);
+  } catch {
+    return null;
+  }
+}
+
+// Whether a paused request is the one a navigation to url starts. Requests never carry the
+// fragment, and Chrome normalizes the URL the way URL does.
+export function isNavigationRequest(requestUrl: string, url: string): boolean {
+  const target = parseUrl(url);
+  if (!target) {
+    return false;
+  }
+  target.hash = '';
+  return requestUrl === target.href;
+}
+
+// The headers of the request with the navigation's own in place of those of the same name,
+// whatever their case
+export function withNavigationHeaders(
+  requestHeaders: Record<string, string>,
+  headers: Record<string, string>
+): Protocol.Fetch.HeaderEntry[] {
+  const replaced = new Set(Object.keys(headers).map(name => name.toLowerCase()));
+  return [
+    ...Object.entries(requestHeaders)
+      .filter(([name]) => !replaced.has(name.toLowerCase()))
+      .map(([name, value]) => ({ name, value })),
+    ...Object.entries(headers).map(([name, value]) => ({ name, value }))
+  ];
+}
+
+// Content types Chromium renders in the tab rather than handing to the download manager
+const RENDERABLE_CONTENT_TYPES = [
+  /^(text|image|audio|video)\//i,
+  /^application\/(json|javascript|ecmascript|xml|xhtml\+xml)$/i,
+  /\+(xml|json)$/i
+];
+
+// Chromium aborts a navigation with ERR_ABORTED when it hands the response over to the
+// download manager instead of rendering it: the server asked for an attachment, or the
+// content type is one the browser cannot display. Any other abort is a real failure.
+export function isDownloadResponse(
+  status: number | null,
+  headers: Record<string, string>
+): boolean {
+  const disposition = headers['content-disposition'] ?? '';
+  if (/^\s*attachment/i.test(disposition)) {
+    return true;
+  }
+  if (status === null || status < 200 || status >= 300) {
+    return false;
+  }
+
+  // Without a content type Chromium sniffs the body and renders it
+  const contentType = (headers['content-type'] ?? '').split(';')[0]?.trim() ?? '';
+  return (
+    contentType !== '' && !RENDERABLE_CONTENT_TYPES.some(pattern => pattern.test(contentType))
+  );
+}
+
+// Prefers the Content-Disposition file name and falls back to the last URL path segment
+export function getDownloadFilename(headers: Record<string, string>, url: string): string | null {
+  const disposition = headers['content-disposition'] ?? '';
+
+  const encoded = disposition.match(/filename\*\s*=\s*(?:UTF-8|utf-8)?''([^;]+)/);
+  if (encoded?.[1]) {
+    try {
+      return decodeURIComponent(encoded[1].trim());
+    } catch {
+      return encoded[1].trim();
+    }
+  }
+
+  const plain = disposition.match(/filename\s*=\s*("([^"]*)"|[^;]+)/);
+  const name = plain?.[2] ?? plain?.[1];
+  if (name) {
+    return name.trim();
+  }
+
+  try {
+    const segment = new URL(url).pathname.split('/').pop();
+    return segment ? decodeURIComponent(segment) : null;
+  } catch {
+    return null;
+  }
+}
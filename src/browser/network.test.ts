@@ -0,0 +1,61 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { InflightRequests, resolveIdleOptions } from './network.js';
+
+describe('Network helpers', () => {
+  describe('resolveIdleOptions', () => {
+    it('should apply defaults', () => {
+      expect(resolveIdleOptions({})).toEqual({ idleTime: 500, maxInflight: 0, timeout: 30000 });
+    });
+
+    it('should reject negative and fractional values', () => {
+      expect(() => resolveIdleOptions({ idleTime: -1 })).toThrow(BrowserError);
+      expect(() => resolveIdleOptions({ maxInflightRequests: 1.5 })).toThrow(/integer/);
+      expect(() => resolveIdleOptions({ timeout: Number.NaN })).toThrow(/timeout/);
+    });
+  });
+
+  describe('InflightRequests', () => {
+    it('should resolve once the network stays quiet', async () => {
+      const requests = new InflightRequests<string>();
+      requests.add('a', 'https://example.com/a');
+      setTimeout(() => requests.remove('a'), 30);
+
+      const result = await requests.waitForIdle(50, 0, 5000);
+      expect(result).toMatchObject({ idle: true, timedOut: false, inflight: 0, pending: [] });
+      expect(result.elapsedMs).toBeGreaterThanOrEqual(70);
+    });
+
+    it('should restart the idle time when a request starts', async () => {
+      const requests = new InflightRequests<string>();
+      setTimeout(() => requests.add('a', 'https://example.com/a'), 30);
+      setTimeout(() => requests.remove('a'), 60);
+
+      const result = await requests.waitForIdle(80, 0, 5000);
+      expect(result.idle).toBe(true);
+      expect(result.elapsedMs).toBeGreaterThanOrEqual(130);
+    });
+
+    it('should allow up to maxInflight pending requests', async () => {
+      const requests = new InflightRequests<string>();
+      requests.add('poll', 'https://example.com/poll');
+
+      const result = await requests.waitForIdle(20, 1, 5000);
+      expect(result).toMatchObject({ idle: true, inflight: 1 });
+    });
+
+    it('should list the pending requests on timeout', async () => {
+      const requests = new InflightRequests<string>();
+      requests.add('a', 'https://example.com/a');
+      requests.add('b', 'https://example.com/b');
+
+      const result = await requests.waitForIdle(20, 0, 60);
+      expect(result).toMatchObject({
+        idle: false,
+        timedOut: true,
+        inflight: 2,
+        pending: ['https://example.com/a', 'https://example.com/b']
+      });
+    });
+  });
+});
@@ -0,0 +1,105 @@
+import {
+  InvalidArgumentError,
+  type NetworkIdleResult,
+  type WaitForNetworkIdleRequest
+} from '../types/index.js';
+
+const DEFAULT_IDLE_TIME = 500;
+const DEFAULT_TIMEOUT = 30000;
+// enough to see which requests hold the page busy without flooding the response
+const MAX_PENDING_URLS = 50;
+
+export function resolveIdleOptions(request: WaitForNetworkIdleRequest): {
+  idleTime: number;
+  maxInflight: number;
+  timeout: number;
+} {
+  const idleTime = request.idleTime ?? DEFAULT_IDLE_TIME;
+  const maxInflight = request.maxInflightRequests ?? 0;
+  const timeout = request.timeout ?? DEFAULT_TIMEOUT;
+  if (typeof idleTime !== 'number' || !(idleTime >= 0)) {
+    throw new InvalidArgumentError('idleTime must be a non-negative number of milliseconds');
+  }
+  if (!Number.isInteger(maxInflight) || maxInflight < 0) {
+    throw new InvalidArgumentError('maxInflightRequests must be a non-negative integer');
+  }
+  if (typeof timeout !== 'number' || !(timeout >= 0)) {
+    throw new InvalidArgumentError('timeout must be a non-negative number of milliseconds');
+  }
+  return { idleTime, maxInflight, timeout };
+}
+
+// Requests a page has sent that have neither finished nor failed yet. Tracked for the whole
+// life of the tab so a wait started after a click also sees the requests the click set off.
+export class InflightRequests<T = unknown> {
+  private pending: Map<T, string> = new Map();
+  private listeners: Set<() => void> = new Set();
+
+  get size(): number {
+    return this.pending.size;
+  }
+
+  add(request: T, url: string): void {
+    this.pending.set(request, url);
+    this.notify();
+  }
+
+  remove(request: T): void {
+    if (this.pending.delete(request)) {
+      this.notify();
+    }
+  }
+
+  clear(): void {
+    this.pending.clear();
+    this.notify();
+  }
+
+  urls(): string[] {
+    return [...this.pending.values()].slice(0, MAX_PENDING_URLS);
+  }
+
+  // Resolves once at most maxInflight requests stay pending for idleTime without a break, or
+  // when the timeout runs out (0 waits forever)
+  waitForIdle(idleTime: number, maxInflight: number, timeout: number): Promise<NetworkIdleResult> {
+    const startedAt = Date.now();
+    return new Promise(resolve => {
+      let idleTimer: NodeJS.Timeout | null = null;
+      let timeoutTimer: NodeJS.Timeout | null = null;
+
+      const finish = (idle: boolean) => {
+        if (idleTimer) clearTimeout(idleTimer);
+        if (timeoutTimer) clearTimeout(timeoutTimer);
+        this.listeners.delete(check);
+        resolve({
+          idle,
+          timedOut: !idle,
+          elapsedMs: Date.now() - startedAt,
+          inflight: this.pending.size,
+          pending: idle ? [] : this.urls()
+        });
+      };
+
+      const check = () => {
+        if (this.pending.size > maxInflight) {
+          if (idleTimer) clearTimeout(idleTimer);
+          idleTimer = null;
+        } else if (!idleTimer) {
+          idleTimer = setTimeout(() => finish(true), idleTime);
+        }
+      };
+
+      this.listeners.add(check);
+      if (timeout > 0) {
+        timeoutTimer = setTimeout(() => finish(false), timeout);
+      }
+      check();
+    });
+  }
+
+  private notify(): void {
+    for (const listener of this.listeners) {
+      listener();
+    }
+  }
+}
@@ -0,0 +1,59 @@
+import fs from 'node:fs';
+import os from 'node:os';
+import path from 'node:path';
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import { InvalidArgumentError } from '../types/index.js';
+import { resolveClientPath } from './paths.js';
+
+describe('Client paths', () => {
+  let root: string;
+  let base: string;
+
+  beforeEach(() => {
+    root = fs.mkdtempSync(path.join(os.tmpdir(), 'pcs-paths-'));
+    base = path.join(root, '.pcs');
+    fs.mkdirSync(path.join(base, 'screenshots'), { recursive: true });
+  });
+
+  afterEach(() => {
+    fs.rmSync(root, { recursive: true, force: true });
+  });
+
+  it('should resolve relative paths against the working directory', () => {
+    expect(resolveClientPath(base, 'page.png')).toBe(path.join(base, 'page.png'));
+    expect(resolveClientPath(base, 'screenshots/../reports/a.pdf')).toBe(
+      path.join(base, 'reports', 'a.pdf')
+    );
+    expect(resolveClientPath(base, path.join(base, 'screenshots', 'b.png'))).toBe(
+      path.join(base, 'screenshots', 'b.png')
+    );
+  });
+
+  it('should refuse paths outside the working directory', () => {
+    expect(() => resolveClientPath(base, '../outside.png')).toThrow(InvalidArgumentError);
+    expect(() => resolveClientPath(base, 'screenshots/../../outside.png')).toThrow(
+      /inside the working directory/
+    );
+    expect(() => resolveClientPath(base, path.join(root, 'outside.png'))).toThrow(
+      /inside the working directory/
+    );
+    expect(() => resolveClientPath(base, '/etc/passwd')).toThrow(/inside the working directory/);
+    expect(() => resolveClientPath(base, '.')).toThrow(/inside the working directory/);
+  });
+
+  it('should refuse symlinks leading out of the working directory', () => {
+    fs.symlinkSync(root, path.join(base, 'escape'));
+    expect(() => resolveClientPath(base, 'escape/outside.png')).toThrow(
+      /inside the working directory/
+    );
+  });
+
+  it("should refuse the server's own files", () => {
+    expect(() => resolveClientPath(base, '.secret')).toThrow(/server's own files/);
+    expect(() => resolveClientPath(base, 'config.json')).toThrow(/server's own files/);
+    expect(() => resolveClientPath(base, '.browser/Default/Cookies')).toThrow(
+      /server's own files/
+    );
+    expect(() => resolveClientPath(base, 'reports/config.json')).not.toThrow();
+  });
+});
@@ -0,0 +1,44 @@
+import fs from 'node:fs';
+import path from 'node:path';
+import { InvalidArgumentError } from '../types/index.js';
+
+// The server's own state in the working directory: the API key, the config that picks the
+// Chrome binary, the Chrome profile and the launched browsers' pids
+const RESERVED_ENTRIES = ['.secret', 'config.json', '.browser', '.browser-pids.json'];
+
+// Resolves symlinks in the part of filePath that exists, a file about to be written doesn't
+function realpathOfExisting(filePath: string): string {
+  let existing = filePath;
+  const rest: string[] = [];
+  while (!fs.lstatSync(existing, { throwIfNoEntry: false })) {
+    const parent = path.dirname(existing);
+    if (parent === existing) {
+      break;
+    }
+    rest.unshift(path.basename(existing));
+    existing = parent;
+  }
+  return path.join(fs.realpathSync(existing), ...rest);
+}
+
+// Resolves a path from an HTTP or MCP client against baseDir, the working directory. Clients
+// must not reach other files of the user running the server, so a path that leaves baseDir
+// (absolute, through .. or through a symlink) or points at the server's own state is refused.
+export function resolveClientPath(baseDir: string, filePath: string): string {
+  const resolved = path.resolve(baseDir, filePath);
+  const relative = path.relative(realpathOfExisting(baseDir), realpathOfExisting(resolved));
+  if (
+    relative === '' ||
+    relative === '..' ||
+    relative.startsWith(`..${path.sep}`) ||
+    path.isAbsolute(relative)
+  ) {
+    throw new InvalidArgumentError(`Path ${filePath} must be inside the working directory`);
+  }
+
+  const entry = relative.split(path.sep)[0]?.toLowerCase() ?? '';
+  if (RESERVED_ENTRIES.includes(entry)) {
+    throw new InvalidArgumentError(`Path ${filePath} must not point at the server's own files`);
+  }
+  return resolved;
+}
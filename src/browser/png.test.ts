@@ -0,0 +1,55 @@
+import { deflateSync } from 'node:zlib';
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { decodePng, encodePng } from './png.js';
+
+// The decoder does not check CRCs, so hand-made test images leave them zero
+function rawPng(width: number, height: number, colorType: number, rows: number[][]) {
+  const chunk = (type: string, data: Buffer) => {
+    const length = Buffer.alloc(4);
+    length.writeUInt32BE(data.length);
+    return Buffer.concat([length, Buffer.from(type, 'latin1'), data, Buffer.alloc(4)]);
+  };
+  const header = Buffer.alloc(13);
+  header.writeUInt32BE(width, 0);
+  header.writeUInt32BE(height, 4);
+  header.writeUInt8(8, 8);
+  header.writeUInt8(colorType, 9);
+  return Buffer.concat([
+    Buffer.from([137, 80, 78, 71, 13, 10, 26, 10]),
+    chunk('IHDR', header),
+    chunk('IDAT', deflateSync(Buffer.from(rows.flat()))),
+    chunk('IEND', Buffer.alloc(0))
+  ]);
+}
+
+describe('PNG helpers', () => {
+  it('should round-trip RGBA images', () => {
+    const image = {
+      width: 2,
+      height: 2,
+      data: Buffer.from([255, 0, 0, 255, 0, 255, 0, 128, 0, 0, 255, 0, 10, 20, 30, 255])
+    };
+    expect(decodePng(encodePng(image))).toEqual(image);
+  });
+
+  it('should undo row filters', () => {
+    // Grayscale 3x3 of 10 20 30 / 40 50 60 / 70 80 90 with Sub, Up and Paeth filters
+    const png = rawPng(3, 3, 0, [
+      [1, 10, 10, 10],
+      [2, 30, 30, 30],
+      [4, 30, 10, 10]
+    ]);
+    const { data } = decodePng(png);
+    const grays = Array.from({ length: 9 }, (_, i) => data[i * 4]);
+    expect(grays).toEqual([10, 20, 30, 40, 50, 60, 70, 80, 90]);
+    expect(data[3]).toBe(255);
+  });
+
+  it('should reject other formats', () => {
+    expect(() => decodePng(Buffer.from('GIF89a'))).toThrow(BrowserError);
+    const interlaced = rawPng(1, 1, 0, [[0, 0]]);
+    interlaced.writeUInt8(1, 28);
+    expect(() => decodePng(interlaced)).toThrow(/non-interlaced/);
+  });
+});
@@ -0,0 +1,168 @@
+import { deflateSync, inflateSync } from 'node:zlib';
+import { BrowserError } from '../types/index.js';
+
+export interface RgbaImage {
+  width: number;
+  height: number;
+  data: Buffer; // 4 bytes per pixel, row by row
+}
+
+const SIGNATURE = Buffer.from([137, 80, 78, 71, 13, 10, 26, 10]);
+
+// Samples per pixel of each color type: grayscale, RGB, palette, grayscale and alpha, RGBA
+const CHANNELS: Record<number, number> = { 0: 1, 2: 3, 3: 1, 4: 2, 6: 4 };
+
+const CRC_TABLE = Array.from({ length: 256 }, (_, n) => {
+  let c = n;
+  for (let k = 0; k < 8; k++) {
+    c = c & 1 ? 0xedb88320 ^ (c >>> 1) : c >>> 1;
+  }
+  return c >>> 0;
+});
+
+function crc32(data: Buffer): number {
+  let crc = 0xffffffff;
+  for (const byte of data) {
+    crc = (CRC_TABLE[(crc ^ byte) & 0xff] ?? 0) ^ (crc >>> 8);
+  }
+  return (crc ^ 0xffffffff) >>> 0;
+}
+
+function paeth(a: number, b: number, c: number): number {
+  const p = a + b - c;
+  const pa = Math.abs(p - a);
+  const pb = Math.abs(p - b);
+  const pc = Math.abs(p - c);
+  if (pa <= pb && pa <= pc) return a;
+  return pb <= pc ? b : c;
+}
+
+// Decodes the 8-bit, non-interlaced PNGs Chrome and most image tools write, in any color type
+export function decodePng(png: Buffer): RgbaImage {
+  if (png.length < SIGNATURE.length || !png.subarray(0, SIGNATURE.length).equals(SIGNATURE)) {
+    throw new BrowserError('Image is not a PNG');
+  }
+
+  let width = 0;
+  let height = 0;
+  let bitDepth = 0;
+  let colorType = 0;
+  let interlace = 0;
+  let palette: Buffer | null = null;
+  let transparency: Buffer | null = null;
+  const compressed: Buffer[] = [];
+  for (let offset = SIGNATURE.length; offset + 8 <= png.length; ) {
+    const length = png.readUInt32BE(offset);
+    const type = png.toString('latin1', offset + 4, offset + 8);
+    const chunk = png.subarray(offset + 8, offset + 8 + length);
+    offset += length + 12;
+    if (type === 'IHDR') {
+      width = chunk.readUInt32BE(0);
+      height = chunk.readUInt32BE(4);
+      bitDepth = chunk.readUInt8(8);
+      colorType = chunk.readUInt8(9);
+      interlace = chunk.readUInt8(12);
+    } else if (type === 'PLTE') {
+      palette = chunk;
+    } else if (type === 'tRNS') {
+      transparency = chunk;
+    } else if (type === 'IDAT') {
+      compressed.push(chunk);
+    } else if (type === 'IEND') {
+      break;
+    }
+  }
+
+  const channels = CHANNELS[colorType];
+  if (!width || !height || !channels) {
+    throw new BrowserError('PNG has no valid image header');
+  }
+  if (bitDepth !== 8 || interlace !== 0) {
+    throw new BrowserError('Only 8-bit, non-interlaced PNGs are supported');
+  }
+  if (colorType === 3 && !palette) {
+    throw new BrowserError('PNG uses a palette but has none');
+  }
+
+  const stride = width * channels;
+  const raw = inflateSync(Buffer.concat(compressed));
+  if (raw.length < (stride + 1) * height) {
+    throw new BrowserError('PNG image data is truncated');
+  }
+
+  // Undo the per-row filters, each byte is predicted from its left, upper and upper-left
+  // neighbors of the same channel
+  const samples = Buffer.alloc(stride * height);
+  for (let y = 0; y < height; y++) {
+    const filter = raw[y * (stride + 1)];
+    const source = y * (stride + 1) + 1;
+    const row = y * stride;
+    for (let x = 0; x < stride; x++) {
+      const left = x >= channels ? (samples[row + x - channels] ?? 0) : 0;
+      const up = y > 0 ? (samples[row - stride + x] ?? 0) : 0;
+      const upLeft = x >= channels && y > 0 ? (samples[row - stride + x - channels] ?? 0) : 0;
+      const value = raw[source + x] ?? 0;
+      let predicted = 0;
+      if (filter === 1) predicted = left;
+      else if (filter === 2) predicted = up;
+      else if (filter === 3) predicted = (left + up) >> 1;
+      else if (filter === 4) predicted = paeth(left, up, upLeft);
+      samples[row + x] = (value + predicted) & 0xff;
+    }
+  }
+
+  const data = Buffer.alloc(width * height * 4);
+  for (let i = 0; i < width * height; i++) {
+    const s = i * channels;
+    let rgba: [number, number, number, number];
+    if (colorType === 6) {
+      rgba = [samples[s] ?? 0, samples[s + 1] ?? 0, samples[s + 2] ?? 0, samples[s + 3] ?? 0];
+    } else if (colorType === 2) {
+      rgba = [samples[s] ?? 0, samples[s + 1] ?? 0, samples[s + 2] ?? 0, 255];
+    } else if (colorType === 3) {
+      const index = samples[s] ?? 0;
+      rgba = [
+        palette?.[index * 3] ?? 0,
+        palette?.[index * 3 + 1] ?? 0,
+        palette?.[index * 3 + 2] ?? 0,
+        transparency?.[index] ?? 255
+      ];
+    } else {
+      const gray = samples[s] ?? 0;
+      rgba = [gray, gray, gray, colorType === 4 ? (samples[s + 1] ?? 0) : 255];
+    }
+    data.set(rgba, i * 4);
+  }
+  return { width, height, data };
+}
+
+function chunk(type: string, data: Buffer): Buffer {
+  const header = Buffer.alloc(8);
+  header.writeUInt32BE(data.length, 0);
+  header.write(type, 4, 'latin1');
+  const crc = Buffer.alloc(4);
+  crc.writeUInt32BE(crc32(Buffer.concat([header.subarray(4), data])), 0);
+  return Buffer.concat([header, data, crc]);
+}
+
+// Encodes an RGBA image without filtering, which is plenty for diff images that are mostly
+// flat color
+export function encodePng(image: RgbaImage): Buffer {
+  const header = Buffer.alloc(13);
+  header.writeUInt32BE(image.width, 0);
+  header.writeUInt32BE(image.height, 4);
+  header.writeUInt8(8, 8); // bit depth
+  header.writeUInt8(6, 9); // RGBA
+
+  const stride = image.width * 4;
+  const raw = Buffer.alloc((stride + 1) * image.height);
+  for (let y = 0; y < image.height; y++) {
+    image.data.copy(raw, y * (stride + 1) + 1, y * stride, (y + 1) * stride);
+  }
+  return Buffer.concat([
+    SIGNATURE,
+    chunk('IHDR', header),
+    chunk('IDAT', deflateSync(raw)),
+    chunk('IEND', Buffer.alloc(0))
+  ]);
+}
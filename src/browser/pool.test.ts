@@ -0,0 +1,67 @@
+import { describe, expect, it } from 'vitest';
+import { PagePool } from './pool.js';
+
+const settle = () => new Promise(resolve => setTimeout(resolve, 0));
+
+describe('PagePool', () => {
+  const poolOf = (size: number) => {
+    let created = 0;
+    const destroyed: number[] = [];
+    const pool = new PagePool(size, async () => ++created, item => destroyed.push(item));
+    return { pool, destroyed };
+  };
+
+  it('should hand out warm items and warm a replacement', async () => {
+    const { pool } = poolOf(2);
+    expect(pool.acquire()).toBeNull();
+    await settle();
+    expect(pool.status()).toEqual({ size: 2, idle: 2, hits: 0, misses: 1 });
+
+    expect(pool.acquire()).toBe(2);
+    await settle();
+    expect(pool.status()).toEqual({ size: 2, idle: 2, hits: 1, misses: 1 });
+  });
+
+  it('should only keep returned items while there is room', async () => {
+    const { pool } = poolOf(1);
+    pool.fill();
+    await settle();
+    const item = pool.acquire()!;
+    expect(pool.release(item)).toBe(false); // the replacement is already warming
+    await settle();
+
+    const taken = pool.acquire()!;
+    pool.drain();
+    expect(pool.release(taken)).toBe(true);
+    expect(pool.status().idle).toBe(1);
+  });
+
+  it('should destroy items that finish warming after a drain', async () => {
+    const { pool, destroyed } = poolOf(2);
+    pool.fill();
+    expect(pool.drain()).toEqual([]);
+    await settle();
+    expect(destroyed).toEqual([1, 2]);
+    expect(pool.status().idle).toBe(0);
+  });
+
+  it('should retry a failed creation on the next acquire', async () => {
+    let fail = true;
+    const pool = new PagePool(
+      1,
+      async () => {
+        if (fail) throw new Error('browser gone');
+        return 'page';
+      },
+      () => {}
+    );
+    pool.fill();
+    await settle();
+    expect(pool.status().idle).toBe(0);
+
+    fail = false;
+    expect(pool.acquire()).toBeNull();
+    await settle();
+    expect(pool.acquire()).toBe('page');
+  });
+});
@@ -0,0 +1,77 @@
+import type { PagePoolStatus } from '../types/index.js';
+
+// Keeps up to size items warm so callers skip the wait for a new one. Taking an item
+// starts warming a replacement, and items given back are kept while there is room. Items
+// still being created when the pool is drained are destroyed as soon as they are ready.
+export class PagePool<T> {
+  readonly size: number;
+  private readonly create: () => Promise<T>;
+  private readonly destroy: (item: T) => void;
+  private idle: T[] = [];
+  private warming = 0;
+  private generation = 0;
+  private hits = 0;
+  private misses = 0;
+
+  constructor(size: number, create: () => Promise<T>, destroy: (item: T) => void) {
+    this.size = size;
+    this.create = create;
+    this.destroy = destroy;
+  }
+
+  // null when no warm item is ready, the caller creates its own then
+  acquire(): T | null {
+    const item = this.idle.pop() ?? null;
+    if (item) {
+      this.hits++;
+    } else {
+      this.misses++;
+    }
+    this.fill();
+    return item;
+  }
+
+  // Whether the item was kept, the caller disposes of it otherwise
+  release(item: T): boolean {
+    if (this.idle.length + this.warming >= this.size) {
+      return false;
+    }
+    this.idle.push(item);
+    return true;
+  }
+
+  fill(): void {
+    const generation = this.generation;
+    while (this.idle.length + this.warming < this.size) {
+      this.warming++;
+      this.create()
+        .then(item => {
+          if (generation === this.generation && this.idle.length < this.size) {
+            this.idle.push(item);
+          } else {
+            this.destroy(item);
+          }
+        })
+        // the factory reports its own failures, the next acquire tries again
+        .catch(() => {})
+        .finally(() => {
+          if (generation === this.generation) {
+            this.warming--;
+          }
+        });
+    }
+  }
+
+  // Empties the pool and hands the idle items to the caller, e.g. when their browser is gone
+  drain(): T[] {
+    this.generation++;
+    this.warming = 0;
+    const items = this.idle;
+    this.idle = [];
+    return items;
+  }
+
+  status(): PagePoolStatus {
+    return { size: this.size, idle: this.idle.length, hits: this.hits, misses: this.misses };
+  }
+}
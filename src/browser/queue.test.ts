@@ -0,0 +1,81 @@
+import { describe, expect, it } from 'vitest';
+import { OperationTimeoutError, ShuttingDownError } from '../types/index.js';
+import { OperationQueue } from './queue.js';
+
+describe('OperationQueue', () => {
+  it('should never queue without a limit', async () => {
+    const queue = new OperationQueue(0, 1000);
+    const releases = await Promise.all([1, 2, 3].map(n => queue.acquire(`op${n}`)));
+
+    expect(queue.status()).toEqual({
+      maxConcurrency: 0,
+      queueTimeout: 1000,
+      running: 3,
+      queued: 0
+    });
+    for (const release of releases) release();
+    expect(queue.status().running).toBe(0);
+  });
+
+  it('should grant slots in FIFO order as they free up', async () => {
+    const queue = new OperationQueue(1, 0);
+    const order: string[] = [];
+    const first = await queue.acquire('first');
+    const second = queue.acquire('second').then(release => {
+      order.push('second');
+      return release;
+    });
+    const third = queue.acquire('third').then(release => {
+      order.push('third');
+      return release;
+    });
+
+    expect(queue.status()).toMatchObject({ running: 1, queued: 2 });
+    first();
+    first(); // releasing twice frees one slot only
+    (await second)();
+    (await third)();
+
+    expect(order).toEqual(['second', 'third']);
+    expect(queue.status()).toMatchObject({ running: 0, queued: 0 });
+  });
+
+  it('should hold the slot until the task settles', async () => {
+    const queue = new OperationQueue(1, 0);
+    const failing = queue.run('failing', async () => {
+      throw new Error('boom');
+    });
+    const next = queue.run('next', async () => queue.status());
+
+    await expect(failing).rejects.toThrow('boom');
+    expect(await next).toMatchObject({ running: 1, queued: 0 });
+  });
+
+  it('should fail with a timeout once the queue timeout runs out', async () => {
+    const queue = new OperationQueue(1, 20);
+    const release = await queue.acquire('busy');
+
+    const waiting = queue.acquire('browser_click');
+    await expect(waiting).rejects.toThrow(OperationTimeoutError);
+    await expect(waiting).rejects.toThrow(/browser_click timed out .* behind 1 running/);
+    expect(queue.status()).toMatchObject({ running: 1, queued: 0 });
+    release();
+    expect(queue.status().running).toBe(0);
+  });
+
+  it('should reject waiting and new operations once closed', async () => {
+    const queue = new OperationQueue(1, 0);
+    const release = await queue.acquire('busy');
+    const waiting = queue.acquire('browser_click');
+
+    queue.close(new ShuttingDownError());
+    await expect(waiting).rejects.toThrow(ShuttingDownError);
+    await expect(queue.acquire('browser_navigate')).rejects.toThrow(ShuttingDownError);
+    expect(queue.status()).toMatchObject({ running: 1, queued: 0 });
+
+    expect(await queue.idle(10)).toBe(false);
+    const idle = queue.idle(1000);
+    release();
+    expect(await idle).toBe(true);
+  });
+});
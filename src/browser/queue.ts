@@ -0,0 +1,118 @@
+import { OperationTimeoutError, type QueueStatus } from '../types/index.js';
+
+interface Waiter {
+  grant: () => void;
+  reject: (error: Error) => void;
+  timer: NodeJS.Timeout | null;
+}
+
+// FIFO semaphore for browser operations. Calls beyond maxConcurrency wait for a slot instead
+// of failing, until queueTimeout runs out (0 waits forever). A maxConcurrency of 0 never
+// queues anything.
+export class OperationQueue {
+  private readonly maxConcurrency: number;
+  private readonly queueTimeout: number;
+  private running = 0;
+  private waiting: Waiter[] = [];
+  private idleWaiters: Array<() => void> = [];
+  private closedWith: Error | null = null;
+
+  constructor(maxConcurrency: number, queueTimeout: number) {
+    this.maxConcurrency = maxConcurrency;
+    this.queueTimeout = queueTimeout;
+  }
+
+  // Resolves with the function that gives the slot back, which is safe to call more than once
+  acquire(operation: string): Promise<() => void> {
+    if (this.closedWith) {
+      return Promise.reject(this.closedWith);
+    }
+    if (!this.maxConcurrency || this.running < this.maxConcurrency) {
+      this.running++;
+      return Promise.resolve(this.releaser());
+    }
+
+    const startedAt = Date.now();
+    return new Promise((resolve, reject) => {
+      const waiter: Waiter = {
+        grant: () => {
+          if (waiter.timer) clearTimeout(waiter.timer);
+          this.running++;
+          resolve(this.releaser());
+        },
+        reject: error => {
+          if (waiter.timer) clearTimeout(waiter.timer);
+          reject(error);
+        },
+        timer: null
+      };
+      if (this.queueTimeout) {
+        waiter.timer = setTimeout(() => {
+          this.waiting = this.waiting.filter(other => other !== waiter);
+          const detail = `waited in the queue behind ${this.running} running operations`;
+          reject(
+            new OperationTimeoutError(operation, this.queueTimeout, Date.now() - startedAt, detail)
+          );
+        }, this.queueTimeout);
+      }
+      this.waiting.push(waiter);
+    });
+  }
+
+  // Runs the task once a slot is free and holds the slot until the task settles
+  async run<T>(operation: string, task: () => Promise<T>): Promise<T> {
+    const release = await this.acquire(operation);
+    try {
+      return await task();
+    } finally {
+      release();
+    }
+  }
+
+  // Fails the waiting and all later operations with error, running ones keep their slots
+  close(error: Error): void {
+    this.closedWith = error;
+    for (const waiter of this.waiting.splice(0)) {
+      waiter.reject(error);
+    }
+  }
+
+  // Resolves with true once no operation runs, or with false after timeout ms
+  idle(timeout: number): Promise<boolean> {
+    if (this.running === 0) {
+      return Promise.resolve(true);
+    }
+    return new Promise(resolve => {
+      const done = (idle: boolean) => {
+        clearTimeout(timer);
+        this.idleWaiters = this.idleWaiters.filter(other => other !== onIdle);
+        resolve(idle);
+      };
+      const onIdle = () => done(true);
+      const timer = setTimeout(() => done(false), timeout);
+      this.idleWaiters.push(onIdle);
+    });
+  }
+
+  status(): QueueStatus {
+    return {
+      maxConcurrency: this.maxConcurrency,
+      queueTimeout: this.queueTimeout,
+      running: this.running,
+      queued: this.waiting.length
+    };
+  }
+
+  private releaser(): () => void {
+    let released = false;
+    return () => {
+      if (released) return;
+      released = true;
+      this.running--;
+      this.waiting.shift()?.grant();
+      if (this.running === 0) {
+        for (const onIdle of [...this.idleWaiters]) onIdle();
+      }
+    };
+  }
+}
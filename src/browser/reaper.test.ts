@@ -0,0 +1,138 @@
+import { type ChildProcess, spawn } from 'node:child_process';
+import fs from 'node:fs';
+import os from 'node:os';
+import path from 'node:path';
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import {
+  type BrowserProcess,
+  chromeProfileOwner,
+  forgetBrowserProcess,
+  isAlive,
+  isOwnedBrowser,
+  lockProfile,
+  processGroupMemory,
+  readBrowserProcesses,
+  reapOrphanedBrowsers,
+  recordBrowserProcess,
+  unlockProfile
+} from './reaper.js';
+
+const waitForExit = (child: ChildProcess) =>
+  new Promise(resolve => (child.exitCode !== null ? resolve(null) : child.once('exit', resolve)));
+
+describe.skipIf(process.platform === 'win32')('Browser process reaper', () => {
+  let directory: string;
+  let file: string;
+  let children: ChildProcess[];
+
+  // Stands in for Chrome: a long running process with our profile on its command line
+  const fakeBrowser = (userDataDir: string) => {
+    const child = spawn(
+      process.execPath,
+      ['-e', 'setInterval(() => {}, 1000)', '--', `--user-data-dir=${userDataDir}`],
+      { detached: true, stdio: 'ignore' }
+    );
+    children.push(child);
+    return child;
+  };
+
+  const record = (pid: number, ownerPid: number, userDataDir: string): BrowserProcess => ({
+    pid,
+    ownerPid,
+    userDataDir,
+    startedAt: new Date().toISOString()
+  });
+
+  beforeEach(() => {
+    directory = fs.mkdtempSync(path.join(os.tmpdir(), 'pcs-reaper-'));
+    file = path.join(directory, '.browser-pids.json');
+    children = [];
+  });
+
+  afterEach(() => {
+    for (const child of children) child.kill('SIGKILL');
+    fs.rmSync(directory, { recursive: true, force: true });
+  });
+
+  it('should record and forget browsers, removing the file when none are left', () => {
+    recordBrowserProcess(file, record(101, process.pid, '/data/.browser'));
+    recordBrowserProcess(file, record(102, process.pid, '/data/.browser'));
+    recordBrowserProcess(file, record(101, process.pid, '/data/.browser'));
+    expect(readBrowserProcesses(file).map(r => r.pid)).toEqual([102, 101]);
+
+    forgetBrowserProcess(file, 101);
+    forgetBrowserProcess(file, 102);
+    expect(fs.existsSync(file)).toBe(false);
+    expect(readBrowserProcesses(file)).toEqual([]);
+  });
+
+  it('should ignore a corrupt state file', () => {
+    fs.writeFileSync(file, '{not json');
+    expect(readBrowserProcesses(file)).toEqual([]);
+    fs.writeFileSync(file, JSON.stringify([{ pid: 'x' }, { pid: 7 }]));
+    expect(readBrowserProcesses(file)).toEqual([{ pid: 7 }]);
+  });
+
+  it('should only claim processes running on the recorded profile', async () => {
+    const child = fakeBrowser(path.join(directory, '.browser'));
+    await new Promise(resolve => child.once('spawn', resolve));
+    const pid = child.pid ?? 0;
+
+    expect(isOwnedBrowser(record(pid, 1, path.join(directory, '.browser')))).toBe(true);
+    expect(isOwnedBrowser(record(pid, 1, '/somewhere/else'))).toBe(false);
+  });
+
+  it('should measure the memory of a process group', async () => {
+    const child = fakeBrowser(path.join(directory, '.browser'));
+    await new Promise(resolve => child.once('spawn', resolve));
+
+    expect(processGroupMemory(child.pid ?? 0)).toBeGreaterThan(1024 * 1024);
+    expect(processGroupMemory(2 ** 22 + 1)).toBeNull();
+  });
+
+  it('should reap browsers of a pcs that is gone and keep those of a running one', async () => {
+    const orphan = fakeBrowser(path.join(directory, '.browser'));
+    const owned = fakeBrowser(path.join(directory, '.browser'));
+    const stranger = fakeBrowser('/not/ours');
+    await Promise.all([orphan, owned, stranger].map(c => new Promise(r => c.once('spawn', r))));
+
+    const gone = spawn(process.execPath, ['-e', '']);
+    await waitForExit(gone);
+    const userDataDir = path.join(directory, '.browser');
+    recordBrowserProcess(file, record(orphan.pid ?? 0, gone.pid ?? 0, userDataDir));
+    recordBrowserProcess(file, record(owned.pid ?? 0, process.ppid, userDataDir));
+    recordBrowserProcess(file, record(stranger.pid ?? 0, gone.pid ?? 0, userDataDir));
+
+    expect(reapOrphanedBrowsers(file)).toEqual([orphan.pid]);
+    await waitForExit(orphan);
+    expect(isAlive(owned.pid ?? 0)).toBe(true);
+    expect(isAlive(stranger.pid ?? 0)).toBe(true);
+    expect(readBrowserProcesses(file).map(r => r.pid)).toEqual([owned.pid]);
+  });
+
+  it('should lock a profile for one process, taking over stale locks', async () => {
+    const userDataDir = path.join(directory, 'profile');
+    const lock = path.join(userDataDir, 'pcs.lock');
+    expect(lockProfile(userDataDir)).toBeNull();
+    expect(lockProfile(userDataDir)).toBeNull();
+
+    const other = fakeBrowser(userDataDir);
+    fs.writeFileSync(lock, String(other.pid));
+    expect(lockProfile(userDataDir)).toBe(other.pid);
+    unlockProfile(userDataDir);
+    expect(fs.existsSync(lock)).toBe(true);
+
+    const gone = spawn(process.execPath, ['-e', '']);
+    await waitForExit(gone);
+    fs.writeFileSync(lock, String(gone.pid));
+    expect(lockProfile(userDataDir)).toBeNull();
+    unlockProfile(userDataDir);
+    expect(fs.existsSync(lock)).toBe(false);
+  });
+
+  it("should read the owner of Chrome's profile lock", () => {
+    expect(chromeProfileOwner(directory)).toBeNull();
+    fs.symlinkSync('build-host-1234', path.join(directory, 'SingletonLock'));
+    expect(chromeProfileOwner(directory)).toEqual({ host: 'build-host', pid: 1234 });
+  });
+});
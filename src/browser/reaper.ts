@@ -0,0 +1,218 @@
+import { execFileSync } from 'node:child_process';
+import fs from 'node:fs';
+import path from 'node:path';
+
+// A browser this server launched, kept in a state file so a later start can clean up after
+// a run that was killed before it could close its browsers
+export interface BrowserProcess {
+  pid: number; // also the process group of its renderers, Puppeteer launches it detached
+  ownerPid: number; // the pcs process that launched it
+  userDataDir: string;
+  startedAt: string;
+}
+
+export function readBrowserProcesses(file: string): BrowserProcess[] {
+  try {
+    const records = JSON.parse(fs.readFileSync(file, 'utf8'));
+    return Array.isArray(records)
+      ? records.filter(record => Number.isInteger(record?.pid) && record.pid > 0)
+      : [];
+  } catch {
+    return [];
+  }
+}
+
+function writeBrowserProcesses(file: string, records: BrowserProcess[]): void {
+  if (records.length) {
+    fs.writeFileSync(file, JSON.stringify(records, null, 2));
+  } else {
+    fs.rmSync(file, { force: true });
+  }
+}
+
+export function recordBrowserProcess(file: string, record: BrowserProcess): void {
+  const records = readBrowserProcesses(file).filter(other => other.pid !== record.pid);
+  writeBrowserProcesses(file, [...records, record]);
+}
+
+export function forgetBrowserProcess(file: string, pid: number): void {
+  writeBrowserProcesses(file, readBrowserProcesses(file).filter(record => record.pid !== pid));
+}
+
+export function isAlive(pid: number): boolean {
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    // EPERM means it runs, just as another user
+    return (error as NodeJS.ErrnoException).code === 'EPERM';
+  }
+}
+
+function commandLine(pid: number): string | null {
+  try {
+    if (process.platform === 'linux') {
+      return fs.readFileSync(`/proc/${pid}/cmdline`, 'utf8').replace(/\0/g, ' ');
+    }
+    if (process.platform === 'win32') {
+      return execFileSync(
+        'powershell.exe',
+        [
+          '-NoProfile',
+          '-Command',
+          `(Get-CimInstance Win32_Process -Filter "ProcessId=${pid}").CommandLine`
+        ],
+        { encoding: 'utf8', windowsHide: true }
+      );
+    }
+    return execFileSync('ps', ['-o', 'command=', '-p', String(pid)], { encoding: 'utf8' });
+  } catch {
+    return null;
+  }
+}
+
+// PIDs get reused, so a recorded one only counts as ours while it still runs Chrome on our
+// profile
+export function isOwnedBrowser(record: BrowserProcess): boolean {
+  return commandLine(record.pid)?.includes(`--user-data-dir=${record.userDataDir}`) ?? false;
+}
+
+// Kills the browser together with its renderers, which share its process group
+export function killProcessTree(pid: number): void {
+  try {
+    if (process.platform === 'win32') {
+      execFileSync('taskkill', ['/pid', String(pid), '/T', '/F'], { windowsHide: true });
+    } else {
+      process.kill(-pid, 'SIGKILL');
+    }
+  } catch {
+    try {
+      process.kill(pid, 'SIGKILL');
+    } catch {
+      // already gone
+    }
+  }
+}
+
+// Whether a browser recorded in file still runs on userDataDir, Chrome refuses to share it
+export function isProfileInUse(file: string, userDataDir: string): boolean {
+  return readBrowserProcesses(file).some(
+    record => record.userDataDir === userDataDir && isAlive(record.pid) && isOwnedBrowser(record)
+  );
+}
+
+// Chrome marks a profile as taken with these, a browser that was killed leaves them behind
+// and, on another host name (a new container), the next launch then fails with "profile in
+// use". Only call this while no browser runs on the profile.
+export function removeProfileLocks(userDataDir: string): void {
+  for (const name of ['SingletonLock', 'SingletonSocket', 'SingletonCookie', 'lockfile']) {
+    fs.rmSync(path.join(userDataDir, name), { force: true });
+  }
+}
+
+// Chrome points SingletonLock at "<host>-<pid>" of the browser holding the profile
+export function chromeProfileOwner(userDataDir: string): { host: string; pid: number } | null {
+  try {
+    const target = fs.readlinkSync(path.join(userDataDir, 'SingletonLock'));
+    const match = target.match(/^(.+)-(\d+)$/);
+    return match?.[1] ? { host: match[1], pid: Number(match[2]) } : null;
+  } catch {
+    return null;
+  }
+}
+
+const PROFILE_LOCK = 'pcs.lock';
+
+function readLockOwner(file: string): number | null {
+  try {
+    const pid = Number(fs.readFileSync(file, 'utf8').trim());
+    return Number.isInteger(pid) && pid > 0 ? pid : null;
+  } catch {
+    return null;
+  }
+}
+
+// Takes the profile for this process, so two servers never share it. Returns the pid of the
+// live process holding it instead, a lock whose owner is gone is taken over.
+export function lockProfile(userDataDir: string): number | null {
+  const file = path.join(userDataDir, PROFILE_LOCK);
+  fs.mkdirSync(userDataDir, { recursive: true });
+  for (;;) {
+    try {
+      fs.writeFileSync(file, String(process.pid), { flag: 'wx' });
+      return null;
+    } catch (error) {
+      if ((error as NodeJS.ErrnoException).code !== 'EEXIST') {
+        throw error;
+      }
+    }
+    const owner = readLockOwner(file);
+    if (owner === process.pid) {
+      return null;
+    }
+    if (owner !== null && isAlive(owner)) {
+      return owner;
+    }
+    fs.rmSync(file, { force: true });
+  }
+}
+
+export function unlockProfile(userDataDir: string): void {
+  const file = path.join(userDataDir, PROFILE_LOCK);
+  if (readLockOwner(file) === process.pid) {
+    fs.rmSync(file, { force: true });
+  }
+}
+
+// Resident memory in bytes of the browser and its renderers, which share its process group,
+// or null where it can't be read
+export function processGroupMemory(pid: number): number | null {
+  try {
+    if (process.platform === 'linux') {
+      let total = 0;
+      for (const entry of fs.readdirSync('/proc')) {
+        if (!/^\d+$/.test(entry)) continue;
+        try {
+          // The command name may contain spaces, the fields after it don't
+          const stat = fs.readFileSync(`/proc/${entry}/stat`, 'utf8');
+          const group = Number(stat.slice(stat.lastIndexOf(')') + 2).split(' ')[2]);
+          if (group !== pid) continue;
+          const status = fs.readFileSync(`/proc/${entry}/status`, 'utf8');
+          total += Number(status.match(/^VmRSS:\s+(\d+) kB/m)?.[1] ?? 0) * 1024;
+        } catch {
+          // exited while we looked
+        }
+      }
+      return total || null;
+    }
+    if (process.platform === 'win32') {
+      return null;
+    }
+    const lines = execFileSync('ps', ['-A', '-o', 'pgid=,rss='], { encoding: 'utf8' }).split('\n');
+    let total = 0;
+    for (const line of lines) {
+      const [group, rss] = line.trim().split(/\s+/).map(Number);
+      if (group === pid) total += (rss ?? 0) * 1024;
+    }
+    return total || null;
+  } catch {
+    return null;
+  }
+}
+
+// Kills the recorded browsers whose pcs is gone but which still run, and returns their PIDs.
+// Browsers of a pcs that is still running are left alone.
+export function reapOrphanedBrowsers(file: string): number[] {
+  const reaped: number[] = [];
+  const kept: BrowserProcess[] = [];
+  for (const record of readBrowserProcesses(file)) {
+    if (record.ownerPid !== process.pid && isAlive(record.ownerPid)) {
+      kept.push(record);
+    } else if (isAlive(record.pid) && isOwnedBrowser(record)) {
+      killProcessTree(record.pid);
+      reaped.push(record.pid);
+    }
+  }
+  writeBrowserProcesses(file, kept);
+  return reaped;
+}
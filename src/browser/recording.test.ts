@@ -0,0 +1,56 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { Recording, resolveRecordingOptions } from './recording.js';
+
+describe('Recording', () => {
+  const fakeRecorder = () => {
+    const recorder = {
+      stops: 0,
+      async stop() {
+        recorder.stops++;
+      }
+    };
+    return recorder;
+  };
+
+  describe('resolveRecordingOptions', () => {
+    it('should default to a WebM at 30 fps for up to ten minutes', () => {
+      expect(resolveRecordingOptions({}, 'recordings/s1.webm')).toEqual({
+        path: 'recordings/s1.webm',
+        format: 'webm',
+        fps: 30,
+        maxDuration: 600000
+      });
+      expect(resolveRecordingOptions({ path: 'run.GIF', fps: 10 }, 'x.webm')).toMatchObject({
+        format: 'gif',
+        fps: 10
+      });
+    });
+
+    it('should reject other formats and out of range limits', () => {
+      expect(() => resolveRecordingOptions({ path: 'run.mp4' }, 'x.webm')).toThrow(BrowserError);
+      expect(() => resolveRecordingOptions({ fps: 120 }, 'x.webm')).toThrow(/fps/);
+      expect(() => resolveRecordingOptions({ maxDuration: 0 }, 'x.webm')).toThrow(/maxDuration/);
+    });
+  });
+
+  it('should stop the recorder once', async () => {
+    const recorder = fakeRecorder();
+    const recording = new Recording(recorder, 'p1', '/x.webm', 60000);
+
+    await Promise.all([recording.stop(), recording.stop()]);
+    expect(recorder.stops).toBe(1);
+    expect(recording.truncated).toBe(false);
+  });
+
+  it('should stop by itself at the maximum duration', async () => {
+    const recorder = fakeRecorder();
+    const recording = new Recording(recorder, 'p1', '/x.webm', 10);
+
+    await new Promise(resolve => setTimeout(resolve, 30));
+    expect(recorder.stops).toBe(1);
+    expect(recording.truncated).toBe(true);
+    await recording.stop();
+    expect(recorder.stops).toBe(1);
+  });
+});
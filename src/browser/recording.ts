@@ -0,0 +1,86 @@
+import path from 'node:path';
+import { BrowserError, InvalidArgumentError, type StartRecordingRequest } from '../types/index.js';
+
+// Puppeteer's default frame rate
+export const DEFAULT_RECORDING_FPS = 30;
+
+// Bounds the file size of a recording nobody stops
+export const DEFAULT_MAX_RECORDING_DURATION = 10 * 60 * 1000;
+
+// What Puppeteer's screencast can encode, ffmpeg picks the codec from the format
+export const RECORDING_FORMATS = ['webm', 'gif'] as const;
+
+export type RecordingFormat = (typeof RECORDING_FORMATS)[number];
+
+export interface RecordingOptions {
+  path: string;
+  format: RecordingFormat;
+  fps: number;
+  maxDuration: number;
+}
+
+// The format follows the file extension, WebM unless the path ends in .gif
+export function resolveRecordingOptions(
+  request: StartRecordingRequest,
+  defaultPath: string
+): RecordingOptions {
+  const file = request.path || defaultPath;
+  const extension = path.extname(file).slice(1).toLowerCase();
+  const format = RECORDING_FORMATS.find(value => value === extension);
+  if (!format) {
+    throw new BrowserError(`Recording path must end in .webm or .gif, got ${file}`);
+  }
+
+  const fps = request.fps ?? DEFAULT_RECORDING_FPS;
+  if (!Number.isInteger(fps) || fps < 1 || fps > 60) {
+    throw new InvalidArgumentError('Recording fps must be an integer from 1 to 60');
+  }
+  const maxDuration = request.maxDuration ?? DEFAULT_MAX_RECORDING_DURATION;
+  if (!Number.isInteger(maxDuration) || maxDuration <= 0) {
+    throw new InvalidArgumentError(
+      'Recording maxDuration must be a positive number of milliseconds'
+    );
+  }
+  return { path: file, format, fps, maxDuration };
+}
+
+// A screencast in progress. It stops once with stop() or when it reaches its maximum
+// duration, after which the file is complete.
+export class Recording {
+  readonly pageId: string;
+  readonly path: string;
+  truncated = false; // stopped by the maximum duration
+  private readonly recorder: { stop(): Promise<void> };
+  private readonly startedAt = Date.now();
+  private readonly timer: NodeJS.Timeout;
+  private stopping: Promise<void> | null = null;
+  private stoppedAt: number | null = null;
+
+  constructor(
+    recorder: { stop(): Promise<void> },
+    pageId: string,
+    filePath: string,
+    maxDuration: number
+  ) {
+    this.recorder = recorder;
+    this.pageId = pageId;
+    this.path = filePath;
+    this.timer = setTimeout(() => {
+      this.truncated = true;
+      this.stop().catch(() => {});
+    }, maxDuration);
+    this.timer.unref();
+  }
+
+  get durationMs(): number {
+    return (this.stoppedAt ?? Date.now()) - this.startedAt;
+  }
+
+  stop(): Promise<void> {
+    clearTimeout(this.timer);
+    this.stopping ??= this.recorder.stop().finally(() => {
+      this.stoppedAt = Date.now();
+    });
+    return this.stopping;
+  }
+}
@@ -0,0 +1,64 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError, type ErrorCode } from '../types/index.js';
+import { resolveRetryPolicy, retryDelay, runWithRetry } from './retry.js';
+
+describe('Retry', () => {
+  const codeOf = (error: unknown) => (error as { code: ErrorCode }).code;
+  const failing = (codes: ErrorCode[]) => {
+    const calls: number[] = [];
+    const task = async (attempt: number) => {
+      calls.push(attempt);
+      const code = codes[attempt - 1];
+      if (code) throw { code };
+      return 'done';
+    };
+    return { calls, task };
+  };
+
+  it('should default to 3 attempts on element errors', () => {
+    expect(resolveRetryPolicy({})).toEqual({
+      attempts: 3,
+      backoffMs: 200,
+      retryOn: ['STALE_ELEMENT', 'DETACHED_FRAME', 'SELECTOR_NOT_FOUND']
+    });
+    expect(() => resolveRetryPolicy({ attempts: 0 })).toThrow(BrowserError);
+    expect(() => resolveRetryPolicy({ backoffMs: -1 })).toThrow(/backoffMs/);
+    expect(() => resolveRetryPolicy({ retryOn: ['NOPE' as ErrorCode] })).toThrow(/one of: /);
+  });
+
+  it('should double the backoff after each retry', () => {
+    const policy = resolveRetryPolicy({ backoffMs: 100 });
+    expect([2, 3, 4].map(attempt => retryDelay(policy, attempt))).toEqual([100, 200, 400]);
+  });
+
+  it('should retry retryable failures until an attempt succeeds', async () => {
+    const { calls, task } = failing(['STALE_ELEMENT', 'DETACHED_FRAME']);
+    const policy = resolveRetryPolicy({ backoffMs: 0 });
+    const outcome = await runWithRetry(policy, Infinity, task, codeOf);
+    expect(outcome).toEqual({ ok: true, value: 'done', attempts: 3 });
+    expect(calls).toEqual([1, 2, 3]);
+  });
+
+  it('should stop at other codes, the last attempt or the deadline', async () => {
+    const policy = resolveRetryPolicy({ attempts: 2, backoffMs: 0 });
+    const invalid = failing(['INVALID_ARGUMENT']);
+    expect(await runWithRetry(policy, Infinity, invalid.task, codeOf)).toMatchObject({
+      ok: false,
+      error: { code: 'INVALID_ARGUMENT' },
+      attempts: 1
+    });
+
+    const stale = failing(['STALE_ELEMENT', 'STALE_ELEMENT', 'STALE_ELEMENT']);
+    expect(await runWithRetry(policy, Infinity, stale.task, codeOf)).toMatchObject({
+      ok: false,
+      attempts: 2
+    });
+
+    const slow = resolveRetryPolicy({ backoffMs: 1000 });
+    const late = failing(['STALE_ELEMENT']);
+    expect(await runWithRetry(slow, Date.now() + 500, late.task, codeOf)).toMatchObject({
+      ok: false,
+      attempts: 1
+    });
+  });
+});
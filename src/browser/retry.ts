@@ -0,0 +1,79 @@
+import {
+  ERROR_CODES,
+  type ErrorCode,
+  InvalidArgumentError,
+  type RetryOptions
+} from '../types/index.js';
+
+// Failures that come from the page changing under the call rather than from the call itself
+export const DEFAULT_RETRY_CODES: ErrorCode[] = [
+  'STALE_ELEMENT',
+  'DETACHED_FRAME',
+  'SELECTOR_NOT_FOUND'
+];
+
+export const DEFAULT_RETRY_ATTEMPTS = 3;
+export const DEFAULT_RETRY_BACKOFF_MS = 200;
+const MAX_RETRY_ATTEMPTS = 10;
+
+export interface RetryPolicy {
+  attempts: number;
+  backoffMs: number;
+  retryOn: ErrorCode[];
+}
+
+export type RetryOutcome<T> =
+  | { ok: true; value: T; attempts: number }
+  | { ok: false; error: unknown; attempts: number };
+
+export function resolveRetryPolicy(options: RetryOptions): RetryPolicy {
+  const attempts = options.attempts ?? DEFAULT_RETRY_ATTEMPTS;
+  if (!Number.isInteger(attempts) || attempts < 1 || attempts > MAX_RETRY_ATTEMPTS) {
+    throw new InvalidArgumentError(
+      `retry attempts must be an integer from 1 to ${MAX_RETRY_ATTEMPTS}`
+    );
+  }
+  const backoffMs = options.backoffMs ?? DEFAULT_RETRY_BACKOFF_MS;
+  if (!Number.isInteger(backoffMs) || backoffMs < 0) {
+    throw new InvalidArgumentError(
+      'retry backoffMs must be a non-negative integer of milliseconds'
+    );
+  }
+  const retryOn = options.retryOn ?? DEFAULT_RETRY_CODES;
+  const invalid = retryOn.find(code => !ERROR_CODES.includes(code));
+  if (invalid !== undefined) {
+    throw new InvalidArgumentError(`retry retryOn codes must be one of: ${ERROR_CODES.join(', ')}`);
+  }
+  return { attempts, backoffMs, retryOn };
+}
+
+// Milliseconds to wait before the given attempt, the first retry being attempt 2
+export function retryDelay(policy: RetryPolicy, attempt: number): number {
+  return policy.backoffMs * 2 ** (attempt - 2);
+}
+
+// Runs task until it succeeds, fails with a code the policy does not retry or runs out of
+// attempts. A retry that could not start before the deadline is not made, so retries never
+// stretch the caller's budget.
+export async function runWithRetry<T>(
+  policy: RetryPolicy,
+  deadline: number,
+  task: (attempt: number) => Promise<T>,
+  codeOf: (error: unknown) => ErrorCode
+): Promise<RetryOutcome<T>> {
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return { ok: true, value: await task(attempt), attempts: attempt };
+    } catch (error) {
+      const delay = retryDelay(policy, attempt + 1);
+      if (
+        attempt >= policy.attempts ||
+        !policy.retryOn.includes(codeOf(error)) ||
+        Date.now() + delay >= deadline
+      ) {
+        return { ok: false, error, attempts: attempt };
+      }
+      await new Promise(resolve => setTimeout(resolve, delay));
+    }
+  }
+}
@@ -0,0 +1,77 @@
+import { describe, expect, it } from 'vitest';
+import type { CDPSession } from 'puppeteer-core';
+import { BrowserError, type ScreencastFrame } from '../types/index.js';
+import { resolveScreencastOptions, Screencast } from './screencast.js';
+
+describe('Screencast', () => {
+  const fakeSession = () => {
+    const handlers = new Map<string, (event: any) => void>();
+    const session = {
+      sent: [] as string[],
+      handlers,
+      on: (event: string, handler: (event: any) => void) => handlers.set(event, handler),
+      off: (event: string) => handlers.delete(event),
+      send: async (method: string) => session.sent.push(method),
+      detach: async () => session.sent.push('detach')
+    };
+    return session;
+  };
+
+  const screencastOf = (session: object, onFrame: (frame: ScreencastFrame) => void = () => {}) =>
+    new Screencast(session as CDPSession, { fps: 2, quality: 60 }, onFrame);
+
+  const frameAt = (timestamp: number) => ({
+    data: 'AAAA',
+    sessionId: 1,
+    metadata: { timestamp, deviceWidth: 800, deviceHeight: 600 }
+  });
+
+  describe('resolveScreencastOptions', () => {
+    it('should default to 5 fps at quality 60', () => {
+      expect(resolveScreencastOptions({})).toEqual({ fps: 5, quality: 60 });
+      expect(resolveScreencastOptions({ fps: 2, quality: 0, maxWidth: 640 })).toEqual({
+        fps: 2,
+        quality: 0,
+        maxWidth: 640
+      });
+    });
+
+    it('should reject out of range values', () => {
+      expect(() => resolveScreencastOptions({ fps: 0 })).toThrow(BrowserError);
+      expect(() => resolveScreencastOptions({ quality: 101 })).toThrow(/quality/);
+      expect(() => resolveScreencastOptions({ maxHeight: -1 })).toThrow(/maxHeight/);
+    });
+  });
+
+  it('should ack every frame and drop those above the fps', async () => {
+    const session = fakeSession();
+    const frames: ScreencastFrame[] = [];
+    const screencast = screencastOf(session, frame => frames.push(frame));
+    await screencast.start();
+
+    const emit = session.handlers.get('Page.screencastFrame')!;
+    for (const timestamp of [100, 100.2, 100.5, 101.4]) {
+      emit(frameAt(timestamp));
+    }
+    expect(frames.map(frame => frame.timestamp)).toEqual([100000, 100500, 101400]);
+    expect(frames[2]).toMatchObject({ index: 3, data: 'AAAA', width: 800, height: 600 });
+    expect(session.sent.filter(method => method === 'Page.screencastFrameAck')).toHaveLength(4);
+  });
+
+  it('should settle once and stop Chrome only when the page is still there', async () => {
+    const session = fakeSession();
+    const screencast = screencastOf(session);
+
+    const [first, second] = await Promise.all([screencast.stop(), screencast.stop('closed')]);
+    expect(first).toEqual(second);
+    expect(first.reason).toBe('stopped');
+    expect(screencast.stopped).toBe(true);
+    expect(session.handlers.size).toBe(0);
+    await screencast.done;
+    expect(session.sent).toContain('Page.stopScreencast');
+
+    const closed = fakeSession();
+    expect((await screencastOf(closed).stop('closed')).reason).toBe('closed');
+    expect(closed.sent).toEqual([]);
+  });
+});
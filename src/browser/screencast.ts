@@ -0,0 +1,111 @@
+import type { CDPSession, Protocol } from 'puppeteer-core';
+import {
+  InvalidArgumentError,
+  type ScreencastFrame,
+  type ScreencastResult,
+  type StartScreencastRequest
+} from '../types/index.js';
+
+// Enough for a live view without flooding the client
+export const DEFAULT_SCREENCAST_FPS = 5;
+export const DEFAULT_SCREENCAST_QUALITY = 60;
+
+export interface ScreencastOptions {
+  fps: number;
+  quality: number;
+  maxWidth?: number;
+  maxHeight?: number;
+}
+
+export function resolveScreencastOptions(request: StartScreencastRequest): ScreencastOptions {
+  const fps = request.fps ?? DEFAULT_SCREENCAST_FPS;
+  if (!Number.isInteger(fps) || fps < 1 || fps > 30) {
+    throw new InvalidArgumentError('Screencast fps must be an integer from 1 to 30');
+  }
+  const quality = request.quality ?? DEFAULT_SCREENCAST_QUALITY;
+  if (!Number.isInteger(quality) || quality < 0 || quality > 100) {
+    throw new InvalidArgumentError('Screencast quality must be an integer from 0 to 100');
+  }
+  const options: ScreencastOptions = { fps, quality };
+  for (const key of ['maxWidth', 'maxHeight'] as const) {
+    const value = request[key];
+    if (value !== undefined) {
+      if (!Number.isInteger(value) || value <= 0) {
+        throw new InvalidArgumentError(`Screencast ${key} must be a positive number of pixels`);
+      }
+      options[key] = value;
+    }
+  }
+  return options;
+}
+
+// A live stream of JPEG frames from Chrome's Page.startScreencast on a CDP session of its
+// own. Chrome sends a frame whenever the page repaints, frames closer together than the
+// fps allows are acked and dropped. done settles once, when stop() is called.
+export class Screencast {
+  readonly done: Promise<ScreencastResult>;
+  private readonly cdp: CDPSession;
+  private readonly options: ScreencastOptions;
+  private readonly onFrame: (frame: ScreencastFrame) => void;
+  private readonly startedAt = Date.now();
+  private frames = 0;
+  private lastFrameAt: number | null = null;
+  private result: ScreencastResult | null = null;
+  private resolve: (result: ScreencastResult) => void = () => {};
+
+  constructor(
+    cdp: CDPSession,
+    options: ScreencastOptions,
+    onFrame: (frame: ScreencastFrame) => void
+  ) {
+    this.cdp = cdp;
+    this.options = options;
+    this.onFrame = onFrame;
+    this.done = new Promise(resolve => {
+      this.resolve = resolve;
+    });
+    this.cdp.on('Page.screencastFrame', this.handleFrame);
+  }
+
+  get stopped(): boolean {
+    return this.result !== null;
+  }
+
+  async start(): Promise<void> {
+    await this.cdp.send('Page.startScreencast', { format: 'jpeg', ...this.options });
+  }
+
+  // Pass closed when the page is gone, there is nothing left to tell Chrome then
+  stop(reason: ScreencastResult['reason'] = 'stopped'): Promise<ScreencastResult> {
+    if (!this.result) {
+      this.result = { frames: this.frames, durationMs: Date.now() - this.startedAt, reason };
+      this.cdp.off('Page.screencastFrame', this.handleFrame);
+      if (reason === 'stopped') {
+        this.cdp
+          .send('Page.stopScreencast')
+          .then(() => this.cdp.detach())
+          .catch(() => {});
+      }
+      this.resolve(this.result);
+    }
+    return this.done;
+  }
+
+  private readonly handleFrame = (event: Protocol.Page.ScreencastFrameEvent): void => {
+    // Chrome sends nothing more until the frame is acked
+    this.cdp.send('Page.screencastFrameAck', { sessionId: event.sessionId }).catch(() => {});
+    const timestamp = (event.metadata.timestamp ?? Date.now() / 1000) * 1000;
+    if (this.lastFrameAt !== null && timestamp - this.lastFrameAt < 1000 / this.options.fps) {
+      return;
+    }
+    this.lastFrameAt = timestamp;
+    this.frames++;
+    this.onFrame({
+      index: this.frames,
+      data: event.data,
+      width: event.metadata.deviceWidth,
+      height: event.metadata.deviceHeight,
+      timestamp: Math.round(timestamp)
+    });
+  };
+}
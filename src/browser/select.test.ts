@@ -0,0 +1,65 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { matchOptions, type SelectState, toCriteria } from './select.js';
+
+function state(multiple = false): SelectState {
+  return {
+    isSelect: true,
+    multiple,
+    disabled: false,
+    description: 'select',
+    options: [
+      { value: '', label: 'Choose a country', index: 0, disabled: true },
+      { value: 'us', label: 'United States', index: 1, disabled: false },
+      { value: 'de', label: 'Germany', index: 2, disabled: false },
+      { value: 'fr', label: 'France', index: 3, disabled: false }
+    ]
+  };
+}
+
+describe('Select helpers', () => {
+  describe('toCriteria', () => {
+    it('should accept single values and arrays', () => {
+      expect(toCriteria({ value: 'us', label: ['Germany', 'France'], index: 0 })).toEqual({
+        values: ['us'],
+        labels: ['Germany', 'France'],
+        indexes: [0]
+      });
+    });
+
+    it('should require a criterion', () => {
+      expect(() => toCriteria({})).toThrow(BrowserError);
+      expect(() => toCriteria({ value: [] })).toThrow(/value, label or index/);
+      expect(() => toCriteria({ index: -1 })).toThrow(/non-negative/);
+    });
+  });
+
+  describe('matchOptions', () => {
+    it('should match by value, label and index', () => {
+      expect(matchOptions(state(), { value: 'de' }, '#country')).toEqual([2]);
+      expect(matchOptions(state(), { label: '  united   states ' }, '#country')).toEqual([1]);
+      expect(matchOptions(state(), { index: 3 }, '#country')).toEqual([3]);
+    });
+
+    it('should select several options of a multiple select', () => {
+      expect(matchOptions(state(true), { value: ['fr', 'us'], index: 1 }, '#c')).toEqual([1, 3]);
+    });
+
+    it('should list the options when nothing matches', () => {
+      expect(() => matchOptions(state(), { label: 'Spain', index: 9 }, '#country')).toThrow(
+        'No option of #country matches label "Spain", index 9, options: "" (Choose a country), ' +
+          '"us" (United States), "de" (Germany), "fr" (France)'
+      );
+    });
+
+    it('should reject disabled options', () => {
+      expect(() => matchOptions(state(), { index: 0 }, '#country')).toThrow(/is disabled/);
+    });
+
+    it('should reject several options on a single select', () => {
+      expect(() => matchOptions(state(), { value: ['us', 'de'] }, '#country')).toThrow(
+        '#country is not a multiple select but 2 options match'
+      );
+    });
+  });
+});
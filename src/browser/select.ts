@@ -0,0 +1,133 @@
+import {
+  BrowserError,
+  InvalidArgumentError,
+  type OptionCriteria,
+  type SelectOptionInfo
+} from '../types/index.js';
+
+export interface SelectState {
+  isSelect: boolean;
+  multiple: boolean;
+  disabled: boolean;
+  description: string; // what the element is when it is not a select
+  options: SelectOptionInfo[];
+}
+
+interface NormalizedCriteria {
+  values: string[];
+  labels: string[];
+  indexes: number[];
+}
+
+function toArray<T>(value: T | T[] | undefined): T[] {
+  return value === undefined ? [] : Array.isArray(value) ? value : [value];
+}
+
+function normalizeLabel(label: string): string {
+  return label.replace(/\s+/g, ' ').trim();
+}
+
+export function toCriteria(criteria: OptionCriteria): NormalizedCriteria {
+  const normalized = {
+    values: toArray(criteria.value).map(String),
+    labels: toArray(criteria.label).map(String),
+    indexes: toArray(criteria.index)
+  };
+  if (!normalized.values.length && !normalized.labels.length && !normalized.indexes.length) {
+    throw new InvalidArgumentError('A value, label or index of the option to select is required');
+  }
+  if (normalized.indexes.some(index => !Number.isInteger(index) || index < 0)) {
+    throw new InvalidArgumentError('Option indexes must be non-negative integers');
+  }
+  return normalized;
+}
+
+export function describeOptions(options: SelectOptionInfo[]): string {
+  if (!options.length) {
+    return 'the select has no options';
+  }
+  return `options: ${options.map(option => `"${option.value}" (${option.label})`).join(', ')}`;
+}
+
+// Indexes of the options to select. Labels match with collapsed whitespace, then
+// case-insensitively. Fails listing the available options when a criterion matches nothing,
+// and when a single select would end up with several options.
+export function matchOptions(
+  state: SelectState,
+  criteria: OptionCriteria,
+  selector: string
+): number[] {
+  const { values, labels, indexes } = toCriteria(criteria);
+  const { options } = state;
+  const matched = new Set<number>();
+  const missing: string[] = [];
+
+  for (const value of values) {
+    const option = options.find(candidate => candidate.value === value);
+    if (option) matched.add(option.index);
+    else missing.push(`value "${value}"`);
+  }
+  for (const label of labels) {
+    const wanted = normalizeLabel(label);
+    const option =
+      options.find(candidate => normalizeLabel(candidate.label) === wanted) ??
+      options.find(
+        candidate => normalizeLabel(candidate.label).toLowerCase() === wanted.toLowerCase()
+      );
+    if (option) matched.add(option.index);
+    else missing.push(`label "${label}"`);
+  }
+  for (const index of indexes) {
+    if (index < options.length) matched.add(index);
+    else missing.push(`index ${index}`);
+  }
+
+  if (missing.length) {
+    throw new BrowserError(
+      `No option of ${selector} matches ${missing.join(', ')}, ${describeOptions(options)}`
+    );
+  }
+  const disabled = options.filter(option => matched.has(option.index) && option.disabled);
+  if (disabled.length) {
+    throw new BrowserError(
+      `Option ${disabled.map(option => `"${option.value}"`).join(', ')} of ${selector} is disabled`
+    );
+  }
+  if (matched.size > 1 && !state.multiple) {
+    throw new BrowserError(
+      `${selector} is not a multiple select but ${matched.size} options match`
+    );
+  }
+  return [...matched].sort((a, b) => a - b);
+}
+
+// The functions below run in the page
+
+export function inspectSelect(el: any): SelectState {
+  const tag = String(el.tagName).toLowerCase();
+  const isSelect = tag === 'select';
+  return {
+    isSelect,
+    multiple: isSelect && Boolean(el.multiple),
+    disabled: Boolean(el.disabled),
+    description: tag === 'input' ? `input[type=${String(el.type).toLowerCase()}]` : tag,
+    options: isSelect
+      ? Array.from(el.options as any[], (option: any, index: number) => ({
+          value: String(option.value),
+          label: String(option.label || option.text),
+          index,
+          disabled: Boolean(option.disabled)
+        }))
+      : []
+  };
+}
+
+// Selects exactly the given options and fires the events a user's choice would, so
+// frameworks listening for input or change pick it up
+export function applySelection(el: any, indexes: number[]): void {
+  for (const [index, option] of Array.from(el.options as any[]).entries()) {
+    option.selected = indexes.includes(index);
+  }
+  el.dispatchEvent(new (globalThis as any).Event('input', { bubbles: true }));
+  el.dispatchEvent(new (globalThis as any).Event('change', { bubbles: true }));
+}
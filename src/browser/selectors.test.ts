@@ -0,0 +1,89 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import {
+  elementNotFoundMessage,
+  isPiercingSelector,
+  isStaleElementError,
+  toElementIndex,
+  toPiercingSelector,
+  toSelector
+} from './selectors.js';
+
+describe('Selector helpers', () => {
+  describe('toSelector', () => {
+    it('should pass CSS selectors through', () => {
+      expect(toSelector({ selector: '#submit' })).toBe('#submit');
+    });
+
+    it('should prefer xpath over selector', () => {
+      expect(toSelector({ selector: '#submit', xpath: '//button' })).toBe('xpath///button');
+    });
+
+    it('should read selectors that look like XPath as XPath', () => {
+      expect(toSelector({ selector: "//button[contains(., 'Submit')]" })).toBe(
+        "xpath///button[contains(., 'Submit')]"
+      );
+      expect(toSelector({ selector: '(//li)[2]' })).toBe('xpath/(//li)[2]');
+      expect(toSelector({ selector: 'li:nth-child(2)' })).toBe('li:nth-child(2)');
+    });
+
+    it('should follow an explicit selectorType', () => {
+      expect(toSelector({ selector: 'id("cart")', selectorType: 'xpath' })).toBe(
+        'xpath/id("cart")'
+      );
+      expect(toSelector({ selector: '//odd', selectorType: 'css' })).toBe('//odd');
+      expect(() => toSelector({ selector: '#a', selectorType: 'text' as any })).toThrow(/one of/);
+    });
+
+    it('should require a selector or xpath', () => {
+      expect(() => toSelector({})).toThrow(BrowserError);
+    });
+  });
+
+  describe('toElementIndex', () => {
+    it('should default to the first match and reject bad indexes', () => {
+      expect(toElementIndex({})).toBe(0);
+      expect(toElementIndex({ index: 2 })).toBe(2);
+      expect(() => toElementIndex({ index: -1 })).toThrow(/non-negative/);
+      expect(() => toElementIndex({ index: 1.5 })).toThrow(BrowserError);
+    });
+  });
+
+  describe('shadow roots', () => {
+    it('should recognise piercing selectors', () => {
+      expect(isPiercingSelector('my-cart >>> button.buy')).toBe(true);
+      expect(isPiercingSelector('my-cart >>>> button')).toBe(true);
+      expect(isPiercingSelector('pierce/button.buy')).toBe(true);
+      expect(isPiercingSelector('my-cart > button')).toBe(false);
+    });
+
+    it('should only offer a piercing fallback for plain CSS', () => {
+      expect(toPiercingSelector('button.buy')).toBe('pierce/button.buy');
+      expect(toPiercingSelector('my-cart >>> button')).toBeNull();
+      expect(toPiercingSelector('xpath///button')).toBeNull();
+      expect(toPiercingSelector('button::-p-text(Buy)')).toBeNull();
+    });
+
+    it('should explain how to pierce a shadow boundary', () => {
+      expect(elementNotFoundMessage('#buy')).toBe('No element found for selector: #buy');
+      expect(elementNotFoundMessage('#buy', { shadowMatches: 1 })).toBe(
+        'No element found for selector: #buy, 1 match is inside shadow roots that CSS ' +
+          'selectors do not cross: use "<host> >>> #buy" or "pierce/#buy"'
+      );
+      expect(elementNotFoundMessage('li', { index: 3, matches: 2 })).toBe(
+        'No element found for selector: li, index 3 is out of range of its 2 matches'
+      );
+    });
+  });
+
+  describe('isStaleElementError', () => {
+    it('should detect detached node errors', () => {
+      expect(isStaleElementError(new Error('Node is detached from document'))).toBe(true);
+      expect(isStaleElementError(new Error('Execution context was destroyed'))).toBe(true);
+    });
+
+    it('should not retry on missing elements', () => {
+      expect(isStaleElementError(new Error('No element found for selector: #x'))).toBe(false);
+    });
+  });
+});
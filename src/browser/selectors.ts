@@ -0,0 +1,98 @@
+import { type ElementQuery, InvalidArgumentError, SELECTOR_TYPES } from '../types/index.js';
+
+export interface ElementTarget extends ElementQuery {
+  selector?: string | undefined;
+  xpath?: string | undefined;
+}
+
+// No CSS selector starts like an XPath expression does, e.g. //button or (//li)[2]
+export function looksLikeXPath(selector: string): boolean {
+  return /^\s*(\/\/|\()/.test(selector);
+}
+
+// Turns a CSS selector or XPath expression into a Puppeteer selector. XPath goes through
+// the built-in xpath/ query handler so it needs no escaping. A selector is read as XPath
+// when selectorType says so or, without one, when it looks like XPath.
+export function toSelector(target: ElementTarget): string {
+  if (target.xpath) {
+    return `xpath/${target.xpath}`;
+  }
+  if (!target.selector) {
+    throw new InvalidArgumentError('Either selector or xpath is required');
+  }
+  if (target.selectorType !== undefined && !SELECTOR_TYPES.includes(target.selectorType)) {
+    throw new InvalidArgumentError(`selectorType must be one of: ${SELECTOR_TYPES.join(', ')}`);
+  }
+  const xpath = target.selectorType
+    ? target.selectorType === 'xpath'
+    : looksLikeXPath(target.selector);
+  return xpath && !target.selector.startsWith('xpath/')
+    ? `xpath/${target.selector}`
+    : target.selector;
+}
+
+// Element tools act on the first match unless told which one
+export function toElementIndex(target: ElementQuery): number {
+  const index = target.index ?? 0;
+  if (!Number.isInteger(index) || index < 0) {
+    throw new InvalidArgumentError('index must be a non-negative integer');
+  }
+  return index;
+}
+
+// CSS selectors stop at shadow roots. Puppeteer's selectors pierce them with >>> (the
+// element anywhere in the shadow tree of the host, e.g. "my-cart >>> button.buy"), >>>>
+// (only in the host's own shadow root) or the pierce/ prefix (in every shadow root).
+export function isPiercingSelector(selector: string): boolean {
+  return selector.startsWith('pierce/') || selector.includes('>>>');
+}
+
+// The pierce/ selector that finds the element of a plain CSS selector behind shadow roots,
+// null when the selector already pierces them or is not CSS (xpath/, text/, aria/, ::-p-)
+export function toPiercingSelector(selector: string): string | null {
+  if (isPiercingSelector(selector) || /^[a-z]+\//.test(selector) || selector.includes('::-p-')) {
+    return null;
+  }
+  return `pierce/${selector}`;
+}
+
+export interface ElementMiss {
+  index?: number; // the match that was asked for
+  matches?: number; // how many there are
+  shadowMatches?: number; // how many there are behind shadow roots
+}
+
+// Says how many elements matched when the index was past them, and how to reach the
+// element when it is only there behind a shadow root
+export function elementNotFoundMessage(selector: string, miss: ElementMiss = {}): string {
+  const message = `No element found for selector: ${selector}`;
+  if (miss.index) {
+    return `${message}, index ${miss.index} is out of range of its ${miss.matches ?? 0} matches`;
+  }
+  const shadowMatches = miss.shadowMatches ?? 0;
+  if (shadowMatches === 0) {
+    return message;
+  }
+  const matches = shadowMatches === 1 ? '1 match is' : `${shadowMatches} matches are`;
+  return (
+    `${message}, ${matches} inside shadow roots that CSS selectors do not cross: ` +
+    `use "<host> >>> ${selector}" or "pierce/${selector}"`
+  );
+}
+
+// Errors raised when an element handle outlives the node it pointed at, typically because a
+// SPA re-rendered between lookup and action. Looking the element up again usually succeeds.
+const STALE_ELEMENT_PATTERNS = [
+  /detached/i,
+  /not attached/i,
+  /Execution context was destroyed/i,
+  /Cannot find context with specified id/i,
+  /JSHandle is disposed/i,
+  /Could not find node with given id/i,
+  /Node is either not (visible|clickable)/i
+];
+
+export function isStaleElementError(error: unknown): boolean {
+  const message = error instanceof Error ? error.message : String(error);
+  return STALE_ELEMENT_PATTERNS.some(pattern => pattern.test(message));
+}
@@ -0,0 +1,75 @@
+import { describe, expect, it } from 'vitest';
+import type { Cookie } from 'puppeteer-core';
+import { BrowserError, type StorageState } from '../types/index.js';
+import {
+  decodeStorageState,
+  encodeStorageState,
+  fromStateCookie,
+  isStateOrigin,
+  parseStorageState,
+  toStateCookie
+} from './state.js';
+
+const state: StorageState = {
+  cookies: [
+    {
+      name: 'sid',
+      value: 'abc',
+      domain: '.example.com',
+      path: '/',
+      expires: -1,
+      httpOnly: true,
+      secure: true,
+      sameSite: 'Lax'
+    }
+  ],
+  origins: [{ origin: 'https://example.com', localStorage: [{ name: 'token', value: 'xyz' }] }]
+};
+
+describe('Session state', () => {
+  it('should convert cookies to and from the storageState format', () => {
+    const cookie = {
+      name: 'sid',
+      value: 'abc',
+      domain: '.example.com',
+      path: '/',
+      expires: -1,
+      size: 6,
+      httpOnly: true,
+      secure: true,
+      session: true
+    } as Cookie;
+
+    expect(toStateCookie(cookie)).toEqual(state.cookies[0]);
+    expect(fromStateCookie({ ...state.cookies[0]!, expires: 1900000000 })).toMatchObject({
+      name: 'sid',
+      expires: 1900000000
+    });
+    expect(fromStateCookie(state.cookies[0]!)).not.toHaveProperty('expires');
+  });
+
+  it('should only keep the storage of HTTP(S) origins', () => {
+    expect(isStateOrigin('https://example.com')).toBe(true);
+    expect(isStateOrigin('http://localhost:3000')).toBe(true);
+    expect(isStateOrigin('file://')).toBe(false);
+  });
+
+  it('should round-trip plain and encrypted files', () => {
+    const plain = encodeStorageState(state, null);
+    expect(JSON.parse(plain.toString())).toEqual(state);
+    expect(decodeStorageState(plain, null)).toEqual(state);
+
+    const encrypted = encodeStorageState(state, 'secret');
+    expect(encrypted.toString()).not.toContain('xyz');
+    expect(decodeStorageState(encrypted, 'secret')).toEqual(state);
+    expect(() => decodeStorageState(encrypted, null)).toThrow(/passphrase is required/);
+    expect(() => decodeStorageState(encrypted, 'wrong')).toThrow(/Invalid passphrase/);
+  });
+
+  it('should reject files that are not storage state', () => {
+    expect(() => parseStorageState('not json')).toThrow(BrowserError);
+    expect(() => parseStorageState('{"origins":[]}')).toThrow(/expected cookies/);
+    expect(() => parseStorageState('{"cookies":[],"origins":[{}]}')).toThrow(/origins must/);
+    expect(parseStorageState('{"cookies":[]}')).toEqual({ cookies: [], origins: [] });
+  });
+});
@@ -0,0 +1,125 @@
+import { createCipheriv, createDecipheriv, randomBytes, scryptSync } from 'node:crypto';
+import type { Cookie } from 'puppeteer-core';
+import {
+  type CookieInput,
+  InvalidArgumentError,
+  type StorageState,
+  type StorageStateCookie
+} from '../types/index.js';
+
+// Files are written in Playwright's storageState format, so state saved by either tool can
+// be loaded by the other. Encrypted files wrap that JSON in an envelope.
+
+const CIPHER = 'aes-256-gcm';
+
+interface EncryptedState {
+  encrypted: typeof CIPHER;
+  salt: string;
+  iv: string;
+  tag: string;
+  data: string;
+}
+
+// Session cookies are stored with expires -1 like Playwright does
+export function toStateCookie(cookie: Cookie): StorageStateCookie {
+  return {
+    name: cookie.name,
+    value: cookie.value,
+    domain: cookie.domain,
+    path: cookie.path,
+    expires: cookie.session || cookie.expires < 0 ? -1 : cookie.expires,
+    httpOnly: cookie.httpOnly,
+    secure: cookie.secure,
+    sameSite: cookie.sameSite ?? 'Lax'
+  };
+}
+
+export function fromStateCookie(cookie: StorageStateCookie): CookieInput {
+  const { expires, ...input } = cookie;
+  return expires >= 0 ? { ...input, expires } : input;
+}
+
+// Only pages served over HTTP(S) keep their localStorage in the file
+export function isStateOrigin(origin: string): boolean {
+  return /^https?:\/\//.test(origin);
+}
+
+export function parseStorageState(json: string): StorageState {
+  let state: any;
+  try {
+    state = JSON.parse(json);
+  } catch (error) {
+    throw new InvalidArgumentError(`Invalid state file: ${error}`);
+  }
+  if (
+    !Array.isArray(state?.cookies) ||
+    (state.origins !== undefined && !Array.isArray(state.origins))
+  ) {
+    throw new InvalidArgumentError('Invalid state file: expected cookies and origins lists');
+  }
+  for (const origin of state.origins ?? []) {
+    if (typeof origin?.origin !== 'string' || !Array.isArray(origin.localStorage)) {
+      throw new InvalidArgumentError(
+        'Invalid state file: origins must have an origin and localStorage'
+      );
+    }
+  }
+  return { cookies: state.cookies, origins: state.origins ?? [] };
+}
+
+function deriveKey(passphrase: string, salt: Buffer): Buffer {
+  return scryptSync(passphrase, salt, 32);
+}
+
+// Plain JSON without a passphrase, AES-256-GCM with a key derived from it otherwise
+export function encodeStorageState(state: StorageState, passphrase: string | null): Buffer {
+  const json = JSON.stringify(state, null, 2);
+  if (!passphrase) {
+    return Buffer.from(json);
+  }
+  const salt = randomBytes(16);
+  const iv = randomBytes(12);
+  const cipher = createCipheriv(CIPHER, deriveKey(passphrase, salt), iv);
+  const data = Buffer.concat([cipher.update(json, 'utf8'), cipher.final()]);
+  const envelope: EncryptedState = {
+    encrypted: CIPHER,
+    salt: salt.toString('base64'),
+    iv: iv.toString('base64'),
+    tag: cipher.getAuthTag().toString('base64'),
+    data: data.toString('base64')
+  };
+  return Buffer.from(JSON.stringify(envelope));
+}
+
+export function decodeStorageState(file: Buffer, passphrase: string | null): StorageState {
+  const text = file.toString('utf8');
+  let envelope: Partial<EncryptedState> | null = null;
+  try {
+    envelope = JSON.parse(text);
+  } catch {
+    // reported by parseStorageState
+  }
+  if (envelope?.encrypted !== CIPHER) {
+    return parseStorageState(text);
+  }
+  if (!passphrase) {
+    throw new InvalidArgumentError('State file is encrypted, a passphrase is required');
+  }
+
+  let json: string;
+  try {
+    const decipher = createDecipheriv(
+      CIPHER,
+      deriveKey(passphrase, Buffer.from(envelope.salt ?? '', 'base64')),
+      Buffer.from(envelope.iv ?? '', 'base64')
+    );
+    decipher.setAuthTag(Buffer.from(envelope.tag ?? '', 'base64'));
+    json = Buffer.concat([
+      decipher.update(Buffer.from(envelope.data ?? '', 'base64')),
+      decipher.final()
+    ]).toString('utf8');
+  } catch {
+    throw new InvalidArgumentError('Invalid passphrase, cannot decrypt the state file');
+  }
+  return parseStorageState(json);
+}
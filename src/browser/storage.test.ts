@@ -0,0 +1,84 @@
+import { afterEach, beforeEach, describe, expect, it } from 'vitest';
+import { readStorage, removeStorage, storageOrigin, writeStorage } from './storage.js';
+
+// Minimal stand-in for the page's Storage objects
+class FakeStorage {
+  private items = new Map<string, string>();
+
+  get length(): number {
+    return this.items.size;
+  }
+
+  key(index: number): string | null {
+    return Array.from(this.items.keys())[index] ?? null;
+  }
+
+  getItem(key: string): string | null {
+    return this.items.get(key) ?? null;
+  }
+
+  setItem(key: string, value: string): void {
+    this.items.set(key, value);
+  }
+
+  removeItem(key: string): void {
+    this.items.delete(key);
+  }
+
+  clear(): void {
+    this.items.clear();
+  }
+}
+
+describe('Storage helpers', () => {
+  describe('storageOrigin', () => {
+    it('should return the origin of web pages', () => {
+      expect(storageOrigin('https://app.example.com:8443/login?next=/')).toBe(
+        'https://app.example.com:8443'
+      );
+    });
+
+    it('should return null for pages without an origin', () => {
+      expect(storageOrigin('about:blank')).toBeNull();
+      expect(storageOrigin('data:text/html,<p>hi</p>')).toBeNull();
+      expect(storageOrigin('')).toBeNull();
+    });
+
+    it('should treat file pages as one origin', () => {
+      expect(storageOrigin('file:///tmp/index.html')).toBe('file://');
+    });
+  });
+
+  describe('page storage functions', () => {
+    const globals = globalThis as any;
+
+    beforeEach(() => {
+      globals.localStorage = new FakeStorage();
+      globals.sessionStorage = new FakeStorage();
+    });
+
+    afterEach(() => {
+      delete globals.localStorage;
+      delete globals.sessionStorage;
+    });
+
+    it('should write and read items of the chosen storage', () => {
+      expect(writeStorage('local', { token: 'abc', theme: 'dark' })).toBe(2);
+      expect(readStorage('local', [])).toEqual({ token: 'abc', theme: 'dark' });
+      expect(readStorage('session', [])).toEqual({});
+    });
+
+    it('should only read requested keys that exist', () => {
+      writeStorage('session', { token: 'abc', theme: 'dark' });
+      expect(readStorage('session', ['token', 'missing'])).toEqual({ token: 'abc' });
+    });
+
+    it('should remove given keys or everything', () => {
+      writeStorage('local', { a: '1', b: '2', c: '3' });
+      expect(removeStorage('local', ['a', 'missing'])).toBe(1);
+      expect(readStorage('local', [])).toEqual({ b: '2', c: '3' });
+      expect(removeStorage('local', [])).toBe(2);
+      expect(readStorage('local', [])).toEqual({});
+    });
+  });
+});
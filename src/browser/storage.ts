@@ -0,0 +1,61 @@
+import type { StorageType } from '../types/index.js';
+
+// The origin whose storage a page at the URL can reach, or null when it has none yet.
+// about:blank, data: and other opaque origins have no storage of their own; file: pages
+// share one origin in Chrome even though the URL spec treats it as opaque.
+export function storageOrigin(url: string): string | null {
+  let parsed: URL;
+  try {
+    parsed = new URL(url);
+  } catch {
+    return null;
+  }
+  if (parsed.protocol === 'file:') {
+    return 'file://';
+  }
+  return parsed.origin === 'null' ? null : parsed.origin;
+}
+
+// The functions below run in the page, so they only use what the page provides
+
+export function readStorage(type: StorageType, keys: string[]): Record<string, string> {
+  const storage = (globalThis as any)[`${type}Storage`];
+  const items: Record<string, string> = {};
+  const wanted: string[] = keys.length
+    ? keys
+    : Array.from({ length: storage.length }, (_, i) => storage.key(i));
+  for (const key of wanted) {
+    const value = storage.getItem(key);
+    if (value !== null) {
+      items[key] = value;
+    }
+  }
+  return items;
+}
+
+export function writeStorage(type: StorageType, items: Record<string, string>): number {
+  const storage = (globalThis as any)[`${type}Storage`];
+  const entries = Object.entries(items);
+  for (const [key, value] of entries) {
+    storage.setItem(key, value);
+  }
+  return entries.length;
+}
+
+// Removes the given keys, or everything when none are given, and returns how many existed
+export function removeStorage(type: StorageType, keys: string[]): number {
+  const storage = (globalThis as any)[`${type}Storage`];
+  if (!keys.length) {
+    const count = storage.length;
+    storage.clear();
+    return count;
+  }
+  let removed = 0;
+  for (const key of keys) {
+    if (storage.getItem(key) !== null) {
+      storage.removeItem(key);
+      removed++;
+    }
+  }
+  return removed;
+}
@@ -0,0 +1,60 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError, OperationTimeoutError } from '../types/index.js';
+import { asTimeoutError, validateTimeout, withDeadline } from './timeouts.js';
+
+describe('Timeout helpers', () => {
+  describe('validateTimeout', () => {
+    it('should accept non-negative integers', () => {
+      expect(validateTimeout(0)).toBe(0);
+      expect(validateTimeout(45000)).toBe(45000);
+    });
+
+    it('should reject negative, fractional and non-numeric values', () => {
+      expect(() => validateTimeout(-1)).toThrow(BrowserError);
+      expect(() => validateTimeout(1.5)).toThrow(/integer/);
+      expect(() => validateTimeout('30000' as any)).toThrow(/integer/);
+    });
+  });
+
+  describe('asTimeoutError', () => {
+    it('should map Puppeteer timeouts, wrapped or not', () => {
+      const timeout = new Error('Waiting for selector `#go` failed');
+      timeout.name = 'TimeoutError';
+      const wrapped = new BrowserError(
+        'Failed to navigate: TimeoutError: Navigation timeout of 30000 ms exceeded'
+      );
+
+      expect(asTimeoutError(timeout, 'browser_click', 30000, Date.now())).toMatchObject({
+        code: 'TIMEOUT',
+        operation: 'browser_click',
+        timeout: 30000
+      });
+      expect(asTimeoutError(wrapped, 'browser_navigate', 30000, Date.now())?.message).toMatch(
+        /^browser_navigate timed out after \d+ ms: Failed to navigate/
+      );
+    });
+
+    it('should leave other failures alone', () => {
+      const error = new BrowserError('Failed to click element: No element found');
+      expect(asTimeoutError(error, 'browser_click', 30000, Date.now())).toBeNull();
+    });
+  });
+
+  describe('withDeadline', () => {
+    it('should resolve with the task result in time', async () => {
+      expect(await withDeadline('browser_get_html', 1000, async () => 'html')).toBe('html');
+    });
+
+    it('should fail with a structured error once the timeout passes', async () => {
+      const slow = new Promise(resolve => setTimeout(resolve, 500));
+      const error = await withDeadline('browser_pdf', 20, () => slow).catch(error => error);
+
+      expect(error).toBeInstanceOf(OperationTimeoutError);
+      expect(error.toJSON()).toMatchObject({
+        code: 'TIMEOUT',
+        details: { operation: 'browser_pdf', timeout: 20 }
+      });
+      expect(error.elapsedMs).toBeGreaterThanOrEqual(15);
+    });
+  });
+});
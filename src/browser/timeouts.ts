@@ -0,0 +1,57 @@
+import { BrowserError, InvalidArgumentError, OperationTimeoutError } from '../types/index.js';
+
+// Puppeteer throws TimeoutError, but most tools wrap it in a BrowserError with the original
+// message, e.g. "Navigation timeout of 30000 ms exceeded"
+const TIMEOUT_MESSAGE = /TimeoutError|timed out|\d+ ?ms exceeded/i;
+
+export function validateTimeout(timeout: number): number {
+  if (typeof timeout !== 'number' || !Number.isInteger(timeout) || timeout < 0) {
+    throw new InvalidArgumentError('timeout must be a non-negative integer of milliseconds');
+  }
+  return timeout;
+}
+
+// Maps any timeout the call ran into to an OperationTimeoutError, null for other failures
+export function asTimeoutError(
+  error: unknown,
+  operation: string,
+  timeout: number,
+  startedAt: number
+): OperationTimeoutError | null {
+  if (error instanceof OperationTimeoutError) {
+    return error;
+  }
+  const isTimeout =
+    error instanceof Error &&
+    (error.name === 'TimeoutError' || TIMEOUT_MESSAGE.test(error.message));
+  if (!isTimeout) {
+    return null;
+  }
+  return new OperationTimeoutError(operation, timeout, Date.now() - startedAt, error.message);
+}
+
+// Fails with an OperationTimeoutError once timeout milliseconds pass, 0 waits indefinitely.
+// The task keeps running, only the caller stops waiting for it.
+export async function withDeadline<T>(
+  operation: string,
+  timeout: number,
+  task: () => Promise<T>
+): Promise<T> {
+  if (timeout === 0) {
+    return task();
+  }
+
+  const startedAt = Date.now();
+  let timer: NodeJS.Timeout | undefined;
+  const deadline = new Promise<never>((_, reject) => {
+    timer = setTimeout(
+      () => reject(new OperationTimeoutError(operation, timeout, Date.now() - startedAt)),
+      timeout
+    );
+  });
+  try {
+    return await Promise.race([task(), deadline]);
+  } finally {
+    clearTimeout(timer);
+  }
+}
@@ -0,0 +1,25 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { swipeOffset, validatePinch, validateSwipe, validateTap } from './touch.js';
+
+describe('Touch gestures', () => {
+  it('should accept elements and points', () => {
+    expect(() => validateTap({ selector: '.card', count: 2 })).not.toThrow();
+    expect(() => validateSwipe({ x: 200, y: 400, direction: 'left' })).not.toThrow();
+    expect(() => validatePinch({ xpath: '//canvas', scale: 0.5 })).not.toThrow();
+  });
+
+  it('should reject incomplete targets and bad values', () => {
+    expect(() => validateTap({ x: 10 })).toThrow(BrowserError);
+    expect(() => validateTap({ x: 10, y: 10, count: 0 })).toThrow(/count/);
+    expect(() => validateSwipe({ x: 1, y: 1, direction: 'sideways' as any })).toThrow(/one of/);
+    expect(() => validateSwipe({ x: 1, y: 1, direction: 'up', distance: -5 })).toThrow(/distance/);
+    expect(() => validatePinch({ x: 1, y: 1, scale: 0 })).toThrow(/scale/);
+    expect(() => validatePinch({ x: 1, y: 1, scale: 2, speed: 0 })).toThrow(/speed/);
+  });
+
+  it('should move the finger in the swipe direction', () => {
+    expect(swipeOffset('left')).toEqual({ x: -300, y: 0 });
+    expect(swipeOffset('down', 120)).toEqual({ x: 0, y: 120 });
+  });
+});
@@ -0,0 +1,74 @@
+import {
+  InvalidArgumentError,
+  type PinchRequest,
+  type Point,
+  SWIPE_DIRECTIONS,
+  type SwipeDirection,
+  type SwipeRequest,
+  type TapRequest,
+  type TouchTarget
+} from '../types/index.js';
+
+export const DEFAULT_SWIPE_DISTANCE = 300;
+
+export function validateTouchTarget(target: TouchTarget): void {
+  if (target.selector || target.xpath) {
+    return;
+  }
+  if (!Number.isFinite(target.x) || !Number.isFinite(target.y)) {
+    throw new InvalidArgumentError(
+      'A touch target must have a selector, an xpath, or x and y coordinates'
+    );
+  }
+}
+
+export function validateTap(request: TapRequest): void {
+  validateTouchTarget(request);
+  if (request.count !== undefined && !(Number.isInteger(request.count) && request.count >= 1)) {
+    throw new InvalidArgumentError('Tap count must be a positive integer');
+  }
+  if (request.duration !== undefined && !(request.duration >= 0)) {
+    throw new InvalidArgumentError('Tap duration must be a non-negative number of milliseconds');
+  }
+}
+
+export function validateSwipe(request: SwipeRequest): void {
+  validateTouchTarget(request);
+  if (!SWIPE_DIRECTIONS.includes(request.direction)) {
+    throw new InvalidArgumentError(
+      `Swipe direction must be one of: ${SWIPE_DIRECTIONS.join(', ')}`
+    );
+  }
+  if (request.distance !== undefined && !(request.distance > 0)) {
+    throw new InvalidArgumentError('Swipe distance must be a positive number of pixels');
+  }
+  validateSpeed(request.speed);
+}
+
+export function validatePinch(request: PinchRequest): void {
+  validateTouchTarget(request);
+  if (!(request.scale > 0) || !Number.isFinite(request.scale)) {
+    throw new InvalidArgumentError('Pinch scale must be a positive number, e.g. 2 to zoom in');
+  }
+  validateSpeed(request.speed);
+}
+
+function validateSpeed(speed: number | undefined): void {
+  if (speed !== undefined && !(speed > 0)) {
+    throw new InvalidArgumentError('Gesture speed must be a positive number of pixels per second');
+  }
+}
+
+// How far the finger moves along each axis, y grows downwards like in the viewport
+export function swipeOffset(direction: SwipeDirection, distance = DEFAULT_SWIPE_DISTANCE): Point {
+  switch (direction) {
+    case 'left':
+      return { x: -distance, y: 0 };
+    case 'right':
+      return { x: distance, y: 0 };
+    case 'up':
+      return { x: 0, y: -distance };
+    case 'down':
+      return { x: 0, y: distance };
+  }
+}
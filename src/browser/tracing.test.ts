@@ -0,0 +1,43 @@
+import { gunzipSync } from 'node:zlib';
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { packTrace, validateCategories } from './tracing.js';
+
+const encode = (value: unknown) => new TextEncoder().encode(JSON.stringify(value));
+
+describe('Tracing helpers', () => {
+  describe('validateCategories', () => {
+    it('should keep the default when no categories are given', () => {
+      expect(validateCategories(undefined)).toBeUndefined();
+    });
+
+    it('should accept included and excluded categories', () => {
+      const categories = ['devtools.timeline', 'disabled-by-default-v8.cpu_profiler', '-v8'];
+      expect(validateCategories(categories)).toEqual(categories);
+    });
+
+    it('should reject empty lists and malformed names', () => {
+      expect(() => validateCategories([])).toThrow(BrowserError);
+      expect(() => validateCategories(['a,b'])).toThrow(/Invalid trace category/);
+      expect(() => validateCategories('v8' as any)).toThrow(/array/);
+    });
+  });
+
+  describe('packTrace', () => {
+    it('should return small traces as JSON', () => {
+      const trace = { traceEvents: [{ name: 'a' }, { name: 'b' }], metadata: {} };
+      const data = encode(trace);
+      expect(packTrace(data)).toEqual({ size: data.length, events: 2, trace });
+    });
+
+    it('should gzip traces larger than the limit', () => {
+      const trace = [{ name: 'a' }, { name: 'b' }, { name: 'c' }];
+      const data = encode(trace);
+      const result = packTrace(data, 10);
+      expect(result).toMatchObject({ size: data.length, events: 3 });
+      expect(result.trace).toBeUndefined();
+      const json = gunzipSync(Buffer.from(result.gzip ?? '', 'base64')).toString('utf8');
+      expect(JSON.parse(json)).toEqual(trace);
+    });
+  });
+});
@@ -0,0 +1,41 @@
+import { gzipSync } from 'node:zlib';
+import { InvalidArgumentError, type TracingResult } from '../types/index.js';
+
+// Larger traces are returned gzipped, JSON responses of that size choke most MCP clients
+export const MAX_INLINE_TRACE_SIZE = 5 * 1024 * 1024;
+
+// Chrome trace categories such as devtools.timeline or disabled-by-default-v8.cpu_profiler,
+// a leading "-" excludes the category
+const CATEGORY = /^-?[\w.*-]+$/;
+
+export function validateCategories(categories: string[] | undefined): string[] | undefined {
+  if (categories === undefined) {
+    return undefined;
+  }
+  if (!Array.isArray(categories) || categories.length === 0) {
+    throw new InvalidArgumentError('categories must be a non-empty array of trace category names');
+  }
+  for (const category of categories) {
+    if (typeof category !== 'string' || !CATEGORY.test(category)) {
+      throw new InvalidArgumentError(`Invalid trace category: ${JSON.stringify(category)}`);
+    }
+  }
+  return categories;
+}
+
+// Counts the trace events and returns the trace as JSON, or gzipped and base64 encoded when
+// it is larger than maxInlineSize
+export function packTrace(
+  data: Uint8Array,
+  maxInlineSize = MAX_INLINE_TRACE_SIZE
+): Omit<TracingResult, 'path'> {
+  const json = Buffer.from(data);
+  const trace = JSON.parse(json.toString('utf8'));
+  // Chrome writes an object with traceEvents, the legacy format is a bare array of events
+  const events = Array.isArray(trace) ? trace : trace?.traceEvents;
+  const count = Array.isArray(events) ? events.length : 0;
+  if (json.length <= maxInlineSize) {
+    return { size: json.length, events: count, trace };
+  }
+  return { size: json.length, events: count, gzip: gzipSync(json).toString('base64') };
+}
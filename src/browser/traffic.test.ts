@@ -0,0 +1,107 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import {
+  compileTrafficFilter,
+  decodeResponseBody,
+  matchesTraffic,
+  nearMatches,
+  RecentTraffic,
+  resolveCaptureOptions
+} from './traffic.js';
+
+const seen = (url: string, method = 'GET', status: number | null = null) => ({
+  url,
+  method,
+  status
+});
+
+describe('Traffic waits', () => {
+  describe('compileTrafficFilter', () => {
+    it('should match URL, method and status', () => {
+      const filter = compileTrafficFilter({ url: '**/api/users*', method: 'post', status: 201 });
+      expect(matchesTraffic(filter, seen('https://app.test/api/users', 'POST', 201))).toBe(true);
+      expect(matchesTraffic(filter, seen('https://app.test/api/users', 'GET', 201))).toBe(false);
+      expect(matchesTraffic(filter, seen('https://app.test/api/users', 'POST', 500))).toBe(false);
+
+      const regex = compileTrafficFilter({ url: 'graphql\\?op=\\w+', regex: true });
+      expect(matchesTraffic(regex, seen('https://app.test/graphql?op=Me'))).toBe(true);
+    });
+
+    it('should reject bad patterns and values', () => {
+      expect(() => compileTrafficFilter({ url: '' })).toThrow(BrowserError);
+      expect(() => compileTrafficFilter({ url: '(', regex: true })).toThrow(/Invalid URL pattern/);
+      expect(() => compileTrafficFilter({ url: '**', method: 'GET /' })).toThrow(/method/);
+      expect(() => compileTrafficFilter({ url: '**', status: 42 })).toThrow(/status/);
+      expect(() => compileTrafficFilter({ url: '**', lookback: -1 })).toThrow(/lookback/);
+    });
+  });
+
+  it('should list the near misses newest first', () => {
+    const filter = compileTrafficFilter({ url: '**/api/orders', method: 'POST' });
+    const traffic = [
+      seen('https://app.test/api/orders', 'GET'),
+      seen('https://app.test/logo.png'),
+      seen('https://app.test/api/orders/42')
+    ];
+    expect(nearMatches(filter, traffic).map(entry => entry.url)).toEqual([
+      'https://app.test/api/orders',
+      'https://app.test/api/orders/42'
+    ]);
+  });
+
+  it('should find earlier traffic with lookback and wait for later traffic', async () => {
+    const traffic = new RecentTraffic<string>();
+    const filter = compileTrafficFilter({ url: '**/api/*' });
+    traffic.add('earlier', seen('https://app.test/api/me'));
+    expect(await traffic.waitFor(filter, Date.now() - 1000, 100)).toBe('earlier');
+
+    const waiting = traffic.waitFor(filter, Date.now() + 1, 1000);
+    await new Promise(resolve => setTimeout(resolve, 5));
+    traffic.add('image', seen('https://app.test/logo.png'));
+    traffic.add('later', seen('https://app.test/api/cart'));
+    expect(await waiting).toBe('later');
+
+    expect(await traffic.waitFor(compileTrafficFilter({ url: '**/none' }), 0, 20)).toBeNull();
+  });
+
+  describe('decodeResponseBody', () => {
+    const options = resolveCaptureOptions({ url: '**', maxBodySize: 64 });
+
+    it('should parse JSON and return text of allowed types', () => {
+      const body = Buffer.from('{"id":1}');
+      expect(decodeResponseBody(body, 'application/json; charset=utf-8', options)).toEqual({
+        contentType: 'application/json',
+        size: 8,
+        format: 'json',
+        body: { id: 1 }
+      });
+      const broken = decodeResponseBody(Buffer.from('{oops'), 'application/ld+json', options);
+      expect(broken).toMatchObject({ format: 'text', body: '{oops' });
+      expect(decodeResponseBody(Buffer.from('<p>'), 'text/html', options).body).toBe('<p>');
+    });
+
+    it('should say why a body is omitted', () => {
+      const png = decodeResponseBody(Buffer.from([0x89, 0x50]), 'image/png', options);
+      expect(png).toMatchObject({ format: null, body: null, omitted: 'binary', size: 2 });
+      const css = decodeResponseBody(Buffer.from('a{}'), 'text/css', {
+        ...options,
+        contentTypes: ['application/json']
+      });
+      expect(css.omitted).toBe('contentType');
+      const large = decodeResponseBody(Buffer.alloc(65, 'a'), 'text/plain', options);
+      expect(large.omitted).toBe('tooLarge');
+    });
+
+    it('should return other allowed types as base64', () => {
+      const image = resolveCaptureOptions({ url: '**', contentTypes: ['image/*'] });
+      expect(decodeResponseBody(Buffer.from('hi'), 'image/gif', image)).toMatchObject({
+        format: 'base64',
+        body: 'aGk='
+      });
+      expect(() => resolveCaptureOptions({ url: '**', contentTypes: ['json'] })).toThrow(
+        /contentTypes/
+      );
+      expect(() => resolveCaptureOptions({ url: '**', maxBodySize: 0 })).toThrow(/maxBodySize/);
+    });
+  });
+});
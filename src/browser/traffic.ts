@@ -0,0 +1,202 @@
+import {
+  type CaptureResponseBodyRequest,
+  type CapturedResponse,
+  InvalidArgumentError,
+  type SeenTraffic,
+  type WaitForResponseRequest
+} from '../types/index.js';
+import { isTextMimeType } from './har.js';
+import { globToRegExp } from './interception.js';
+
+// Enough for the burst of requests one click or page load sets off
+export const MAX_RECENT_TRAFFIC = 200;
+const MAX_NEAR_MATCHES = 10;
+
+export const DEFAULT_CAPTURE_BODY_SIZE = 1024 * 1024;
+export const DEFAULT_CAPTURE_CONTENT_TYPES = [
+  'application/json',
+  '+json',
+  'text/*',
+  'application/xml',
+  '+xml',
+  'application/javascript'
+];
+
+// A media type, a whole type such as text/* or a suffix such as +json
+const CONTENT_TYPE_PATTERN = /^(\+[\w.-]+|[\w.+-]+\/([\w.+-]+|\*))$/;
+
+export interface CaptureOptions {
+  maxBodySize: number;
+  contentTypes: string[];
+}
+
+export interface TrafficFilter {
+  matcher: RegExp;
+  method: string | null;
+  status: number | null;
+  hint: string; // the longest literal part of the pattern, to spot near misses
+}
+
+// URL patterns are globs like those of mock routes, or regular expressions with regex
+export function compileTrafficFilter(request: WaitForResponseRequest): TrafficFilter {
+  if (!request.url || typeof request.url !== 'string') {
+    throw new InvalidArgumentError('A URL pattern is required');
+  }
+  let matcher: RegExp;
+  try {
+    matcher = request.regex ? new RegExp(request.url) : globToRegExp(request.url);
+  } catch (error) {
+    throw new InvalidArgumentError(`Invalid URL pattern ${request.url}: ${error}`);
+  }
+  if (request.method !== undefined && !/^[A-Za-z]+$/.test(request.method)) {
+    throw new InvalidArgumentError('method must be an HTTP method such as GET or POST');
+  }
+  const status = request.status ?? null;
+  if (status !== null && !(Number.isInteger(status) && status >= 100 && status <= 599)) {
+    throw new InvalidArgumentError('status must be an HTTP status code from 100 to 599');
+  }
+  if (request.lookback !== undefined && !(request.lookback >= 0)) {
+    throw new InvalidArgumentError('lookback must be a non-negative number of milliseconds');
+  }
+
+  const literals = request.url.split(request.regex ? /[\\^$.|?*+()[\]{}]/ : /[*{},]/);
+  const hint = literals.reduce((longest, part) => (part.length > longest.length ? part : longest));
+  return { matcher, method: request.method?.toUpperCase() ?? null, status, hint };
+}
+
+export function matchesTraffic(filter: TrafficFilter, seen: SeenTraffic): boolean {
+  return (
+    filter.matcher.test(seen.url) &&
+    (!filter.method || filter.method === seen.method) &&
+    (filter.status === null || filter.status === seen.status)
+  );
+}
+
+// What a wait that timed out saw instead, newest first: traffic to a matching URL with
+// another method or status, then URLs that contain the pattern's longest literal part
+export function nearMatches(filter: TrafficFilter, seen: SeenTraffic[]): SeenTraffic[] {
+  const hint = filter.hint.toLowerCase();
+  const newest = [...seen].reverse();
+  const urlMatches = newest.filter(entry => filter.matcher.test(entry.url));
+  // too short a hint, like a single slash, is part of every URL
+  const similar = newest.filter(
+    entry =>
+      hint.length >= 3 && !filter.matcher.test(entry.url) && entry.url.toLowerCase().includes(hint)
+  );
+  return [...urlMatches, ...similar].slice(0, MAX_NEAR_MATCHES);
+}
+
+// The latest requests or responses of a tab, kept so a wait started after the click that
+// set them off can still find them with lookback
+export class RecentTraffic<T> {
+  private entries: Array<{ item: T; seen: SeenTraffic; at: number }> = [];
+  private listeners: Set<() => void> = new Set();
+
+  add(item: T, seen: SeenTraffic): void {
+    this.entries.push({ item, seen, at: Date.now() });
+    if (this.entries.length > MAX_RECENT_TRAFFIC) {
+      this.entries.shift();
+    }
+    for (const listener of this.listeners) {
+      listener();
+    }
+  }
+
+  seenSince(since: number): SeenTraffic[] {
+    return this.entries.filter(entry => entry.at >= since).map(entry => entry.seen);
+  }
+
+  // Resolves with the oldest match seen from since on, or null once the timeout runs out
+  // (0 waits forever)
+  waitFor(filter: TrafficFilter, since: number, timeout: number): Promise<T | null> {
+    return new Promise(resolve => {
+      let next = 0;
+      let timer: NodeJS.Timeout | null = null;
+
+      const finish = (item: T | null) => {
+        if (timer) clearTimeout(timer);
+        this.listeners.delete(check);
+        resolve(item);
+      };
+
+      const check = () => {
+        // entries dropped from the front shift the ones not checked yet
+        next = Math.max(Math.min(next, this.entries.length - 1), 0);
+        for (; next < this.entries.length; next++) {
+          const entry = this.entries[next]!;
+          if (entry.at >= since && matchesTraffic(filter, entry.seen)) {
+            finish(entry.item);
+            return;
+          }
+        }
+      };
+
+      this.listeners.add(check);
+      if (timeout > 0) {
+        timer = setTimeout(() => finish(null), timeout);
+      }
+      check();
+    });
+  }
+}
+
+export function resolveCaptureOptions(request: CaptureResponseBodyRequest): CaptureOptions {
+  const maxBodySize = request.maxBodySize ?? DEFAULT_CAPTURE_BODY_SIZE;
+  if (!Number.isInteger(maxBodySize) || maxBodySize <= 0) {
+    throw new InvalidArgumentError('maxBodySize must be a positive number of bytes');
+  }
+  const contentTypes = request.contentTypes ?? DEFAULT_CAPTURE_CONTENT_TYPES;
+  if (
+    !Array.isArray(contentTypes) ||
+    contentTypes.some(type => typeof type !== 'string' || !CONTENT_TYPE_PATTERN.test(type))
+  ) {
+    throw new InvalidArgumentError(
+      'contentTypes must be media types such as application/json, text/* or +json'
+    );
+  }
+  return { maxBodySize, contentTypes: contentTypes.map(type => type.toLowerCase()) };
+}
+
+// The media type of a Content-Type header, without charset or other parameters
+export function toMediaType(contentType: string): string {
+  return (contentType.split(';')[0] ?? '').trim().toLowerCase();
+}
+
+export function isAllowedContentType(mediaType: string, allowed: string[]): boolean {
+  return allowed.some(pattern => {
+    if (pattern.startsWith('+')) return mediaType.endsWith(pattern);
+    if (pattern.endsWith('/*')) return mediaType.startsWith(pattern.slice(0, -1));
+    return mediaType === pattern;
+  });
+}
+
+// Chrome hands bodies over already decompressed, so this only decides how to return them.
+// JSON that does not parse is returned as text.
+export function decodeResponseBody(
+  body: Buffer,
+  contentType: string,
+  options: CaptureOptions
+): Pick<CapturedResponse, 'contentType' | 'size' | 'format' | 'body' | 'omitted'> {
+  const mediaType = toMediaType(contentType);
+  const described = { contentType: mediaType, size: body.length };
+  const isText = isTextMimeType(mediaType);
+  if (!isAllowedContentType(mediaType, options.contentTypes)) {
+    const omitted = isText ? 'contentType' : 'binary';
+    return { ...described, format: null, body: null, omitted };
+  }
+  if (body.length > options.maxBodySize) {
+    return { ...described, format: null, body: null, omitted: 'tooLarge' };
+  }
+  if (!isText) {
+    return { ...described, format: 'base64', body: body.toString('base64') };
+  }
+  const text = body.toString('utf8');
+  if (/[/+]json$/.test(mediaType)) {
+    try {
+      return { ...described, format: 'json', body: JSON.parse(text) };
+    } catch {
+      // returned as text
+    }
+  }
+  return { ...described, format: 'text', body: text };
+}
@@ -0,0 +1,62 @@
+import fs from 'node:fs/promises';
+import path from 'node:path';
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import { decodeBlob, inspectFileInput, removeUploadDirs, writeBlobs } from './upload.js';
+
+describe('Upload helpers', () => {
+  describe('inspectFileInput', () => {
+    it('should recognize file inputs and their multiple attribute', () => {
+      expect(
+        inspectFileInput({ tagName: 'INPUT', type: 'file', multiple: true, disabled: false })
+      ).toEqual({
+        isFileInput: true,
+        multiple: true,
+        disabled: false,
+        description: 'input[type=file]'
+      });
+    });
+
+    it('should describe other elements', () => {
+      expect(inspectFileInput({ tagName: 'INPUT', type: 'text' }).description).toBe(
+        'input[type=text]'
+      );
+      expect(inspectFileInput({ tagName: 'BUTTON' })).toMatchObject({
+        isFileInput: false,
+        description: 'button'
+      });
+    });
+  });
+
+  describe('decodeBlob', () => {
+    it('should decode base64 and data URLs', () => {
+      expect(decodeBlob({ name: 'a.txt', data: 'aGVsbG8=' }).data.toString()).toBe('hello');
+      expect(
+        decodeBlob({ name: 'a.txt', data: 'data:text/plain;base64,aGVsbG8=' }).data.toString()
+      ).toBe('hello');
+    });
+
+    it('should strip directories from the name', () => {
+      expect(decodeBlob({ name: '../../etc/passwd', data: '' }).name).toBe('passwd');
+    });
+
+    it('should reject missing names and invalid base64', () => {
+      expect(() => decodeBlob({ name: '', data: 'aGVsbG8=' })).toThrow(BrowserError);
+      expect(() => decodeBlob({ name: 'a.txt', data: 'not base64!' })).toThrow('base64');
+    });
+  });
+
+  describe('writeBlobs', () => {
+    it('should stage files under their own names and remove them again', async () => {
+      const { dir, paths } = await writeBlobs([
+        { name: 'a.txt', data: 'aGVsbG8=' },
+        { name: 'a.txt', data: 'd29ybGQ=' }
+      ]);
+      expect(paths.map(filePath => path.basename(filePath))).toEqual(['a.txt', 'a.txt']);
+      expect(await fs.readFile(paths[1] as string, 'utf8')).toBe('world');
+
+      await removeUploadDirs([dir]);
+      await expect(fs.stat(dir)).rejects.toThrow();
+    });
+  });
+});
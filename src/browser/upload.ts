@@ -0,0 +1,63 @@
+import fs from 'node:fs/promises';
+import os from 'node:os';
+import path from 'node:path';
+import { BrowserError, type UploadBlob } from '../types/index.js';
+
+export interface FileInputState {
+  isFileInput: boolean;
+  multiple: boolean;
+  disabled: boolean;
+  description: string; // what the element is when it is not a file input
+}
+
+// Runs in the page
+export function inspectFileInput(el: any): FileInputState {
+  const tag = String(el.tagName).toLowerCase();
+  const type = tag === 'input' ? String(el.type).toLowerCase() : null;
+  return {
+    isFileInput: type === 'file',
+    multiple: Boolean(el.multiple),
+    disabled: Boolean(el.disabled),
+    description: type ? `input[type=${type}]` : tag
+  };
+}
+
+// Accepts plain base64 or a data: URL
+export function decodeBlob(blob: UploadBlob): { name: string; data: Buffer } {
+  const name = path.basename(String(blob.name ?? ''));
+  if (!name || name === '.' || name === '..') {
+    throw new BrowserError('Every uploaded file needs a file name');
+  }
+  const base64 = String(blob.data ?? '')
+    .replace(/^data:[^,]*;base64,/, '')
+    .replace(/\s+/g, '');
+  if (!/^[A-Za-z0-9+/]*={0,2}$/.test(base64) || base64.length % 4 === 1) {
+    throw new BrowserError(`File ${name} is not valid base64`);
+  }
+  return { name, data: Buffer.from(base64, 'base64') };
+}
+
+// Writes the blobs into a fresh temporary directory under their own names so the page sees
+// the intended file names. The caller removes the directory once the page is done with it.
+export async function writeBlobs(blobs: UploadBlob[]): Promise<{ dir: string; paths: string[] }> {
+  const decoded = blobs.map(decodeBlob);
+  const dir = await fs.mkdtemp(path.join(os.tmpdir(), 'pcs-upload-'));
+  try {
+    const paths: string[] = [];
+    for (const [index, { name, data }] of decoded.entries()) {
+      // a subdirectory per file keeps duplicate names apart
+      const filePath = path.join(dir, String(index), name);
+      await fs.mkdir(path.dirname(filePath));
+      await fs.writeFile(filePath, data);
+      paths.push(filePath);
+    }
+    return { dir, paths };
+  } catch (error) {
+    await fs.rm(dir, { recursive: true, force: true });
+    throw error;
+  }
+}
+
+export async function removeUploadDirs(dirs: string[]): Promise<void> {
+  await Promise.all(dirs.map(dir => fs.rm(dir, { recursive: true, force: true }).catch(() => {})));
+}
@@ -0,0 +1,41 @@
+import { describe, expect, it } from 'vitest';
+import { toUserAgentOverride } from './useragent.js';
+
+const HEADLESS =
+  'Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) ' +
+  'HeadlessChrome/131.0.6778.85 Safari/537.36';
+
+describe('User agent', () => {
+  it('should drop the headless token and keep the Chrome version', () => {
+    const override = toUserAgentOverride(HEADLESS)!;
+    expect(override.userAgent).toBe(
+      'Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) ' +
+        'Chrome/131.0.6778.85 Safari/537.36'
+    );
+    expect(override.userAgentMetadata).toMatchObject({
+      fullVersion: '131.0.6778.85',
+      platform: 'Linux',
+      mobile: false
+    });
+    expect(override.userAgentMetadata.brands).toContainEqual({
+      brand: 'Google Chrome',
+      version: '131'
+    });
+    expect(override.userAgentMetadata.fullVersionList).toContainEqual({
+      brand: 'Chromium',
+      version: '131.0.6778.85'
+    });
+  });
+
+  it('should report the platform of the user agent', () => {
+    const windows = HEADLESS.replace('X11; Linux x86_64', 'Windows NT 10.0; Win64; x64');
+    const mac = HEADLESS.replace('X11; Linux x86_64', 'Macintosh; Intel Mac OS X 10_15_7');
+    expect(toUserAgentOverride(windows)?.userAgentMetadata.platform).toBe('Windows');
+    expect(toUserAgentOverride(mac)?.userAgentMetadata.platform).toBe('macOS');
+  });
+
+  it('should leave user agents without the headless token alone', () => {
+    expect(toUserAgentOverride(HEADLESS.replace('HeadlessChrome', 'Chrome'))).toBeNull();
+    expect(toUserAgentOverride('Custom/1.0')).toBeNull();
+  });
+});
@@ -0,0 +1,50 @@
+import type { Protocol } from 'puppeteer-core';
+
+export interface UserAgentOverride {
+  userAgent: string;
+  userAgentMetadata: Protocol.Emulation.UserAgentMetadata;
+}
+
+const HEADLESS_TOKEN = /HeadlessChrome\/(\d+)((?:\.\d+)*)/;
+
+function toPlatform(userAgent: string): string {
+  if (userAgent.includes('Windows')) return 'Windows';
+  if (userAgent.includes('Macintosh')) return 'macOS';
+  if (userAgent.includes('CrOS')) return 'Chrome OS';
+  return 'Linux';
+}
+
+// The user agent of the desktop Chrome the browser is, derived from the one it reports so the
+// version always matches the running build. Client hints are sent along since Chrome's own
+// still carry the HeadlessChrome brand. Browsers without the token, visible ones or those
+// launched with a --user-agent of their own, are left as they are.
+export function toUserAgentOverride(browserUserAgent: string): UserAgentOverride | null {
+  const match = HEADLESS_TOKEN.exec(browserUserAgent);
+  if (!match) {
+    return null;
+  }
+  const major = match[1]!;
+  const fullVersion = `${major}${match[2] ?? ''}`;
+  const brands = (
+    version: string,
+    otherVersion: string
+  ): Protocol.Emulation.UserAgentBrandVersion[] => [
+    { brand: 'Not)A;Brand', version: otherVersion },
+    { brand: 'Google Chrome', version },
+    { brand: 'Chromium', version }
+  ];
+  return {
+    userAgent: browserUserAgent.replace(HEADLESS_TOKEN, `Chrome/${fullVersion}`),
+    userAgentMetadata: {
+      brands: brands(major, '99'),
+      fullVersionList: brands(fullVersion, '99.0.0.0'),
+      fullVersion,
+      platform: toPlatform(browserUserAgent),
+      platformVersion: '',
+      architecture: browserUserAgent.includes('arm') ? 'arm' : 'x86',
+      model: '',
+      mobile: false,
+      bitness: '64'
+    }
+  };
+}
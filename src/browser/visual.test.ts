@@ -0,0 +1,110 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import type { RgbaImage } from './png.js';
+import { diffImages, parseBaseline, resolveCompareOptions } from './visual.js';
+
+function solid(width: number, height: number, rgba: number[]): RgbaImage {
+  const data = Buffer.alloc(width * height * 4);
+  for (let i = 0; i < width * height; i++) data.set(rgba, i * 4);
+  return { width, height, data };
+}
+
+function paint(image: RgbaImage, x: number, y: number, rgba: number[]): RgbaImage {
+  const data = Buffer.from(image.data);
+  data.set(rgba, (y * image.width + x) * 4);
+  return { ...image, data };
+}
+
+const WHITE = [255, 255, 255, 255];
+const BLACK = [0, 0, 0, 255];
+const options = { threshold: 0.1, ignoreAntialiasing: true };
+
+describe('Visual diff helpers', () => {
+  describe('resolveCompareOptions', () => {
+    it('should apply defaults', () => {
+      expect(resolveCompareOptions({ baseline: 'baseline.png' })).toEqual({
+        threshold: 0.1,
+        ignoreAntialiasing: true,
+        maxDiffPercentage: 0
+      });
+    });
+
+    it('should reject a missing baseline and out of range values', () => {
+      expect(() => resolveCompareOptions({} as any)).toThrow(BrowserError);
+      expect(() => resolveCompareOptions({ baseline: 'a.png', threshold: 2 })).toThrow(
+        /threshold/
+      );
+      expect(() => resolveCompareOptions({ baseline: 'a.png', maxDiffPercentage: -1 })).toThrow(
+        /maxDiffPercentage/
+      );
+      expect(() => resolveCompareOptions({ baseline: 'a.png', diffImage: 'svg' as any })).toThrow(
+        /diffImage/
+      );
+    });
+  });
+
+  describe('parseBaseline', () => {
+    it('should tell base64 data from file paths', () => {
+      expect(parseBaseline('iVBORw0KGgoAAAA')).toHaveProperty('data');
+      expect(parseBaseline('data:image/png;base64,iVBORw0KGgoAAAA')).toHaveProperty('data');
+      expect(parseBaseline('baselines/home.png')).toEqual({ path: 'baselines/home.png' });
+    });
+  });
+
+  describe('diffImages', () => {
+    it('should find no differences in identical images', () => {
+      const image = solid(4, 4, WHITE);
+      expect(diffImages(image, solid(4, 4, WHITE), options)).toMatchObject({
+        diffPixels: 0,
+        antialiasedPixels: 0
+      });
+    });
+
+    it('should count changed pixels and mark them red', () => {
+      const result = diffImages(solid(8, 8, WHITE), paint(solid(8, 8, WHITE), 3, 3, BLACK), {
+        threshold: 0.1,
+        ignoreAntialiasing: false
+      });
+      expect(result.diffPixels).toBe(1);
+      expect([...result.image.data.subarray((3 * 8 + 3) * 4, (3 * 8 + 3) * 4 + 4)]).toEqual([
+        255, 0, 0, 255
+      ]);
+    });
+
+    it('should tolerate small color changes below the threshold', () => {
+      const slightlyOff = paint(solid(4, 4, WHITE), 1, 1, [250, 250, 250, 255]);
+      expect(diffImages(solid(4, 4, WHITE), slightlyOff, options).diffPixels).toBe(0);
+      expect(
+        diffImages(solid(4, 4, WHITE), slightlyOff, { ...options, threshold: 0 }).diffPixels
+      ).toBe(1);
+    });
+
+    it('should ignore anti-aliased edges when asked to', () => {
+      // Half black, half white, with the edge pixel shifting to gray as text rendering does
+      const edge = (gray: number) => {
+        let image = solid(6, 6, WHITE);
+        for (let y = 0; y < 6; y++) {
+          for (let x = 0; x < 3; x++) image = paint(image, x, y, BLACK);
+          image = paint(image, 3, y, [gray, gray, gray, 255]);
+        }
+        return image;
+      };
+      const before = edge(255);
+      const after = edge(128);
+
+      expect(diffImages(before, after, options)).toMatchObject({
+        diffPixels: 0,
+        antialiasedPixels: 6
+      });
+      expect(diffImages(before, after, { ...options, ignoreAntialiasing: false }).diffPixels).toBe(
+        6
+      );
+    });
+
+    it('should refuse images of different sizes', () => {
+      expect(() => diffImages(solid(2, 2, WHITE), solid(3, 2, WHITE), options)).toThrow(
+        /same size/
+      );
+    });
+  });
+});
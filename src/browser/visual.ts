@@ -0,0 +1,191 @@
+import {
+  type CompareScreenshotRequest,
+  DIFF_IMAGE_MODES,
+  InvalidArgumentError
+} from '../types/index.js';
+import type { RgbaImage } from './png.js';
+
+export interface ImageDiffOptions {
+  threshold: number;
+  ignoreAntialiasing: boolean;
+  maxDiffPercentage: number;
+}
+
+export interface ImageDiff {
+  diffPixels: number;
+  antialiasedPixels: number;
+  image: RgbaImage; // faded copy of the page, differences in red and anti-aliasing in yellow
+}
+
+export function resolveCompareOptions(request: CompareScreenshotRequest): ImageDiffOptions {
+  if (typeof request.baseline !== 'string' || !request.baseline) {
+    throw new InvalidArgumentError('baseline is required, as a path to a PNG or base64 PNG data');
+  }
+  const threshold = request.threshold ?? 0.1;
+  if (typeof threshold !== 'number' || !(threshold >= 0 && threshold <= 1)) {
+    throw new InvalidArgumentError('threshold must be a number from 0 to 1');
+  }
+  const maxDiffPercentage = request.maxDiffPercentage ?? 0;
+  if (
+    typeof maxDiffPercentage !== 'number' ||
+    !(maxDiffPercentage >= 0 && maxDiffPercentage <= 100)
+  ) {
+    throw new InvalidArgumentError('maxDiffPercentage must be a number from 0 to 100');
+  }
+  if (request.diffImage !== undefined && !DIFF_IMAGE_MODES.includes(request.diffImage)) {
+    throw new InvalidArgumentError(`diffImage must be one of: ${DIFF_IMAGE_MODES.join(', ')}`);
+  }
+  return { threshold, ignoreAntialiasing: request.ignoreAntialiasing ?? true, maxDiffPercentage };
+}
+
+// base64 PNGs start with the encoded signature, anything else is taken as a file path
+export function parseBaseline(baseline: string): { data: Buffer } | { path: string } {
+  const base64 = baseline.replace(/^data:image\/png;base64,/, '');
+  if (base64.startsWith('iVBORw0KGgo')) {
+    return { data: Buffer.from(base64, 'base64') };
+  }
+  return { path: baseline };
+}
+
+const rgb2y = (r: number, g: number, b: number) =>
+  r * 0.29889531 + g * 0.58662247 + b * 0.11448223;
+const rgb2i = (r: number, g: number, b: number) =>
+  r * 0.59597799 - g * 0.2741761 - b * 0.32180189;
+const rgb2q = (r: number, g: number, b: number) =>
+  r * 0.21147017 - g * 0.52261711 + b * 0.31114694;
+
+// Blends a channel onto white by its alpha
+const blend = (value: number, alpha: number) => 255 + (value - 255) * alpha;
+
+function channels(data: Buffer, offset: number): [number, number, number, number] {
+  return [data[offset] ?? 0, data[offset + 1] ?? 0, data[offset + 2] ?? 0, data[offset + 3] ?? 0];
+}
+
+// Perceived color difference in YIQ space (Kotsarenko and Ramos), signed by which side is
+// brighter. The largest possible value is 35215.
+function colorDelta(a: Buffer, b: Buffer, k: number, m: number, brightnessOnly: boolean) {
+  let [r1, g1, b1, a1] = channels(a, k);
+  let [r2, g2, b2, a2] = channels(b, m);
+  if (r1 === r2 && g1 === g2 && b1 === b2 && a1 === a2) {
+    return 0;
+  }
+  if (a1 < 255) {
+    [r1, g1, b1] = [blend(r1, a1 / 255), blend(g1, a1 / 255), blend(b1, a1 / 255)];
+  }
+  if (a2 < 255) {
+    [r2, g2, b2] = [blend(r2, a2 / 255), blend(g2, a2 / 255), blend(b2, a2 / 255)];
+  }
+
+  const y1 = rgb2y(r1, g1, b1);
+  const y2 = rgb2y(r2, g2, b2);
+  const y = y1 - y2;
+  if (brightnessOnly) {
+    return y;
+  }
+  const i = rgb2i(r1, g1, b1) - rgb2i(r2, g2, b2);
+  const q = rgb2q(r1, g1, b1) - rgb2q(r2, g2, b2);
+  const delta = 0.5053 * y * y + 0.299 * i * i + 0.1957 * q * q;
+  return y1 > y2 ? -delta : delta;
+}
+
+// Whether more than two of the pixel's neighbors have exactly its color
+function hasManySiblings(image: RgbaImage, x1: number, y1: number): boolean {
+  const { width, height, data } = image;
+  const x0 = Math.max(x1 - 1, 0);
+  const y0 = Math.max(y1 - 1, 0);
+  const x2 = Math.min(x1 + 1, width - 1);
+  const y2 = Math.min(y1 + 1, height - 1);
+  const pos = (y1 * width + x1) * 4;
+  let zeroes = x1 === x0 || x1 === x2 || y1 === y0 || y1 === y2 ? 1 : 0;
+  for (let x = x0; x <= x2; x++) {
+    for (let y = y0; y <= y2; y++) {
+      if (x === x1 && y === y1) continue;
+      const other = (y * width + x) * 4;
+      if (data.compare(data, pos, pos + 4, other, other + 4) === 0) {
+        zeroes++;
+      }
+      if (zeroes > 2) return true;
+    }
+  }
+  return false;
+}
+
+// Anti-aliased pixels sit between a darkest and a brightest neighbor that both belong to
+// solid areas in both images (Vysniauskas, "Anti-aliased Pixel and Intensity Slope Detector")
+function isAntialiased(image: RgbaImage, x1: number, y1: number, other: RgbaImage): boolean {
+  const { width, height, data } = image;
+  const x0 = Math.max(x1 - 1, 0);
+  const y0 = Math.max(y1 - 1, 0);
+  const x2 = Math.min(x1 + 1, width - 1);
+  const y2 = Math.min(y1 + 1, height - 1);
+  const pos = (y1 * width + x1) * 4;
+  let zeroes = x1 === x0 || x1 === x2 || y1 === y0 || y1 === y2 ? 1 : 0;
+  let min = 0;
+  let max = 0;
+  let darkest = { x: 0, y: 0 };
+  let brightest = { x: 0, y: 0 };
+  for (let x = x0; x <= x2; x++) {
+    for (let y = y0; y <= y2; y++) {
+      if (x === x1 && y === y1) continue;
+      const delta = colorDelta(data, data, pos, (y * width + x) * 4, true);
+      if (delta === 0) {
+        zeroes++;
+        if (zeroes > 2) return false;
+      } else if (delta < min) {
+        min = delta;
+        darkest = { x, y };
+      } else if (delta > max) {
+        max = delta;
+        brightest = { x, y };
+      }
+    }
+  }
+  if (min === 0 || max === 0) {
+    return false;
+  }
+  return (
+    (hasManySiblings(image, darkest.x, darkest.y) &&
+      hasManySiblings(other, darkest.x, darkest.y)) ||
+    (hasManySiblings(image, brightest.x, brightest.y) &&
+      hasManySiblings(other, brightest.x, brightest.y))
+  );
+}
+
+// Compares two images of the same size pixel by pixel, counting pixels whose color differs
+// by more than the threshold (0 exact, 1 anything goes)
+export function diffImages(
+  baseline: RgbaImage,
+  actual: RgbaImage,
+  options: Pick<ImageDiffOptions, 'threshold' | 'ignoreAntialiasing'>
+): ImageDiff {
+  const { width, height } = actual;
+  if (baseline.width !== width || baseline.height !== height) {
+    throw new InvalidArgumentError('Images must have the same size to be compared');
+  }
+
+  const maxDelta = 35215 * options.threshold * options.threshold;
+  const output = Buffer.alloc(width * height * 4);
+  let diffPixels = 0;
+  let antialiasedPixels = 0;
+  for (let y = 0; y < height; y++) {
+    for (let x = 0; x < width; x++) {
+      const pos = (y * width + x) * 4;
+      const delta = colorDelta(baseline.data, actual.data, pos, pos, false);
+      if (Math.abs(delta) <= maxDelta) {
+        const [r, g, b, a] = channels(actual.data, pos);
+        const gray = blend(rgb2y(r, g, b), (0.1 * a) / 255);
+        output.set([gray, gray, gray, 255], pos);
+      } else if (
+        options.ignoreAntialiasing &&
+        (isAntialiased(baseline, x, y, actual) || isAntialiased(actual, x, y, baseline))
+      ) {
+        antialiasedPixels++;
+        output.set([255, 255, 0, 255], pos);
+      } else {
+        diffPixels++;
+        output.set([255, 0, 0, 255], pos);
+      }
+    }
+  }
+  return { diffPixels, antialiasedPixels, image: { width, height, data: output } };
+}
@@ -0,0 +1,76 @@
+import { describe, expect, it } from 'vitest';
+import { BrowserError } from '../types/index.js';
+import {
+  isTimeoutError,
+  resolveStableOptions,
+  StabilityTracker,
+  toPredicateExpression
+} from './waits.js';
+
+describe('Wait helpers', () => {
+  describe('toPredicateExpression', () => {
+    it('should evaluate plain expressions', () => {
+      expect((0, eval)(toPredicateExpression('1 + 1 === 2'))).toBe(true);
+    });
+
+    it('should call function sources instead of returning them', () => {
+      expect((0, eval)(toPredicateExpression('() => false'))).toBe(false);
+      expect((0, eval)(toPredicateExpression('function () { return 42; }'))).toBe(42);
+    });
+  });
+
+  describe('isTimeoutError', () => {
+    it('should recognize Puppeteer timeout errors by name', () => {
+      class TimeoutError extends Error {
+        override name = 'TimeoutError';
+      }
+      expect(isTimeoutError(new TimeoutError('Waiting failed'))).toBe(true);
+    });
+
+    it('should ignore other errors', () => {
+      expect(isTimeoutError(new Error('Waiting failed'))).toBe(false);
+      expect(isTimeoutError('TimeoutError')).toBe(false);
+    });
+  });
+
+  describe('resolveStableOptions', () => {
+    it('should default to a 1px tolerance for 200ms without a timeout', () => {
+      expect(resolveStableOptions({})).toEqual({ tolerance: 1, stableTime: 200, timeout: 0 });
+    });
+
+    it('should reject negative values', () => {
+      expect(() => resolveStableOptions({ tolerance: -1 })).toThrow(BrowserError);
+      expect(() => resolveStableOptions({ stableTime: -5 })).toThrow(/stableTime/);
+      expect(() => resolveStableOptions({ timeout: Number.NaN })).toThrow(/timeout/);
+    });
+  });
+
+  describe('StabilityTracker', () => {
+    const box = (x: number, width = 100) => ({ x, y: 0, width, height: 50 });
+
+    it('should be stable once the box stays put for the stable time', () => {
+      const tracker = new StabilityTracker(1, 200);
+      expect(tracker.update(box(0), 0)).toBe(false);
+      expect(tracker.update(box(40), 50)).toBe(false);
+      expect(tracker.update(box(80), 100)).toBe(false);
+      expect(tracker.update(box(80.5), 200)).toBe(false);
+      expect(tracker.update(box(80), 300)).toBe(true);
+      expect(tracker.moves).toBe(2);
+    });
+
+    it('should count resizes and slow drift as moves', () => {
+      const tracker = new StabilityTracker(1, 100);
+      tracker.update(box(0), 0);
+      tracker.update(box(0, 120), 50);
+      tracker.update(box(0.6, 120), 100);
+      expect(tracker.update(box(1.2, 120), 150)).toBe(false);
+      expect(tracker.moves).toBe(2);
+    });
+
+    it('should never be stable without a box', () => {
+      const tracker = new StabilityTracker(1, 0);
+      expect(tracker.update(null, 0)).toBe(false);
+      expect(tracker.update(box(0), 10)).toBe(true);
+    });
+  });
+});
@@ -0,0 +1,94 @@
+import { type ClipRect, InvalidArgumentError, type WaitForStableRequest } from '../types/index.js';
+
+export const DEFAULT_STABLE_TOLERANCE = 1;
+export const DEFAULT_STABLE_TIME = 200;
+export const STABLE_POLL_INTERVAL = 50;
+
+export function isTimeoutError(error: unknown): boolean {
+  return error instanceof Error && error.name === 'TimeoutError';
+}
+
+// Puppeteer evaluates a string predicate as an expression, so "() => ready" would be truthy
+// straight away. Both plain expressions and function sources are accepted: a function is
+// called on every poll.
+export function toPredicateExpression(script: string): string {
+  return `(() => {
+  const value = (${script});
+  return typeof value === 'function' ? value() : value;
+})()`;
+}
+
+// Runs in the page to explain why a wait did not succeed, e.g. the element exists but is
+// hidden by CSS or has no size
+export function describeElementState(el: any): Record<string, unknown> {
+  const style = (globalThis as any).getComputedStyle(el);
+  const rect = el.getBoundingClientRect();
+  return {
+    tag: String(el.tagName).toLowerCase(),
+    id: el.id || null,
+    className: typeof el.className === 'string' && el.className ? el.className : null,
+    visible: style.visibility !== 'hidden' && rect.width > 0 && rect.height > 0,
+    display: style.display,
+    visibility: style.visibility,
+    opacity: style.opacity,
+    boundingBox: { x: rect.x, y: rect.y, width: rect.width, height: rect.height }
+  };
+}
+
+export function resolveStableOptions(request: WaitForStableRequest): {
+  tolerance: number;
+  stableTime: number;
+  timeout: number;
+} {
+  const tolerance = request.tolerance ?? DEFAULT_STABLE_TOLERANCE;
+  const stableTime = request.stableTime ?? DEFAULT_STABLE_TIME;
+  const timeout = request.timeout ?? 0;
+  if (typeof tolerance !== 'number' || !(tolerance >= 0)) {
+    throw new InvalidArgumentError('tolerance must be a non-negative number of pixels');
+  }
+  if (typeof stableTime !== 'number' || !(stableTime >= 0)) {
+    throw new InvalidArgumentError('stableTime must be a non-negative number of milliseconds');
+  }
+  if (typeof timeout !== 'number' || !(timeout >= 0)) {
+    throw new InvalidArgumentError('timeout must be a non-negative number of milliseconds');
+  }
+  return { tolerance, stableTime, timeout };
+}
+
+// Follows the bounding box of an element across polls. Each box is compared with the one it
+// settled at, not the previous poll, so a slow drift still counts as moving once it adds up.
+// A missing box, while the element is not in the DOM or not rendered, is never stable.
+export class StabilityTracker {
+  moves = 0;
+  private readonly tolerance: number;
+  private readonly stableTime: number;
+  private anchor: ClipRect | null = null;
+  private stillSince = 0;
+
+  constructor(tolerance: number, stableTime: number) {
+    this.tolerance = tolerance;
+    this.stableTime = stableTime;
+  }
+
+  // Whether the box has stayed within the tolerance for stableTime as of now
+  update(box: ClipRect | null, now: number): boolean {
+    if (!box) {
+      this.anchor = null;
+      return false;
+    }
+    const anchor = this.anchor;
+    const moved =
+      anchor !== null &&
+      (['x', 'y', 'width', 'height'] as const).some(
+        key => Math.abs(box[key] - anchor[key]) > this.tolerance
+      );
+    if (!anchor || moved) {
+      if (moved) {
+        this.moves++;
+      }
+      this.anchor = box;
+      this.stillSince = now;
+    }
+    return now - this.stillSince >= this.stableTime;
+  }
+}
@@ -1,7 +1,37 @@
 import fs from 'node:fs';
 import path from 'node:path';
 import { afterEach, beforeEach, describe, expect, it, vi } from 'vitest';
-import { ensureBaseWorkingDirectory, loadConfig, saveConfig, updateConfig } from './index.js';
+import {
+  ensureBaseWorkingDirectory,
+  getAuthToken,
+  getCdpEndpoint,
+  getChromePath,
+  getDefaultTimeout,
+  getDefaultViewport,
+  getExtensions,
+  getHeadless,
+  getHost,
+  getIgnoreHttpsErrors,
+  getLaunchArgs,
+  getLogFile,
+  getLogFormat,
+  getLogLevel,
+  getMaxConcurrency,
+  getNavigationTimeout,
+  getPagePoolSize,
+  getPort,
+  getProxy,
+  getQueueTimeout,
+  getSessionTtl,
+  getShutdownTimeout,
+  getTransport,
+  getUserDataDir,
+  isLoopbackHost,
+  isRawCdpEnabled,
+  loadConfig,
+  saveConfig,
+  updateConfig
+} from './index.js';
 
 // Mock fs module
 vi.mock('fs');
@@ -147,4 +177,679 @@ describe('Configuration Management', () => {
       });
     });
   });
+
+  describe('getCdpEndpoint', () => {
+    const originalArgv = process.argv;
+    const originalEnv = process.env['PCS_CDP_ENDPOINT'];
+
+    afterEach(() => {
+      process.argv = originalArgv;
+      if (originalEnv === undefined) {
+        delete process.env['PCS_CDP_ENDPOINT'];
+      } else {
+        process.env['PCS_CDP_ENDPOINT'] = originalEnv;
+      }
+    });
+
+    it('should return null when nothing is configured', () => {
+      process.argv = ['node', 'server.js'];
+      delete process.env['PCS_CDP_ENDPOINT'];
+
+      expect(getCdpEndpoint({ chromePath: null, port: 3000 })).toBeNull();
+    });
+
+    it('should read the endpoint from the command line', () => {
+      process.argv = [
+        'node',
+        'server.js',
+        '--cdp-endpoint',
+        'ws://127.0.0.1:9222/devtools/browser/x'
+      ];
+      process.env['PCS_CDP_ENDPOINT'] = 'http://127.0.0.1:9333';
+
+      expect(getCdpEndpoint()).toBe('ws://127.0.0.1:9222/devtools/browser/x');
+    });
+
+    it('should accept the inline flag form', () => {
+      process.argv = ['node', 'server.js', '--cdp-endpoint=http://127.0.0.1:9222'];
+
+      expect(getCdpEndpoint()).toBe('http://127.0.0.1:9222');
+    });
+
+    it('should prefer the environment over config.json', () => {
+      process.argv = ['node', 'server.js'];
+      process.env['PCS_CDP_ENDPOINT'] = 'http://127.0.0.1:9333';
+
+      expect(
+        getCdpEndpoint({ chromePath: null, port: 3000, cdpEndpoint: 'http://127.0.0.1:9222' })
+      ).toBe('http://127.0.0.1:9333');
+    });
+  });
+
+  describe('getProxy', () => {
+    const originalArgv = process.argv;
+    const originalEnv = process.env['PCS_PROXY'];
+
+    afterEach(() => {
+      process.argv = originalArgv;
+      if (originalEnv === undefined) {
+        delete process.env['PCS_PROXY'];
+      } else {
+        process.env['PCS_PROXY'] = originalEnv;
+      }
+    });
+
+    it('should return null when nothing is configured', () => {
+      process.argv = ['node', 'server.js'];
+      delete process.env['PCS_PROXY'];
+
+      expect(getProxy({ chromePath: null, port: 3000 })).toBeNull();
+    });
+
+    it('should prefer the command line over the environment and config.json', () => {
+      process.argv = ['node', 'server.js', '--proxy=http://127.0.0.1:8080'];
+      process.env['PCS_PROXY'] = 'socks5://127.0.0.1:1080';
+
+      expect(
+        getProxy({ chromePath: null, port: 3000, proxy: 'http://proxy.example.com:3128' })
+      ).toBe('http://127.0.0.1:8080');
+    });
+
+    it('should prefer the environment over config.json', () => {
+      process.argv = ['node', 'server.js'];
+      process.env['PCS_PROXY'] = 'socks5://127.0.0.1:1080';
+
+      expect(
+        getProxy({ chromePath: null, port: 3000, proxy: 'http://proxy.example.com:3128' })
+      ).toBe('socks5://127.0.0.1:1080');
+    });
+  });
+
+  describe('getPort', () => {
+    const originalArgv = process.argv;
+
+    afterEach(() => {
+      process.argv = originalArgv;
+    });
+
+    it('should prefer the command line over config.json', () => {
+      process.argv = ['node', 'server.js', '--port', '8931'];
+
+      expect(getPort({ chromePath: null, port: 3000 })).toBe(8931);
+    });
+
+    it('should keep the configured port without a valid flag', () => {
+      process.argv = ['node', 'server.js'];
+      expect(getPort({ chromePath: null, port: 4000 })).toBe(4000);
+
+      process.argv = ['node', 'server.js', '--port=70000'];
+      expect(getPort({ chromePath: null, port: 4000 })).toBe(3000);
+    });
+  });
+
+  describe('getTransport', () => {
+    const originalArgv = process.argv;
+    const originalEnv = process.env['PCS_TRANSPORT'];
+
+    afterEach(() => {
+      process.argv = originalArgv;
+      if (originalEnv === undefined) {
+        delete process.env['PCS_TRANSPORT'];
+      } else {
+        process.env['PCS_TRANSPORT'] = originalEnv;
+      }
+    });
+
+    it('should default to stdio', () => {
+      process.argv = ['node', 'server.js'];
+      delete process.env['PCS_TRANSPORT'];
+
+      expect(getTransport()).toBe('stdio');
+    });
+
+    it('should prefer the command line over the environment', () => {
+      process.argv = ['node', 'server.js', '--transport', 'http'];
+      process.env['PCS_TRANSPORT'] = 'stdio';
+
+      expect(getTransport()).toBe('http');
+    });
+
+    it('should fall back to stdio for unknown transports', () => {
+      process.argv = ['node', 'server.js'];
+      process.env['PCS_TRANSPORT'] = 'websocket';
+
+      expect(getTransport()).toBe('stdio');
+    });
+  });
+
+  describe('getHost', () => {
+    const originalArgv = process.argv;
+    const originalEnv = process.env['PCS_HOST'];
+
+    afterEach(() => {
+      process.argv = originalArgv;
+      if (originalEnv === undefined) {
+        delete process.env['PCS_HOST'];
+      } else {
+        process.env['PCS_HOST'] = originalEnv;
+      }
+    });
+
+    it('should leave the choice to the server when nothing is configured', () => {
+      process.argv = ['node', 'server.js'];
+      delete process.env['PCS_HOST'];
+
+      expect(getHost({ chromePath: null, port: 3000 })).toBeNull();
+    });
+
+    it('should prefer the command line over the environment and config.json', () => {
+      process.argv = ['node', 'server.js', '--host', '10.0.0.5'];
+      process.env['PCS_HOST'] = '0.0.0.0';
+
+      expect(getHost({ chromePath: null, port: 3000, host: '127.0.0.1' })).toBe('10.0.0.5');
+
+      process.argv = ['node', 'server.js'];
+      expect(getHost({ chromePath: null, port: 3000, host: '127.0.0.1' })).toBe('0.0.0.0');
+    });
+  });
+
+  describe('isLoopbackHost', () => {
+    it('should only accept addresses of this machine', () => {
+      expect(isLoopbackHost('localhost')).toBe(true);
+      expect(isLoopbackHost('127.0.0.1')).toBe(true);
+      expect(isLoopbackHost('127.1.2.3')).toBe(true);
+      expect(isLoopbackHost('::1')).toBe(true);
+      expect(isLoopbackHost('0.0.0.0')).toBe(false);
+      expect(isLoopbackHost('::')).toBe(false);
+      expect(isLoopbackHost('127.0.0.1.example.com')).toBe(false);
+    });
+  });
+
+  describe('getAuthToken', () => {
+    const originalEnv = process.env['PCS_AUTH_TOKEN'];
+
+    afterEach(() => {
+      if (originalEnv === undefined) {
+        delete process.env['PCS_AUTH_TOKEN'];
+      } else {
+        process.env['PCS_AUTH_TOKEN'] = originalEnv;
+      }
+    });
+
+    it('should prefer the environment over config.json', () => {
+      process.env['PCS_AUTH_TOKEN'] = 'from-env';
+      expect(getAuthToken({ chromePath: null, port: 3000, auth: { token: 'from-file' } })).toBe(
+        'from-env'
+      );
+
+      delete process.env['PCS_AUTH_TOKEN'];
+      expect(getAuthToken({ chromePath: null, port: 3000, auth: { token: 'from-file' } })).toBe(
+        'from-file'
+      );
+      expect(getAuthToken({ chromePath: null, port: 3000 })).toBeNull();
+    });
+  });
+
+  describe('getChromePath', () => {
+    const originalArgv = process.argv;
+    const originalEnv = process.env['PCS_CHROME_PATH'];
+
+    afterEach(() => {
+      process.argv = originalArgv;
+      if (originalEnv === undefined) {
+        delete process.env['PCS_CHROME_PATH'];
+      } else {
+        process.env['PCS_CHROME_PATH'] = originalEnv;
+      }
+    });
+
+    it('should fall back to config.json', () => {
+      process.argv = ['node', 'server.js'];
+      delete process.env['PCS_CHROME_PATH'];
+
+      expect(getChromePath({ chromePath: '/opt/chrome/chrome', port: 3000 })).toBe(
+        '/opt/chrome/chrome'
+      );
+      expect(getChromePath({ chromePath: null, port: 3000 })).toBeNull();
+    });
+
+    it('should prefer the command line over the environment and config.json', () => {
+      process.argv = ['node', 'server.js', '--chrome-path', '/usr/bin/chromium'];
+      process.env['PCS_CHROME_PATH'] = '/usr/bin/google-chrome';
+
+      expect(getChromePath({ chromePath: '/opt/chrome/chrome', port: 3000 })).toBe(
+        '/usr/bin/chromium'
+      );
+    });
+
+    it('should prefer the environment over config.json', () => {
+      process.argv = ['node', 'server.js'];
+      process.env['PCS_CHROME_PATH'] = '/usr/bin/google-chrome';
+
+      expect(getChromePath({ chromePath: '/opt/chrome/chrome', port: 3000 })).toBe(
+        '/usr/bin/google-chrome'
+      );
+    });
+  });
+
+  describe('getLaunchArgs', () => {
+    const originalArgv = process.argv;
+    const originalEnv = process.env['PCS_LAUNCH_ARGS'];
+
+    afterEach(() => {
+      process.argv = originalArgv;
+      if (originalEnv === undefined) {
+        delete process.env['PCS_LAUNCH_ARGS'];
+      } else {
+        process.env['PCS_LAUNCH_ARGS'] = originalEnv;
+      }
+    });
+
+    it('should return no flags when nothing is configured', () => {
+      process.argv = ['node', 'server.js'];
+      delete process.env['PCS_LAUNCH_ARGS'];
+
+      expect(getLaunchArgs({ chromePath: null, port: 3000 })).toEqual([]);
+    });
+
+    it('should split the environment on whitespace, keeping quoted values together', () => {
+      process.argv = ['node', 'server.js'];
+      process.env['PCS_LAUNCH_ARGS'] =
+        ' --lang=de-DE  --user-agent="Mozilla/5.0 (X11)" !--disable-gpu';
+
+      expect(getLaunchArgs({ chromePath: null, port: 3000, launchArgs: ['--kiosk'] })).toEqual([
+        '--lang=de-DE',
+        '--user-agent=Mozilla/5.0 (X11)',
+        '!--disable-gpu'
+      ]);
+    });
+
+    it('should read the list from config.json', () => {
+      process.argv = ['node', 'server.js'];
+      delete process.env['PCS_LAUNCH_ARGS'];
+
+      expect(getLaunchArgs({ chromePath: null, port: 3000, launchArgs: ['--kiosk'] })).toEqual([
+        '--kiosk'
+      ]);
+    });
+  });
+
+  describe('getExtensions', () => {
+    const originalEnv = process.env['PCS_EXTENSIONS'];
+
+    afterEach(() => {
+      if (originalEnv === undefined) {
+        delete process.env['PCS_EXTENSIONS'];
+      } else {
+        process.env['PCS_EXTENSIONS'] = originalEnv;
+      }
+    });
+
+    it('should split the environment like PATH and fall back to config.json', () => {
+      delete process.env['PCS_EXTENSIONS'];
+      expect(getExtensions()).toEqual([]);
+      expect(getExtensions({ chromePath: null, port: 3000, extensions: ['/ext/a'] })).toEqual([
+        '/ext/a'
+      ]);
+
+      process.env['PCS_EXTENSIONS'] = ['/ext/a', '/ext/b', ''].join(path.delimiter);
+      expect(getExtensions()).toEqual(['/ext/a', '/ext/b']);
+    });
+  });
+
+  describe('getUserDataDir', () => {
+    const originalEnv = process.env['PCS_USER_DATA_DIR'];
+
+    afterEach(() => {
+      if (originalEnv === undefined) {
+        delete process.env['PCS_USER_DATA_DIR'];
+      } else {
+        process.env['PCS_USER_DATA_DIR'] = originalEnv;
+      }
+    });
+
+    it('should prefer the environment over config.json and resolve the path', () => {
+      delete process.env['PCS_USER_DATA_DIR'];
+      expect(getUserDataDir({ chromePath: null, port: 3000 })).toBeNull();
+      expect(getUserDataDir({ chromePath: null, port: 3000, userDataDir: '/data/a' })).toBe(
+        '/data/a'
+      );
+
+      process.env['PCS_USER_DATA_DIR'] = 'profiles/main';
+      expect(getUserDataDir({ chromePath: null, port: 3000, userDataDir: '/data/a' })).toBe(
+        path.resolve('profiles/main')
+      );
+    });
+  });
+
+  describe('getHeadless', () => {
+    const originalEnv = process.env['PCS_HEADLESS'];
+
+    afterEach(() => {
+      if (originalEnv === undefined) {
+        delete process.env['PCS_HEADLESS'];
+      } else {
+        process.env['PCS_HEADLESS'] = originalEnv;
+      }
+    });
+
+    it('should return null when nothing is configured', () => {
+      delete process.env['PCS_HEADLESS'];
+
+      expect(getHeadless({ chromePath: null, port: 3000 })).toBeNull();
+    });
+
+    it('should map the supported modes', () => {
+      const modes = { true: true, new: true, FALSE: false, shell: 'shell', yes: null };
+      for (const [value, expected] of Object.entries(modes)) {
+        process.env['PCS_HEADLESS'] = value;
+        expect(getHeadless()).toBe(expected);
+      }
+    });
+
+    it('should prefer the environment over config.json', () => {
+      process.env['PCS_HEADLESS'] = 'false';
+      expect(getHeadless({ chromePath: null, port: 3000, headless: 'shell' })).toBe(false);
+
+      delete process.env['PCS_HEADLESS'];
+      expect(getHeadless({ chromePath: null, port: 3000, headless: true })).toBe(true);
+    });
+  });
+
+  describe('getDefaultTimeout', () => {
+    const originalEnv = process.env['PCS_DEFAULT_TIMEOUT'];
+
+    afterEach(() => {
+      if (originalEnv === undefined) {
+        delete process.env['PCS_DEFAULT_TIMEOUT'];
+      } else {
+        process.env['PCS_DEFAULT_TIMEOUT'] = originalEnv;
+      }
+    });
+
+    it("should default to Puppeteer's 30 seconds", () => {
+      delete process.env['PCS_DEFAULT_TIMEOUT'];
+
+      expect(getDefaultTimeout()).toBe(30000);
+    });
+
+    it('should read PCS_DEFAULT_TIMEOUT and ignore invalid values', () => {
+      process.env['PCS_DEFAULT_TIMEOUT'] = '60000';
+      expect(getDefaultTimeout()).toBe(60000);
+
+      process.env['PCS_DEFAULT_TIMEOUT'] = '-5';
+      expect(getDefaultTimeout()).toBe(30000);
+
+      process.env['PCS_DEFAULT_TIMEOUT'] = 'soon';
+      expect(getDefaultTimeout()).toBe(30000);
+    });
+  });
+
+  describe('getNavigationTimeout', () => {
+    const originalAction = process.env['PCS_DEFAULT_TIMEOUT'];
+    const originalNavigation = process.env['PCS_NAVIGATION_TIMEOUT'];
+
+    afterEach(() => {
+      for (const [name, value] of [
+        ['PCS_DEFAULT_TIMEOUT', originalAction],
+        ['PCS_NAVIGATION_TIMEOUT', originalNavigation]
+      ] as const) {
+        if (value === undefined) {
+          delete process.env[name];
+        } else {
+          process.env[name] = value;
+        }
+      }
+    });
+
+    it('should default to a minute, or the action timeout when that is longer', () => {
+      delete process.env['PCS_DEFAULT_TIMEOUT'];
+      delete process.env['PCS_NAVIGATION_TIMEOUT'];
+      expect(getNavigationTimeout()).toBe(60000);
+
+      process.env['PCS_DEFAULT_TIMEOUT'] = '90000';
+      expect(getNavigationTimeout()).toBe(90000);
+
+      process.env['PCS_DEFAULT_TIMEOUT'] = '0';
+      expect(getNavigationTimeout()).toBe(0);
+    });
+
+    it('should read PCS_NAVIGATION_TIMEOUT and ignore invalid values', () => {
+      delete process.env['PCS_DEFAULT_TIMEOUT'];
+      process.env['PCS_NAVIGATION_TIMEOUT'] = '15000';
+      expect(getNavigationTimeout()).toBe(15000);
+
+      process.env['PCS_NAVIGATION_TIMEOUT'] = 'later';
+      expect(getNavigationTimeout()).toBe(60000);
+    });
+  });
+
+  describe('getMaxConcurrency and getQueueTimeout', () => {
+    const originalLimit = process.env['PCS_MAX_CONCURRENCY'];
+    const originalTimeout = process.env['PCS_QUEUE_TIMEOUT'];
+
+    afterEach(() => {
+      for (const [name, value] of [
+        ['PCS_MAX_CONCURRENCY', originalLimit],
+        ['PCS_QUEUE_TIMEOUT', originalTimeout]
+      ] as const) {
+        if (value === undefined) {
+          delete process.env[name];
+        } else {
+          process.env[name] = value;
+        }
+      }
+    });
+
+    it('should default to no limit and a one minute queue timeout', () => {
+      delete process.env['PCS_MAX_CONCURRENCY'];
+      delete process.env['PCS_QUEUE_TIMEOUT'];
+
+      expect(getMaxConcurrency()).toBe(0);
+      expect(getQueueTimeout()).toBe(60000);
+    });
+
+    it('should read the environment and ignore invalid values', () => {
+      process.env['PCS_MAX_CONCURRENCY'] = '4';
+      process.env['PCS_QUEUE_TIMEOUT'] = '0';
+      expect(getMaxConcurrency()).toBe(4);
+      expect(getQueueTimeout()).toBe(0);
+
+      process.env['PCS_MAX_CONCURRENCY'] = '2.5';
+      process.env['PCS_QUEUE_TIMEOUT'] = '-1';
+      expect(getMaxConcurrency()).toBe(0);
+      expect(getQueueTimeout()).toBe(60000);
+    });
+  });
+
+  describe('getShutdownTimeout', () => {
+    const original = process.env['PCS_SHUTDOWN_TIMEOUT'];
+
+    afterEach(() => {
+      if (original === undefined) {
+        delete process.env['PCS_SHUTDOWN_TIMEOUT'];
+      } else {
+        process.env['PCS_SHUTDOWN_TIMEOUT'] = original;
+      }
+    });
+
+    it('should default to five seconds and ignore invalid values', () => {
+      delete process.env['PCS_SHUTDOWN_TIMEOUT'];
+      expect(getShutdownTimeout()).toBe(5000);
+
+      process.env['PCS_SHUTDOWN_TIMEOUT'] = '0';
+      expect(getShutdownTimeout()).toBe(0);
+
+      process.env['PCS_SHUTDOWN_TIMEOUT'] = 'later';
+      expect(getShutdownTimeout()).toBe(5000);
+    });
+  });
+
+  describe('getDefaultViewport', () => {
+    const original = process.env['PCS_DEFAULT_VIEWPORT'];
+
+    afterEach(() => {
+      if (original === undefined) {
+        delete process.env['PCS_DEFAULT_VIEWPORT'];
+      } else {
+        process.env['PCS_DEFAULT_VIEWPORT'] = original;
+      }
+    });
+
+    it('should pass the value on for the browser manager to parse', () => {
+      delete process.env['PCS_DEFAULT_VIEWPORT'];
+      expect(getDefaultViewport()).toBeNull();
+
+      process.env['PCS_DEFAULT_VIEWPORT'] = '1920x1080@2';
+      expect(getDefaultViewport()).toBe('1920x1080@2');
+    });
+  });
+
+  describe('getIgnoreHttpsErrors', () => {
+    const originalArgv = process.argv;
+    const originalEnv = process.env['PCS_IGNORE_HTTPS_ERRORS'];
+
+    afterEach(() => {
+      process.argv = originalArgv;
+      if (originalEnv === undefined) {
+        delete process.env['PCS_IGNORE_HTTPS_ERRORS'];
+      } else {
+        process.env['PCS_IGNORE_HTTPS_ERRORS'] = originalEnv;
+      }
+    });
+
+    it('should stay off unless the flag, the environment or config.json turns it on', () => {
+      process.argv = ['node', 'server.js'];
+      delete process.env['PCS_IGNORE_HTTPS_ERRORS'];
+      expect(getIgnoreHttpsErrors({ chromePath: null, port: 3000 })).toBe(false);
+
+      process.argv = ['node', 'server.js', '--ignore-https-errors'];
+      expect(getIgnoreHttpsErrors()).toBe(true);
+
+      process.argv = ['node', 'server.js'];
+      process.env['PCS_IGNORE_HTTPS_ERRORS'] = 'true';
+      expect(getIgnoreHttpsErrors()).toBe(true);
+
+      delete process.env['PCS_IGNORE_HTTPS_ERRORS'];
+      expect(getIgnoreHttpsErrors({ chromePath: null, port: 3000, ignoreHTTPSErrors: true })).toBe(
+        true
+      );
+    });
+  });
+
+  describe('getPagePoolSize', () => {
+    const original = process.env['PCS_PAGE_POOL_SIZE'];
+
+    afterEach(() => {
+      if (original === undefined) {
+        delete process.env['PCS_PAGE_POOL_SIZE'];
+      } else {
+        process.env['PCS_PAGE_POOL_SIZE'] = original;
+      }
+    });
+
+    it('should be off by default and ignore invalid values', () => {
+      delete process.env['PCS_PAGE_POOL_SIZE'];
+      expect(getPagePoolSize()).toBe(0);
+
+      process.env['PCS_PAGE_POOL_SIZE'] = '4';
+      expect(getPagePoolSize()).toBe(4);
+
+      process.env['PCS_PAGE_POOL_SIZE'] = 'many';
+      expect(getPagePoolSize()).toBe(0);
+    });
+  });
+
+  describe('getSessionTtl', () => {
+    const original = process.env['PCS_SESSION_TTL'];
+
+    afterEach(() => {
+      if (original === undefined) {
+        delete process.env['PCS_SESSION_TTL'];
+      } else {
+        process.env['PCS_SESSION_TTL'] = original;
+      }
+    });
+
+    it('should default to 30 minutes and ignore invalid values', () => {
+      delete process.env['PCS_SESSION_TTL'];
+      expect(getSessionTtl()).toBe(1800000);
+
+      process.env['PCS_SESSION_TTL'] = '0';
+      expect(getSessionTtl()).toBe(0);
+
+      process.env['PCS_SESSION_TTL'] = '60000';
+      expect(getSessionTtl()).toBe(60000);
+
+      process.env['PCS_SESSION_TTL'] = '-5';
+      expect(getSessionTtl()).toBe(1800000);
+    });
+  });
+
+  describe('getLogLevel, getLogFormat and getLogFile', () => {
+    const original = {
+      PCS_LOG_LEVEL: process.env['PCS_LOG_LEVEL'],
+      PCS_LOG_FORMAT: process.env['PCS_LOG_FORMAT'],
+      PCS_LOG_FILE: process.env['PCS_LOG_FILE']
+    };
+
+    afterEach(() => {
+      for (const [name, value] of Object.entries(original)) {
+        if (value === undefined) {
+          delete process.env[name];
+        } else {
+          process.env[name] = value;
+        }
+      }
+    });
+
+    it('should default to info logfmt on stderr', () => {
+      for (const name of Object.keys(original)) delete process.env[name];
+
+      expect(getLogLevel()).toBe('info');
+      expect(getLogFormat()).toBe('logfmt');
+      expect(getLogFile()).toBeNull();
+    });
+
+    it('should read the environment and ignore invalid values', () => {
+      process.env['PCS_LOG_LEVEL'] = 'DEBUG';
+      process.env['PCS_LOG_FORMAT'] = 'json';
+      process.env['PCS_LOG_FILE'] = '/var/log/pcs.log';
+      expect(getLogLevel()).toBe('debug');
+      expect(getLogFormat()).toBe('json');
+      expect(getLogFile()).toBe('/var/log/pcs.log');
+
+      process.env['PCS_LOG_LEVEL'] = 'verbose';
+      process.env['PCS_LOG_FORMAT'] = 'xml';
+      expect(getLogLevel()).toBe('info');
+      expect(getLogFormat()).toBe('logfmt');
+    });
+  });
+
+  describe('isRawCdpEnabled', () => {
+    const originalArgv = process.argv;
+    const originalEnv = process.env['PCS_ENABLE_RAW_CDP'];
+
+    afterEach(() => {
+      process.argv = originalArgv;
+      if (originalEnv === undefined) {
+        delete process.env['PCS_ENABLE_RAW_CDP'];
+      } else {
+        process.env['PCS_ENABLE_RAW_CDP'] = originalEnv;
+      }
+    });
+
+    it('should stay off unless the flag or the environment turns it on', () => {
+      process.argv = ['node', 'server.js', '--transport', 'http'];
+      delete process.env['PCS_ENABLE_RAW_CDP'];
+      expect(isRawCdpEnabled()).toBe(false);
+
+      process.argv = ['node', 'server.js', '--enable-raw-cdp'];
+      expect(isRawCdpEnabled()).toBe(true);
+
+      process.argv = ['node', 'server.js'];
+      process.env['PCS_ENABLE_RAW_CDP'] = 'true';
+      expect(isRawCdpEnabled()).toBe(true);
+    });
+  });
 });
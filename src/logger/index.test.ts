@@ -0,0 +1,53 @@
+import { describe, expect, it } from 'vitest';
+import type { LogFormat, LogLevel } from '../config/index.js';
+import { formatLogEntry, Logger } from './index.js';
+
+describe('Logger', () => {
+  const capture = (level: LogLevel, format: LogFormat) => {
+    const lines: string[] = [];
+    return { lines, logger: new Logger(level, format, line => lines.push(line)) };
+  };
+
+  it('should format logfmt, quoting values that need it', () => {
+    expect(
+      formatLogEntry('logfmt', {
+        level: 'warn',
+        msg: 'operation failed',
+        tool: 'browser_click',
+        durationMs: 12,
+        error: new Error('No element found for selector: #buy'),
+        sessionId: undefined
+      })
+    ).toBe(
+      'level=warn msg="operation failed" tool=browser_click durationMs=12 ' +
+        'error="No element found for selector: #buy"'
+    );
+    expect(formatLogEntry('logfmt', { pids: [1, 2], quote: 'a"b' })).toBe(
+      'pids=[1,2] quote="a\\"b"'
+    );
+  });
+
+  it('should format JSON lines', () => {
+    expect(formatLogEntry('json', { msg: 'page crashed', error: new Error('boom') })).toBe(
+      '{"msg":"page crashed","error":"boom"}'
+    );
+  });
+
+  it('should drop entries below the level', () => {
+    const { lines, logger } = capture('warn', 'json');
+    logger.debug('hidden');
+    logger.info('hidden');
+    logger.warn('shown', { sessionId: 's1' });
+    logger.error('shown too');
+
+    expect(lines).toHaveLength(2);
+    expect(JSON.parse(lines[0] ?? '')).toMatchObject({ level: 'warn', sessionId: 's1' });
+    expect(lines[1]?.endsWith('\n')).toBe(true);
+  });
+
+  it('should log nothing when silent', () => {
+    const { lines, logger } = capture('silent', 'logfmt');
+    logger.error('hidden');
+    expect(lines).toEqual([]);
+  });
+});
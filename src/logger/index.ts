@@ -0,0 +1,114 @@
+import fs from 'node:fs';
+import {
+  getLogFile,
+  getLogFormat,
+  getLogLevel,
+  type LogFormat,
+  type LogLevel
+} from '../config/index.js';
+
+// Operational log for whoever runs pcs: browser lifecycle, every tool call and errors with
+// their codes, one line per event. Stays off stdout, the stdio transport speaks MCP there.
+// The debug namespaces (DEBUG=pcs:*) remain the developer's tracing.
+
+export type LogFields = Record<string, unknown>;
+
+type Severity = Exclude<LogLevel, 'silent'>;
+
+const SEVERITIES: Record<LogLevel, number> = {
+  debug: 10,
+  info: 20,
+  warn: 30,
+  error: 40,
+  silent: 99
+};
+
+function toValue(value: unknown): unknown {
+  return value instanceof Error ? value.message : value;
+}
+
+function formatLogfmtValue(value: unknown): string {
+  const text = typeof value === 'string' ? value : JSON.stringify(value);
+  return /^[^\s"=\\]+$/.test(text) ? text : JSON.stringify(text);
+}
+
+export function formatLogEntry(format: LogFormat, entry: LogFields): string {
+  const fields = Object.entries(entry)
+    .filter(([, value]) => value !== undefined)
+    .map(([key, value]) => [key, toValue(value)] as const);
+  if (format === 'json') {
+    return JSON.stringify(Object.fromEntries(fields));
+  }
+  return fields.map(([key, value]) => `${key}=${formatLogfmtValue(value)}`).join(' ');
+}
+
+export class Logger {
+  private readonly level: LogLevel;
+  private readonly format: LogFormat;
+  private readonly write: (line: string) => void;
+
+  constructor(level: LogLevel, format: LogFormat, write: (line: string) => void) {
+    this.level = level;
+    this.format = format;
+    this.write = write;
+  }
+
+  enabled(level: Severity): boolean {
+    return SEVERITIES[level] >= SEVERITIES[this.level];
+  }
+
+  log(level: Severity, msg: string, fields: LogFields = {}): void {
+    if (!this.enabled(level)) {
+      return;
+    }
+    const entry = { time: new Date().toISOString(), level, msg, ...fields };
+    this.write(`${formatLogEntry(this.format, entry)}\n`);
+  }
+
+  debug(msg: string, fields?: LogFields): void {
+    this.log('debug', msg, fields);
+  }
+
+  info(msg: string, fields?: LogFields): void {
+    this.log('info', msg, fields);
+  }
+
+  warn(msg: string, fields?: LogFields): void {
+    this.log('warn', msg, fields);
+  }
+
+  error(msg: string, fields?: LogFields): void {
+    this.log('error', msg, fields);
+  }
+}
+
+// Lines are written synchronously so the last ones before a crash or process.exit survive
+function createWriter(): (line: string) => void {
+  const file = getLogFile();
+  if (file) {
+    try {
+      const fd = fs.openSync(file, 'a');
+      return line => fs.writeSync(fd, line);
+    } catch (error) {
+      process.stderr.write(`Cannot open PCS_LOG_FILE ${file}, logging to stderr: ${error}\n`);
+    }
+  }
+  return line => process.stderr.write(line);
+}
+
+export const logger = new Logger(getLogLevel(), getLogFormat(), createWriter());
+
+// One line per tool call or HTTP browser operation, failures at warn with their error code
+export function logOperation(
+  tool: string,
+  startedAt: number,
+  sessionId: string | null,
+  failure: { code: string; message?: string } | null
+): void {
+  const fields = { tool, sessionId: sessionId ?? undefined, durationMs: Date.now() - startedAt };
+  if (failure) {
+    logger.warn('operation failed', { ...fields, code: failure.code, error: failure.message });
+  } else {
+    logger.info('operation', fields);
+  }
+}
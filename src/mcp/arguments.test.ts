@@ -0,0 +1,60 @@
+import { describe, expect, it } from 'vitest';
+import { z } from 'zod';
+import { invalidArguments, toArgumentIssue } from './arguments.js';
+
+describe('Tool arguments', () => {
+  const schema = z.object({
+    selector: z.string(),
+    format: z.enum(['png', 'jpeg']).optional(),
+    quality: z.number().int().max(100).optional(),
+    clip: z.object({ x: z.number() }).optional()
+  });
+
+  const issuesOf = (args: unknown) => {
+    const parsed = schema.safeParse(args);
+    if (parsed.success) {
+      throw new Error('expected the arguments to be rejected');
+    }
+    return parsed.error.issues.map(toArgumentIssue);
+  };
+
+  it('should name missing fields and wrong types', () => {
+    expect(issuesOf({ clip: { x: '1' } })).toEqual([
+      { field: 'selector', problem: 'missing', message: "field 'selector' is required" },
+      { field: 'clip.x', problem: 'type', message: "field 'clip.x' must be a number, got string" }
+    ]);
+    expect(issuesOf(null)).toEqual([
+      {
+        field: 'arguments',
+        problem: 'type',
+        message: "field 'arguments' must be an object, got null"
+      }
+    ]);
+  });
+
+  it('should list the allowed values and ranges', () => {
+    expect(issuesOf({ selector: 'img', format: 'gif', quality: 101 })).toEqual([
+      {
+        field: 'format',
+        problem: 'enum',
+        message: `field 'format' must be one of: png, jpeg, got "gif"`
+      },
+      {
+        field: 'quality',
+        problem: 'range',
+        message: "field 'quality' is out of range: Number must be less than or equal to 100"
+      }
+    ]);
+  });
+
+  it('should report every field as an INVALID_ARGUMENT error', () => {
+    const parsed = schema.safeParse({ format: 'gif' });
+    const error = invalidArguments('browser_screenshot', parsed.error!);
+    expect(error.code).toBe('INVALID_ARGUMENT');
+    expect(error.message).toBe(
+      "Invalid arguments for browser_screenshot: field 'selector' is required; " +
+        `field 'format' must be one of: png, jpeg, got "gif"`
+    );
+    expect(error.details?.['fields']).toHaveLength(2);
+  });
+});
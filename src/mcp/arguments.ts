@@ -0,0 +1,59 @@
+import type { z } from 'zod';
+import type { ArgumentIssue, ToolError } from '../types/index.js';
+
+function withArticle(type: string): string {
+  return /^[aeiou]/.test(type) ? `an ${type}` : `a ${type}`;
+}
+
+export function toArgumentIssue(issue: z.ZodIssue): ArgumentIssue {
+  const field = issue.path.join('.') || 'arguments';
+  const named = `field '${field}'`;
+  switch (issue.code) {
+    case 'invalid_type':
+      if (issue.received === 'undefined') {
+        return { field, problem: 'missing', message: `${named} is required` };
+      }
+      return {
+        field,
+        problem: 'type',
+        message: `${named} must be ${withArticle(issue.expected)}, got ${issue.received}`
+      };
+    case 'invalid_enum_value':
+      return {
+        field,
+        problem: 'enum',
+        message:
+          `${named} must be one of: ${issue.options.join(', ')}, ` +
+          `got ${JSON.stringify(issue.received)}`
+      };
+    case 'invalid_literal':
+      return {
+        field,
+        problem: 'enum',
+        message: `${named} must be ${JSON.stringify(issue.expected)}`
+      };
+    case 'too_small':
+    case 'too_big':
+    case 'not_multiple_of':
+      return { field, problem: 'range', message: `${named} is out of range: ${issue.message}` };
+    case 'unrecognized_keys':
+      return {
+        field,
+        problem: 'invalid',
+        message: `${named} has unknown keys: ${issue.keys.join(', ')}`
+      };
+    default:
+      return { field, problem: 'invalid', message: `${named} is invalid: ${issue.message}` };
+  }
+}
+
+// Every argument that does not fit the schema of the tool, so a caller can fix them in one go
+// instead of hitting a confusing failure deep inside Puppeteer
+export function invalidArguments(tool: string, error: z.ZodError): ToolError {
+  const fields = error.issues.map(toArgumentIssue);
+  return {
+    code: 'INVALID_ARGUMENT',
+    message: `Invalid arguments for ${tool}: ${fields.map(issue => issue.message).join('; ')}`,
+    details: { fields }
+  };
+}
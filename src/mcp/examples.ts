@@ -0,0 +1,127 @@
+export interface ToolExample {
+  description: string;
+  args: Record<string, unknown>;
+}
+
+// Calls browser_describe_tool shows along with the schema of a tool. Tools without examples
+// are described by their schema alone.
+export const TOOL_EXAMPLES: Record<string, ToolExample[]> = {
+  browser_open_tab: [
+    { description: 'Open a headless tab', args: { url: 'https://example.com' } },
+    {
+      description: 'Open a visible tab to watch what happens',
+      args: { url: 'https://example.com', headless: false }
+    }
+  ],
+  browser_create_session: [
+    {
+      description: 'Start an isolated session that skips images and fonts',
+      args: { url: 'https://example.com', isolated: true, blockResourceTypes: ['image', 'font'] }
+    }
+  ],
+  browser_navigate: [
+    {
+      description: 'Load a page and wait until the network is quiet',
+      args: { sessionId: 'SESSION_ID', url: 'https://example.com/login', waitUntil: 'networkidle2' }
+    },
+    {
+      description: 'Open an image that checks it was linked from the gallery',
+      args: {
+        tabId: 'TAB_ID',
+        url: 'https://cdn.example.com/photo.jpg',
+        referer: 'https://example.com/gallery'
+      }
+    }
+  ],
+  browser_click: [
+    {
+      description: 'Click a button by CSS selector',
+      args: { tabId: 'TAB_ID', selector: '#submit' }
+    },
+    {
+      description: 'Click the second link with some text, found by XPath',
+      args: { tabId: 'TAB_ID', xpath: "//a[contains(., 'Next')]", index: 1 }
+    }
+  ],
+  browser_type: [
+    {
+      description: 'Replace the text of a search box and press Enter',
+      args: {
+        tabId: 'TAB_ID',
+        selector: 'input[name=q]',
+        text: 'puppeteer',
+        clear: true,
+        afterKey: 'Enter'
+      }
+    }
+  ],
+  browser_fill_form: [
+    {
+      description: 'Set a field at once',
+      args: { tabId: 'TAB_ID', selector: '#email', value: 'me@example.com' }
+    }
+  ],
+  browser_wait_for_selector: [
+    {
+      description: 'Wait up to 10 seconds for a dialog to show',
+      args: { tabId: 'TAB_ID', selector: '[role=dialog]', visible: true, timeout: 10000 }
+    }
+  ],
+  browser_get_text: [
+    {
+      description: 'Read the main content of an article',
+      args: { tabId: 'TAB_ID', mode: 'readability' }
+    }
+  ],
+  browser_evaluate: [
+    {
+      description: 'Count the elements matching a selector passed as an argument',
+      args: {
+        tabId: 'TAB_ID',
+        functionBody: 'const [selector] = args; return document.querySelectorAll(selector).length',
+        args: ['li']
+      }
+    }
+  ],
+  browser_screenshot: [
+    { description: 'Capture the whole page', args: { tabId: 'TAB_ID', fullPage: true } },
+    {
+      description: 'Capture a component with some room around it on a known background',
+      args: { tabId: 'TAB_ID', selector: '.card', padding: 16, background: '#ffffff' }
+    }
+  ],
+  browser_export_cookies: [
+    {
+      description: 'Write a cookies.txt file for curl -b or yt-dlp --cookies',
+      args: { sessionId: 'SESSION_ID', format: 'netscape', path: 'cookies.txt' }
+    }
+  ],
+  browser_add_init_script: [
+    {
+      description: 'Turn on a feature flag before the app reads it on every page load',
+      args: {
+        sessionId: 'SESSION_ID',
+        name: 'feature-flags',
+        script: 'window.__FLAGS__ = { newCheckout: true };'
+      }
+    }
+  ],
+  browser_run_sequence: [
+    {
+      description: 'Log in and check where the page ended up in one call',
+      args: {
+        sessionId: 'SESSION_ID',
+        steps: [
+          { tool: 'browser_navigate', args: { url: 'https://example.com/login' } },
+          { tool: 'browser_type', args: { selector: '#user', text: 'me' } },
+          { tool: 'browser_click', args: { selector: '#submit', waitForNavigation: true } },
+          { tool: 'browser_get_url' }
+        ]
+      }
+    }
+  ],
+  browser_describe_tool: [
+    { description: 'List every tool', args: {} },
+    { description: 'Describe one tool', args: { name: 'browser_click' } }
+  ]
+};
@@ -0,0 +1,67 @@
+import { describe, expect, it } from 'vitest';
+import type { ServerStatus } from '../types/index.js';
+import { Counter, Histogram, recordOperation, renderMetrics } from './index.js';
+
+const status: ServerStatus = {
+  healthy: true,
+  version: '1.0.0',
+  uptimeMs: 1500,
+  browsers: [
+    { headless: true, state: 'connected', pid: 42, memoryBytes: 2048 },
+    { headless: false, state: 'idle', pid: null, memoryBytes: null }
+  ],
+  queue: { maxConcurrency: 2, queueTimeout: 1000, running: 1, queued: 3 },
+  pagePool: { size: 0, idle: 0, hits: 0, misses: 0 },
+  tabs: 4,
+  sessions: 2,
+  sessionTtl: 1800000,
+  idleSessions: []
+};
+
+describe('Metrics', () => {
+  it('should render counters per label set', () => {
+    const counter = new Counter('pcs_test_total', 'Test counter');
+    counter.inc({ tool: 'browser_click' });
+    counter.inc({ tool: 'browser_click' }, 2);
+    counter.inc({ tool: 'say "hi"\n' });
+
+    expect(counter.render()).toEqual([
+      '# HELP pcs_test_total Test counter',
+      '# TYPE pcs_test_total counter',
+      'pcs_test_total{tool="browser_click"} 3',
+      'pcs_test_total{tool="say \\"hi\\"\\n"} 1'
+    ]);
+  });
+
+  it('should render cumulative histogram buckets', () => {
+    const histogram = new Histogram('pcs_test_seconds', 'Test histogram', [0.1, 1]);
+    histogram.observe({ tool: 'a' }, 0.05);
+    histogram.observe({ tool: 'a' }, 0.5);
+    histogram.observe({ tool: 'a' }, 5);
+
+    expect(histogram.render().slice(2)).toEqual([
+      'pcs_test_seconds_bucket{tool="a",le="0.1"} 1',
+      'pcs_test_seconds_bucket{tool="a",le="1"} 2',
+      'pcs_test_seconds_bucket{tool="a",le="+Inf"} 3',
+      'pcs_test_seconds_sum{tool="a"} 5.55',
+      'pcs_test_seconds_count{tool="a"} 3'
+    ]);
+  });
+
+  it('should expose operations, errors and the server status', () => {
+    const route = 'POST /api/tabs/click/:tabId';
+    recordOperation('browser_navigate', Date.now(), null);
+    recordOperation(route, Date.now(), 'SELECTOR_NOT_FOUND');
+    const text = renderMetrics(status);
+
+    expect(text).toContain('pcs_tool_calls_total{tool="browser_navigate",status="ok"} 1');
+    expect(text).toContain(`pcs_tool_calls_total{tool="${route}",status="error"} 1`);
+    expect(text).toContain('pcs_errors_total{code="SELECTOR_NOT_FOUND"} 1');
+    expect(text).toContain('pcs_tool_call_duration_seconds_count{tool="browser_navigate"} 1');
+    expect(text).toContain('pcs_queue_waiting 3');
+    expect(text).toContain('pcs_browser_up{browser="visible"} 0');
+    expect(text).toContain('pcs_browser_memory_bytes{browser="headless"} 2048');
+    expect(text).not.toContain('pcs_browser_memory_bytes{browser="visible"}');
+    expect(text).toContain('pcs_build_info{version="1.0.0"} 1');
+  });
+});
@@ -0,0 +1,172 @@
+import type { BrowserStatus, ErrorCode, ServerStatus } from '../types/index.js';
+
+// Hand-rolled Prometheus text exposition, the handful of series pcs exports doesn't warrant a
+// client library. Labels only ever take tool names, route templates and error codes, never
+// selectors, URLs or IDs, so the number of series stays bounded.
+
+type Labels = Record<string, string>;
+
+const DURATION_BUCKETS = [0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60];
+
+function escapeLabel(value: string): string {
+  return value.replace(/\\/g, '\\\\').replace(/"/g, '\\"').replace(/\n/g, '\\n');
+}
+
+function formatLabels(labels: Labels): string {
+  const pairs = Object.entries(labels).map(([name, value]) => `${name}="${escapeLabel(value)}"`);
+  return pairs.length ? `{${pairs.join(',')}}` : '';
+}
+
+function header(name: string, help: string, type: string): string[] {
+  return [`# HELP ${name} ${help}`, `# TYPE ${name} ${type}`];
+}
+
+export class Counter {
+  readonly name: string;
+  readonly help: string;
+  private values: Map<string, number> = new Map();
+
+  constructor(name: string, help: string) {
+    this.name = name;
+    this.help = help;
+  }
+
+  inc(labels: Labels = {}, value = 1): void {
+    const key = formatLabels(labels);
+    this.values.set(key, (this.values.get(key) ?? 0) + value);
+  }
+
+  render(): string[] {
+    const lines = header(this.name, this.help, 'counter');
+    for (const [key, value] of this.values) {
+      lines.push(`${this.name}${key} ${value}`);
+    }
+    return lines;
+  }
+}
+
+export class Histogram {
+  readonly name: string;
+  readonly help: string;
+  private readonly buckets: number[];
+  private values: Map<string, { labels: Labels; counts: number[]; sum: number; count: number }> =
+    new Map();
+
+  constructor(name: string, help: string, buckets = DURATION_BUCKETS) {
+    this.name = name;
+    this.help = help;
+    this.buckets = buckets;
+  }
+
+  observe(labels: Labels, value: number): void {
+    const key = formatLabels(labels);
+    const series = this.values.get(key) ?? {
+      labels,
+      counts: this.buckets.map(() => 0),
+      sum: 0,
+      count: 0
+    };
+    this.buckets.forEach((bound, index) => {
+      if (value <= bound) series.counts[index]!++;
+    });
+    series.sum += value;
+    series.count++;
+    this.values.set(key, series);
+  }
+
+  render(): string[] {
+    const lines = header(this.name, this.help, 'histogram');
+    for (const { labels, counts, sum, count } of this.values.values()) {
+      this.buckets.forEach((bound, index) => {
+        const bucket = formatLabels({ ...labels, le: String(bound) });
+        lines.push(`${this.name}_bucket${bucket} ${counts[index]}`);
+      });
+      lines.push(`${this.name}_bucket${formatLabels({ ...labels, le: '+Inf' })} ${count}`);
+      lines.push(`${this.name}_sum${formatLabels(labels)} ${sum}`);
+      lines.push(`${this.name}_count${formatLabels(labels)} ${count}`);
+    }
+    return lines;
+  }
+}
+
+function gauge(name: string, help: string, series: Array<[Labels, number | null]>): string[] {
+  const lines = header(name, help, 'gauge');
+  for (const [labels, value] of series) {
+    if (value !== null) lines.push(`${name}${formatLabels(labels)} ${value}`);
+  }
+  return lines;
+}
+
+const toolCalls = new Counter(
+  'pcs_tool_calls_total',
+  'MCP tool calls and HTTP browser operations by outcome'
+);
+const toolDuration = new Histogram(
+  'pcs_tool_call_duration_seconds',
+  'Duration of MCP tool calls and HTTP browser operations, including the wait in the queue'
+);
+const toolErrors = new Counter('pcs_errors_total', 'Failed operations by error code');
+export const browserLaunches = new Counter(
+  'pcs_browser_launches_total',
+  'Browsers launched or attached, including relaunches after a crash'
+);
+export const browserCrashes = new Counter(
+  'pcs_browser_crashes_total',
+  'Browsers that crashed or disconnected without being closed'
+);
+export const sessionsExpired = new Counter(
+  'pcs_sessions_expired_total',
+  'Sessions closed after staying idle for longer than PCS_SESSION_TTL'
+);
+
+// tool is an MCP tool name or a route template such as "POST /api/tabs/click/:tabId"
+export function recordOperation(tool: string, startedAt: number, code: ErrorCode | null): void {
+  toolCalls.inc({ tool, status: code ? 'error' : 'ok' });
+  toolDuration.observe({ tool }, (Date.now() - startedAt) / 1000);
+  if (code) {
+    toolErrors.inc({ code });
+  }
+}
+
+function perBrowser(
+  status: ServerStatus,
+  value: (browser: BrowserStatus) => number | null
+): Array<[Labels, number | null]> {
+  return status.browsers.map(browser => [
+    { browser: browser.headless ? 'headless' : 'visible' },
+    value(browser)
+  ]);
+}
+
+export function renderMetrics(status: ServerStatus): string {
+  return [
+    ...toolCalls.render(),
+    ...toolDuration.render(),
+    ...toolErrors.render(),
+    ...browserLaunches.render(),
+    ...browserCrashes.render(),
+    ...sessionsExpired.render(),
+    ...gauge('pcs_sessions', 'Open sessions', [[{}, status.sessions]]),
+    ...gauge('pcs_tabs', 'Open tabs and session pages', [[{}, status.tabs]]),
+    ...gauge('pcs_queue_running', 'Browser operations running', [[{}, status.queue.running]]),
+    ...gauge('pcs_queue_waiting', 'Browser operations waiting for a slot', [
+      [{}, status.queue.queued]
+    ]),
+    ...gauge(
+      'pcs_browser_up',
+      'Whether the browser is connected',
+      perBrowser(status, browser => (browser.state === 'connected' ? 1 : 0))
+    ),
+    ...gauge(
+      'pcs_browser_memory_bytes',
+      'Resident memory of the browser and its renderers',
+      perBrowser(status, browser => browser.memoryBytes)
+    ),
+    ...gauge('pcs_process_resident_memory_bytes', 'Resident memory of the pcs process', [
+      [{}, process.memoryUsage.rss()]
+    ]),
+    ...gauge('pcs_uptime_seconds', 'Seconds since pcs started', [[{}, status.uptimeMs / 1000]]),
+    ...gauge('pcs_build_info', 'Version of pcs', [[{ version: status.version }, 1]]),
+    ''
+  ].join('\n');
+}
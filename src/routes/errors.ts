@@ -0,0 +1,24 @@
+import type { Response } from 'express';
+import { toToolError } from '../browser/errors.js';
+import type { ErrorCode } from '../types/index.js';
+
+const STATUS_CODES: Partial<Record<ErrorCode, number>> = {
+  INVALID_ARGUMENT: 400,
+  TAB_NOT_FOUND: 404,
+  SESSION_NOT_FOUND: 404,
+  SESSION_CRASHED: 410,
+  PROFILE_LOCKED: 409,
+  SHUTTING_DOWN: 503
+};
+
+// Sends a failed operation with the same code and details the MCP tools return
+export function sendError(res: Response, error: unknown) {
+  const { code, message, details } = toToolError(error);
+  res.locals['error'] = { code, message }; // logged and counted in pcs_errors_total
+  return res.status(STATUS_CODES[code] ?? 500).json({
+    success: false,
+    error: message,
+    code,
+    ...(details && { details })
+  });
+}
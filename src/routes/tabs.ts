@@ -1,29 +1,140 @@
 import { type Request, type Response, Router } from 'express';
 import { BrowserManagerSingleton } from '../browser/BrowserManager.js';
+import { validateTextRequest } from '../browser/content.js';
+import { resolveDevice } from '../browser/devices.js';
 import {
+  canonicalLocale,
+  isValidTimezone,
+  NO_MEDIA_EMULATION,
+  resolveMediaEmulation,
+  resolveNetworkConditions,
+  validateCpuThrottlingRate,
+  validateGeolocation
+} from '../browser/emulation.js';
+import { resolveScrollMode, validateDrag } from '../browser/mouse.js';
+import { toNavigationError, WAIT_UNTIL_VALUES, type WaitUntil } from '../browser/navigation.js';
+import { resolveIdleOptions } from '../browser/network.js';
+import { toCriteria } from '../browser/select.js';
+import { toElementIndex, toSelector } from '../browser/selectors.js';
+import { decodeBlob } from '../browser/upload.js';
+import { resolveCompareOptions } from '../browser/visual.js';
+import { isRawCdpEnabled } from '../config/index.js';
+import {
+  type AccessibilitySnapshotResult,
+  AFTER_KEYS,
   type ApiResponse,
+  type AttributeResult,
+  type BoundingBoxResult,
+  type CapturedResponse,
+  type CaptureResponseBodyRequest,
+  type CdpCommandResult,
   type ClickRequest,
+  type ClipboardResult,
+  type CompareScreenshotRequest,
+  type CompareScreenshotResult,
+  type CountElementsResult,
+  type CoverageResult,
+  type CpuThrottlingResult,
+  type DragRequest,
+  type DragResult,
+  type ElementExistsResult,
+  type ElementRequest,
+  type EmulateDeviceRequest,
+  type EmulateMediaRequest,
+  type EmulatedDevice,
   type EvalRequest,
+  type EvaluateRequest,
   type FillRequest,
+  type FixedElementMode,
   type FocusRequest,
+  type FrameInfo,
+  type GeolocationRequest,
+  type GeolocationResult,
+  type GetTextRequest,
   type HoverRequest,
+  type KeyboardRequest,
+  type KeyboardResult,
+  type LaunchSettings,
+  type LocaleResult,
+  type MediaEmulation,
+  type MetricsResult,
   type NavigateRequest,
+  type NavigationResult,
+  type NetworkConditionsRequest,
+  type NetworkConditionsResult,
+  type NetworkIdleResult,
   type OpenTabRequest,
+  type PdfRequest,
+  type PinchRequest,
+  type PropertyResult,
   type ReloadRequest,
+  type ScreenshotRequest,
+  type ScrollRequest,
+  type ScrollResult,
+  type SelectorType,
   type SelectRequest,
-  TabNotFoundError,
+  type SelectResult,
+  type SetViewportRequest,
+  type SnapshotRequest,
+  type SnapshotResult,
+  type StartCoverageRequest,
+  STORAGE_TYPES,
+  type StorageResult,
+  type StorageType,
+  type SwipeRequest,
+  type TapRequest,
+  type TextMode,
+  type TextResult,
+  type TouchResult,
+  type TypeRequest,
+  type UploadFileRequest,
+  type UploadResult,
+  type Viewport,
+  type VisionDeficiency,
   type WaitForFunctionRequest,
+  type WaitForFunctionResult,
   type WaitForNavigationRequest,
-  type WaitForSelectorRequest
+  type WaitForNetworkIdleRequest,
+  type WaitForRequestRequest,
+  type WaitForResponseRequest,
+  type WaitForSelectorRequest,
+  type WaitForSelectorResult,
+  type WaitForStableRequest,
+  type WaitForStableResult,
+  type WaitForTrafficResult
 } from '../types/index.js';
+import { sendError } from './errors.js';
 
 const router = Router();
 
 // Initialize browser manager
 let browserManager: ReturnType<typeof BrowserManagerSingleton>;
 
-export function initializeTabsRoutes(chromePath?: string | null): void {
-  browserManager = BrowserManagerSingleton(chromePath);
+// Storage routes default to localStorage
+function parseStorageType(value: unknown): StorageType | null {
+  const type = value ?? 'local';
+  return STORAGE_TYPES.includes(type as StorageType) ? (type as StorageType) : null;
+}
+
+// Element routes read the element from the query string like /text does
+function parseElementRequest(query: Request['query']): ElementRequest {
+  const { selector, xpath, selectorType, index, frame } = query;
+  return {
+    selector: typeof selector === 'string' ? selector : undefined,
+    xpath: typeof xpath === 'string' ? xpath : undefined,
+    selectorType: typeof selectorType === 'string' ? (selectorType as SelectorType) : undefined,
+    index: typeof index === 'string' ? Number(index) : undefined,
+    frame: typeof frame === 'string' ? frame : undefined
+  };
+}
+
+export function initializeTabsRoutes(
+  chromePath?: string | null,
+  cdpEndpoint?: string | null,
+  proxy?: string | null,
+  launch?: LaunchSettings
+): void {
+  browserManager = BrowserManagerSingleton(chromePath, cdpEndpoint, proxy, launch);
 }
 
 /**
@@ -81,10 +192,7 @@ router.post('/open', async (req: Request, res: Response) => {
 
     return res.json(response);
   } catch (error) {
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return sendError(res, error);
   }
 });
 
@@ -129,10 +237,7 @@ router.get('/list', async (_req: Request, res: Response) => {
 
     return res.json(response);
   } catch (error) {
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return sendError(res, error);
   }
 });
 
@@ -157,9 +262,82 @@ router.get('/list', async (_req: Request, res: Response) => {
  *             properties:
  *               url:
  *                 type: string
+ *               waitUntil:
+ *                 type: string
+ *                 enum: [load, domcontentloaded, networkidle0, networkidle2]
+ *                 description: When to consider navigation complete (default networkidle2)
+ *               timeout:
+ *                 type: number
+ *                 description: Maximum navigation time in milliseconds
+ *               returnPartialOnTimeout:
+ *                 type: boolean
+ *                 description: Report a timeout as a partial load instead of failing
+ *                 default: false
+ *               referer:
+ *                 type: string
+ *                 description: Referrer of this navigation alone
+ *               headers:
+ *                 type: object
+ *                 additionalProperties:
+ *                   type: string
+ *                 description: >
+ *                   Headers sent with the navigation request only, never with its
+ *                   subresources or later requests
  *     responses:
  *       200:
- *         description: Navigation successful
+ *         description: Navigation finished (loaded, download, or error with net error code)
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 error:
+ *                   type: string
+ *                   description: error outcome only, e.g. "net::ERR_NAME_NOT_RESOLVED at https://..."
+ *                 code:
+ *                   type: string
+ *                   description: error outcome only, NAVIGATION_FAILED
+ *                 details:
+ *                   type: object
+ *                   description: error outcome only, netError, netErrorCategory, url and status
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     outcome:
+ *                       type: string
+ *                       enum: [loaded, download, error]
+ *                     url:
+ *                       type: string
+ *                     status:
+ *                       type: number
+ *                       nullable: true
+ *                     headers:
+ *                       type: object
+ *                       additionalProperties:
+ *                         type: string
+ *                     filename:
+ *                       type: string
+ *                       nullable: true
+ *                     errorCode:
+ *                       type: string
+ *                     errorCategory:
+ *                       type: string
+ *                       enum: [dns, connection, certificate, blocked, aborted, response, url, other]
+ *                     partial:
+ *                       type: boolean
+ *                       description: Set when the navigation timed out with returnPartialOnTimeout
+ *                     readyState:
+ *                       type: string
+ *                       nullable: true
+ *                     warnings:
+ *                       type: array
+ *                       items:
+ *                         type: string
+ *                       description: Navigation headers that were left out
+ *       400:
+ *         description: Invalid request
  */
 router.post('/goto/:tabId', async (req: Request, res: Response) => {
   try {
@@ -180,21 +358,36 @@ router.post('/goto/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    await browserManager.navigateTab(tabId, request.url);
-
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
+    if (request.waitUntil && !WAIT_UNTIL_VALUES.includes(request.waitUntil as WaitUntil)) {
+      return res.status(400).json({
         success: false,
-        error: error.message
+        error: `waitUntil must be one of: ${WAIT_UNTIL_VALUES.join(', ')}`
       });
     }
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
+    const result = await browserManager.navigateTab(tabId, request.url, {
+      waitUntil: request.waitUntil as WaitUntil | undefined,
+      timeout: request.timeout,
+      returnPartialOnTimeout: request.returnPartialOnTimeout,
+      referer: request.referer,
+      headers: request.headers
     });
+
+    // Answered like a failed request, with the result along for compatibility
+    if (result.outcome === 'error') {
+      const { code, message, details } = toNavigationError(result);
+      res.locals['error'] = { code, message };
+      return res.json({ success: false, error: message, code, details, data: result });
+    }
+
+    const response: ApiResponse<NavigationResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
@@ -214,6 +407,87 @@ router.post('/goto/:tabId', async (req: Request, res: Response) => {
  *         name: fullPage
  *         schema:
  *           type: boolean
+ *       - in: query
+ *         name: fixedElements
+ *         description: fullPage only, what to do with fixed and sticky elements
+ *         schema:
+ *           type: string
+ *           enum: [freeze, hide, keep]
+ *       - in: query
+ *         name: loadLazyContent
+ *         description: fullPage only, scroll through the page first (default true)
+ *         schema:
+ *           type: boolean
+ *       - in: query
+ *         name: selector
+ *         description: Selector of a single element to capture, CSS or XPath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: index
+ *         schema:
+ *           type: integer
+ *         description: Which match to capture, 0-based in document order, default 0
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *       - in: query
+ *         name: padding
+ *         description: element only, pixels around the element to include, up to the page edges
+ *         schema:
+ *           type: number
+ *       - in: query
+ *         name: clip
+ *         description: Region to capture as "x,y,width,height"
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: format
+ *         schema:
+ *           type: string
+ *           enum: [png, jpeg, webp]
+ *       - in: query
+ *         name: quality
+ *         description: 0-100, jpeg and webp only
+ *         schema:
+ *           type: integer
+ *       - in: query
+ *         name: omitBackground
+ *         schema:
+ *           type: boolean
+ *       - in: query
+ *         name: background
+ *         description: Hex color, e.g. "#ffffff", painted where the page has no background
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: encoding
+ *         schema:
+ *           type: string
+ *           enum: [base64, file]
+ *       - in: query
+ *         name: path
+ *         description: Output file when encoding is "file"
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: visionDeficiency
+ *         description: Vision deficiency to emulate for this capture only
+ *         schema:
+ *           type: string
+ *           enum: [none, blurredVision, reducedContrast, achromatopsia, deuteranopia, protanopia, tritanopia]
  *     responses:
  *       200:
  *         description: Screenshot taken successfully
@@ -230,6 +504,12 @@ router.post('/goto/:tabId', async (req: Request, res: Response) => {
  *                     screenshot:
  *                       type: string
  *                       format: base64
+ *                     path:
+ *                       type: string
+ *                     mimeType:
+ *                       type: string
+ *       400:
+ *         description: Invalid screenshot options
  */
 router.get('/screenshot/:tabId', async (req: Request, res: Response) => {
   try {
@@ -243,34 +523,93 @@ router.get('/screenshot/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    const screenshot = await browserManager.screenshotTab(tabId, fullPage);
+    const request: ScreenshotRequest = { fullPage, ...parseElementRequest(req.query) };
+    const { clip, format, quality, encoding, path, visionDeficiency } = req.query;
+    const { fixedElements, padding, background } = req.query;
+
+    if (padding !== undefined) request.padding = Number(padding);
+    if (typeof background === 'string') request.background = background;
+    if (typeof fixedElements === 'string') {
+      request.fixedElements = fixedElements as FixedElementMode;
+    }
+    if (req.query['loadLazyContent'] !== undefined) {
+      request.loadLazyContent = req.query['loadLazyContent'] === 'true';
+    }
+    if (req.query['omitBackground'] !== undefined) {
+      request.omitBackground = req.query['omitBackground'] === 'true';
+    }
+    if (typeof path === 'string') request.path = path;
+    if (typeof visionDeficiency === 'string') {
+      request.visionDeficiency = visionDeficiency as VisionDeficiency;
+    }
+
+    if (typeof clip === 'string') {
+      const [x, y, width, height] = clip.split(',').map(Number);
+      if ([x, y, width, height].some(n => n === undefined || !Number.isFinite(n))) {
+        return res.status(400).json({
+          success: false,
+          error: 'clip must be "x,y,width,height"'
+        });
+      }
+      request.clip = { x: x!, y: y!, width: width!, height: height! };
+    }
+
+    if (format !== undefined) {
+      if (format !== 'png' && format !== 'jpeg' && format !== 'webp') {
+        return res.status(400).json({
+          success: false,
+          error: 'format must be one of: png, jpeg, webp'
+        });
+      }
+      request.format = format;
+    }
+
+    if (quality !== undefined) {
+      const value = Number(quality);
+      if (!Number.isInteger(value) || value < 0 || value > 100) {
+        return res.status(400).json({
+          success: false,
+          error: 'quality must be an integer between 0 and 100'
+        });
+      }
+      request.quality = value;
+    }
+
+    if (encoding !== undefined) {
+      if (encoding !== 'base64' && encoding !== 'file') {
+        return res.status(400).json({
+          success: false,
+          error: 'encoding must be one of: base64, file'
+        });
+      }
+      request.encoding = encoding;
+    }
+
+    const result = await browserManager.captureScreenshot(tabId, request);
 
-    const response: ApiResponse<{ screenshot: string }> = {
+    const response: ApiResponse<{ screenshot?: string; path?: string; mimeType: string }> = {
       success: true,
-      data: { screenshot }
+      data: {
+        mimeType: result.mimeType,
+        ...(result.data ? { screenshot: result.data } : {}),
+        ...(result.path ? { path: result.path } : {})
+      }
     };
 
     return res.json(response);
   } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
-
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/click/{tabId}:
+ * /api/tabs/compareScreenshot/{tabId}:
  *   post:
- *     summary: Click element in tab
+ *     summary: Diff a screenshot of the tab against a baseline PNG
+ *     description: >
+ *       Captures the viewport, the full page, an element or a clip as PNG and compares it pixel
+ *       by pixel with the baseline. Images of different sizes fail with sizeMismatch.
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -284,57 +623,117 @@ router.get('/screenshot/:tabId', async (req: Request, res: Response) => {
  *         application/json:
  *           schema:
  *             type: object
+ *             required:
+ *               - baseline
  *             properties:
+ *               baseline:
+ *                 type: string
+ *                 description: PNG file relative to the working directory, or base64 PNG data
+ *               fullPage:
+ *                 type: boolean
  *               selector:
  *                 type: string
- *               waitForNavigation:
+ *               clip:
+ *                 type: object
+ *                 properties:
+ *                   x:
+ *                     type: number
+ *                   y:
+ *                     type: number
+ *                   width:
+ *                     type: number
+ *                   height:
+ *                     type: number
+ *               threshold:
+ *                 type: number
+ *                 description: Per-pixel color difference from 0 to 1 (default 0.1)
+ *               ignoreAntialiasing:
  *                 type: boolean
+ *                 description: Do not count anti-aliased edges as differences (default true)
+ *               maxDiffPercentage:
+ *                 type: number
+ *                 description: Percentage of differing pixels that still passes (default 0)
+ *               diffImage:
+ *                 type: string
+ *                 enum: [none, base64, file]
+ *                 description: Return or write a PNG highlighting differences (default none)
+ *               diffPath:
+ *                 type: string
  *     responses:
  *       200:
- *         description: Click successful
+ *         description: Comparison result
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     passed:
+ *                       type: boolean
+ *                     sizeMismatch:
+ *                       type: boolean
+ *                     diffPixels:
+ *                       type: number
+ *                     antialiasedPixels:
+ *                       type: number
+ *                     diffPercentage:
+ *                       type: number
+ *                     diff:
+ *                       type: string
+ *                     diffPath:
+ *                       type: string
+ *       400:
+ *         description: Missing baseline or invalid options
+ *       404:
+ *         description: Tab not found
  */
-router.post('/click/:tabId', async (req: Request, res: Response) => {
+router.post('/compareScreenshot/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: ClickRequest = req.body;
+    const request: CompareScreenshotRequest = req.body ?? {};
 
-    if (!request.selector) {
+    if (!tabId) {
       return res.status(400).json({
         success: false,
-        error: 'Selector is required'
+        error: 'Tab ID is required'
       });
     }
 
-    if (!tabId) {
+    let validationError: string | null = null;
+    try {
+      resolveCompareOptions(request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
       return res.status(400).json({
         success: false,
-        error: 'Tab ID is required'
+        error: validationError
       });
     }
 
-    await browserManager.clickElement(tabId, request.selector, request.waitForNavigation);
+    const result = await browserManager.compareScreenshot(tabId, request);
 
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
+    const response: ApiResponse<CompareScreenshotResult> = {
+      success: true,
+      data: result
+    };
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/hover/{tabId}:
+ * /api/tabs/pdf/{tabId}:
  *   post:
- *     summary: Hover over element in tab
+ *     summary: Render tab to PDF
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -343,60 +742,110 @@ router.post('/click/:tabId', async (req: Request, res: Response) => {
  *         schema:
  *           type: string
  *     requestBody:
- *       required: true
  *       content:
  *         application/json:
  *           schema:
  *             type: object
  *             properties:
- *               selector:
+ *               format:
+ *                 type: string
+ *                 description: Paper format (Letter, Legal, Tabloid, Ledger, A0-A6)
+ *               landscape:
+ *                 type: boolean
+ *               margin:
+ *                 type: object
+ *                 properties:
+ *                   top:
+ *                     type: string
+ *                   right:
+ *                     type: string
+ *                   bottom:
+ *                     type: string
+ *                   left:
+ *                     type: string
+ *               printBackground:
+ *                 type: boolean
+ *               scale:
+ *                 type: number
+ *               headerTemplate:
+ *                 type: string
+ *               footerTemplate:
  *                 type: string
+ *               emulatePrintMedia:
+ *                 type: boolean
+ *                 description: Render with print media styles (default true)
+ *               encoding:
+ *                 type: string
+ *                 enum: [base64, file]
+ *               path:
+ *                 type: string
+ *                 description: Output file when encoding is "file"
  *     responses:
  *       200:
- *         description: Hover successful
+ *         description: PDF generated successfully
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     pdf:
+ *                       type: string
+ *                       format: base64
+ *                     path:
+ *                       type: string
+ *                     mimeType:
+ *                       type: string
  */
-router.post('/hover/:tabId', async (req: Request, res: Response) => {
+router.post('/pdf/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: HoverRequest = req.body;
+    const request: PdfRequest = req.body ?? {};
 
-    if (!request.selector) {
+    if (!tabId) {
       return res.status(400).json({
         success: false,
-        error: 'Selector is required'
+        error: 'Tab ID is required'
       });
     }
 
-    if (!tabId) {
+    if (
+      request.encoding !== undefined &&
+      request.encoding !== 'base64' &&
+      request.encoding !== 'file'
+    ) {
       return res.status(400).json({
         success: false,
-        error: 'Tab ID is required'
+        error: 'encoding must be one of: base64, file'
       });
     }
 
-    await browserManager.hoverElement(tabId, request.selector);
+    const result = await browserManager.generatePdf(tabId, request);
 
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
+    const response: ApiResponse<{ pdf?: string; path?: string; mimeType: string }> = {
+      success: true,
+      data: {
+        mimeType: result.mimeType,
+        ...(result.data ? { pdf: result.data } : {}),
+        ...(result.path ? { path: result.path } : {})
+      }
+    };
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/fill/{tabId}:
+ * /api/tabs/snapshot/{tabId}:
  *   post:
- *     summary: Fill form field in tab
+ *     summary: Save the tab as a self-contained MHTML archive
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -405,62 +854,81 @@ router.post('/hover/:tabId', async (req: Request, res: Response) => {
  *         schema:
  *           type: string
  *     requestBody:
- *       required: true
  *       content:
  *         application/json:
  *           schema:
  *             type: object
  *             properties:
- *               selector:
+ *               encoding:
  *                 type: string
- *               value:
+ *                 enum: [base64, file]
+ *               path:
  *                 type: string
+ *                 description: Output file when encoding is "file"
  *     responses:
  *       200:
- *         description: Fill successful
+ *         description: Snapshot saved
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     mimeType:
+ *                       type: string
+ *                     size:
+ *                       type: integer
+ *                     data:
+ *                       type: string
+ *                       format: base64
+ *                     path:
+ *                       type: string
  */
-router.post('/fill/:tabId', async (req: Request, res: Response) => {
+router.post('/snapshot/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: FillRequest = req.body;
+    const request: SnapshotRequest = req.body ?? {};
 
-    if (!request.selector || !request.value) {
+    if (!tabId) {
       return res.status(400).json({
         success: false,
-        error: 'Selector and value are required'
+        error: 'Tab ID is required'
       });
     }
 
-    if (!tabId) {
+    if (
+      request.encoding !== undefined &&
+      request.encoding !== 'base64' &&
+      request.encoding !== 'file'
+    ) {
       return res.status(400).json({
         success: false,
-        error: 'Tab ID is required'
+        error: 'encoding must be one of: base64, file'
       });
     }
 
-    await browserManager.fillField(tabId, request.selector, request.value);
+    const result = await browserManager.saveSnapshot(tabId, request);
 
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
+    const response: ApiResponse<SnapshotResult> = {
+      success: true,
+      data: result
+    };
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/select/{tabId}:
+ * /api/tabs/click/{tabId}:
  *   post:
- *     summary: Select dropdown option in tab
+ *     summary: Click element in tab
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -475,23 +943,70 @@ router.post('/fill/:tabId', async (req: Request, res: Response) => {
  *           schema:
  *             type: object
  *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
  *               selector:
  *                 type: string
- *               value:
+ *               xpath:
+ *                 type: string
+ *                 description: XPath expression to use instead of selector
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *               waitForNavigation:
+ *                 type: boolean
+ *               waitForVisible:
+ *                 type: boolean
+ *               waitForEnabled:
+ *                 type: boolean
+ *               button:
  *                 type: string
+ *                 enum: [left, right, middle]
+ *               clickCount:
+ *                 type: integer
+ *               timeout:
+ *                 type: number
+ *                 description: Maximum time to wait and retry stale elements in milliseconds
  *     responses:
  *       200:
- *         description: Select successful
+ *         description: Click successful
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     boundingBox:
+ *                       type: object
+ *                       nullable: true
+ *                       properties:
+ *                         x:
+ *                           type: number
+ *                         y:
+ *                           type: number
+ *                         width:
+ *                           type: number
+ *                         height:
+ *                           type: number
  */
-router.post('/select/:tabId', async (req: Request, res: Response) => {
+router.post('/click/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: SelectRequest = req.body;
+    const request: ClickRequest = req.body;
 
-    if (!request.selector || !request.value) {
+    if (!request.selector && !request.xpath) {
       return res.status(400).json({
         success: false,
-        error: 'Selector and value are required'
+        error: 'Selector is required'
       });
     }
 
@@ -502,29 +1017,118 @@ router.post('/select/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    await browserManager.selectOption(tabId, request.selector, request.value);
+    const boundingBox = await browserManager.clickElement(
+      tabId,
+      toSelector(request),
+      request.waitForNavigation,
+      {
+        button: request.button,
+        clickCount: request.clickCount,
+        waitForVisible: request.waitForVisible,
+        waitForEnabled: request.waitForEnabled,
+        timeout: request.timeout,
+        frame: request.frame,
+        index: toElementIndex(request)
+      }
+    );
 
-    return res.json({ success: true });
+    return res.json({ success: true, data: { boundingBox } });
   } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/hover/{tabId}:
+ *   post:
+ *     summary: Hover over element in tab
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *     responses:
+ *       200:
+ *         description: Hover successful
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     boundingBox:
+ *                       type: object
+ *                       nullable: true
+ */
+router.post('/hover/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: HoverRequest = req.body;
+
+    if (!request.selector && !request.xpath) {
+      return res.status(400).json({
         success: false,
-        error: error.message
+        error: 'Selector is required'
       });
     }
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const boundingBox = await browserManager.hoverElement(
+      tabId,
+      toSelector(request),
+      request.frame,
+      toElementIndex(request)
+    );
+
+    return res.json({ success: true, data: { boundingBox } });
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/eval/{tabId}:
+ * /api/tabs/scroll/{tabId}:
  *   post:
- *     summary: Execute JavaScript in tab
+ *     summary: Scroll by pixels, to an element, or to the bottom of the page
+ *     description: >
+ *       Give exactly one of selector or xpath, x and y, or toBottom. Scrolling to the bottom
+ *       waits for lazy-loaded content after each jump and stops once the page height holds
+ *       still or maxIterations is reached.
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -539,11 +1143,35 @@ router.post('/select/:tabId', async (req: Request, res: Response) => {
  *           schema:
  *             type: object
  *             properties:
- *               script:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *               selectorType:
  *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *               x:
+ *                 type: number
+ *               y:
+ *                 type: number
+ *               toBottom:
+ *                 type: boolean
+ *               maxIterations:
+ *                 type: integer
+ *                 default: 20
+ *               idleTimeout:
+ *                 type: number
+ *                 default: 1000
  *     responses:
  *       200:
- *         description: Script executed successfully
+ *         description: Scroll position after scrolling
  *         content:
  *           application/json:
  *             schema:
@@ -554,18 +1182,34 @@ router.post('/select/:tabId', async (req: Request, res: Response) => {
  *                 data:
  *                   type: object
  *                   properties:
- *                     result:
- *                       type: any
+ *                     scrollX:
+ *                       type: number
+ *                     scrollY:
+ *                       type: number
+ *                     scrollHeight:
+ *                       type: number
+ *                     viewportHeight:
+ *                       type: number
+ *                     iterations:
+ *                       type: integer
+ *                     heightStable:
+ *                       type: boolean
  */
-router.post('/eval/:tabId', async (req: Request, res: Response) => {
+router.post('/scroll/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: EvalRequest = req.body;
+    const request: ScrollRequest = req.body ?? {};
 
-    if (!request.script) {
+    let validationError: string | null = null;
+    try {
+      resolveScrollMode(request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
       return res.status(400).json({
         success: false,
-        error: 'Script is required'
+        error: validationError
       });
     }
 
@@ -576,34 +1220,24 @@ router.post('/eval/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    const result = await browserManager.evaluateScript(tabId, request.script);
+    const result = await browserManager.scrollPage(tabId, request);
 
-    const response: ApiResponse<{ result: any }> = {
+    const response: ApiResponse<ScrollResult> = {
       success: true,
-      data: { result }
+      data: result
     };
 
     return res.json(response);
   } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
-
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/close/{tabId}:
- *   delete:
- *     summary: Close tab
+ * /api/tabs/drag/{tabId}:
+ *   post:
+ *     summary: Drag with the mouse from one element or point to another
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -611,13 +1245,63 @@ router.post('/eval/:tabId', async (req: Request, res: Response) => {
  *         required: true
  *         schema:
  *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required:
+ *               - from
+ *               - to
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               from:
+ *                 type: object
+ *                 description: A selector, an xpath, or x and y viewport coordinates
+ *               to:
+ *                 type: object
+ *                 description: A selector, an xpath, or x and y viewport coordinates
+ *               steps:
+ *                 type: integer
+ *                 default: 10
  *     responses:
  *       200:
- *         description: Tab closed successfully
+ *         description: Points the drag started and ended at
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     from:
+ *                       type: object
+ *                     to:
+ *                       type: object
  */
-router.delete('/close/:tabId', async (req: Request, res: Response) => {
+router.post('/drag/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
+    const request: DragRequest = req.body ?? {};
+
+    let validationError: string | null = null;
+    try {
+      validateDrag(request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
+        success: false,
+        error: validationError
+      });
+    }
 
     if (!tabId) {
       return res.status(400).json({
@@ -626,76 +1310,3396 @@ router.delete('/close/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    await browserManager.closeTab(tabId);
+    const result = await browserManager.dragElement(tabId, request);
 
-    return res.json({ success: true });
+    const response: ApiResponse<DragResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
   } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/tap/{tabId}:
+ *   post:
+ *     summary: Tap an element or point with a finger
+ *     description: >
+ *       Needs touch enabled on the tab first, by emulating a phone or tablet or a custom device
+ *       with hasTouch.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *               x:
+ *                 type: number
+ *                 description: Viewport coordinate in CSS pixels, with y instead of an element
+ *               y:
+ *                 type: number
+ *               count:
+ *                 type: integer
+ *                 default: 1
+ *               duration:
+ *                 type: number
+ *                 description: Milliseconds each finger stays down
+ *                 default: 50
+ *     responses:
+ *       200:
+ *         description: Where the gesture started
+ *       400:
+ *         description: Invalid target, or touch is not enabled
+ *       404:
+ *         description: Tab not found
+ */
+router.post('/tap/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: TapRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
         success: false,
-        error: error.message
+        error: 'Tab ID is required'
       });
     }
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    const result = await browserManager.tap(tabId, request);
+
+    const response: ApiResponse<TouchResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/closeAll:
- *   delete:
- *     summary: Close all open tabs
+ * /api/tabs/swipe/{tabId}:
+ *   post:
+ *     summary: Swipe a finger across the page
+ *     description: >
+ *       The finger moves in the direction, so up scrolls the page down. Needs touch enabled
+ *       on the tab first, by emulating a phone or tablet or a custom device with hasTouch.
  *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [direction]
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *               x:
+ *                 type: number
+ *                 description: Viewport coordinate in CSS pixels, with y instead of an element
+ *               y:
+ *                 type: number
+ *               direction:
+ *                 type: string
+ *                 enum: [left, right, up, down]
+ *               distance:
+ *                 type: number
+ *                 default: 300
+ *               speed:
+ *                 type: number
+ *                 description: Pixels per second
+ *                 default: 800
  *     responses:
  *       200:
- *         description: All tabs closed successfully
+ *         description: Where the gesture started
+ *       400:
+ *         description: Invalid target or direction, or touch is not enabled
+ *       404:
+ *         description: Tab not found
  */
-router.delete('/closeAll', async (_req: Request, res: Response) => {
+router.post('/swipe/:tabId', async (req: Request, res: Response) => {
   try {
-    await browserManager.closeAllTabs();
+    const { tabId } = req.params;
+    const request: SwipeRequest = req.body ?? {};
 
-    return res.json({ success: true });
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.swipe(tabId, request);
+
+    const response: ApiResponse<TouchResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
   } catch (error) {
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/cleanBrowserData:
- *   delete:
- *     summary: Clean browser data directory
+ * /api/tabs/pinch/{tabId}:
+ *   post:
+ *     summary: Pinch with two fingers to zoom
+ *     description: >
+ *       A scale above 1 zooms in, below 1 zooms out. Needs touch enabled on the tab first, by
+ *       emulating a phone or tablet or a custom device with hasTouch.
  *     tags: [Tabs]
- *     description: Cleans the browser data directory by removing the .browser folder and all its contents. This closes all tabs and browsers before cleaning.
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [scale]
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *               x:
+ *                 type: number
+ *                 description: Viewport coordinate in CSS pixels, with y instead of an element
+ *               y:
+ *                 type: number
+ *               scale:
+ *                 type: number
+ *               speed:
+ *                 type: number
+ *                 description: Pixels per second
+ *                 default: 800
  *     responses:
  *       200:
- *         description: Browser data cleaned successfully
+ *         description: Where the gesture started
+ *       400:
+ *         description: Invalid target or scale, or touch is not enabled
+ *       404:
+ *         description: Tab not found
  */
-router.delete('/cleanBrowserData', async (_req: Request, res: Response) => {
+router.post('/pinch/:tabId', async (req: Request, res: Response) => {
   try {
-    await browserManager.cleanBrowserData();
+    const { tabId } = req.params;
+    const request: PinchRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.pinch(tabId, request);
+
+    const response: ApiResponse<TouchResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/type/{tabId}:
+ *   post:
+ *     summary: Type text into an editable element key by key
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *                 description: XPath expression, used instead of selector
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *               text:
+ *                 type: string
+ *               clear:
+ *                 type: boolean
+ *                 description: Clear the existing value before typing
+ *               delay:
+ *                 type: number
+ *                 description: Delay between keystrokes in milliseconds
+ *               afterKey:
+ *                 type: string
+ *                 enum: [Enter, Tab]
+ *     responses:
+ *       200:
+ *         description: Typing successful
+ *       500:
+ *         description: Element missing or not editable
+ */
+router.post('/type/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: TypeRequest = req.body;
+
+    if ((!request.selector && !request.xpath) || typeof request.text !== 'string') {
+      return res.status(400).json({
+        success: false,
+        error: 'Selector and text are required'
+      });
+    }
+
+    if (request.afterKey && !AFTER_KEYS.includes(request.afterKey)) {
+      return res.status(400).json({
+        success: false,
+        error: `afterKey must be one of: ${AFTER_KEYS.join(', ')}`
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.typeText(tabId, toSelector(request), request.text, {
+      clear: request.clear,
+      delay: request.delay,
+      afterKey: request.afterKey,
+      frame: request.frame,
+      index: toElementIndex(request)
+    });
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/keyboard/{tabId}:
+ *   post:
+ *     summary: Press a key or a shortcut
+ *     description: >
+ *       Sends the key to the focused element. Key names follow the DOM key values, such as
+ *       Enter, Escape, ArrowDown, F5, Numpad1 or a single character, and shortcuts join them
+ *       with +, e.g. Control+A. down holds the keys until up releases them.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [key]
+ *             properties:
+ *               key:
+ *                 type: string
+ *               action:
+ *                 type: string
+ *                 enum: [press, down, up]
+ *                 default: press
+ *               delay:
+ *                 type: number
+ *                 description: Milliseconds between keydown and keyup when pressing
+ *     responses:
+ *       200:
+ *         description: The keys that were sent
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     action:
+ *                       type: string
+ *                     keys:
+ *                       type: array
+ *                       items:
+ *                         type: string
+ *       400:
+ *         description: Unknown key name or invalid shortcut
+ */
+router.post('/keyboard/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: KeyboardRequest = req.body;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.pressKeys(tabId, request);
+
+    const response: ApiResponse<KeyboardResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/upload/{tabId}:
+ *   post:
+ *     summary: Attach files to a file input
+ *     description: >
+ *       Attaches files on the server by path and files sent as base64, then fires the change
+ *       event. Several files need an input with the multiple attribute.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *                 description: XPath expression, used instead of selector
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *               paths:
+ *                 type: array
+ *                 description: Files on the server inside its working directory, relative to it
+ *                 items:
+ *                   type: string
+ *               files:
+ *                 type: array
+ *                 items:
+ *                   type: object
+ *                   properties:
+ *                     name:
+ *                       type: string
+ *                     data:
+ *                       type: string
+ *                       description: Base64 content or a data URL
+ *     responses:
+ *       200:
+ *         description: Names of the attached files
+ *       400:
+ *         description: No files given or invalid base64
+ *       500:
+ *         description: Element missing or not a file input
+ */
+router.post('/upload/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: UploadFileRequest = req.body;
+    const paths = request.paths ?? [];
+    const files = request.files ?? [];
+
+    if (!request.selector && !request.xpath) {
+      return res.status(400).json({
+        success: false,
+        error: 'Selector is required'
+      });
+    }
+
+    if (!Array.isArray(paths) || !Array.isArray(files) || paths.length + files.length === 0) {
+      return res.status(400).json({
+        success: false,
+        error: 'At least one file path or base64 file is required'
+      });
+    }
+
+    let validationError: string | null = null;
+    try {
+      files.forEach(file => decodeBlob(file));
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
+        success: false,
+        error: validationError
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.uploadFile(
+      tabId,
+      toSelector(request),
+      paths,
+      files,
+      request.frame,
+      toElementIndex(request)
+    );
+
+    const response: ApiResponse<UploadResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/fill/{tabId}:
+ *   post:
+ *     summary: Set the value of a form field instantly
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *                 description: XPath expression, used instead of selector
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *               value:
+ *                 type: string
+ *     responses:
+ *       200:
+ *         description: Fill successful
+ *       500:
+ *         description: Element missing or not editable
+ */
+router.post('/fill/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: FillRequest = req.body;
+
+    if ((!request.selector && !request.xpath) || typeof request.value !== 'string') {
+      return res.status(400).json({
+        success: false,
+        error: 'Selector and value are required'
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.fillField(
+      tabId,
+      toSelector(request),
+      request.value,
+      request.frame,
+      toElementIndex(request)
+    );
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/select/{tabId}:
+ *   post:
+ *     summary: Select dropdown option in tab
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               value:
+ *                 description: Option value attribute, or an array for a multiple select
+ *                 oneOf:
+ *                   - type: string
+ *                   - type: array
+ *                     items:
+ *                       type: string
+ *               label:
+ *                 description: Visible option text, or an array for a multiple select
+ *                 oneOf:
+ *                   - type: string
+ *                   - type: array
+ *                     items:
+ *                       type: string
+ *               index:
+ *                 description: Zero-based option position, or an array for a multiple select
+ *                 oneOf:
+ *                   - type: integer
+ *                   - type: array
+ *                     items:
+ *                       type: integer
+ *     responses:
+ *       200:
+ *         description: Options selected
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     selected:
+ *                       type: array
+ *                       items:
+ *                         type: object
+ *                         properties:
+ *                           value:
+ *                             type: string
+ *                           label:
+ *                             type: string
+ *                           index:
+ *                             type: integer
+ *                           disabled:
+ *                             type: boolean
+ */
+router.post('/select/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: SelectRequest = req.body;
+
+    if (!request.selector && !request.xpath) {
+      return res.status(400).json({
+        success: false,
+        error: 'Selector is required'
+      });
+    }
+
+    let validationError: string | null = null;
+    try {
+      toCriteria(request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
+        success: false,
+        error: validationError
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.selectOption(
+      tabId,
+      toSelector(request),
+      { value: request.value, label: request.label, index: request.index },
+      request.frame
+    );
+
+    const response: ApiResponse<SelectResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/eval/{tabId}:
+ *   post:
+ *     summary: Execute JavaScript in tab
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               script:
+ *                 type: string
+ *     responses:
+ *       200:
+ *         description: Script executed successfully
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     result:
+ *                       type: any
+ */
+router.post('/eval/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: EvalRequest = req.body;
+
+    if (!request.script) {
+      return res.status(400).json({
+        success: false,
+        error: 'Script is required'
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.evaluateScript(tabId, request.script);
+
+    const response: ApiResponse<{ result: any }> = {
+      success: true,
+      data: { result }
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/evaluate/{tabId}:
+ *   post:
+ *     summary: Run a function body in tab and return its JSON result
+ *     description: >
+ *       The body runs inside an async function with the arguments bound to args and must
+ *       return a JSON-serializable value. DOM nodes, functions and circular structures are
+ *       rejected with a descriptive error.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               functionBody:
+ *                 type: string
+ *               args:
+ *                 type: array
+ *                 items: {}
+ *               timeout:
+ *                 type: number
+ *                 description: Milliseconds to wait for the result, 0 to disable
+ *     responses:
+ *       200:
+ *         description: Function evaluated successfully
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     result:
+ *                       type: any
+ */
+router.post('/evaluate/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: EvaluateRequest = req.body;
+
+    if (typeof request.functionBody !== 'string') {
+      return res.status(400).json({
+        success: false,
+        error: 'Function body is required'
+      });
+    }
+
+    if (request.args !== undefined && !Array.isArray(request.args)) {
+      return res.status(400).json({
+        success: false,
+        error: 'args must be an array'
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.evaluateFunction(
+      tabId,
+      request.functionBody,
+      request.args,
+      request.timeout
+    );
+
+    const response: ApiResponse<{ result: unknown }> = {
+      success: true,
+      data: { result }
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/close/{tabId}:
+ *   delete:
+ *     summary: Close tab
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     responses:
+ *       200:
+ *         description: Tab closed successfully
+ */
+router.delete('/close/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.closeTab(tabId);
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/closeAll:
+ *   delete:
+ *     summary: Close all open tabs
+ *     tags: [Tabs]
+ *     responses:
+ *       200:
+ *         description: All tabs closed successfully
+ */
+router.delete('/closeAll', async (_req: Request, res: Response) => {
+  try {
+    await browserManager.closeAllTabs();
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/cleanBrowserData:
+ *   delete:
+ *     summary: Clean browser data directory
+ *     tags: [Tabs]
+ *     description: Cleans the browser data directory by removing the .browser folder and all its contents. This closes all tabs and browsers before cleaning.
+ *     responses:
+ *       200:
+ *         description: Browser data cleaned successfully
+ */
+router.delete('/cleanBrowserData', async (_req: Request, res: Response) => {
+  try {
+    await browserManager.cleanBrowserData();
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/bringToFront/{tabId}:
+ *   post:
+ *     summary: Bring tab to front
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     responses:
+ *       200:
+ *         description: Tab brought to front successfully
+ */
+router.post('/bringToFront/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.bringToFront(tabId);
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/focus/{tabId}:
+ *   post:
+ *     summary: Focus element in tab
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *                 description: XPath expression, used instead of selector
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *     responses:
+ *       200:
+ *         description: Element focused successfully
+ */
+router.post('/focus/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: FocusRequest = req.body;
+
+    if (!request.selector && !request.xpath) {
+      return res.status(400).json({
+        success: false,
+        error: 'Selector is required'
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.focusElement(
+      tabId,
+      toSelector(request),
+      request.frame,
+      toElementIndex(request)
+    );
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/goBack/{tabId}:
+ *   post:
+ *     summary: Navigate back in browser history
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     responses:
+ *       200:
+ *         description: Navigated back successfully
+ */
+router.post('/goBack/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.goBack(tabId);
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/goForward/{tabId}:
+ *   post:
+ *     summary: Navigate forward in browser history
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     responses:
+ *       200:
+ *         description: Navigated forward successfully
+ */
+router.post('/goForward/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.goForward(tabId);
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/reload/{tabId}:
+ *   post:
+ *     summary: Reload tab
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: false
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               waitUntil:
+ *                 type: string
+ *     responses:
+ *       200:
+ *         description: Tab reloaded successfully
+ */
+router.post('/reload/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: ReloadRequest = req.body;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.reloadTab(tabId, request.waitUntil);
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/waitForSelector/{tabId}:
+ *   post:
+ *     summary: Wait for selector to appear, become visible or become hidden in tab
+ *     description: >
+ *       A timeout is not an error: the response has success false, timedOut true and a hint
+ *       describing the page when the wait gave up.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *                 description: XPath expression, used instead of selector
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *               timeout:
+ *                 type: number
+ *               visible:
+ *                 type: boolean
+ *               hidden:
+ *                 type: boolean
+ *                 description: Wait for the element to be hidden or removed instead
+ *     responses:
+ *       200:
+ *         description: Wait finished or timed out
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     found:
+ *                       type: boolean
+ *                     timedOut:
+ *                       type: boolean
+ *                     elapsedMs:
+ *                       type: number
+ *                     boundingBox:
+ *                       type: object
+ *                       nullable: true
+ *                     hint:
+ *                       type: object
+ *                       properties:
+ *                         url:
+ *                           type: string
+ *                         readyState:
+ *                           type: string
+ *                         matchCount:
+ *                           type: number
+ *                         shadowMatchCount:
+ *                           type: number
+ *                         firstMatch:
+ *                           type: object
+ *                           nullable: true
+ */
+router.post('/waitForSelector/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: WaitForSelectorRequest = req.body;
+
+    if (!request.selector && !request.xpath) {
+      return res.status(400).json({
+        success: false,
+        error: 'Selector is required'
+      });
+    }
+
+    if (request.visible && request.hidden) {
+      return res.status(400).json({
+        success: false,
+        error: 'visible and hidden cannot both be set'
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.waitForSelector(tabId, toSelector(request), {
+      timeout: request.timeout ?? browserManager.resolveTimeout({ tabId }),
+      visible: request.visible ?? false,
+      hidden: request.hidden ?? false,
+      index: toElementIndex(request),
+      ...(request.frame ? { frame: request.frame } : {})
+    });
+
+    const response: ApiResponse<WaitForSelectorResult> = {
+      success: !result.timedOut,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/waitForStable/{tabId}:
+ *   post:
+ *     summary: Wait for an element to stop moving and resizing
+ *     description: >
+ *       Polls the bounding box of the element until it stays within tolerance pixels for
+ *       stableTime milliseconds, e.g. before clicking something that is still animating. A
+ *       timeout is not an error: the response has success false, timedOut true and the last
+ *       bounding box.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               selector:
+ *                 type: string
+ *               xpath:
+ *                 type: string
+ *                 description: XPath expression, used instead of selector
+ *               selectorType:
+ *                 type: string
+ *                 enum: [css, xpath]
+ *                 description: How to read selector, default xpath when it starts with // or (
+ *               index:
+ *                 type: integer
+ *                 description: Which match to act on, 0-based in document order, default 0
+ *               tolerance:
+ *                 type: number
+ *                 default: 1
+ *                 description: Pixels the element may move or resize by and still be stable
+ *               stableTime:
+ *                 type: number
+ *                 default: 200
+ *                 description: Milliseconds the element has to stay put
+ *               timeout:
+ *                 type: number
+ *                 description: Milliseconds, 0 waits forever (default the session timeout)
+ *     responses:
+ *       200:
+ *         description: The element settled or the wait timed out
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     stable:
+ *                       type: boolean
+ *                     timedOut:
+ *                       type: boolean
+ *                     elapsedMs:
+ *                       type: number
+ *                     boundingBox:
+ *                       type: object
+ *                       nullable: true
+ *                     moves:
+ *                       type: integer
+ *       400:
+ *         description: Missing selector or invalid tolerance, stableTime or timeout
+ */
+router.post('/waitForStable/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: WaitForStableRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.waitForStable(tabId, request);
+
+    const response: ApiResponse<WaitForStableResult> = {
+      success: result.stable,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/waitForFunction/{tabId}:
+ *   post:
+ *     summary: Wait for an expression or function to return truthy value
+ *     description: >
+ *       A timeout is not an error: the response has success false and timedOut true.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               frame:
+ *                 type: string
+ *                 description: Frame ID from /frames, frame name, URL or iframe selector
+ *               functionScript:
+ *                 type: string
+ *               timeout:
+ *                 type: number
+ *               polling:
+ *                 oneOf:
+ *                   - type: string
+ *                     enum: [raf, mutation]
+ *                   - type: number
+ *     responses:
+ *       200:
+ *         description: Wait finished or timed out
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     satisfied:
+ *                       type: boolean
+ *                     timedOut:
+ *                       type: boolean
+ *                     elapsedMs:
+ *                       type: number
+ *                     value:
+ *                       type: any
+ */
+router.post('/waitForFunction/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: WaitForFunctionRequest = req.body;
+
+    if (!request.functionScript) {
+      return res.status(400).json({
+        success: false,
+        error: 'Function script is required'
+      });
+    }
+
+    if (
+      request.polling !== undefined &&
+      request.polling !== 'raf' &&
+      request.polling !== 'mutation' &&
+      !(typeof request.polling === 'number' && request.polling > 0)
+    ) {
+      return res.status(400).json({
+        success: false,
+        error: 'polling must be "raf", "mutation" or a positive interval in milliseconds'
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const options: { timeout: number; polling?: 'raf' | 'mutation' | number; frame?: string } = {
+      timeout: request.timeout ?? browserManager.resolveTimeout({ tabId })
+    };
+    if (request.polling !== undefined) options.polling = request.polling;
+    if (request.frame) options.frame = request.frame;
+
+    const result = await browserManager.waitForFunction(tabId, request.functionScript, options);
+
+    const response: ApiResponse<WaitForFunctionResult> = {
+      success: result.satisfied,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/waitForNavigation/{tabId}:
+ *   post:
+ *     summary: Wait for navigation to complete
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: false
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               timeout:
+ *                 type: number
+ *               waitUntil:
+ *                 type: string
+ *     responses:
+ *       200:
+ *         description: Navigation completed successfully
+ */
+router.post('/waitForNavigation/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: WaitForNavigationRequest = req.body;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    await browserManager.waitForNavigation(tabId, {
+      timeout: request.timeout ?? browserManager.resolveTimeout({ tabId }, true),
+      waitUntil: request.waitUntil ?? 'load'
+    });
+
+    return res.json({ success: true });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/waitForNetworkIdle/{tabId}:
+ *   post:
+ *     summary: Wait for network activity to settle, independent of navigation
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: false
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               idleTime:
+ *                 type: number
+ *                 default: 500
+ *               maxInflightRequests:
+ *                 type: integer
+ *                 default: 0
+ *               timeout:
+ *                 type: number
+ *                 description: Milliseconds (default the session's default timeout)
+ *     responses:
+ *       200:
+ *         description: Whether the network went idle; pending request URLs on timeout
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     idle:
+ *                       type: boolean
+ *                     timedOut:
+ *                       type: boolean
+ *                     elapsedMs:
+ *                       type: number
+ *                     inflight:
+ *                       type: integer
+ *                     pending:
+ *                       type: array
+ *                       items:
+ *                         type: string
+ */
+router.post('/waitForNetworkIdle/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: WaitForNetworkIdleRequest = req.body ?? {};
+
+    let validationError: string | null = null;
+    try {
+      resolveIdleOptions(request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
+        success: false,
+        error: validationError
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.waitForNetworkIdle(tabId, request);
+
+    const response: ApiResponse<NetworkIdleResult> = {
+      success: result.idle,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/waitForRequest/{tabId}:
+ *   post:
+ *     summary: Wait for the tab to send a matching request
+ *     description: >
+ *       Resolves with the URL, method, headers and, with includeBody, post data of the first
+ *       matching request. lookback also finds requests sent shortly before the call, such as
+ *       those of a click that just happened.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [url]
+ *             properties:
+ *               url:
+ *                 type: string
+ *                 description: URL glob such as **/api/users*, or a regular expression with regex
+ *               regex:
+ *                 type: boolean
+ *               method:
+ *                 type: string
+ *               lookback:
+ *                 type: number
+ *                 description: Milliseconds before the call to search as well
+ *                 default: 0
+ *               includeBody:
+ *                 type: boolean
+ *                 default: false
+ *               timeout:
+ *                 type: number
+ *                 description: Milliseconds (default the session's default timeout)
+ *     responses:
+ *       200:
+ *         description: The matched request, or what came close on timeout
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     matched:
+ *                       type: boolean
+ *                     timedOut:
+ *                       type: boolean
+ *                     elapsedMs:
+ *                       type: number
+ *                     entry:
+ *                       type: object
+ *                       nullable: true
+ *                     nearMatches:
+ *                       type: array
+ *                       items:
+ *                         type: object
+ *       400:
+ *         description: Invalid URL pattern, method or status
+ */
+router.post('/waitForRequest/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: WaitForRequestRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.waitForRequest(tabId, request);
+
+    const response: ApiResponse<WaitForTrafficResult> = {
+      success: result.matched,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/waitForResponse/{tabId}:
+ *   post:
+ *     summary: Wait for the tab to receive a matching response
+ *     description: >
+ *       Resolves with the URL, status, headers and, with includeBody, body of the first matching
+ *       response. Binary bodies are base64 encoded. On timeout the responses that matched the
+ *       URL but not method or status come first in nearMatches.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [url]
+ *             properties:
+ *               url:
+ *                 type: string
+ *                 description: URL glob such as **/api/users*, or a regular expression with regex
+ *               regex:
+ *                 type: boolean
+ *               method:
+ *                 type: string
+ *               status:
+ *                 type: integer
+ *               lookback:
+ *                 type: number
+ *                 description: Milliseconds before the call to search as well
+ *                 default: 0
+ *               includeBody:
+ *                 type: boolean
+ *                 default: false
+ *               timeout:
+ *                 type: number
+ *                 description: Milliseconds (default the session's default timeout)
+ *     responses:
+ *       200:
+ *         description: The matched response, or what came close on timeout
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     matched:
+ *                       type: boolean
+ *                     timedOut:
+ *                       type: boolean
+ *                     elapsedMs:
+ *                       type: number
+ *                     entry:
+ *                       type: object
+ *                       nullable: true
+ *                     nearMatches:
+ *                       type: array
+ *                       items:
+ *                         type: object
+ *       400:
+ *         description: Invalid URL pattern, method or status
+ */
+router.post('/waitForResponse/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: WaitForResponseRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.waitForResponse(tabId, request);
+
+    const response: ApiResponse<WaitForTrafficResult> = {
+      success: result.matched,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/captureResponseBody/{tabId}:
+ *   post:
+ *     summary: Return the body of a response the tab received
+ *     description: >
+ *       Waits for a response matching the URL, method and status like /waitForResponse and
+ *       returns its decompressed body, parsed when it is JSON. Bodies larger than maxBodySize
+ *       or of a media type outside contentTypes are omitted, and omitted says why.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [url]
+ *             properties:
+ *               url:
+ *                 type: string
+ *                 description: URL glob such as **/api/users*, or a regular expression with regex
+ *               regex:
+ *                 type: boolean
+ *               method:
+ *                 type: string
+ *               status:
+ *                 type: integer
+ *               lookback:
+ *                 type: number
+ *                 description: Milliseconds before the call to search as well
+ *                 default: 0
+ *               maxBodySize:
+ *                 type: integer
+ *                 description: Bytes
+ *                 default: 1048576
+ *               contentTypes:
+ *                 type: array
+ *                 description: Media types such as application/json, text/* or +json
+ *                 items:
+ *                   type: string
+ *               timeout:
+ *                 type: number
+ *                 description: Milliseconds (default the session's default timeout)
+ *     responses:
+ *       200:
+ *         description: The captured body, or what came close on timeout
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     matched:
+ *                       type: boolean
+ *                     timedOut:
+ *                       type: boolean
+ *                     elapsedMs:
+ *                       type: number
+ *                     entry:
+ *                       type: object
+ *                       nullable: true
+ *                       properties:
+ *                         url:
+ *                           type: string
+ *                         status:
+ *                           type: integer
+ *                         contentType:
+ *                           type: string
+ *                         size:
+ *                           type: integer
+ *                         format:
+ *                           type: string
+ *                           enum: [json, text, base64]
+ *                           nullable: true
+ *                         body: {}
+ *                         omitted:
+ *                           type: string
+ *                           enum: [binary, contentType, tooLarge]
+ *                     nearMatches:
+ *                       type: array
+ *                       items:
+ *                         type: object
+ *       400:
+ *         description: Invalid URL pattern, size cap or content types
+ */
+router.post('/captureResponseBody/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: CaptureResponseBodyRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.captureResponseBody(tabId, request);
+
+    const response: ApiResponse<WaitForTrafficResult<CapturedResponse>> = {
+      success: result.matched,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/frames/{tabId}:
+ *   get:
+ *     summary: List the frames of a tab as a tree
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     responses:
+ *       200:
+ *         description: Frame tree starting at the main frame
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     id:
+ *                       type: string
+ *                     name:
+ *                       type: string
+ *                     url:
+ *                       type: string
+ *                     parentId:
+ *                       type: string
+ *                       nullable: true
+ *                     children:
+ *                       type: array
+ *                       items:
+ *                         type: object
+ */
+router.get('/frames/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const frames = await browserManager.listFrames(tabId);
+
+    const response: ApiResponse<FrameInfo> = {
+      success: true,
+      data: frames
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/url/{tabId}:
+ *   get:
+ *     summary: Get current URL of tab
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     responses:
+ *       200:
+ *         description: URL retrieved successfully
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     url:
+ *                       type: string
+ */
+router.get('/url/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const url = await browserManager.getTabUrl(tabId);
+
+    const response: ApiResponse<{ url: string }> = {
+      success: true,
+      data: { url }
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/html/{tabId}:
+ *   get:
+ *     summary: Get current HTML content of tab
+ *     description: The DOM as serialized now, after scripts ran, or the outer HTML of one element
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selector
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: index
+ *         schema:
+ *           type: integer
+ *         description: Which match to use, 0-based in document order, default 0
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *     responses:
+ *       200:
+ *         description: HTML retrieved successfully
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     html:
+ *                       type: string
+ */
+router.get('/html/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const { selector, xpath, selectorType, index, frame } = req.query;
+    const html = await browserManager.getTabHtml(tabId, {
+      selector: typeof selector === 'string' ? selector : undefined,
+      xpath: typeof xpath === 'string' ? xpath : undefined,
+      selectorType: typeof selectorType === 'string' ? (selectorType as SelectorType) : undefined,
+      index: typeof index === 'string' ? Number(index) : undefined,
+      frame: typeof frame === 'string' ? frame : undefined
+    });
+
+    const response: ApiResponse<{ html: string }> = {
+      success: true,
+      data: { html }
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/accessibility/{tabId}:
+ *   get:
+ *     summary: Get the accessibility tree of the tab as JSON
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selector
+ *         schema:
+ *           type: string
+ *         description: Root element of the snapshot, default the whole page
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: index
+ *         schema:
+ *           type: integer
+ *         description: Which match to use, 0-based in document order, default 0
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *       - in: query
+ *         name: interestingOnly
+ *         schema:
+ *           type: boolean
+ *           default: true
+ *         description: Drop nodes without semantics such as wrapper divs
+ *     responses:
+ *       200:
+ *         description: Tree of roles, names, values and states with the node count
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     snapshot:
+ *                       type: object
+ *                       nullable: true
+ *                     nodes:
+ *                       type: integer
+ *       404:
+ *         description: Tab not found
+ */
+router.get('/accessibility/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const { selector, xpath, selectorType, index, frame, interestingOnly } = req.query;
+    const result = await browserManager.getAccessibilitySnapshot(tabId, {
+      selector: typeof selector === 'string' ? selector : undefined,
+      xpath: typeof xpath === 'string' ? xpath : undefined,
+      selectorType: typeof selectorType === 'string' ? (selectorType as SelectorType) : undefined,
+      index: typeof index === 'string' ? Number(index) : undefined,
+      frame: typeof frame === 'string' ? frame : undefined,
+      interestingOnly: interestingOnly !== 'false'
+    });
+
+    const response: ApiResponse<AccessibilitySnapshotResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/text/{tabId}:
+ *   get:
+ *     summary: Get the rendered text or the main content of the tab
+ *     description: >
+ *       raw mode returns the rendered text of the element (the body by default). readability
+ *       mode finds the main content of the page and drops navigation, ads and other
+ *       boilerplate.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: mode
+ *         schema:
+ *           type: string
+ *           enum: [raw, readability]
+ *           default: raw
+ *       - in: query
+ *         name: selector
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: index
+ *         schema:
+ *           type: integer
+ *         description: Which match to use, 0-based in document order, default 0
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *       - in: query
+ *         name: maxLength
+ *         schema:
+ *           type: integer
+ *           default: 50000
+ *     responses:
+ *       200:
+ *         description: Title and text of the page
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     mode:
+ *                       type: string
+ *                     title:
+ *                       type: string
+ *                     url:
+ *                       type: string
+ *                     text:
+ *                       type: string
+ *                     length:
+ *                       type: integer
+ *                     truncated:
+ *                       type: boolean
+ */
+router.get('/text/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const { selector, xpath, selectorType, index, frame, mode, maxLength } = req.query;
+    const request: GetTextRequest = {
+      selector: typeof selector === 'string' ? selector : undefined,
+      xpath: typeof xpath === 'string' ? xpath : undefined,
+      selectorType: typeof selectorType === 'string' ? (selectorType as SelectorType) : undefined,
+      index: typeof index === 'string' ? Number(index) : undefined,
+      frame: typeof frame === 'string' ? frame : undefined,
+      mode: typeof mode === 'string' ? (mode as TextMode) : undefined,
+      maxLength: typeof maxLength === 'string' ? Number(maxLength) : undefined
+    };
+
+    let validationError: string | null = null;
+    try {
+      validateTextRequest(request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
+        success: false,
+        error: validationError
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.getText(tabId, request);
+
+    const response: ApiResponse<TextResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/attribute/{tabId}:
+ *   get:
+ *     summary: Read an HTML attribute of an element
+ *     description: >
+ *       The attribute as written in the DOM, null when the element does not have it.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: name
+ *         required: true
+ *         schema:
+ *           type: string
+ *         description: Attribute name, e.g. href or aria-expanded
+ *       - in: query
+ *         name: selector
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: index
+ *         schema:
+ *           type: integer
+ *         description: Which match to use, 0-based in document order, default 0
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *     responses:
+ *       200:
+ *         description: Name and value of the attribute
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     name:
+ *                       type: string
+ *                     value:
+ *                       type: string
+ *                       nullable: true
+ *       404:
+ *         description: Tab not found or no element matches the selector
+ */
+router.get('/attribute/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const { name } = req.query;
+    const request = {
+      ...parseElementRequest(req.query),
+      name: typeof name === 'string' ? name : ''
+    };
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.getAttribute(tabId, request);
+
+    const response: ApiResponse<AttributeResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/property/{tabId}:
+ *   get:
+ *     summary: Read a DOM property of an element
+ *     description: >
+ *       The live state of the element, such as the value typed into an input or whether a
+ *       checkbox is checked, as JSON. null for undefined.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: name
+ *         required: true
+ *         schema:
+ *           type: string
+ *         description: Property name or dotted path, e.g. value, checked or validity.valid
+ *       - in: query
+ *         name: selector
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: index
+ *         schema:
+ *           type: integer
+ *         description: Which match to use, 0-based in document order, default 0
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *     responses:
+ *       200:
+ *         description: Name and JSON value of the property
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     name:
+ *                       type: string
+ *                     value:
+ *                       description: Any JSON value, null for undefined
+ *       404:
+ *         description: Tab not found or no element matches the selector
+ */
+router.get('/property/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const { name } = req.query;
+    const request = {
+      ...parseElementRequest(req.query),
+      name: typeof name === 'string' ? name : ''
+    };
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.getProperty(tabId, request);
+
+    const response: ApiResponse<PropertyResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/boundingBox/{tabId}:
+ *   get:
+ *     summary: Get the position and size of an element
+ *     description: >
+ *       CSS pixels relative to the main frame. boundingBox is null when the element is not
+ *       rendered.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selector
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: index
+ *         schema:
+ *           type: integer
+ *         description: Which match to use, 0-based in document order, default 0
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *     responses:
+ *       200:
+ *         description: Bounding box and visibility of the element
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     boundingBox:
+ *                       type: object
+ *                       nullable: true
+ *                       properties:
+ *                         x:
+ *                           type: number
+ *                         y:
+ *                           type: number
+ *                         width:
+ *                           type: number
+ *                         height:
+ *                           type: number
+ *                     visible:
+ *                       type: boolean
+ *                     inViewport:
+ *                       type: boolean
+ *       404:
+ *         description: Tab not found or no element matches the selector
+ */
+router.get('/boundingBox/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request = parseElementRequest(req.query);
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.getBoundingBox(tabId, request);
+
+    const response: ApiResponse<BoundingBoxResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/count/{tabId}:
+ *   get:
+ *     summary: Count the elements that match a selector
+ *     description: >
+ *       Checks the DOM as it is now without waiting, use /waitForSelector to wait.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selector
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *       - in: query
+ *         name: visible
+ *         schema:
+ *           type: boolean
+ *           default: false
+ *         description: Only count visible elements
+ *     responses:
+ *       200:
+ *         description: Number of matching elements
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     count:
+ *                       type: integer
+ */
+router.get('/count/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.countElements(tabId, {
+      ...parseElementRequest(req.query),
+      visible: req.query['visible'] === 'true'
+    });
+
+    const response: ApiResponse<CountElementsResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/exists/{tabId}:
+ *   get:
+ *     summary: Check whether an element matches a selector
+ *     description: >
+ *       Checks the DOM as it is now without waiting, use /waitForSelector to wait.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selector
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: xpath
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: selectorType
+ *         schema:
+ *           type: string
+ *           enum: [css, xpath]
+ *         description: How to read selector, default xpath when it starts with // or (
+ *       - in: query
+ *         name: frame
+ *         schema:
+ *           type: string
+ *         description: Frame ID from /frames, frame name, URL or iframe selector
+ *       - in: query
+ *         name: visible
+ *         schema:
+ *           type: boolean
+ *           default: false
+ *         description: Only count visible elements
+ *     responses:
+ *       200:
+ *         description: Whether any element matches
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     exists:
+ *                       type: boolean
+ *                     count:
+ *                       type: integer
+ */
+router.get('/exists/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.elementExists(tabId, {
+      ...parseElementRequest(req.query),
+      visible: req.query['visible'] === 'true'
+    });
+
+    const response: ApiResponse<ElementExistsResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/storage/{tabId}:
+ *   get:
+ *     summary: Read localStorage or sessionStorage of the tab's current origin
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *       - in: query
+ *         name: type
+ *         schema:
+ *           type: string
+ *           enum: [local, session]
+ *         description: Storage to read (default local)
+ *       - in: query
+ *         name: key
+ *         schema:
+ *           type: array
+ *           items:
+ *             type: string
+ *         description: Only return these keys, repeat for several
+ *     responses:
+ *       200:
+ *         description: Origin and stored items
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     origin:
+ *                       type: string
+ *                     type:
+ *                       type: string
+ *                     items:
+ *                       type: object
+ *                       additionalProperties:
+ *                         type: string
+ *       404:
+ *         description: Tab not found
+ *   post:
+ *     summary: Write items to localStorage or sessionStorage of the tab's current origin
+ *     description: Fails when the tab has not navigated to an origin yet (about:blank).
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               type:
+ *                 type: string
+ *                 enum: [local, session]
+ *               items:
+ *                 type: object
+ *                 additionalProperties:
+ *                   type: string
+ *     responses:
+ *       200:
+ *         description: Number of items written
+ *       400:
+ *         description: Invalid storage type or items
+ *       404:
+ *         description: Tab not found
+ *   delete:
+ *     summary: Remove the given keys, or all items, from the tab's storage
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               type:
+ *                 type: string
+ *                 enum: [local, session]
+ *               keys:
+ *                 type: array
+ *                 items:
+ *                   type: string
+ *     responses:
+ *       200:
+ *         description: Number of items removed
+ *       404:
+ *         description: Tab not found
+ */
+router.get('/storage/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const type = parseStorageType(req.query['type']);
+    const { key } = req.query;
+
+    if (!tabId || !type) {
+      return res.status(400).json({
+        success: false,
+        error: `Tab ID is required and type must be one of: ${STORAGE_TYPES.join(', ')}`
+      });
+    }
+
+    const keys = (Array.isArray(key) ? key : key ? [key] : []).map(String);
+    const result = await browserManager.getStorage(tabId, type, keys);
+
+    const response: ApiResponse<StorageResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+router.post('/storage/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const type = parseStorageType(req.body?.type);
+    const items: Record<string, string> = req.body?.items;
+
+    if (!tabId || !type) {
+      return res.status(400).json({
+        success: false,
+        error: `Tab ID is required and type must be one of: ${STORAGE_TYPES.join(', ')}`
+      });
+    }
+
+    if (
+      !items ||
+      typeof items !== 'object' ||
+      Array.isArray(items) ||
+      Object.values(items).some(value => typeof value !== 'string')
+    ) {
+      return res.status(400).json({
+        success: false,
+        error: 'items must be an object of string values'
+      });
+    }
+
+    const count = await browserManager.setStorage(tabId, type, items);
+
+    return res.json({ success: true, data: { count } });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+router.delete('/storage/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const type = parseStorageType(req.body?.type);
+    const keys: string[] = req.body?.keys ?? [];
+
+    if (!tabId || !type) {
+      return res.status(400).json({
+        success: false,
+        error: `Tab ID is required and type must be one of: ${STORAGE_TYPES.join(', ')}`
+      });
+    }
+
+    if (!Array.isArray(keys)) {
+      return res.status(400).json({
+        success: false,
+        error: 'keys must be an array'
+      });
+    }
+
+    const removed = await browserManager.clearStorage(tabId, type, keys.map(String));
+
+    return res.json({ success: true, data: { removed } });
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/emulateDevice/{tabId}:
+ *   post:
+ *     summary: Emulate a preset or custom device
+ *     description: >
+ *       Sets viewport, device scale factor, mobile mode, touch support and user agent together.
+ *       Fields given alongside a preset override it; without a preset width and height are
+ *       required.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               device:
+ *                 type: string
+ *                 description: Preset name, e.g. iPhone 14, Pixel 7 or iPad
+ *               landscape:
+ *                 type: boolean
+ *               width:
+ *                 type: number
+ *               height:
+ *                 type: number
+ *               deviceScaleFactor:
+ *                 type: number
+ *               isMobile:
+ *                 type: boolean
+ *               hasTouch:
+ *                 type: boolean
+ *               userAgent:
+ *                 type: string
+ *     responses:
+ *       200:
+ *         description: The applied device profile
+ *       400:
+ *         description: Unknown device or missing dimensions
+ *       404:
+ *         description: Tab not found
+ */
+router.post('/emulateDevice/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: EmulateDeviceRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    let device: EmulatedDevice;
+    try {
+      device = resolveDevice(request);
+    } catch (error) {
+      return res.status(400).json({
+        success: false,
+        error: error instanceof Error ? error.message : String(error)
+      });
+    }
+
+    await browserManager.emulateDevice(tabId, request);
+
+    const response: ApiResponse<EmulatedDevice> = {
+      success: true,
+      data: device
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/viewport/{tabId}:
+ *   post:
+ *     summary: Resize the viewport without emulating a device
+ *     description: >
+ *       Takes effect right away and lasts for the tab until changed. deviceScaleFactor and
+ *       isMobile keep their current values unless given, touch and orientation are kept.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [width, height]
+ *             properties:
+ *               width:
+ *                 type: integer
+ *                 maximum: 16384
+ *               height:
+ *                 type: integer
+ *                 maximum: 16384
+ *               deviceScaleFactor:
+ *                 type: number
+ *               isMobile:
+ *                 type: boolean
+ *     responses:
+ *       200:
+ *         description: The viewport now in effect
+ *       400:
+ *         description: Dimensions out of range
+ *       404:
+ *         description: Tab not found
+ */
+router.post('/viewport/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: SetViewportRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const viewport = await browserManager.setViewport(tabId, request);
+
+    const response: ApiResponse<Viewport> = {
+      success: true,
+      data: viewport
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/geolocation/{tabId}:
+ *   post:
+ *     summary: Override geolocation and grant the permission to the origin under test
+ *     description: >
+ *       The permission goes to the tab's current origin unless origin is given, so the tab
+ *       must have navigated to the site first.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [latitude, longitude]
+ *             properties:
+ *               latitude:
+ *                 type: number
+ *               longitude:
+ *                 type: number
+ *               accuracy:
+ *                 type: number
+ *                 description: Meters
+ *               origin:
+ *                 type: string
+ *     responses:
+ *       200:
+ *         description: Geolocation set
+ *       400:
+ *         description: Coordinates out of range
+ *       404:
+ *         description: Tab not found
+ */
+router.post('/geolocation/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const request: GeolocationRequest = req.body ?? {};
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    let validationError: string | null = null;
+    try {
+      validateGeolocation(request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
+        success: false,
+        error: validationError
+      });
+    }
+
+    const result = await browserManager.setGeolocation(tabId, request);
+
+    const response: ApiResponse<GeolocationResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+/**
+ * @swagger
+ * /api/tabs/clipboard/{tabId}:
+ *   get:
+ *     summary: Read the text on the clipboard
+ *     description: >
+ *       Grants clipboard access to the tab's current origin only, which must be a secure
+ *       page (HTTPS or localhost). The old headless shell has no clipboard.
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     responses:
+ *       200:
+ *         description: The origin granted access and the clipboard text
+ *         content:
+ *           application/json:
+ *             schema:
+ *               type: object
+ *               properties:
+ *                 success:
+ *                   type: boolean
+ *                 data:
+ *                   type: object
+ *                   properties:
+ *                     origin:
+ *                       type: string
+ *                     text:
+ *                       type: string
+ *       404:
+ *         description: Tab not found
+ *   post:
+ *     summary: Put text on the clipboard
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [text]
+ *             properties:
+ *               text:
+ *                 type: string
+ *     responses:
+ *       200:
+ *         description: Text written
+ *       400:
+ *         description: Missing text
+ *       404:
+ *         description: Tab not found
+ */
+router.get('/clipboard/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
+
+    const result = await browserManager.readClipboard(tabId);
+
+    const response: ApiResponse<ClipboardResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+router.post('/clipboard/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const { text } = req.body ?? {};
+
+    if (typeof text !== 'string') {
+      return res.status(400).json({
+        success: false,
+        error: 'text is required'
+      });
+    }
+
+    if (!tabId) {
+      return res.status(400).json({
+        success: false,
+        error: 'Tab ID is required'
+      });
+    }
 
-    return res.json({ success: true });
+    const result = await browserManager.writeClipboard(tabId, text);
+
+    const response: ApiResponse<ClipboardResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
   } catch (error) {
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/bringToFront/{tabId}:
+ * /api/tabs/timezone/{tabId}:
  *   post:
- *     summary: Bring tab to front
+ *     summary: Override the timezone of the tab
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -703,13 +4707,27 @@ router.delete('/cleanBrowserData', async (_req: Request, res: Response) => {
  *         required: true
  *         schema:
  *           type: string
+ *     requestBody:
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               timezoneId:
+ *                 type: string
+ *                 description: IANA timezone ID, omit to restore the system timezone
  *     responses:
  *       200:
- *         description: Tab brought to front successfully
+ *         description: Timezone set
+ *       400:
+ *         description: Unknown timezone
+ *       404:
+ *         description: Tab not found
  */
-router.post('/bringToFront/:tabId', async (req: Request, res: Response) => {
+router.post('/timezone/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
+    const timezoneId: string | undefined = req.body?.timezoneId;
 
     if (!tabId) {
       return res.status(400).json({
@@ -718,29 +4736,29 @@ router.post('/bringToFront/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    await browserManager.bringToFront(tabId);
-
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
+    if (
+      timezoneId !== undefined &&
+      (typeof timezoneId !== 'string' || !isValidTimezone(timezoneId))
+    ) {
+      return res.status(400).json({
         success: false,
-        error: error.message
+        error: `Invalid timezone ${timezoneId}, expected an IANA ID like Europe/Berlin`
       });
     }
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    await browserManager.setTimezone(tabId, timezoneId);
+
+    return res.json({ success: true, data: { timezoneId: timezoneId ?? null } });
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/focus/{tabId}:
+ * /api/tabs/locale/{tabId}:
  *   post:
- *     summary: Focus element in tab
+ *     summary: Override navigator.language, Intl locale and Accept-Language of the tab
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -749,60 +4767,68 @@ router.post('/bringToFront/:tabId', async (req: Request, res: Response) => {
  *         schema:
  *           type: string
  *     requestBody:
- *       required: true
  *       content:
  *         application/json:
  *           schema:
  *             type: object
  *             properties:
- *               selector:
+ *               locale:
  *                 type: string
+ *                 description: BCP 47 tag such as de-DE, omit to remove the override
  *     responses:
  *       200:
- *         description: Element focused successfully
+ *         description: Applied locale and Accept-Language header
+ *       400:
+ *         description: Malformed locale
+ *       404:
+ *         description: Tab not found
  */
-router.post('/focus/:tabId', async (req: Request, res: Response) => {
+router.post('/locale/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: FocusRequest = req.body;
+    const locale: string | undefined = req.body?.locale;
 
-    if (!request.selector) {
+    if (!tabId) {
       return res.status(400).json({
         success: false,
-        error: 'Selector is required'
+        error: 'Tab ID is required'
       });
     }
 
-    if (!tabId) {
+    let validationError: string | null = null;
+    try {
+      if (locale) canonicalLocale(String(locale));
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
       return res.status(400).json({
         success: false,
-        error: 'Tab ID is required'
+        error: validationError
       });
     }
 
-    await browserManager.focusElement(tabId, request.selector);
+    const result = await browserManager.setLocale(tabId, locale);
 
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
+    const response: ApiResponse<LocaleResult> = {
+      success: true,
+      data: result
+    };
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/goBack/{tabId}:
+ * /api/tabs/media/{tabId}:
  *   post:
- *     summary: Navigate back in browser history
+ *     summary: Emulate the color scheme, reduced motion or media type of the tab
+ *     description: >
+ *       Fields left out keep their current emulation, null removes it. PDFs of the tab keep the
+ *       color scheme and reduced motion and use print media unless emulatePrintMedia is false.
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -810,13 +4836,38 @@ router.post('/focus/:tabId', async (req: Request, res: Response) => {
  *         required: true
  *         schema:
  *           type: string
+ *     requestBody:
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               colorScheme:
+ *                 type: string
+ *                 enum: [light, dark, no-preference]
+ *                 nullable: true
+ *                 description: prefers-color-scheme
+ *               reducedMotion:
+ *                 type: string
+ *                 enum: [reduce, no-preference]
+ *                 nullable: true
+ *                 description: prefers-reduced-motion
+ *               mediaType:
+ *                 type: string
+ *                 enum: [screen, print]
+ *                 nullable: true
  *     responses:
  *       200:
- *         description: Navigated back successfully
+ *         description: The media emulation now in effect, null where the browser's own applies
+ *       400:
+ *         description: Invalid value
+ *       404:
+ *         description: Tab not found
  */
-router.post('/goBack/:tabId', async (req: Request, res: Response) => {
+router.post('/media/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
+    const request: EmulateMediaRequest = req.body ?? {};
 
     if (!tabId) {
       return res.status(400).json({
@@ -825,29 +4876,40 @@ router.post('/goBack/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    await browserManager.goBack(tabId);
-
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
+    let validationError: string | null = null;
+    try {
+      resolveMediaEmulation(NO_MEDIA_EMULATION, request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
         success: false,
-        error: error.message
+        error: validationError
       });
     }
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    const result = await browserManager.emulateMedia(tabId, request);
+
+    const response: ApiResponse<MediaEmulation> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/goForward/{tabId}:
+ * /api/tabs/visionDeficiency/{tabId}:
  *   post:
- *     summary: Navigate forward in browser history
+ *     summary: Render the tab as people with a vision deficiency see it
+ *     description: >
+ *       Screenshots of the tab show the emulated deficiency. none removes the emulation, which
+ *       also ends when the tab or its session closes.
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -855,11 +4917,26 @@ router.post('/goBack/:tabId', async (req: Request, res: Response) => {
  *         required: true
  *         schema:
  *           type: string
+ *     requestBody:
+ *       required: true
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             required: [type]
+ *             properties:
+ *               type:
+ *                 type: string
+ *                 enum: [none, blurredVision, reducedContrast, achromatopsia, deuteranopia, protanopia, tritanopia]
  *     responses:
  *       200:
- *         description: Navigated forward successfully
+ *         description: The vision deficiency now emulated
+ *       400:
+ *         description: Unknown vision deficiency
+ *       404:
+ *         description: Tab not found
  */
-router.post('/goForward/:tabId', async (req: Request, res: Response) => {
+router.post('/visionDeficiency/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
 
@@ -870,29 +4947,27 @@ router.post('/goForward/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    await browserManager.goForward(tabId);
+    const result = await browserManager.emulateVisionDeficiency(tabId, req.body?.type);
 
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
+    const response: ApiResponse<{ visionDeficiency: VisionDeficiency }> = {
+      success: true,
+      data: result
+    };
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/reload/{tabId}:
+ * /api/tabs/networkConditions/{tabId}:
  *   post:
- *     summary: Reload tab
+ *     summary: Throttle the connection of the tab or take it offline
+ *     description: >
+ *       Values override the preset's. Without a preset or values the throttling is removed.
+ *       While throttled the default navigation timeout of the tab is four times longer.
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -901,22 +4976,35 @@ router.post('/goForward/:tabId', async (req: Request, res: Response) => {
  *         schema:
  *           type: string
  *     requestBody:
- *       required: false
  *       content:
  *         application/json:
  *           schema:
  *             type: object
  *             properties:
- *               waitUntil:
+ *               preset:
  *                 type: string
+ *                 enum: [slow-3g, fast-3g, offline]
+ *               downloadKbps:
+ *                 type: number
+ *               uploadKbps:
+ *                 type: number
+ *               latency:
+ *                 type: number
+ *                 description: Milliseconds added to every request
+ *               offline:
+ *                 type: boolean
  *     responses:
  *       200:
- *         description: Tab reloaded successfully
+ *         description: Applied conditions and the navigation timeout
+ *       400:
+ *         description: Unknown preset or invalid values
+ *       404:
+ *         description: Tab not found
  */
-router.post('/reload/:tabId', async (req: Request, res: Response) => {
+router.post('/networkConditions/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: ReloadRequest = req.body;
+    const request: NetworkConditionsRequest = req.body ?? {};
 
     if (!tabId) {
       return res.status(400).json({
@@ -925,29 +5013,52 @@ router.post('/reload/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    await browserManager.reloadTab(tabId, request.waitUntil);
-
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
+    let validationError: string | null = null;
+    try {
+      resolveNetworkConditions(request);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
         success: false,
-        error: error.message
+        error: validationError
       });
     }
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    const result = await browserManager.setNetworkConditions(tabId, request);
+
+    const response: ApiResponse<NetworkConditionsResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/waitForSelector/{tabId}:
+ * /api/tabs/cpuThrottling/{tabId}:
+ *   get:
+ *     summary: Get the CPU slowdown rate of the tab
+ *     tags: [Tabs]
+ *     parameters:
+ *       - in: path
+ *         name: tabId
+ *         required: true
+ *         schema:
+ *           type: string
+ *     responses:
+ *       200:
+ *         description: Current rate, 1 when not throttled
+ *       404:
+ *         description: Tab not found
  *   post:
- *     summary: Wait for selector to appear in tab
+ *     summary: Slow down the CPU of the tab to simulate a slower device
+ *     description: The throttling ends when the tab or its session closes.
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -961,29 +5072,47 @@ router.post('/reload/:tabId', async (req: Request, res: Response) => {
  *         application/json:
  *           schema:
  *             type: object
+ *             required: [rate]
  *             properties:
- *               selector:
- *                 type: string
- *               timeout:
+ *               rate:
  *                 type: number
- *               visible:
- *                 type: boolean
+ *                 minimum: 1
+ *                 description: Slowdown factor, e.g. 4 or 6; 1 restores full speed
  *     responses:
  *       200:
- *         description: Selector found successfully
+ *         description: Applied rate
+ *       400:
+ *         description: Rate below 1
+ *       404:
+ *         description: Tab not found
  */
-router.post('/waitForSelector/:tabId', async (req: Request, res: Response) => {
+router.get('/cpuThrottling/:tabId', (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: WaitForSelectorRequest = req.body;
 
-    if (!request.selector) {
+    if (!tabId) {
       return res.status(400).json({
         success: false,
-        error: 'Selector is required'
+        error: 'Tab ID is required'
       });
     }
 
+    const response: ApiResponse<CpuThrottlingResult> = {
+      success: true,
+      data: browserManager.getCpuThrottling(tabId)
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
+  }
+});
+
+router.post('/cpuThrottling/:tabId', async (req: Request, res: Response) => {
+  try {
+    const { tabId } = req.params;
+    const rate = req.body?.rate;
+
     if (!tabId) {
       return res.status(400).json({
         success: false,
@@ -991,32 +5120,40 @@ router.post('/waitForSelector/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    await browserManager.waitForSelector(tabId, request.selector, {
-      timeout: request.timeout ?? 30000,
-      visible: request.visible ?? false
-    });
-
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
+    let validationError: string | null = null;
+    try {
+      validateCpuThrottlingRate(rate);
+    } catch (error) {
+      validationError = error instanceof Error ? error.message : String(error);
+    }
+    if (validationError) {
+      return res.status(400).json({
         success: false,
-        error: error.message
+        error: validationError
       });
     }
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    const result = await browserManager.setCpuThrottling(tabId, rate);
+
+    const response: ApiResponse<CpuThrottlingResult> = {
+      success: true,
+      data: result
+    };
+
+    return res.json(response);
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/waitForFunction/{tabId}:
+ * /api/tabs/cdp/{tabId}:
  *   post:
- *     summary: Wait for function to return truthy value
+ *     summary: Send a raw Chrome DevTools Protocol command to the tab
+ *     description: >
+ *       Returns the protocol result as is. Only available when the server runs with
+ *       --enable-raw-cdp, since commands are not checked.
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -1030,59 +5167,52 @@ router.post('/waitForSelector/:tabId', async (req: Request, res: Response) => {
  *         application/json:
  *           schema:
  *             type: object
+ *             required: [method]
  *             properties:
- *               functionScript:
+ *               method:
  *                 type: string
- *               timeout:
- *                 type: number
+ *                 description: Domain.command, e.g. Animation.setPlaybackRate
+ *               params:
+ *                 type: object
  *     responses:
  *       200:
- *         description: Function returned truthy value
+ *         description: Result of the command
+ *       400:
+ *         description: Malformed method or params
+ *       404:
+ *         description: Tab not found, or raw CDP is not enabled
  */
-router.post('/waitForFunction/:tabId', async (req: Request, res: Response) => {
-  try {
-    const { tabId } = req.params;
-    const request: WaitForFunctionRequest = req.body;
+if (isRawCdpEnabled()) {
+  router.post('/cdp/:tabId', async (req: Request, res: Response) => {
+    try {
+      const { tabId } = req.params;
 
-    if (!request.functionScript) {
-      return res.status(400).json({
-        success: false,
-        error: 'Function script is required'
-      });
-    }
+      if (!tabId) {
+        return res.status(400).json({
+          success: false,
+          error: 'Tab ID is required'
+        });
+      }
 
-    if (!tabId) {
-      return res.status(400).json({
-        success: false,
-        error: 'Tab ID is required'
-      });
-    }
+      const result = await browserManager.sendCdpCommand(tabId, req.body ?? {});
 
-    await browserManager.waitForFunction(tabId, request.functionScript, {
-      timeout: request.timeout ?? 30000
-    });
+      const response: ApiResponse<CdpCommandResult> = {
+        success: true,
+        data: result
+      };
 
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
+      return res.json(response);
+    } catch (error) {
+      return sendError(res, error);
     }
-
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
-  }
-});
+  });
+}
 
 /**
  * @swagger
- * /api/tabs/waitForNavigation/{tabId}:
+ * /api/tabs/startCoverage/{tabId}:
  *   post:
- *     summary: Wait for navigation to complete
+ *     summary: Start recording which JavaScript and CSS the tab uses
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -1091,24 +5221,33 @@ router.post('/waitForFunction/:tabId', async (req: Request, res: Response) => {
  *         schema:
  *           type: string
  *     requestBody:
- *       required: false
  *       content:
  *         application/json:
  *           schema:
  *             type: object
  *             properties:
- *               timeout:
- *                 type: number
- *               waitUntil:
- *                 type: string
+ *               js:
+ *                 type: boolean
+ *                 default: true
+ *               css:
+ *                 type: boolean
+ *                 default: true
+ *               resetOnNavigation:
+ *                 type: boolean
+ *                 default: true
+ *                 description: Only count the page loaded last
  *     responses:
  *       200:
- *         description: Navigation completed successfully
+ *         description: Coverage started
+ *       400:
+ *         description: Neither js nor css enabled
+ *       404:
+ *         description: Tab not found
  */
-router.post('/waitForNavigation/:tabId', async (req: Request, res: Response) => {
+router.post('/startCoverage/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
-    const request: WaitForNavigationRequest = req.body;
+    const request: StartCoverageRequest = req.body ?? {};
 
     if (!tabId) {
       return res.status(400).json({
@@ -1117,32 +5256,26 @@ router.post('/waitForNavigation/:tabId', async (req: Request, res: Response) =>
       });
     }
 
-    await browserManager.waitForNavigation(tabId, {
-      timeout: request.timeout ?? 30000,
-      waitUntil: request.waitUntil ?? 'load'
-    });
-
-    return res.json({ success: true });
-  } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
+    if (request.js === false && request.css === false) {
+      return res.status(400).json({
         success: false,
-        error: error.message
+        error: 'Enable js, css or both to collect coverage'
       });
     }
 
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    const result = await browserManager.startCoverage(tabId, request);
+
+    return res.json({ success: true, data: result });
+  } catch (error) {
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/url/{tabId}:
- *   get:
- *     summary: Get current URL of tab
+ * /api/tabs/stopCoverage/{tabId}:
+ *   post:
+ *     summary: Stop recording coverage and report used and unused bytes per file
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -1150,9 +5283,18 @@ router.post('/waitForNavigation/:tabId', async (req: Request, res: Response) =>
  *         required: true
  *         schema:
  *           type: string
+ *     requestBody:
+ *       content:
+ *         application/json:
+ *           schema:
+ *             type: object
+ *             properties:
+ *               detailed:
+ *                 type: boolean
+ *                 description: Include the used and unused ranges of every file
  *     responses:
  *       200:
- *         description: URL retrieved successfully
+ *         description: JavaScript and CSS totals and files, most unused bytes first
  *         content:
  *           application/json:
  *             schema:
@@ -1163,10 +5305,20 @@ router.post('/waitForNavigation/:tabId', async (req: Request, res: Response) =>
  *                 data:
  *                   type: object
  *                   properties:
- *                     url:
- *                       type: string
+ *                     js:
+ *                       type: object
+ *                       nullable: true
+ *                     css:
+ *                       type: object
+ *                       nullable: true
+ *                     files:
+ *                       type: array
+ *                       items:
+ *                         type: object
+ *       404:
+ *         description: Tab not found
  */
-router.get('/url/:tabId', async (req: Request, res: Response) => {
+router.post('/stopCoverage/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
 
@@ -1177,34 +5329,28 @@ router.get('/url/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    const url = await browserManager.getTabUrl(tabId);
+    const result = await browserManager.stopCoverage(tabId, req.body?.detailed === true);
 
-    const response: ApiResponse<{ url: string }> = {
+    const response: ApiResponse<CoverageResult> = {
       success: true,
-      data: { url }
+      data: result
     };
 
     return res.json(response);
   } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
-
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return sendError(res, error);
   }
 });
 
 /**
  * @swagger
- * /api/tabs/html/{tabId}:
+ * /api/tabs/metrics/{tabId}:
  *   get:
- *     summary: Get current HTML content of tab
+ *     summary: Get Core Web Vitals and runtime metrics
+ *     description: >
+ *       Returns TTFB, FCP, LCP and CLS of the current document from the browser's performance
+ *       entries, plus Chrome's runtime counters such as JSHeapUsedSize and LayoutCount. LCP and
+ *       CLS keep changing while the page loads, so call it once the page has settled.
  *     tags: [Tabs]
  *     parameters:
  *       - in: path
@@ -1212,9 +5358,10 @@ router.get('/url/:tabId', async (req: Request, res: Response) => {
  *         required: true
  *         schema:
  *           type: string
+ *         description: Tab ID
  *     responses:
  *       200:
- *         description: HTML retrieved successfully
+ *         description: Metrics of the current document, durations in milliseconds
  *         content:
  *           application/json:
  *             schema:
@@ -1225,10 +5372,38 @@ router.get('/url/:tabId', async (req: Request, res: Response) => {
  *                 data:
  *                   type: object
  *                   properties:
- *                     html:
+ *                     url:
  *                       type: string
+ *                     vitals:
+ *                       type: object
+ *                       description: Null when the browser did not report the value
+ *                       properties:
+ *                         ttfb:
+ *                           type: number
+ *                           nullable: true
+ *                         fcp:
+ *                           type: number
+ *                           nullable: true
+ *                         lcp:
+ *                           type: number
+ *                           nullable: true
+ *                         cls:
+ *                           type: number
+ *                           nullable: true
+ *                         domContentLoaded:
+ *                           type: number
+ *                           nullable: true
+ *                         load:
+ *                           type: number
+ *                           nullable: true
+ *                     runtime:
+ *                       type: object
+ *                       additionalProperties:
+ *                         type: number
+ *       404:
+ *         description: Tab not found
  */
-router.get('/html/:tabId', async (req: Request, res: Response) => {
+router.get('/metrics/:tabId', async (req: Request, res: Response) => {
   try {
     const { tabId } = req.params;
 
@@ -1239,26 +5414,16 @@ router.get('/html/:tabId', async (req: Request, res: Response) => {
       });
     }
 
-    const html = await browserManager.getTabHtml(tabId);
+    const result = await browserManager.getMetrics(tabId);
 
-    const response: ApiResponse<{ html: string }> = {
+    const response: ApiResponse<MetricsResult> = {
       success: true,
-      data: { html }
+      data: result
     };
 
     return res.json(response);
   } catch (error) {
-    if (error instanceof TabNotFoundError) {
-      return res.status(404).json({
-        success: false,
-        error: error.message
-      });
-    }
-
-    return res.status(500).json({
-      success: false,
-      error: error instanceof Error ? error.message : 'Unknown error'
-    });
+    return sendError(res, error);
   }
 });
 
@@ -1,21 +1,54 @@
 import crypto from 'node:crypto';
+import type { Server } from 'node:http';
 import path from 'node:path';
 import cors from 'cors';
 import express from 'express';
 import { statelessHandler } from 'express-mcp-handler';
+import { SSEServerTransport } from '@modelcontextprotocol/sdk/server/sse.js';
+import { StdioServerTransport } from '@modelcontextprotocol/sdk/server/stdio.js';
 import helmet from 'helmet';
 // import morgan from 'morgan';
 import swaggerJsdoc from 'swagger-jsdoc';
 import swaggerUi from 'swagger-ui-express';
 import { createAuthMiddleware } from './auth/index.js';
-import { loadConfig } from './config/index.js';
+import { BrowserManagerSingleton } from './browser/BrowserManager.js';
+import { toToolError } from './browser/errors.js';
+import {
+  getAuthToken,
+  getCdpEndpoint,
+  getChromePath,
+  getDefaultViewport,
+  getExtensions,
+  getHeadless,
+  getHost,
+  getIgnoreHttpsErrors,
+  getLaunchArgs,
+  getPort,
+  getProxy,
+  getShutdownTimeout,
+  getTransport,
+  getUserDataDir,
+  isLoopbackHost,
+  loadConfig,
+  VERSION
+} from './config/index.js';
+import { logger, logOperation } from './logger/index.js';
 import { initializeMcpServer } from './mcp/index.js';
+import { recordOperation, renderMetrics } from './metrics/index.js';
 import { initializeTabsRoutes, tabsRouter } from './routes/tabs.js';
 import { resourcesRouter } from './routes/resources.js';
+import { initializeSessionsRoutes, sessionsRouter } from './routes/sessions.js';
+import type { ToolError } from './types/index.js';
 import createDebug from 'debug';
 
 const debug = createDebug('pcs:server');
 
+// Report the version and exit before starting any servers (the launcher forwards this)
+if (process.argv.includes('--version')) {
+  console.log(`pcs ${VERSION}`);
+  process.exit(0);
+}
+
 // JWT verification script for /jwt-verify endpoint
 const JWT_VERIFY_SCRIPT = `
 import { createRemoteJWKSet, jwtVerify } from '/jose/index.js';
@@ -85,12 +118,25 @@ app.use(
 
 // Load configuration
 const config = loadConfig();
+config.port = getPort(config);
+const transport = getTransport();
+const cdpEndpoint = getCdpEndpoint(config);
+const proxy = getProxy(config);
+const chromePath = getChromePath(config);
+const launch = {
+  args: getLaunchArgs(config),
+  headless: getHeadless(config),
+  userDataDir: getUserDataDir(config),
+  extensions: getExtensions(config),
+  viewport: getDefaultViewport(),
+  ignoreHTTPSErrors: getIgnoreHttpsErrors(config)
+};
 
 // Create authentication middleware
 const authenticate = createAuthMiddleware(config);
 
-// Middleware
-app.use(express.json());
+// Middleware, with room for base64 uploads and baseline screenshots in JSON bodies
+app.use(express.json({ limit: '25mb' }));
 app.use(express.urlencoded({ extended: true }));
 
 const baseApiSearchPath = path.resolve(__dirname, '../src/routes');
@@ -101,7 +147,7 @@ const swaggerSpec = swaggerJsdoc({
     openapi: '3.0.0',
     info: {
       title: 'Puppeteer Command Server',
-      version: '1.0.0',
+      version: VERSION,
       description: 'Browser automation server with HTTP and MCP endpoints'
     },
     servers: [
@@ -140,6 +186,10 @@ const swaggerSpec = swaggerJsdoc({
         name: 'Tabs',
         description: 'Browser tab operations'
       },
+      {
+        name: 'Sessions',
+        description: 'Persistent browser sessions'
+      },
       {
         name: 'Resources',
         description: 'Screenshot resource management'
@@ -181,15 +231,56 @@ app.get('/jwt-verify', (_req, res) => {
 });
 
 // Initialize browser manager and routes
-initializeTabsRoutes(config.chromePath);
+initializeTabsRoutes(chromePath, cdpEndpoint, proxy, launch);
+initializeSessionsRoutes(chromePath, cdpEndpoint, proxy, launch);
+
+const browserManager = BrowserManagerSingleton(chromePath, cdpEndpoint, proxy, launch);
+
+// Counts every browser operation by route template, the IDs in the path stay out of the labels
+const recordRequest: express.RequestHandler = (req, res, next) => {
+  const startedAt = Date.now();
+  res.on('finish', () => {
+    const route = `${req.method} ${req.baseUrl}${req.route?.path ?? ''}`;
+    let failure: ToolError | null = res.locals['error'] ?? null;
+    if (!failure && res.statusCode >= 400) {
+      const code = res.statusCode === 400 ? 'INVALID_ARGUMENT' : 'INTERNAL_ERROR';
+      failure = { code, message: `HTTP ${res.statusCode}` };
+    }
+    recordOperation(route, startedAt, failure?.code ?? null);
+    const sessionId = browserManager.resolveSessionId({ ...req.body, ...req.params });
+    logOperation(route, startedAt, sessionId, failure);
+  });
+  next();
+};
+
+// Holds one browser operation slot for the whole request, see PCS_MAX_CONCURRENCY
+const queueOperation: express.RequestHandler = async (req, res, next) => {
+  let release: () => void;
+  try {
+    release = await browserManager.acquireOperationSlot(`${req.method} ${req.originalUrl}`);
+  } catch (error) {
+    const { code, message } = toToolError(error);
+    res.locals['error'] = { code, message };
+    res.status(503).json({ success: false, error: message, code });
+    return;
+  }
+  res.on('close', release);
+  next();
+};
 
 // API routes with authentication
-app.use('/api/tabs', authenticate, tabsRouter);
+app.use('/api/tabs', authenticate, recordRequest, queueOperation, tabsRouter);
+app.use('/api/sessions', authenticate, recordRequest, queueOperation, sessionsRouter);
 app.use('/api/resources', authenticate, resourcesRouter);
 
 // MCP server setup
-const mcpServerFactory = () => initializeMcpServer(config.chromePath);
-mcpServerFactory(); // Pre-initialize MCP server for STDIO transport
+const mcpServerFactory = () => initializeMcpServer(chromePath, cdpEndpoint, proxy, launch);
+
+// Every transport gets its own server over the same tools and browser manager
+if (transport === 'stdio') {
+  mcpServerFactory().connect(new StdioServerTransport());
+  logger.info('MCP server listening', { transport: 'stdio' });
+}
 
 // Apply authentication to MCP endpoints
 app.post(
@@ -205,15 +296,59 @@ app.post(
   })
 );
 
+// Legacy HTTP+SSE transport for clients that predate streamable HTTP: the event stream at
+// /sse announces where to post messages, keyed by the connection's session id
+const sseTransports = new Map<string, SSEServerTransport>();
+
+app.get('/sse', authenticate, async (_req, res) => {
+  const sseTransport = new SSEServerTransport('/messages', res);
+  sseTransports.set(sseTransport.sessionId, sseTransport);
+  res.on('close', () => {
+    sseTransports.delete(sseTransport.sessionId);
+    debug('[MCP] SSE connection %s closed.', sseTransport.sessionId);
+  });
+  try {
+    await mcpServerFactory().connect(sseTransport);
+  } catch (error) {
+    debug('[MCP] Error: %O', error);
+  }
+});
+
+app.post('/messages', authenticate, async (req, res) => {
+  const sseTransport = sseTransports.get(String(req.query['sessionId']));
+  if (!sseTransport) {
+    res.status(404).json({
+      success: false,
+      error: 'MCP session not found, connect to /sse first',
+      code: 'NOT_FOUND'
+    });
+    return;
+  }
+  await sseTransport.handlePostMessage(req, res, req.body);
+});
+
 // Health check endpoint
 app.get('/health', (_req, res) => {
   res.json({
     status: 'healthy',
     timestamp: new Date().toISOString(),
-    version: '1.0.0'
+    version: VERSION,
+    queue: browserManager.getStatus().queue
   });
 });
 
+// Prometheus metrics, authenticated like the API
+app.get('/metrics', authenticate, (_req, res) => {
+  res.type('text/plain; version=0.0.4').send(renderMetrics(browserManager.getStatus()));
+});
+
+// Liveness for orchestrators: 503 while a browser is disconnected, so the server gets restarted
+app.get('/healthz', (_req, res) => {
+  const status = browserManager.getStatus();
+  // Session IDs grant access to their pages, so they stay behind authentication
+  res.status(status.healthy ? 200 : 503).json({ ...status, idleSessions: undefined });
+});
+
 // Error handling middleware
 app.use(
   (error: Error, _req: express.Request, res: express.Response, _next: express.NextFunction) => {
@@ -236,17 +371,56 @@ app.use('*', (_req, res) => {
   });
 });
 
+let httpServer: Server | null = null;
+
 const noHttp = process.argv.includes('--no-http');
+// Without a token only this machine may connect, unless the caller opts out explicitly
+const insecure = process.argv.includes('--insecure');
+const authToken = getAuthToken(config);
+const host = getHost(config) ?? (authToken || insecure ? '0.0.0.0' : '127.0.0.1');
 
-if (noHttp) {
+if (noHttp && transport === 'http') {
+  logger.error('--no-http leaves no way to reach the MCP server with --transport http');
+  process.exit(1);
+} else if (noHttp) {
   debug('⚠️  HTTP server is disabled (--no-http flag set)');
+} else if (!authToken && !insecure && !isLoopbackHost(host)) {
+  logger.error('refusing to listen without PCS_AUTH_TOKEN, set one or pass --insecure', { host });
+  process.exit(1);
 } else {
-  app.listen(config.port, '0.0.0.0', () => {
-    debug(`🚀 Puppeteer Command Server running on port ${config.port}`);
+  httpServer = app.listen(config.port, host, () => {
+    logger.info('HTTP server listening', { host, port: config.port, version: VERSION });
+    debug(`🚀 Puppeteer Command Server running on ${host}:${config.port}`);
     debug(`📚 API Documentation: http://localhost:${config.port}/docs`);
     debug(`🔧 MCP Endpoint: http://localhost:${config.port}/mcp`);
+    debug(`📡 MCP SSE Endpoint: http://localhost:${config.port}/sse`);
     debug('🔑 API Key generated and saved to .secret');
   });
 }
 
+// Stops taking requests, lets in-flight operations finish and closes the browsers cleanly,
+// since a Chrome killed outright can leave its profile locked. A second signal exits at once.
+let shuttingDown = false;
+const shutdown = async (signal: NodeJS.Signals) => {
+  if (shuttingDown) {
+    logger.warn('received signal again, exiting without closing the browsers', { signal });
+    process.exit(1);
+  }
+  shuttingDown = true;
+  logger.info('shutting down', { signal });
+  httpServer?.close();
+  httpServer?.closeIdleConnections();
+  try {
+    await browserManager.shutdown(getShutdownTimeout());
+  } catch (error) {
+    logger.error('shutdown failed', { error });
+  }
+  logger.info('shut down');
+  process.exit(0);
+};
+
+for (const signal of ['SIGINT', 'SIGTERM', 'SIGHUP'] as const) {
+  process.on(signal, () => void shutdown(signal));
+}
+
 export default app;